@@ -48,10 +48,25 @@ type ConditionAccessor interface {
 // on whether it is a Living or Batch process respectively.
 // +k8s:deepcopy-gen=false
 type ConditionSet struct {
-	happy      ConditionType
+	happy ConditionType
+	// dependents are the terminal subconditions, on which the happy condition
+	// depends directly or through other subconditions.
 	dependents []ConditionType
+
+	// children maps the happy condition and the subconditions with
+	// dependents to their direct dependents, and parents maps the
+	// subconditions to the conditions directly depending on them.
+	children map[ConditionType][]ConditionType
+	parents  map[ConditionType][]ConditionType
+	// order lists the conditions with dependents, each after its
+	// dependents, so that the happy condition comes last.
+	order []ConditionType
 }
 
+// ConditionDependencies maps conditions to the conditions they directly
+// depend on, forming the graph of the conditions of a ConditionSet.
+type ConditionDependencies map[ConditionType][]ConditionType
+
 // ConditionManager allows a resource to operate on its Conditions using higher
 // order operations.
 type ConditionManager interface {
@@ -71,19 +86,21 @@ type ConditionManager interface {
 	// ClearCondition removes the non terminal condition that matches the ConditionType
 	ClearCondition(t ConditionType) error
 
-	// MarkTrue sets the status of t to true, and then marks the happy condition to
-	// true if all dependents are true.
+	// MarkTrue sets the status of t to true, and then marks the happy condition,
+	// and the conditions depending on t, to true if all their dependents are true.
 	MarkTrue(t ConditionType)
 
 	// MarkTrueWithReason sets the status of t to true with the reason, and then marks the happy
 	// condition to true if all dependents are true.
 	MarkTrueWithReason(t ConditionType, reason, messageFormat string, messageA ...interface{})
 
-	// MarkUnknown sets the status of t to Unknown and also sets the happy condition
-	// to Unknown if no other dependent condition is in an error state.
+	// MarkUnknown sets the status of t to Unknown and also sets the conditions
+	// depending on it, up to the happy condition, to Unknown if no other dependent
+	// condition is in an error state.
 	MarkUnknown(t ConditionType, reason, messageFormat string, messageA ...interface{})
 
-	// MarkFalse sets the status of t and the happy condition to False.
+	// MarkFalse sets the status of t and the conditions depending on it, up to
+	// the happy condition, to False.
 	MarkFalse(t ConditionType, reason, messageFormat string, messageA ...interface{})
 
 	// InitializeConditions updates all Conditions in the ConditionSet to Unknown
@@ -105,6 +122,92 @@ func NewBatchConditionSet(d ...ConditionType) ConditionSet {
 	return newConditionSet(ConditionSucceeded, d...)
 }
 
+// NewLivingConditionSetWithDependencies returns a ConditionSet to hold the
+// conditions for the living resource, whose ConditionReady happy condition
+// depends on the graph of conditions.  For example:
+//
+//	NewLivingConditionSetWithDependencies(ConditionDependencies{
+//		ConditionReady: {"NetworkReady", "ConfigReady"},
+//		"NetworkReady": {"IngressReady", "CertificateReady"},
+//	})
+//
+// It panics if the graph is invalid, see ValidateConditionDependencies.
+func NewLivingConditionSetWithDependencies(deps ConditionDependencies) ConditionSet {
+	return mustNewConditionSet(ConditionReady, deps)
+}
+
+// NewBatchConditionSetWithDependencies returns a ConditionSet to hold the
+// conditions for the batch resource, whose ConditionSucceeded happy condition
+// depends on the graph of conditions.
+// It panics if the graph is invalid, see ValidateConditionDependencies.
+func NewBatchConditionSetWithDependencies(deps ConditionDependencies) ConditionSet {
+	return mustNewConditionSet(ConditionSucceeded, deps)
+}
+
+// ValidateConditionDependencies checks that the graph of conditions has no
+// cycle, and that the happy condition depends on all of its conditions.
+func ValidateConditionDependencies(happy ConditionType, deps ConditionDependencies) error {
+	const (
+		visiting = iota + 1
+		visited
+	)
+	state := make(map[ConditionType]int, len(deps))
+	var visit func(path []ConditionType) error
+	visit = func(path []ConditionType) error {
+		t := path[len(path)-1]
+		switch state[t] {
+		case visiting:
+			return fmt.Errorf("condition dependency cycle: %v", cycle(path))
+		case visited:
+			return nil
+		}
+		state[t] = visiting
+		for _, d := range deps[t] {
+			if err := visit(append(path, d)); err != nil {
+				return err
+			}
+		}
+		state[t] = visited
+		return nil
+	}
+	if err := visit([]ConditionType{happy}); err != nil {
+		return err
+	}
+
+	// Report the conditions the happy condition doesn't depend on in a
+	// stable order.
+	var unreachable []string
+	for t := range deps {
+		if state[t] != visited {
+			unreachable = append(unreachable, string(t))
+		}
+	}
+	if len(unreachable) > 0 {
+		sort.Strings(unreachable)
+		return fmt.Errorf("%s does not depend on the conditions %v", happy, unreachable)
+	}
+	return nil
+}
+
+// cycle returns the cycle at the end of the path, from the first occurrence
+// of its last condition.
+func cycle(path []ConditionType) []ConditionType {
+	last := path[len(path)-1]
+	for i, t := range path {
+		if t == last {
+			return path[i:]
+		}
+	}
+	return path
+}
+
+func mustNewConditionSet(happy ConditionType, deps ConditionDependencies) ConditionSet {
+	if err := ValidateConditionDependencies(happy, deps); err != nil {
+		panic(err)
+	}
+	return newConditionSetWithDependencies(happy, deps)
+}
+
 // newConditionSet returns a ConditionSet to hold the conditions that are
 // important for the caller. The first ConditionType is the overarching status
 // for that will be used to signal the resources' status is Ready or Succeeded.
@@ -117,10 +220,52 @@ func newConditionSet(happy ConditionType, dependents ...ConditionType) Condition
 		}
 		deps = append(deps, d)
 	}
-	return ConditionSet{
-		happy:      happy,
-		dependents: deps,
+	return newConditionSetWithDependencies(happy, ConditionDependencies{happy: deps})
+}
+
+// newConditionSetWithDependencies returns a ConditionSet for the valid graph
+// of conditions.
+func newConditionSetWithDependencies(happy ConditionType, deps ConditionDependencies) ConditionSet {
+	r := ConditionSet{
+		happy:    happy,
+		children: make(map[ConditionType][]ConditionType, len(deps)),
+		parents:  make(map[ConditionType][]ConditionType),
+	}
+	seen := map[ConditionType]bool{happy: true}
+	var visit func(t ConditionType)
+	visit = func(t ConditionType) {
+		for _, d := range deps[t] {
+			if contains(r.children[t], d) {
+				continue
+			}
+			r.children[t] = append(r.children[t], d)
+			r.parents[d] = append(r.parents[d], t)
+			if !seen[d] {
+				seen[d] = true
+				r.dependents = append(r.dependents, d)
+				visit(d)
+			}
+		}
+		if len(r.children[t]) > 0 || t == happy {
+			r.order = append(r.order, t)
+		}
+	}
+	visit(happy)
+	return r
+}
+
+// ancestors returns t and the conditions depending on it, directly or
+// through other subconditions, up to the happy condition.
+func (r ConditionSet) ancestors(t ConditionType) []ConditionType {
+	types := []ConditionType{t}
+	for i := 0; i < len(types); i++ {
+		for _, parent := range r.parents[types[i]] {
+			if !contains(types, parent) {
+				types = append(types, parent)
+			}
+		}
 	}
+	return types
 }
 
 func contains(ct []ConditionType, t ConditionType) bool {
@@ -279,30 +424,43 @@ func (r conditionsImpl) MarkTrueWithReason(t ConditionType, reason, messageForma
 	r.recomputeHappiness(t)
 }
 
-// recomputeHappiness marks the happy condition to true if all other dependents are also true.
+// recomputeHappiness marks the conditions depending on t, up to the happy
+// condition, to true if all of their dependents are also true, from the
+// bottom of the graph of conditions up.  The other conditions are left as
+// they were marked.
 func (r conditionsImpl) recomputeHappiness(t ConditionType) {
-	if c := r.findUnhappyDependent(); c != nil {
-		// Propagate unhappy dependent to happy condition.
-		r.SetCondition(Condition{
-			Type:     r.happy,
-			Status:   c.Status,
-			Reason:   c.Reason,
-			Message:  c.Message,
-			Severity: r.severity(r.happy),
-		})
-	} else if t != r.happy {
-		// Set the happy condition to true.
-		r.SetCondition(Condition{
-			Type:     r.happy,
-			Status:   corev1.ConditionTrue,
-			Severity: r.severity(r.happy),
-		})
+	// The happy condition is recomputed even when t is not in the set.
+	affected := append(r.ancestors(t), r.happy)
+	for _, parent := range r.order {
+		if !contains(affected, parent) {
+			continue
+		}
+		if c := r.findUnhappyDependent(parent); c != nil {
+			// Propagate unhappy dependent to the parent condition.
+			r.SetCondition(Condition{
+				Type:     parent,
+				Status:   c.Status,
+				Reason:   c.Reason,
+				Message:  c.Message,
+				Severity: r.severity(parent),
+			})
+		} else if t != parent {
+			// Set the parent condition to true.
+			r.SetCondition(Condition{
+				Type:     parent,
+				Status:   corev1.ConditionTrue,
+				Severity: r.severity(parent),
+			})
+		}
 	}
 }
 
-func (r conditionsImpl) findUnhappyDependent() *Condition {
+// findUnhappyDependent returns the most recently changed False, or else
+// Unknown, direct dependent of the parent condition, if any.
+func (r conditionsImpl) findUnhappyDependent(parent ConditionType) *Condition {
+	children := r.children[parent]
 	// This only works if there are dependents.
-	if len(r.dependents) == 0 {
+	if len(children) == 0 {
 		return nil
 	}
 
@@ -312,7 +470,7 @@ func (r conditionsImpl) findUnhappyDependent() *Condition {
 	// Filter based on terminal status.
 	n := 0
 	for _, c := range conditions {
-		if c.Severity == ConditionSeverityError && c.Type != r.happy {
+		if c.Severity == ConditionSeverityError && contains(children, c.Type) {
 			conditions[n] = c
 			n++
 		}
@@ -339,7 +497,7 @@ func (r conditionsImpl) findUnhappyDependent() *Condition {
 	}
 
 	// If something was not initialized.
-	if len(r.dependents) > len(conditions) {
+	if len(children) > len(conditions) {
 		return &Condition{
 			Status: corev1.ConditionUnknown,
 		}
@@ -349,8 +507,9 @@ func (r conditionsImpl) findUnhappyDependent() *Condition {
 	return nil
 }
 
-// MarkUnknown sets the status of t to Unknown and also sets the happy condition
-// to Unknown if no other dependent condition is in an error state.
+// MarkUnknown sets the status of t to Unknown and also sets the conditions
+// depending on it, up to the happy condition, to Unknown if no other of their
+// dependent conditions is in an error state.
 func (r conditionsImpl) MarkUnknown(t ConditionType, reason, messageFormat string, messageA ...interface{}) {
 	// set the specified condition
 	r.SetCondition(Condition{
@@ -361,46 +520,52 @@ func (r conditionsImpl) MarkUnknown(t ConditionType, reason, messageFormat strin
 		Severity: r.severity(t),
 	})
 
-	// check the dependents.
-	isDependent := false
-	for _, cond := range r.dependents {
-		c := r.GetCondition(cond)
-		// Failed conditions trump Unknown conditions
-		if c.IsFalse() {
-			// Double check that the happy condition is also false.
-			happy := r.GetCondition(r.happy)
-			if !happy.IsFalse() {
-				r.MarkFalse(r.happy, reason, messageFormat, messageA...)
-			}
-			return
+	// check the conditions depending on it, from the bottom of the graph up.
+	affected := append(r.ancestors(t), r.happy)
+	unknown := []ConditionType{t}
+	for _, parent := range r.order {
+		if !contains(affected, parent) {
+			continue
 		}
-		if cond == t {
-			isDependent = true
+		isDependent, isFalse := false, false
+		for _, cond := range r.children[parent] {
+			// Failed conditions trump Unknown conditions
+			if r.GetCondition(cond).IsFalse() {
+				isFalse = true
+				break
+			}
+			if contains(unknown, cond) {
+				isDependent = true
+			}
 		}
-	}
 
-	if isDependent {
-		// set the happy condition, if it is one of our dependent subconditions.
-		r.SetCondition(Condition{
-			Type:     r.happy,
-			Status:   corev1.ConditionUnknown,
-			Reason:   reason,
-			Message:  fmt.Sprintf(messageFormat, messageA...),
-			Severity: r.severity(r.happy),
-		})
+		if isFalse {
+			// Double check that the parent condition is also false.
+			if !r.GetCondition(parent).IsFalse() {
+				r.markFalse(parent, reason, messageFormat, messageA...)
+			}
+		} else if isDependent {
+			// set the parent condition, if it depends on the subcondition.
+			r.SetCondition(Condition{
+				Type:     parent,
+				Status:   corev1.ConditionUnknown,
+				Reason:   reason,
+				Message:  fmt.Sprintf(messageFormat, messageA...),
+				Severity: r.severity(parent),
+			})
+			unknown = append(unknown, parent)
+		}
 	}
 }
 
-// MarkFalse sets the status of t and the happy condition to False.
+// MarkFalse sets the status of t and the conditions depending on it, up to the
+// happy condition, to False.
 func (r conditionsImpl) MarkFalse(t ConditionType, reason, messageFormat string, messageA ...interface{}) {
-	types := []ConditionType{t}
-	for _, cond := range r.dependents {
-		if cond == t {
-			types = append(types, r.happy)
-		}
-	}
+	r.markFalse(t, reason, messageFormat, messageA...)
+}
 
-	for _, t := range types {
+func (r conditionsImpl) markFalse(t ConditionType, reason, messageFormat string, messageA ...interface{}) {
+	for _, t := range r.ancestors(t) {
 		r.SetCondition(Condition{
 			Type:     t,
 			Status:   corev1.ConditionFalse,
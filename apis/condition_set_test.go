@@ -19,6 +19,8 @@ package apis
 import (
 	"testing"
 
+	"github.com/google/go-cmp/cmp"
+
 	corev1 "k8s.io/api/core/v1"
 )
 
@@ -129,3 +131,205 @@ func TestNonTerminalCondition(t *testing.T) {
 		t.Errorf("MarkFalse(Bar) = %v, wanted %v", got, want)
 	}
 }
+
+func TestValidateConditionDependencies(t *testing.T) {
+	cases := []struct {
+		name    string
+		deps    ConditionDependencies
+		wantErr string
+	}{{
+		name: "empty",
+	}, {
+		name: "nested",
+		deps: ConditionDependencies{
+			ConditionReady: {"NetworkReady", "ConfigReady"},
+			"NetworkReady": {"IngressReady", "CertificateReady"},
+		},
+	}, {
+		name: "shared dependent",
+		deps: ConditionDependencies{
+			ConditionReady: {"NetworkReady", "ConfigReady"},
+			"NetworkReady": {"CertificateReady"},
+			"ConfigReady":  {"CertificateReady"},
+		},
+	}, {
+		name: "self",
+		deps: ConditionDependencies{
+			ConditionReady: {"NetworkReady"},
+			"NetworkReady": {"NetworkReady"},
+		},
+		wantErr: "condition dependency cycle: [NetworkReady NetworkReady]",
+	}, {
+		name: "happy",
+		deps: ConditionDependencies{
+			ConditionReady: {"NetworkReady"},
+			"NetworkReady": {"IngressReady"},
+			"IngressReady": {ConditionReady},
+		},
+		wantErr: "condition dependency cycle: [Ready NetworkReady IngressReady Ready]",
+	}, {
+		name: "cycle below",
+		deps: ConditionDependencies{
+			ConditionReady: {"ConfigReady", "NetworkReady"},
+			"NetworkReady": {"IngressReady"},
+			"IngressReady": {"NetworkReady"},
+		},
+		wantErr: "condition dependency cycle: [NetworkReady IngressReady NetworkReady]",
+	}, {
+		name: "unreachable",
+		deps: ConditionDependencies{
+			ConditionReady: {"NetworkReady"},
+			"ConfigReady":  {"VolumeReady"},
+		},
+		wantErr: "Ready does not depend on the conditions [ConfigReady]",
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateConditionDependencies(ConditionReady, tc.deps)
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Error("ValidateConditionDependencies() =", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tc.wantErr {
+				t.Errorf("ValidateConditionDependencies() = %v, wanted %q", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewLivingConditionSetWithDependenciesPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("NewLivingConditionSetWithDependencies() did not panic on a cycle")
+		}
+	}()
+	NewLivingConditionSetWithDependencies(ConditionDependencies{
+		ConditionReady: {"Foo"},
+		"Foo":          {"Bar"},
+		"Bar":          {"Foo"},
+	})
+}
+
+func TestNestedConditions(t *testing.T) {
+	set := NewLivingConditionSetWithDependencies(ConditionDependencies{
+		ConditionReady: {"NetworkReady", "ConfigReady"},
+		"NetworkReady": {"IngressReady", "CertificateReady"},
+	})
+	if got, want := set.dependents, []ConditionType{"NetworkReady", "IngressReady", "CertificateReady", "ConfigReady"}; !cmp.Equal(got, want) {
+		t.Error("dependents (-want, +got):", cmp.Diff(want, got))
+	}
+
+	status := &TestStatus{}
+	manager := set.Manage(status)
+	manager.InitializeConditions()
+	if got, want := len(status.c), 5; got != want {
+		t.Errorf("InitializeConditions() = %v conditions, wanted %v", got, want)
+	}
+
+	expect := func(step string, want map[ConditionType]corev1.ConditionStatus) {
+		t.Helper()
+		for ct, status := range want {
+			c := manager.GetCondition(ct)
+			if c == nil || c.Status != status {
+				t.Errorf("%s: %s = %v, wanted %v", step, ct, c, status)
+				continue
+			}
+			if c.Severity != ConditionSeverityError {
+				t.Errorf("%s: %s severity = %q, wanted %q", step, ct, c.Severity, ConditionSeverityError)
+			}
+		}
+	}
+
+	manager.MarkTrue("IngressReady")
+	manager.MarkTrue("ConfigReady")
+	expect("some dependents true", map[ConditionType]corev1.ConditionStatus{
+		"NetworkReady": corev1.ConditionUnknown,
+		ConditionReady: corev1.ConditionUnknown,
+	})
+
+	manager.MarkTrue("CertificateReady")
+	expect("all dependents true", map[ConditionType]corev1.ConditionStatus{
+		"NetworkReady": corev1.ConditionTrue,
+		ConditionReady: corev1.ConditionTrue,
+	})
+
+	manager.MarkFalse("CertificateReady", "Expired", "the certificate expired")
+	expect("a nested dependent false", map[ConditionType]corev1.ConditionStatus{
+		"IngressReady": corev1.ConditionTrue,
+		"NetworkReady": corev1.ConditionFalse,
+		"ConfigReady":  corev1.ConditionTrue,
+		ConditionReady: corev1.ConditionFalse,
+	})
+	if got, want := manager.GetTopLevelCondition().Reason, "Expired"; got != want {
+		t.Errorf("Ready reason = %q, wanted %q", got, want)
+	}
+
+	// False conditions trump Unknown ones.
+	manager.MarkUnknown("IngressReady", "Reconciling", "")
+	expect("a nested dependent false and another unknown", map[ConditionType]corev1.ConditionStatus{
+		"NetworkReady": corev1.ConditionFalse,
+		ConditionReady: corev1.ConditionFalse,
+	})
+
+	manager.MarkTrue("CertificateReady")
+	expect("a nested dependent unknown", map[ConditionType]corev1.ConditionStatus{
+		"NetworkReady": corev1.ConditionUnknown,
+		ConditionReady: corev1.ConditionUnknown,
+	})
+	if got, want := manager.GetTopLevelCondition().Reason, "Reconciling"; got != want {
+		t.Errorf("Ready reason = %q, wanted %q", got, want)
+	}
+
+	manager.MarkTrue("IngressReady")
+	manager.MarkUnknown("ConfigReady", "Loading", "")
+	expect("a direct dependent unknown", map[ConditionType]corev1.ConditionStatus{
+		"NetworkReady": corev1.ConditionTrue,
+		ConditionReady: corev1.ConditionUnknown,
+	})
+
+	manager.MarkTrue("ConfigReady")
+	expect("all dependents true again", map[ConditionType]corev1.ConditionStatus{
+		"NetworkReady": corev1.ConditionTrue,
+		ConditionReady: corev1.ConditionTrue,
+	})
+}
+
+func TestNestedConditionsMarkedDirectly(t *testing.T) {
+	set := NewLivingConditionSetWithDependencies(ConditionDependencies{
+		ConditionReady: {"NetworkReady", "ConfigReady"},
+		"NetworkReady": {"IngressReady"},
+		"ConfigReady":  {"DefaultsReady"},
+	})
+	status := &TestStatus{}
+	manager := set.Manage(status)
+	manager.InitializeConditions()
+
+	// Intermediate conditions marked directly keep their status and reason
+	// when an unrelated condition changes.
+	manager.MarkFalse("ConfigReady", "Invalid", "the config is invalid")
+	manager.MarkTrue("IngressReady")
+	if c := manager.GetCondition("ConfigReady"); !c.IsFalse() || c.Reason != "Invalid" {
+		t.Errorf("ConfigReady = %v, wanted False with reason Invalid", c)
+	}
+	if c := manager.GetCondition("NetworkReady"); !c.IsTrue() {
+		t.Errorf("NetworkReady = %v, wanted True", c)
+	}
+	if c := manager.GetTopLevelCondition(); !c.IsFalse() || c.Reason != "Invalid" {
+		t.Errorf("Ready = %v, wanted False with reason Invalid", c)
+	}
+
+	manager.MarkUnknown("NetworkReady", "Probing", "")
+	manager.MarkTrue("DefaultsReady")
+	if c := manager.GetCondition("NetworkReady"); !c.IsUnknown() || c.Reason != "Probing" {
+		t.Errorf("NetworkReady = %v, wanted Unknown with reason Probing", c)
+	}
+	if c := manager.GetCondition("ConfigReady"); !c.IsTrue() {
+		t.Errorf("ConfigReady = %v, wanted True", c)
+	}
+	if c := manager.GetTopLevelCondition(); !c.IsUnknown() || c.Reason != "Probing" {
+		t.Errorf("Ready = %v, wanted Unknown with reason Probing", c)
+	}
+}
@@ -22,6 +22,7 @@ import (
 
 	authenticationv1 "k8s.io/api/authentication/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
 // This is attached to contexts passed to webhook interfaces when
@@ -80,6 +81,13 @@ func WithinSubResourceUpdate(ctx context.Context, base interface{}, sr string) c
 	})
 }
 
+// WithoutUpdate is used to note that the webhook is no longer calling
+// within the context of an Update operation, e.g. to validate the baseline
+// of an Update as it was created.
+func WithoutUpdate(ctx context.Context) context.Context {
+	return context.WithValue(ctx, inUpdateKey{}, nil)
+}
+
 // IsInUpdate checks whether the context is an Update.
 func IsInUpdate(ctx context.Context) bool {
 	return ctx.Value(inUpdateKey{}) != nil
@@ -228,6 +236,38 @@ func IsDifferentNamespaceAllowed(ctx context.Context) bool {
 	return ctx.Value(allowDifferentNamespace{}) != nil
 }
 
+// This is attached to contexts passed to webhook interfaces to direct them
+// to ratchet the validation of updates.
+type ratchetingValidation struct{}
+
+// WithRatchetingValidation notes on the context that the validation of
+// updates to the given kinds, or to all kinds if none are given, should
+// ratchet: errors at field paths that were already invalid in the old
+// object, with unchanged values, are downgraded to warnings, so that only
+// newly introduced violations are rejected.  This lets validation be
+// tightened without making previously accepted objects impossible to edit.
+func WithRatchetingValidation(ctx context.Context, kinds ...schema.GroupKind) context.Context {
+	set := make(map[schema.GroupKind]struct{}, len(kinds))
+	for _, gk := range kinds {
+		set[gk] = struct{}{}
+	}
+	return context.WithValue(ctx, ratchetingValidation{}, set)
+}
+
+// IsRatchetingValidation checks the context to see whether the validation of
+// updates to the given kind should ratchet.
+func IsRatchetingValidation(ctx context.Context, gk schema.GroupKind) bool {
+	set, ok := ctx.Value(ratchetingValidation{}).(map[schema.GroupKind]struct{})
+	if !ok {
+		return false
+	}
+	if len(set) == 0 {
+		return true
+	}
+	_, ok = set[gk]
+	return ok
+}
+
 // This is attached to contexts passed to webhook interfaces when the user
 // has requested DryRun mode.
 type isDryRun struct{}
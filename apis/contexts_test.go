@@ -24,6 +24,7 @@ import (
 	"github.com/google/go-cmp/cmp"
 	authenticationv1 "k8s.io/api/authentication/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
 func TestContexts(t *testing.T) {
@@ -104,6 +105,11 @@ func TestContexts(t *testing.T) {
 		ctx:   WithinCreate(ctx),
 		check: IsInUpdate,
 		want:  false,
+	}, {
+		name:  "not in update (without update)",
+		ctx:   WithoutUpdate(WithinUpdate(ctx, struct{}{})),
+		check: IsInUpdate,
+		want:  false,
 	}, {
 		name:  "in spec",
 		ctx:   WithinSpec(ctx),
@@ -164,6 +170,26 @@ func TestContexts(t *testing.T) {
 		ctx:   WithinParent(ctx, metav1.ObjectMeta{Name: "jack"}),
 		check: IsWithinParent,
 		want:  true,
+	}, {
+		name:  "not ratcheting (bare)",
+		ctx:   ctx,
+		check: isRatchetingDeployments,
+		want:  false,
+	}, {
+		name:  "ratcheting all kinds",
+		ctx:   WithRatchetingValidation(ctx),
+		check: isRatchetingDeployments,
+		want:  true,
+	}, {
+		name:  "ratcheting this kind",
+		ctx:   WithRatchetingValidation(ctx, schema.GroupKind{Group: "apps", Kind: "Deployment"}),
+		check: isRatchetingDeployments,
+		want:  true,
+	}, {
+		name:  "ratcheting other kinds",
+		ctx:   WithRatchetingValidation(ctx, schema.GroupKind{Group: "apps", Kind: "StatefulSet"}),
+		check: isRatchetingDeployments,
+		want:  false,
 	}}
 
 	for _, tc := range tests {
@@ -176,6 +202,10 @@ func TestContexts(t *testing.T) {
 	}
 }
 
+func isRatchetingDeployments(ctx context.Context) bool {
+	return IsRatchetingValidation(ctx, schema.GroupKind{Group: "apps", Kind: "Deployment"})
+}
+
 func TestGetBaseline(t *testing.T) {
 	ctx := context.Background()
 
@@ -189,6 +219,10 @@ func TestGetBaseline(t *testing.T) {
 	if want, got := foo, GetBaseline(ctx); got != want {
 		t.Errorf("GetBaseline() = %v, wanted %v", got, want)
 	}
+
+	if got := GetBaseline(WithoutUpdate(ctx)); got != nil {
+		t.Errorf("GetBaseline() = %v, wanted %v", got, nil)
+	}
 }
 
 func TestGetUserInfo(t *testing.T) {
@@ -58,6 +58,17 @@ type BindableStatus interface {
 	SetObservedGeneration(int64)
 }
 
+// BindableConflictStatus may be implemented by a BindableStatus to surface
+// the conflicts of a Binding with other Bindings writing the same fields of
+// its subjects.
+type BindableConflictStatus interface {
+	// MarkBindingConflict notes the provided conflicts of this Binding.
+	MarkBindingConflict(message string)
+
+	// MarkBindingNoConflict notes that this Binding has no conflicts.
+	MarkBindingNoConflict()
+}
+
 // Bindable may be implemented by Binding resources to use shared libraries.
 type Bindable interface {
 	OneOfOurs
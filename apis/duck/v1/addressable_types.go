@@ -38,7 +38,20 @@ import (
 // typically stored in the object's `status`, as this information may
 // be generated by the controller.
 type Addressable struct {
+	// Name is the name of the address.
+	// +optional
+	Name *string `json:"name,omitempty"`
+
 	URL *apis.URL `json:"url,omitempty"`
+
+	// CACerts is the Certification Authority (CA) certificates in PEM format
+	// that the source trusts when sending events to the sink.
+	// +optional
+	CACerts *string `json:"CACerts,omitempty"`
+
+	// Audience is the OIDC audience for this address.
+	// +optional
+	Audience *string `json:"audience,omitempty"`
 }
 
 var (
@@ -62,7 +75,15 @@ type AddressableType struct {
 // AddressStatus shows how we expect folks to embed Addressable in
 // their Status field.
 type AddressStatus struct {
+	// Address is a single Addressable address.
+	// If Addresses is present, Address will be ignored by clients.
+	// +optional
 	Address *Addressable `json:"address,omitempty"`
+
+	// Addresses is a list of addresses for different protocols (HTTP and HTTPS)
+	// If Addresses is present, Address must be ignored by clients.
+	// +optional
+	Addresses []Addressable `json:"addresses,omitempty"`
 }
 
 // Verify AddressableType resources meet duck contracts.
@@ -89,14 +110,29 @@ func (a *Addressable) ConvertFrom(ctx context.Context, from apis.Convertible) er
 
 // Populate implements duck.Populatable
 func (t *AddressableType) Populate() {
+	name := "http"
+	caCerts := "ca-certs"
+	audience := "foo.com"
 	t.Status = AddressStatus{
-		&Addressable{
+		Address: &Addressable{
 			// Populate ALL fields
+			Name: &name,
 			URL: &apis.URL{
 				Scheme: "http",
 				Host:   "foo.com",
 			},
+			CACerts:  &caCerts,
+			Audience: &audience,
 		},
+		Addresses: []Addressable{{
+			Name: &name,
+			URL: &apis.URL{
+				Scheme: "http",
+				Host:   "foo.com",
+			},
+			CACerts:  &caCerts,
+			Audience: &audience,
+		}},
 	}
 }
 
@@ -17,7 +17,10 @@ limitations under the License.
 package v1
 
 import (
+	"bytes"
 	"context"
+	"encoding/pem"
+	"fmt"
 
 	"github.com/Yangfisher1/knative-common-pkg/apis"
 )
@@ -31,6 +34,17 @@ type Destination struct {
 	// URI can be an absolute URL(non-empty scheme and non-empty host) pointing to the target or a relative URI. Relative URIs will be resolved using the base URI retrieved from Ref.
 	// +optional
 	URI *apis.URL `json:"uri,omitempty"`
+
+	// CACerts are Certification Authority (CA) certificates in PEM format
+	// that the source trusts when sending events to the sink.
+	// They override the CACerts published by the resolved Addressable.
+	// +optional
+	CACerts *string `json:"CACerts,omitempty"`
+
+	// Audience is the OIDC audience.
+	// It overrides the audience published by the resolved Addressable.
+	// +optional
+	Audience *string `json:"audience,omitempty"`
 }
 
 // Validate the Destination has all the necessary fields and check the
@@ -57,12 +71,39 @@ func ValidateDestination(ctx context.Context, dest Destination) *apis.FieldError
 	if ref == nil && uri != nil && (!uri.URL().IsAbs() || uri.Host == "") {
 		return apis.ErrInvalidValue("Relative URI is not allowed when Ref and [apiVersion, kind, name] is absent", "uri")
 	}
+	if dest.CACerts != nil {
+		if err := validateCACerts(*dest.CACerts); err != nil {
+			return err.ViaField("CACerts")
+		}
+	}
 	if ref != nil && uri == nil {
 		return ref.Validate(ctx).ViaField("ref")
 	}
 	return nil
 }
 
+// validateCACerts checks that the given string holds one or more PEM
+// encoded certificates.
+func validateCACerts(caCerts string) *apis.FieldError {
+	rest := []byte(caCerts)
+	var found bool
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			return apis.ErrInvalidValue(fmt.Sprintf("unexpected PEM block of type %q", block.Type), apis.CurrentField)
+		}
+		found = true
+	}
+	if !found || len(bytes.TrimSpace(rest)) != 0 {
+		return apis.ErrInvalidValue("CA certificates must be PEM encoded", apis.CurrentField)
+	}
+	return nil
+}
+
 // GetRef gets the KReference from this Destination, if one is present. If no ref is present,
 // then nil is returned.
 func (d *Destination) GetRef() *KReference {
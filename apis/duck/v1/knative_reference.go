@@ -21,6 +21,8 @@ import (
 	"fmt"
 	"strings"
 
+	"k8s.io/apimachinery/pkg/util/intstr"
+
 	"github.com/Yangfisher1/knative-common-pkg/apis"
 )
 
@@ -49,6 +51,18 @@ type KReference struct {
 	// Note: This API is EXPERIMENTAL and might break anytime. For more details: https://github.com/knative/eventing/issues/5086
 	// +optional
 	Group string `json:"group,omitempty"`
+
+	// Address points to a specific Address Name of the referent's
+	// Addresses.  When omitted, the first address is used.
+	// +optional
+	Address *string `json:"address,omitempty"`
+
+	// Port selects a port of the referent by name or number.  It is only
+	// allowed for references to a v1 Service, where the scheme is picked
+	// from the port's appProtocol.  When omitted, the Service is addressed
+	// over HTTP on its default port.
+	// +optional
+	Port *intstr.IntOrString `json:"port,omitempty"`
 }
 
 func (kr *KReference) Validate(ctx context.Context) *apis.FieldError {
@@ -84,6 +98,7 @@ func (kr *KReference) Validate(ctx context.Context) *apis.FieldError {
 	if kr.Kind == "" {
 		errs = errs.Also(apis.ErrMissingField("kind"))
 	}
+	errs = errs.Also(kr.validatePort(ctx))
 	// Only if namespace is empty validate it. This is to deal with legacy
 	// objects in the storage that may now have the namespace filled in.
 	// Because things get defaulted in other cases, moving forward the
@@ -0,0 +1,158 @@
+/*
+Copyright 2023 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"github.com/Yangfisher1/knative-common-pkg/apis"
+)
+
+// IsServiceReference returns whether the KReference points to a
+// Kubernetes Service.
+func (kr *KReference) IsServiceReference() bool {
+	return kr.APIVersion == "v1" && kr.Kind == "Service"
+}
+
+// FindServicePort returns the port of the Service matching the given port
+// name or number, if any.
+func FindServicePort(svc *corev1.Service, port intstr.IntOrString) (*corev1.ServicePort, bool) {
+	for i := range svc.Spec.Ports {
+		p := &svc.Spec.Ports[i]
+		switch port.Type {
+		case intstr.Int:
+			if p.Port == port.IntVal {
+				return p, true
+			}
+		case intstr.String:
+			if p.Name == port.StrVal {
+				return p, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// ServicePortScheme returns the URL scheme with which to address the given
+// Service port.  It is taken from the port's appProtocol, falling back to
+// the port's name (e.g. "https" or "https-admin") when it is not set.
+func ServicePortScheme(port *corev1.ServicePort) string {
+	proto := port.Name
+	if port.AppProtocol != nil {
+		proto = *port.AppProtocol
+	}
+	proto = strings.ToLower(proto)
+	if proto == "https" || strings.HasPrefix(proto, "https-") {
+		return "https"
+	}
+	return "http"
+}
+
+// ServiceGetter gets the Services referenced by KReferences.
+type ServiceGetter interface {
+	GetService(namespace, name string) (*corev1.Service, error)
+}
+
+// ServiceGetterFunc implements ServiceGetter with a function, e.g. over the
+// lister of a Service informer:
+//
+//	duckv1.ServiceGetterFunc(func(namespace, name string) (*corev1.Service, error) {
+//		return lister.Services(namespace).Get(name)
+//	})
+type ServiceGetterFunc func(namespace, name string) (*corev1.Service, error)
+
+// GetService implements ServiceGetter
+func (f ServiceGetterFunc) GetService(namespace, name string) (*corev1.Service, error) {
+	return f(namespace, name)
+}
+
+// sgKey is used for associating a ServiceGetter with a context.Context
+type sgKey struct{}
+
+// WithServiceGetter notes on the context the ServiceGetter to use for
+// looking up the Services referenced by KReferences, e.g. to check that a
+// referenced port exists.
+func WithServiceGetter(ctx context.Context, sg ServiceGetter) context.Context {
+	return context.WithValue(ctx, sgKey{}, sg)
+}
+
+// GetServiceGetter extracts the ServiceGetter from the context, or nil.
+func GetServiceGetter(ctx context.Context) ServiceGetter {
+	sg, _ := ctx.Value(sgKey{}).(ServiceGetter)
+	return sg
+}
+
+// validatePort checks the KReference's port.  When a ServiceGetter is
+// available on the context and the Service exists, it also checks that
+// the Service exposes the port.  Without one, only the port's syntax is
+// checked.
+func (kr *KReference) validatePort(ctx context.Context) *apis.FieldError {
+	if kr.Port == nil {
+		return nil
+	}
+	if !kr.IsServiceReference() {
+		return apis.ErrDisallowedFields("port")
+	}
+	switch kr.Port.Type {
+	case intstr.Int:
+		if kr.Port.IntVal < 1 || kr.Port.IntVal > 65535 {
+			return apis.ErrOutOfBoundsValue(kr.Port.IntVal, 1, 65535, "port")
+		}
+	case intstr.String:
+		if kr.Port.StrVal == "" {
+			return apis.ErrInvalidValue(kr.Port.StrVal, "port")
+		}
+	}
+
+	sg := GetServiceGetter(ctx)
+	if sg == nil {
+		return nil
+	}
+	ns := kr.Namespace
+	if ns == "" {
+		ns = apis.ParentMeta(ctx).Namespace
+	}
+	svc, err := sg.GetService(ns, kr.Name)
+	if apierrs.IsNotFound(err) {
+		// The Service may not have been created yet.
+		return nil
+	} else if err != nil {
+		return &apis.FieldError{
+			Message: "unable to look up the referenced Service",
+			Paths:   []string{"port"},
+			Details: err.Error(),
+		}
+	}
+	if _, ok := FindServicePort(svc, *kr.Port); !ok {
+		ports := make([]string, 0, len(svc.Spec.Ports))
+		for _, p := range svc.Spec.Ports {
+			ports = append(ports, fmt.Sprintf("%s(%d)", p.Name, p.Port))
+		}
+		return &apis.FieldError{
+			Message: fmt.Sprintf("port %q not found on Service %s/%s", kr.Port.String(), ns, kr.Name),
+			Paths:   []string{"port"},
+			Details: "available ports: " + strings.Join(ports, ", "),
+		}
+	}
+	return nil
+}
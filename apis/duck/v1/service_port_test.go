@@ -0,0 +1,151 @@
+/*
+Copyright 2023 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"github.com/Yangfisher1/knative-common-pkg/apis"
+	"github.com/Yangfisher1/knative-common-pkg/ptr"
+)
+
+func TestServicePortScheme(t *testing.T) {
+	tests := map[string]struct {
+		port corev1.ServicePort
+		want string
+	}{
+		"unnamed":             {port: corev1.ServicePort{Port: 80}, want: "http"},
+		"http name":           {port: corev1.ServicePort{Name: "http"}, want: "http"},
+		"https name":          {port: corev1.ServicePort{Name: "https"}, want: "https"},
+		"https prefixed name": {port: corev1.ServicePort{Name: "https-admin"}, want: "https"},
+		"httpsish name":       {port: corev1.ServicePort{Name: "httpsish"}, want: "http"},
+		"https appProtocol":   {port: corev1.ServicePort{Name: "web", AppProtocol: ptr.String("HTTPS")}, want: "https"},
+		"appProtocol wins":    {port: corev1.ServicePort{Name: "https", AppProtocol: ptr.String("http")}, want: "http"},
+	}
+	for n, tc := range tests {
+		t.Run(n, func(t *testing.T) {
+			if got := ServicePortScheme(&tc.port); got != tc.want {
+				t.Errorf("ServicePortScheme() = %s, wanted %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestValidateServicePort(t *testing.T) {
+	svc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+		Spec: corev1.ServiceSpec{
+			Ports: []corev1.ServicePort{{Name: "http", Port: 80}, {Name: "https", Port: 443}},
+		},
+	}
+	withGetter := WithServiceGetter(context.Background(), ServiceGetterFunc(func(ns, n string) (*corev1.Service, error) {
+		if ns != svc.Namespace || n != svc.Name {
+			return nil, apierrs.NewNotFound(corev1.Resource("services"), n)
+		}
+		return svc, nil
+	}))
+
+	svcRef := func(port intstr.IntOrString) *KReference {
+		return &KReference{
+			APIVersion: "v1",
+			Kind:       "Service",
+			Namespace:  namespace,
+			Name:       name,
+			Port:       &port,
+		}
+	}
+
+	tests := map[string]struct {
+		ref  *KReference
+		ctx  context.Context
+		want *apis.FieldError
+	}{
+		"port by name": {
+			ref: svcRef(intstr.FromString("https")),
+			ctx: withGetter,
+		},
+		"port by number": {
+			ref: svcRef(intstr.FromInt(80)),
+			ctx: withGetter,
+		},
+		"unknown port without getter": {
+			ref: svcRef(intstr.FromString("grpc")),
+			ctx: context.Background(),
+		},
+		"unknown port of missing Service": {
+			ref: &KReference{
+				APIVersion: "v1",
+				Kind:       "Service",
+				Namespace:  namespace,
+				Name:       "missing",
+				Port:       &intstr.IntOrString{Type: intstr.String, StrVal: "grpc"},
+			},
+			ctx: withGetter,
+		},
+		"unknown port": {
+			ref: svcRef(intstr.FromString("grpc")),
+			ctx: withGetter,
+			want: &apis.FieldError{
+				Message: `port "grpc" not found on Service b-namespace/a-name`,
+				Paths:   []string{"port"},
+				Details: "available ports: http(80), https(443)",
+			},
+		},
+		"port out of range": {
+			ref:  svcRef(intstr.FromInt(0)),
+			ctx:  withGetter,
+			want: apis.ErrOutOfBoundsValue(0, 1, 65535, "port"),
+		},
+		"empty port name": {
+			ref:  svcRef(intstr.FromString("")),
+			ctx:  withGetter,
+			want: apis.ErrInvalidValue("", "port"),
+		},
+		"port on a non-Service": {
+			ref: &KReference{
+				APIVersion: apiVersion,
+				Kind:       kind,
+				Namespace:  namespace,
+				Name:       name,
+				Port:       &intstr.IntOrString{Type: intstr.Int, IntVal: 80},
+			},
+			ctx:  withGetter,
+			want: apis.ErrDisallowedFields("port"),
+		},
+	}
+	for n, tc := range tests {
+		t.Run(n, func(t *testing.T) {
+			got := tc.ref.Validate(tc.ctx)
+			if diff := cmp.Diff(tc.want.Error(), got.Error()); diff != "" {
+				t.Error("Validate (-want, +got) =", diff)
+			}
+
+			// Destination validation reports the error on ref.port.
+			got = ValidateDestination(tc.ctx, Destination{Ref: tc.ref})
+			if diff := cmp.Diff(tc.want.ViaField("ref").Error(), got.Error()); diff != "" {
+				t.Error("ValidateDestination (-want, +got) =", diff)
+			}
+		})
+	}
+}
@@ -24,6 +24,7 @@ package v1
 import (
 	apis "github.com/Yangfisher1/knative-common-pkg/apis"
 	runtime "k8s.io/apimachinery/pkg/runtime"
+	intstr "k8s.io/apimachinery/pkg/util/intstr"
 )
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
@@ -34,6 +35,13 @@ func (in *AddressStatus) DeepCopyInto(out *AddressStatus) {
 		*out = new(Addressable)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Addresses != nil {
+		in, out := &in.Addresses, &out.Addresses
+		*out = make([]Addressable, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	return
 }
 
@@ -50,11 +58,26 @@ func (in *AddressStatus) DeepCopy() *AddressStatus {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Addressable) DeepCopyInto(out *Addressable) {
 	*out = *in
+	if in.Name != nil {
+		in, out := &in.Name, &out.Name
+		*out = new(string)
+		**out = **in
+	}
 	if in.URL != nil {
 		in, out := &in.URL, &out.URL
 		*out = new(apis.URL)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.CACerts != nil {
+		in, out := &in.CACerts, &out.CACerts
+		*out = new(string)
+		**out = **in
+	}
+	if in.Audience != nil {
+		in, out := &in.Audience, &out.Audience
+		*out = new(string)
+		**out = **in
+	}
 	return
 }
 
@@ -332,13 +355,23 @@ func (in *Destination) DeepCopyInto(out *Destination) {
 	if in.Ref != nil {
 		in, out := &in.Ref, &out.Ref
 		*out = new(KReference)
-		**out = **in
+		(*in).DeepCopyInto(*out)
 	}
 	if in.URI != nil {
 		in, out := &in.URI, &out.URI
 		*out = new(apis.URL)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.CACerts != nil {
+		in, out := &in.CACerts, &out.CACerts
+		*out = new(string)
+		**out = **in
+	}
+	if in.Audience != nil {
+		in, out := &in.Audience, &out.Audience
+		*out = new(string)
+		**out = **in
+	}
 	return
 }
 
@@ -355,6 +388,16 @@ func (in *Destination) DeepCopy() *Destination {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *KReference) DeepCopyInto(out *KReference) {
 	*out = *in
+	if in.Address != nil {
+		in, out := &in.Address, &out.Address
+		*out = new(string)
+		**out = **in
+	}
+	if in.Port != nil {
+		in, out := &in.Port, &out.Port
+		*out = new(intstr.IntOrString)
+		**out = **in
+	}
 	return
 }
 
@@ -0,0 +1,52 @@
+/*
+Copyright 2023 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package feature
+
+import "context"
+
+// flagsKey is used as the key for associating Flags with a context.Context.
+type flagsKey struct{}
+
+// ToContext attaches the Flags to the context.
+func ToContext(ctx context.Context, flags Flags) context.Context {
+	return context.WithValue(ctx, flagsKey{}, flags)
+}
+
+// FromContext returns the Flags attached to the context, or nil if there
+// are none, in which case all the features are Disabled.
+func FromContext(ctx context.Context) Flags {
+	flags, _ := ctx.Value(flagsKey{}).(Flags)
+	return flags
+}
+
+// storeKey is used as the key for associating a Store with a
+// context.Context.
+type storeKey struct{}
+
+// WithStore associates the Store with the admission controllers constructed
+// with the returned context, which attach its Flags to the context of the
+// requests they admit.
+func WithStore(ctx context.Context, s *Store) context.Context {
+	return context.WithValue(ctx, storeKey{}, s)
+}
+
+// GetStore retrieves the Store associated with the given context via
+// WithStore (above), or nil.
+func GetStore(ctx context.Context) *Store {
+	s, _ := ctx.Value(storeKey{}).(*Store)
+	return s
+}
@@ -0,0 +1,115 @@
+/*
+Copyright 2023 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package feature
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/Yangfisher1/knative-common-pkg/configmap"
+)
+
+const (
+	// configMapNameEnv is the environment variable overriding the name of
+	// the features ConfigMap.
+	configMapNameEnv = "CONFIG_FEATURES_NAME"
+)
+
+// Flag is the state of a feature.
+type Flag string
+
+const (
+	// Enabled turns the feature on: its fields are accepted, and defaulted.
+	Enabled Flag = "Enabled"
+
+	// Allowed accepts the fields of the feature, but doesn't default them.
+	Allowed Flag = "Allowed"
+
+	// Disabled turns the feature off: its fields are rejected.  This is the
+	// state of the features without a Flag.
+	Disabled Flag = "Disabled"
+)
+
+// Flags holds the Flags of features, by name.
+type Flags map[string]Flag
+
+// ConfigMapName gets the name of the features ConfigMap.
+func ConfigMapName() string {
+	if cm := os.Getenv(configMapNameEnv); cm != "" {
+		return cm
+	}
+	return "config-features"
+}
+
+// IsEnabled returns whether the feature is Enabled.
+func (f Flags) IsEnabled(name string) bool {
+	return f[name] == Enabled
+}
+
+// IsAllowed returns whether the feature is Enabled or Allowed, so that its
+// fields may be set.
+func (f Flags) IsAllowed(name string) bool {
+	return f[name] == Enabled || f[name] == Allowed
+}
+
+// NewFlagsFromMap creates Flags from the supplied map, whose keys are the
+// names of the features, on top of the defaults.  Keys starting with an
+// underscore, like the _example key, are skipped.
+func NewFlagsFromMap(defaults Flags, data map[string]string) (Flags, error) {
+	flags := make(Flags, len(defaults)+len(data))
+	for name, flag := range defaults {
+		flags[name] = flag
+	}
+
+	parsers := make([]configmap.ParseFunc, 0, len(data))
+	for name := range data {
+		if !strings.HasPrefix(name, "_") {
+			parsers = append(parsers, asFlag(name, flags))
+		}
+	}
+	if err := configmap.Parse(data, parsers...); err != nil {
+		return nil, err
+	}
+	return flags, nil
+}
+
+// NewFlagsFromConfigMap creates Flags from the supplied ConfigMap, on top of
+// the defaults.
+func NewFlagsFromConfigMap(defaults Flags, cm *corev1.ConfigMap) (Flags, error) {
+	return NewFlagsFromMap(defaults, cm.Data)
+}
+
+// asFlag parses the value at key as a Flag into the flags, ignoring case.
+func asFlag(key string, flags Flags) configmap.ParseFunc {
+	return func(data map[string]string) error {
+		raw, ok := data[key]
+		if !ok {
+			return nil
+		}
+		for _, flag := range []Flag{Enabled, Allowed, Disabled} {
+			if strings.EqualFold(strings.TrimSpace(raw), string(flag)) {
+				flags[key] = flag
+				return nil
+			}
+		}
+		return fmt.Errorf("failed to parse %q: must be one of %q, %q or %q, was %q",
+			key, Enabled, Allowed, Disabled, raw)
+	}
+}
@@ -0,0 +1,120 @@
+/*
+Copyright 2023 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package feature
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestNewFlagsFromMap(t *testing.T) {
+	tests := []struct {
+		name     string
+		defaults Flags
+		data     map[string]string
+		want     Flags
+		wantErr  bool
+	}{{
+		name: "empty",
+		want: Flags{},
+	}, {
+		name:     "defaults",
+		defaults: Flags{"alpha": Allowed},
+		want:     Flags{"alpha": Allowed},
+	}, {
+		name:     "overrides the defaults",
+		defaults: Flags{"alpha": Allowed, "beta": Enabled},
+		data:     map[string]string{"alpha": "Disabled"},
+		want:     Flags{"alpha": Disabled, "beta": Enabled},
+	}, {
+		name: "ignores case",
+		data: map[string]string{"alpha": "enabled", "beta": " ALLOWED "},
+		want: Flags{"alpha": Enabled, "beta": Allowed},
+	}, {
+		name: "skips the example",
+		data: map[string]string{"_example": "alpha: Enabled", "beta": "Enabled"},
+		want: Flags{"beta": Enabled},
+	}, {
+		name:    "invalid",
+		data:    map[string]string{"alpha": "on"},
+		wantErr: true,
+	}}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := NewFlagsFromConfigMap(tc.defaults, &corev1.ConfigMap{Data: tc.data})
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("NewFlagsFromConfigMap() = %v, wanted error %v", err, tc.wantErr)
+			}
+			if !cmp.Equal(got, tc.want) {
+				t.Error("NewFlagsFromConfigMap() (-want, +got):", cmp.Diff(tc.want, got))
+			}
+		})
+	}
+}
+
+func TestNewFlagsFromMapDoesNotChangeDefaults(t *testing.T) {
+	defaults := Flags{"alpha": Disabled}
+	if _, err := NewFlagsFromMap(defaults, map[string]string{"alpha": "Enabled"}); err != nil {
+		t.Fatal("NewFlagsFromMap() =", err)
+	}
+	if got := defaults["alpha"]; got != Disabled {
+		t.Errorf("defaults[alpha] = %q, want %q", got, Disabled)
+	}
+}
+
+func TestFlags(t *testing.T) {
+	flags := Flags{"enabled": Enabled, "allowed": Allowed, "disabled": Disabled}
+
+	tests := []struct {
+		name        string
+		wantEnabled bool
+		wantAllowed bool
+	}{{
+		name:        "enabled",
+		wantEnabled: true,
+		wantAllowed: true,
+	}, {
+		name:        "allowed",
+		wantAllowed: true,
+	}, {
+		name: "disabled",
+	}, {
+		name: "missing",
+	}}
+
+	for _, tc := range tests {
+		if got := flags.IsEnabled(tc.name); got != tc.wantEnabled {
+			t.Errorf("IsEnabled(%q) = %v, want %v", tc.name, got, tc.wantEnabled)
+		}
+		if got := flags.IsAllowed(tc.name); got != tc.wantAllowed {
+			t.Errorf("IsAllowed(%q) = %v, want %v", tc.name, got, tc.wantAllowed)
+		}
+	}
+}
+
+func TestConfigMapName(t *testing.T) {
+	if got, want := ConfigMapName(), "config-features"; got != want {
+		t.Errorf("ConfigMapName() = %q, want %q", got, want)
+	}
+	t.Setenv(configMapNameEnv, "my-features")
+	if got, want := ConfigMapName(), "my-features"; got != want {
+		t.Errorf("ConfigMapName() = %q, want %q", got, want)
+	}
+}
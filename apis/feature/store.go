@@ -0,0 +1,71 @@
+/*
+Copyright 2023 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package feature
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/Yangfisher1/knative-common-pkg/configmap"
+)
+
+// Store keeps the Flags up to date with the features ConfigMap.
+type Store struct {
+	*configmap.UntypedStore
+
+	defaults Flags
+}
+
+// NewStore creates a Store of the Flags, on top of the defaults.  Its
+// WatchConfigs method must be called with a configmap.Watcher to keep it up
+// to date, and its ToContext method passed (or called from) the context
+// function of an admission controller, or the Store associated with the
+// context the admission controller is constructed with (see WithStore).
+func NewStore(logger configmap.Logger, defaults Flags, onAfterStore ...func(name string, value interface{})) *Store {
+	return &Store{
+		UntypedStore: configmap.NewUntypedStore(
+			"features",
+			logger,
+			configmap.Constructors{
+				ConfigMapName(): func(cm *corev1.ConfigMap) (Flags, error) {
+					return NewFlagsFromConfigMap(defaults, cm)
+				},
+			},
+			onAfterStore...,
+		),
+		defaults: defaults,
+	}
+}
+
+// Load returns the current Flags, or the defaults if the ConfigMap has not
+// been seen yet.
+func (s *Store) Load() Flags {
+	if flags, ok := s.UntypedLoad(ConfigMapName()).(Flags); ok {
+		return flags
+	}
+	return s.defaults
+}
+
+// ToContext attaches the current Flags to the context.  It does nothing on a
+// nil Store.
+func (s *Store) ToContext(ctx context.Context) context.Context {
+	if s == nil {
+		return ctx
+	}
+	return ToContext(ctx, s.Load())
+}
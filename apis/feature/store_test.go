@@ -0,0 +1,72 @@
+/*
+Copyright 2023 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package feature
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	. "github.com/Yangfisher1/knative-common-pkg/logging/testing"
+)
+
+func TestStore(t *testing.T) {
+	defaults := Flags{"alpha": Allowed}
+	store := NewStore(TestLogger(t), defaults)
+
+	if got := FromContext(store.ToContext(context.Background())); !cmp.Equal(got, defaults) {
+		t.Errorf("FromContext() = %v before the ConfigMap was seen, want the defaults %v", got, defaults)
+	}
+
+	store.OnConfigChanged(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: ConfigMapName()},
+		Data:       map[string]string{"beta": "Enabled"},
+	})
+	want := Flags{"alpha": Allowed, "beta": Enabled}
+	if got := FromContext(store.ToContext(context.Background())); !cmp.Equal(got, want) {
+		t.Error("FromContext() (-want, +got):", cmp.Diff(want, got))
+	}
+}
+
+func TestNilStore(t *testing.T) {
+	var store *Store
+	if got := FromContext(store.ToContext(context.Background())); got != nil {
+		t.Errorf("FromContext() = %v, want nil", got)
+	}
+}
+
+func TestContext(t *testing.T) {
+	ctx := context.Background()
+	if got := FromContext(ctx); got != nil {
+		t.Errorf("FromContext() = %v, want nil", got)
+	}
+	if got := GetStore(ctx); got != nil {
+		t.Errorf("GetStore() = %v, want nil", got)
+	}
+
+	flags := Flags{"alpha": Enabled}
+	if got := FromContext(ToContext(ctx, flags)); !cmp.Equal(got, flags) {
+		t.Errorf("FromContext() = %v, want %v", got, flags)
+	}
+	store := NewStore(TestLogger(t), nil)
+	if got := GetStore(WithStore(ctx, store)); got != store {
+		t.Errorf("GetStore() = %v, want %v", got, store)
+	}
+}
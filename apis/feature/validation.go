@@ -0,0 +1,45 @@
+/*
+Copyright 2023 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package feature
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/Yangfisher1/knative-common-pkg/apis"
+)
+
+// ValidateGatedField returns an error disallowing the field when its value
+// is set, i.e. not the zero value of its type, while the feature gating it
+// is neither Enabled nor Allowed in the Flags attached to the context.
+func ValidateGatedField(ctx context.Context, feature, field string, value interface{}) *apis.FieldError {
+	if isZero(value) || FromContext(ctx).IsAllowed(feature) {
+		return nil
+	}
+	err := apis.ErrDisallowedFields(field)
+	err.Details = fmt.Sprintf("requires the %q feature to be %s or %s in the %s ConfigMap",
+		feature, Enabled, Allowed, ConfigMapName())
+	return err
+}
+
+func isZero(value interface{}) bool {
+	if value == nil {
+		return true
+	}
+	return reflect.ValueOf(value).IsZero()
+}
@@ -0,0 +1,79 @@
+/*
+Copyright 2023 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package feature
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Yangfisher1/knative-common-pkg/apis"
+)
+
+func TestValidateGatedField(t *testing.T) {
+	var unset *int
+	one := 1
+
+	tests := []struct {
+		name    string
+		flags   Flags
+		value   interface{}
+		wantErr bool
+	}{{
+		name:  "unset",
+		value: unset,
+	}, {
+		name: "nil",
+	}, {
+		name:  "empty string",
+		value: "",
+	}, {
+		name:    "set while disabled",
+		flags:   Flags{"alpha": Disabled},
+		value:   &one,
+		wantErr: true,
+	}, {
+		name:    "set without flags",
+		value:   "value",
+		wantErr: true,
+	}, {
+		name:  "set while allowed",
+		flags: Flags{"alpha": Allowed},
+		value: &one,
+	}, {
+		name:  "set while enabled",
+		flags: Flags{"alpha": Enabled},
+		value: []string{"value"},
+	}}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := ToContext(context.Background(), tc.flags)
+			err := ValidateGatedField(ctx, "alpha", "spec.alpha", tc.value)
+			if !tc.wantErr {
+				if err != nil {
+					t.Error("ValidateGatedField() =", err)
+				}
+				return
+			}
+			want := apis.ErrDisallowedFields("spec.alpha")
+			want.Details = `requires the "alpha" feature to be Enabled or Allowed in the config-features ConfigMap`
+			if got, want := err.Error(), want.Error(); got != want {
+				t.Errorf("ValidateGatedField() = %q, want %q", got, want)
+			}
+		})
+	}
+}
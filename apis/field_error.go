@@ -169,6 +169,34 @@ func (fe *FieldError) At(l DiagnosticLevel) *FieldError {
 	return newErr
 }
 
+// AtWhere is a way to alter the level of the diagnostics held in this
+// FieldError for which the predicate holds, e.g. to downgrade pre-existing
+// errors to warnings.  The predicate is called with each diagnostic
+// separately.
+//
+//	err.AtWhere(WarningLevel, func(fe *FieldError) bool {
+//	  return preexisting(fe.Paths)
+//	})
+func (fe *FieldError) AtWhere(l DiagnosticLevel, pred func(*FieldError) bool) *FieldError {
+	if fe == nil {
+		return nil
+	}
+	newErr := &FieldError{
+		Message: fe.Message,
+		Level:   fe.Level,
+		Details: fe.Details,
+		Paths:   fe.Paths,
+	}
+	if fe.Message != "" && pred(newErr) {
+		newErr.Level = l
+	}
+
+	for _, e := range fe.errors {
+		newErr = newErr.Also(e.AtWhere(l, pred))
+	}
+	return newErr
+}
+
 // Filter is a way to access the set of diagnostics having a particular level.
 //
 //	if err := x.Validate(ctx).Filter(ErrorLevel); err != nil {
@@ -197,6 +225,20 @@ func (fe *FieldError) Filter(l DiagnosticLevel) *FieldError {
 	return newErr
 }
 
+// PathsAt returns the field paths of the diagnostics held in this FieldError
+// having a particular level, without duplicates.
+//
+//	invalid := x.Validate(ctx).PathsAt(ErrorLevel)
+func (fe *FieldError) PathsAt(l DiagnosticLevel) []string {
+	var paths []string
+	for _, e := range fe.normalized() {
+		if e.Level == l {
+			paths = mergePaths(paths, e.Paths)
+		}
+	}
+	return paths
+}
+
 // Also collects errors, returns a new collection of existing errors and new errors.
 func (fe *FieldError) Also(errs ...*FieldError) *FieldError {
 	// Avoid doing any work, if we don't have to.
@@ -341,6 +341,16 @@ missing field(s): bar`,
 		name: "Mix of Errors and Warnings turned to Errors",
 		err:  ErrMissingField("foo").Also(ErrMissingField("bar").At(WarningLevel)).At(ErrorLevel).Filter(ErrorLevel),
 		want: `missing field(s): bar, foo`,
+	}, {
+		name: "Errors at some paths turned to Warnings",
+		err: ErrMissingField("foo").Also(ErrMissingField("bar"), ErrMissingField("baz").At(WarningLevel)).
+			AtWhere(WarningLevel, func(fe *FieldError) bool { return fe.Paths[0] == "bar" }).Filter(WarningLevel),
+		want: `missing field(s): bar, baz`,
+	}, {
+		name: "Errors at other paths are kept",
+		err: ErrMissingField("foo").Also(ErrMissingField("bar"), ErrMissingField("baz").At(WarningLevel)).
+			AtWhere(WarningLevel, func(fe *FieldError) bool { return fe.Paths[0] == "bar" }).Filter(ErrorLevel),
+		want: `missing field(s): foo`,
 	}}
 
 	for _, test := range tests {
@@ -820,6 +830,24 @@ func TestAlsoMultiple(t *testing.T) {
 	}
 }
 
+func TestPathsAt(t *testing.T) {
+	var err *FieldError
+	if got := err.PathsAt(ErrorLevel); len(got) != 0 {
+		t.Errorf("PathsAt() = %v, wanted none", got)
+	}
+
+	err = ErrMissingField("foo", "bar").
+		Also(ErrInvalidValue("x", "baz").ViaField("spec")).
+		Also(ErrDisallowedFields("foo")).
+		Also(ErrMissingField("qux").At(WarningLevel))
+	if got, want := err.PathsAt(ErrorLevel), []string{"foo", "bar", "spec.baz"}; !cmp.Equal(got, want) {
+		t.Errorf("PathsAt(ErrorLevel) = %v, wanted %v", got, want)
+	}
+	if got, want := err.PathsAt(WarningLevel), []string{"qux"}; !cmp.Equal(got, want) {
+		t.Errorf("PathsAt(WarningLevel) = %v, wanted %v", got, want)
+	}
+}
+
 func TestFlatten(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -0,0 +1,356 @@
+/*
+Copyright 2023 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package configmap
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// ValueType is the type of the value of a ConfigMap key, named after the
+// ParseFunc parsing it.
+type ValueType string
+
+const (
+	// StringType values are parsed with AsString.
+	StringType ValueType = "string"
+	// BoolType values are parsed with AsBool.
+	BoolType ValueType = "bool"
+	// IntType values are parsed with AsInt64.
+	IntType ValueType = "int"
+	// FloatType values are parsed with AsFloat64.
+	FloatType ValueType = "float"
+	// DurationType values are parsed with AsDuration.
+	DurationType ValueType = "duration"
+	// QuantityType values are parsed with AsQuantity.
+	QuantityType ValueType = "quantity"
+	// StringSetType values are parsed with AsStringSet.
+	StringSetType ValueType = "stringset"
+	// NamespacedNameType values are parsed with AsNamespacedName.
+	NamespacedNameType ValueType = "namespacedname"
+)
+
+// Key describes a key accepted in a ConfigMap.
+type Key struct {
+	// Name is the name of the key.
+	Name string
+
+	// Prefix makes Name a prefix of the keys described, e.g. for the keys
+	// collected by CollectMapEntriesWithPrefix.
+	Prefix bool
+
+	// Type is the type of the value, StringType if empty.
+	Type ValueType
+
+	// Min and Max optionally bound IntType, FloatType, DurationType and
+	// QuantityType values, and are written like them.  Schema.Check rejects
+	// them on the other types.
+	Min, Max string
+
+	// Values optionally lists the values allowed.
+	Values []string
+
+	// Default is the effective value when the key is not set.
+	Default string
+
+	// Deprecated optionally explains what replaces the key, and marks it as
+	// deprecated.
+	Deprecated string
+}
+
+// UnknownKeyPolicy is what to do with the keys of a ConfigMap that its
+// Schema doesn't describe.
+type UnknownKeyPolicy string
+
+const (
+	// WarnUnknownKeys warns about unknown keys.
+	WarnUnknownKeys UnknownKeyPolicy = "Warn"
+	// RejectUnknownKeys rejects ConfigMaps with unknown keys.
+	RejectUnknownKeys UnknownKeyPolicy = "Reject"
+)
+
+// Schema describes the keys accepted in a ConfigMap, so that they can be
+// validated before its constructor sees them.
+type Schema struct {
+	// Keys are the keys accepted.
+	Keys []Key
+
+	// UnknownKeys is what to do with other keys, WarnUnknownKeys if empty.
+	UnknownKeys UnknownKeyPolicy
+}
+
+// Schemas is a map from ConfigMap names to the Schema of their data.
+type Schemas map[string]Schema
+
+// Check checks the Schemas, naming the ConfigMaps of those that are invalid.
+func (ss Schemas) Check() error {
+	var errs []error
+	for _, name := range sets.StringKeySet(ss).List() {
+		if err := ss[name].Check(); err != nil {
+			errs = append(errs, fmt.Errorf("invalid schema of %q: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Check checks that the keys of the Schema have known types, and that their
+// bounds, if any, are on types that have them and are written like them.
+func (s Schema) Check() error {
+	var errs []error
+	for _, k := range s.Keys {
+		if err := k.check(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// check checks the type and bounds of the key.
+func (k Key) check() error {
+	switch k.Type {
+	case "", StringType, BoolType, StringSetType, NamespacedNameType:
+		if k.Min != "" || k.Max != "" {
+			vt := k.Type
+			if vt == "" {
+				vt = StringType
+			}
+			return fmt.Errorf("%q may not have a minimum or maximum, %s values have no order", k.Name, vt)
+		}
+		return nil
+	case IntType, FloatType, DurationType, QuantityType:
+	default:
+		return fmt.Errorf("unknown type %q of %q", k.Type, k.Name)
+	}
+
+	var lo, hi float64
+	if k.Min != "" {
+		var err error
+		if lo, err = parseValue(k.Type, k.Name+" minimum", k.Min); err != nil {
+			return err
+		}
+	}
+	if k.Max != "" {
+		var err error
+		if hi, err = parseValue(k.Type, k.Name+" maximum", k.Max); err != nil {
+			return err
+		}
+	}
+	if k.Min != "" && k.Max != "" && lo > hi {
+		return fmt.Errorf("%q has a minimum of %s above its maximum of %s", k.Name, k.Min, k.Max)
+	}
+	return nil
+}
+
+// lookup returns the key describing name, if any.
+func (s Schema) lookup(name string) (Key, bool) {
+	for _, k := range s.Keys {
+		if k.Name == name || (k.Prefix && strings.HasPrefix(name, k.Name)) {
+			return k, true
+		}
+	}
+	return Key{}, false
+}
+
+// Validate validates the ConfigMap data against the Schema, and returns
+// warnings about deprecated and, unless rejected, unknown keys.  The
+// ExampleKey is always accepted.
+func (s Schema) Validate(data map[string]string) (warnings []string, err error) {
+	var errs []error
+	for _, name := range sets.StringKeySet(data).List() {
+		if name == ExampleKey {
+			continue
+		}
+		k, ok := s.lookup(name)
+		if !ok {
+			msg := fmt.Sprintf("unknown key %q", name)
+			if suggestion := s.suggest(name); suggestion != "" {
+				msg += fmt.Sprintf(", did you mean %q?", suggestion)
+			}
+			if s.UnknownKeys == RejectUnknownKeys {
+				errs = append(errs, errors.New(msg))
+			} else {
+				warnings = append(warnings, msg)
+			}
+			continue
+		}
+		if k.Deprecated != "" {
+			warnings = append(warnings, fmt.Sprintf("key %q is deprecated: %s", name, k.Deprecated))
+		}
+		if err := k.validate(name, data[name]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return warnings, errors.Join(errs...)
+}
+
+// validate validates the value of the key named name.
+func (k Key) validate(name, raw string) error {
+	if len(k.Values) > 0 && !sets.NewString(k.Values...).Has(raw) {
+		return fmt.Errorf("invalid value %q for %q, must be one of %s", raw, name, strings.Join(k.Values, ", "))
+	}
+	value, err := parseValue(k.Type, name, raw)
+	if err != nil {
+		return err
+	}
+	if k.Min != "" {
+		lo, err := parseValue(k.Type, name+" minimum", k.Min)
+		if err != nil {
+			return err
+		}
+		if value < lo {
+			return fmt.Errorf("%q is %s, must be at least %s", name, raw, k.Min)
+		}
+	}
+	if k.Max != "" {
+		hi, err := parseValue(k.Type, name+" maximum", k.Max)
+		if err != nil {
+			return err
+		}
+		if value > hi {
+			return fmt.Errorf("%q is %s, must be at most %s", name, raw, k.Max)
+		}
+	}
+	return nil
+}
+
+// parseValue parses the value with the ParseFunc of its type, and returns
+// it as a number when it is one, so that it can be compared to a range.
+func parseValue(vt ValueType, name, raw string) (float64, error) {
+	data := map[string]string{name: raw}
+	switch vt {
+	case "", StringType:
+		return 0, nil
+	case BoolType:
+		var b bool
+		if err := AsBool(name, &b)(data); err != nil {
+			return 0, fmt.Errorf("failed to parse %q: %w", name, err)
+		}
+		return 0, nil
+	case IntType:
+		var i int64
+		err := AsInt64(name, &i)(data)
+		return float64(i), err
+	case FloatType:
+		var f float64
+		err := AsFloat64(name, &f)(data)
+		return f, err
+	case DurationType:
+		var d time.Duration
+		err := AsDuration(name, &d)(data)
+		return float64(d), err
+	case QuantityType:
+		var q *resource.Quantity
+		if err := AsQuantity(name, &q)(data); err != nil {
+			return 0, err
+		}
+		return q.AsApproximateFloat64(), nil
+	case StringSetType:
+		var s sets.String
+		return 0, AsStringSet(name, &s)(data)
+	case NamespacedNameType:
+		var nn types.NamespacedName
+		return 0, AsNamespacedName(name, &nn)(data)
+	default:
+		return 0, fmt.Errorf("unknown type %q of %q", vt, name)
+	}
+}
+
+// suggest returns the key closest to the unknown name, if any is close
+// enough to be a typo of it.
+func (s Schema) suggest(name string) string {
+	const maxDistance = 2
+	best, bestDistance := "", maxDistance+1
+	for _, k := range s.Keys {
+		if k.Prefix {
+			continue
+		}
+		if d := editDistance(name, k.Name); d < bestDistance {
+			best, bestDistance = k.Name, d
+		}
+	}
+	return best
+}
+
+// editDistance returns the Levenshtein distance between the strings.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+// Effective returns the settings in effect with the ConfigMap data: its keys,
+// and the defaults of the keys it doesn't set.  The ExampleKey is left out.
+func (s Schema) Effective(data map[string]string) map[string]string {
+	effective := make(map[string]string, len(data)+len(s.Keys))
+	for _, k := range s.Keys {
+		if !k.Prefix && k.Default != "" {
+			effective[k.Name] = k.Default
+		}
+	}
+	for name, value := range data {
+		if name != ExampleKey {
+			effective[name] = value
+		}
+	}
+	return effective
+}
+
+// Diff describes the changes to the effective settings between the old and
+// new ConfigMap data, one line per changed key, in key order.
+func (s Schema) Diff(oldData, newData map[string]string) []string {
+	before, after := s.Effective(oldData), s.Effective(newData)
+
+	names := sets.StringKeySet(before).Union(sets.StringKeySet(after)).List()
+	var diff []string
+	for _, name := range names {
+		oldValue, hadValue := before[name]
+		newValue, hasValue := after[name]
+		_, setBefore := oldData[name]
+		_, setAfter := newData[name]
+		switch {
+		case !hadValue:
+			diff = append(diff, fmt.Sprintf("%s set to %q", name, newValue))
+		case !hasValue:
+			diff = append(diff, fmt.Sprintf("%s unset, was %q", name, oldValue))
+		case oldValue != newValue && setBefore && !setAfter:
+			diff = append(diff, fmt.Sprintf("%s reset to its default %q, was %q", name, newValue, oldValue))
+		case oldValue != newValue:
+			diff = append(diff, fmt.Sprintf("%s changed from %q to %q", name, oldValue, newValue))
+		}
+	}
+	return diff
+}
@@ -0,0 +1,256 @@
+/*
+Copyright 2023 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package configmap
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var testSchema = Schema{
+	Keys: []Key{{
+		Name:    "loglevel.controller",
+		Values:  []string{"debug", "info", "warn", "error"},
+		Default: "info",
+	}, {
+		Name: "enabled",
+		Type: BoolType,
+	}, {
+		Name: "replicas",
+		Type: IntType,
+		Min:  "1",
+		Max:  "10",
+	}, {
+		Name: "timeout",
+		Type: DurationType,
+		Max:  "1m",
+	}, {
+		Name:    "memory",
+		Type:    QuantityType,
+		Min:     "64Mi",
+		Default: "128Mi",
+	}, {
+		Name:       "old-timeout",
+		Type:       DurationType,
+		Deprecated: `use "timeout" instead`,
+	}, {
+		Name:   "label.",
+		Prefix: true,
+	}},
+}
+
+func TestSchemaValidate(t *testing.T) {
+	tests := []struct {
+		name         string
+		schema       Schema
+		data         map[string]string
+		wantWarnings []string
+		wantErr      string
+	}{{
+		name:   "valid",
+		schema: testSchema,
+		data: map[string]string{
+			"loglevel.controller": "debug",
+			"enabled":             "true",
+			"replicas":            "10",
+			"timeout":             "30s",
+			"memory":              "1Gi",
+			"label.team":          "a",
+			ExampleKey:            "anything",
+		},
+	}, {
+		name:         "unknown key",
+		schema:       testSchema,
+		data:         map[string]string{"unknown": "x"},
+		wantWarnings: []string{`unknown key "unknown"`},
+	}, {
+		name:         "typo",
+		schema:       testSchema,
+		data:         map[string]string{"loglevel.controler": "info"},
+		wantWarnings: []string{`unknown key "loglevel.controler", did you mean "loglevel.controller"?`},
+	}, {
+		name: "rejected unknown key",
+		schema: Schema{
+			Keys:        testSchema.Keys,
+			UnknownKeys: RejectUnknownKeys,
+		},
+		data:    map[string]string{"unknown": "x"},
+		wantErr: `unknown key "unknown"`,
+	}, {
+		name:         "deprecated key",
+		schema:       testSchema,
+		data:         map[string]string{"old-timeout": "1s"},
+		wantWarnings: []string{`key "old-timeout" is deprecated: use "timeout" instead`},
+	}, {
+		name:    "not one of the values",
+		schema:  testSchema,
+		data:    map[string]string{"loglevel.controller": "verbose"},
+		wantErr: `invalid value "verbose" for "loglevel.controller", must be one of debug, info, warn, error`,
+	}, {
+		name:    "wrong type",
+		schema:  testSchema,
+		data:    map[string]string{"enabled": "yes"},
+		wantErr: `failed to parse "enabled"`,
+	}, {
+		name:    "below minimum",
+		schema:  testSchema,
+		data:    map[string]string{"replicas": "0"},
+		wantErr: `"replicas" is 0, must be at least 1`,
+	}, {
+		name:    "above maximum",
+		schema:  testSchema,
+		data:    map[string]string{"timeout": "2m"},
+		wantErr: `"timeout" is 2m, must be at most 1m`,
+	}, {
+		name:    "quantity below minimum",
+		schema:  testSchema,
+		data:    map[string]string{"memory": "1Mi"},
+		wantErr: `"memory" is 1Mi, must be at least 64Mi`,
+	}, {
+		name:   "all errors",
+		schema: testSchema,
+		data: map[string]string{
+			"replicas": "0",
+			"timeout":  "2m",
+		},
+		wantErr: "\"replicas\" is 0, must be at least 1\n\"timeout\" is 2m, must be at most 1m",
+	}}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			warnings, err := tc.schema.Validate(tc.data)
+			if tc.wantErr == "" && err != nil {
+				t.Error("Validate() =", err)
+			} else if tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)) {
+				t.Errorf("Validate() = %v, wanted an error containing %q", err, tc.wantErr)
+			}
+			if !cmp.Equal(warnings, tc.wantWarnings) {
+				t.Error("Warnings (-want, +got):", cmp.Diff(tc.wantWarnings, warnings))
+			}
+		})
+	}
+}
+
+func TestSchemaCheck(t *testing.T) {
+	tests := []struct {
+		name    string
+		keys    []Key
+		wantErr string
+	}{{
+		name: "valid",
+		keys: testSchema.Keys,
+	}, {
+		name:    "bounded string",
+		keys:    []Key{{Name: "name", Min: "a"}},
+		wantErr: `"name" may not have a minimum or maximum, string values have no order`,
+	}, {
+		name:    "bounded bool",
+		keys:    []Key{{Name: "enabled", Type: BoolType, Max: "true"}},
+		wantErr: `"enabled" may not have a minimum or maximum, bool values have no order`,
+	}, {
+		name:    "bounded string set",
+		keys:    []Key{{Name: "names", Type: StringSetType, Min: "1"}},
+		wantErr: `"names" may not have a minimum or maximum, stringset values have no order`,
+	}, {
+		name:    "bounded namespaced name",
+		keys:    []Key{{Name: "ref", Type: NamespacedNameType, Max: "ns/name"}},
+		wantErr: `"ref" may not have a minimum or maximum, namespacedname values have no order`,
+	}, {
+		name:    "unknown type",
+		keys:    []Key{{Name: "size", Type: "size"}},
+		wantErr: `unknown type "size" of "size"`,
+	}, {
+		name:    "invalid minimum",
+		keys:    []Key{{Name: "timeout", Type: DurationType, Min: "1"}},
+		wantErr: `timeout minimum`,
+	}, {
+		name:    "minimum above maximum",
+		keys:    []Key{{Name: "replicas", Type: IntType, Min: "10", Max: "1"}},
+		wantErr: `"replicas" has a minimum of 10 above its maximum of 1`,
+	}}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := Schemas{"config-test": {Keys: tc.keys}}.Check()
+			if tc.wantErr == "" && err != nil {
+				t.Error("Check() =", err)
+			} else if tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)) {
+				t.Errorf("Check() = %v, wanted an error containing %q", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestSchemaDiff(t *testing.T) {
+	tests := []struct {
+		name     string
+		old, new map[string]string
+		want     []string
+	}{{
+		name: "no change",
+		old:  map[string]string{"replicas": "1"},
+		new:  map[string]string{"replicas": "1", ExampleKey: "changed"},
+	}, {
+		name: "default made explicit",
+		old:  map[string]string{},
+		new:  map[string]string{"loglevel.controller": "info"},
+	}, {
+		name: "changes",
+		old: map[string]string{
+			"replicas":            "1",
+			"loglevel.controller": "debug",
+			"timeout":             "1s",
+		},
+		new: map[string]string{
+			"replicas": "2",
+			"enabled":  "true",
+			"memory":   "128Mi",
+		},
+		want: []string{
+			`enabled set to "true"`,
+			`loglevel.controller reset to its default "info", was "debug"`,
+			`replicas changed from "1" to "2"`,
+			`timeout unset, was "1s"`,
+		},
+	}}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := testSchema.Diff(tc.old, tc.new); !cmp.Equal(got, tc.want) {
+				t.Error("Diff (-want, +got):", cmp.Diff(tc.want, got))
+			}
+		})
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	for _, tc := range []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"abc", "", 3},
+		{"controler", "controller", 1},
+		{"kitten", "sitting", 3},
+	} {
+		if got := editDistance(tc.a, tc.b); got != tc.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tc.a, tc.b, got, tc.want)
+		}
+	}
+}
@@ -28,11 +28,15 @@ import (
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/workqueue"
@@ -258,7 +262,11 @@ func NewContext(ctx context.Context, r Reconciler, options ControllerOptions) *I
 	if t := GetTracker(ctx); t != nil {
 		i.Tracker = t
 	} else {
-		i.Tracker = tracker.New(i.EnqueueKey, GetTrackerLease(ctx))
+		var opts []tracker.Option
+		if GetTrackerMetrics(ctx) {
+			opts = append(opts, tracker.WithMetrics(i.Name))
+		}
+		i.Tracker = tracker.New(i.EnqueueKey, GetTrackerLease(ctx), opts...)
 	}
 
 	return i
@@ -835,6 +843,26 @@ func GetTracker(ctx context.Context) tracker.Interface {
 	return untyped.(tracker.Interface)
 }
 
+// trackerMetricsKey is used to enable the metrics of trackers on contexts.
+type trackerMetricsKey struct{}
+
+// WithTrackerMetrics enables the reference metrics of the trackers created by
+// NewContext for the controllers constructed with the returned context,
+// tagged with the controllers' names.  Binaries enable them by passing the
+// context to sharedmain, which then registers their views with
+// tracker.RegisterMetrics:
+//
+//	sharedmain.MainWithContext(controller.WithTrackerMetrics(ctx), "controller", ...)
+func WithTrackerMetrics(ctx context.Context) context.Context {
+	return context.WithValue(ctx, trackerMetricsKey{}, struct{}{})
+}
+
+// GetTrackerMetrics returns whether the trackers created by NewContext
+// export reference metrics.
+func GetTrackerMetrics(ctx context.Context) bool {
+	return ctx.Value(trackerMetricsKey{}) != nil
+}
+
 // erKey is used to associate record.EventRecorders with contexts.
 type erKey struct{}
 
@@ -854,6 +882,32 @@ func GetEventRecorder(ctx context.Context) record.EventRecorder {
 	return untyped.(record.EventRecorder)
 }
 
+// GetOrCreateEventRecorder returns the record.EventRecorder on the given
+// context, if any.  Otherwise it returns a recorder logging the events and
+// recording them through the given client as the named component, until
+// the context is cancelled.
+func GetOrCreateEventRecorder(ctx context.Context, events typedcorev1.EventsGetter, component string) record.EventRecorder {
+	if recorder := GetEventRecorder(ctx); recorder != nil {
+		return recorder
+	}
+
+	logger := logging.FromContext(ctx)
+	logger.Debug("Creating event broadcaster")
+	eventBroadcaster := record.NewBroadcaster()
+	watches := []watch.Interface{
+		eventBroadcaster.StartLogging(logger.Named("event-broadcaster").Infof),
+		eventBroadcaster.StartRecordingToSink(
+			&typedcorev1.EventSinkImpl{Interface: events.Events("")}),
+	}
+	go func() {
+		<-ctx.Done()
+		for _, w := range watches {
+			w.Stop()
+		}
+	}()
+	return eventBroadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: component})
+}
+
 func safeKey(key types.NamespacedName) string {
 	if key.Namespace == "" {
 		return key.Name
@@ -38,9 +38,11 @@ import (
 	"k8s.io/client-go/util/workqueue"
 
 	"github.com/Yangfisher1/knative-common-pkg/leaderelection"
+	"github.com/Yangfisher1/knative-common-pkg/metrics/metricstest"
 	"github.com/Yangfisher1/knative-common-pkg/ptr"
 	"github.com/Yangfisher1/knative-common-pkg/reconciler"
 	"github.com/Yangfisher1/knative-common-pkg/system"
+	"github.com/Yangfisher1/knative-common-pkg/tracker"
 
 	. "github.com/Yangfisher1/knative-common-pkg/controller/testing"
 	. "github.com/Yangfisher1/knative-common-pkg/logging/testing"
@@ -1731,6 +1733,41 @@ func TestGetResyncPeriod(t *testing.T) {
 	}
 }
 
+func TestTrackerMetrics(t *testing.T) {
+	ref := tracker.Reference{
+		APIVersion: "ref.knative.dev/v1alpha1",
+		Kind:       "Thing",
+		Namespace:  "ns",
+		Name:       "foo",
+	}
+	track := func(ctx context.Context, name string) {
+		impl := NewContext(ctx, &nopReconciler{}, ControllerOptions{WorkQueueName: name, Logger: TestLogger(t)})
+		if err := impl.Tracker.TrackReference(ref, &Resource{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "bar"}}); err != nil {
+			t.Fatal("TrackReference() =", err)
+		}
+	}
+
+	ctx := context.Background()
+	if GetTrackerMetrics(ctx) {
+		t.Error("GetTrackerMetrics() = true, wanted false")
+	}
+	track(ctx, "no-tracker-metrics")
+	metricstest.CheckStatsNotReported(t, "tracker_exact_references")
+
+	tracker.RegisterMetrics()
+	ctx = WithTrackerMetrics(ctx)
+	if !GetTrackerMetrics(ctx) {
+		t.Error("GetTrackerMetrics() = false, wanted true")
+	}
+	track(ctx, "tracker-metrics")
+	metricstest.CheckLastValueData(t, "tracker_exact_references", map[string]string{
+		"tracker": "tracker-metrics",
+		"group":   "ref.knative.dev",
+		"version": "v1alpha1",
+		"kind":    "Thing",
+	}, 1)
+}
+
 func TestGetEventRecorder(t *testing.T) {
 	ctx := context.Background()
 
@@ -1744,3 +1781,20 @@ func TestGetEventRecorder(t *testing.T) {
 		t.Error("GetEventRecorder() = nil, wanted non-nil")
 	}
 }
+
+func TestGetOrCreateEventRecorder(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	kc := fakekube.NewSimpleClientset()
+
+	if got := GetOrCreateEventRecorder(ctx, kc.CoreV1(), "test"); got == nil {
+		t.Error("GetOrCreateEventRecorder() = nil, wanted non-nil")
+	}
+
+	want := record.NewFakeRecorder(1000)
+	ctx = WithEventRecorder(ctx, want)
+
+	if got := GetOrCreateEventRecorder(ctx, kc.CoreV1(), "test"); got != want {
+		t.Errorf("GetOrCreateEventRecorder() = %v, wanted the recorder on the context", got)
+	}
+}
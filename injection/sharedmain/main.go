@@ -51,6 +51,7 @@ import (
 	"github.com/Yangfisher1/knative-common-pkg/reconciler"
 	"github.com/Yangfisher1/knative-common-pkg/signals"
 	"github.com/Yangfisher1/knative-common-pkg/system"
+	"github.com/Yangfisher1/knative-common-pkg/tracker"
 	"github.com/Yangfisher1/knative-common-pkg/version"
 	"github.com/Yangfisher1/knative-common-pkg/webhook"
 )
@@ -240,7 +241,11 @@ func MainWithConfig(ctx context.Context, component string, cfg *rest.Config, cto
 			leaderElectionConfig.GetComponentConfig(component))
 	}
 
+	if controller.GetTrackerMetrics(ctx) {
+		tracker.RegisterMetrics()
+	}
 	controllers, webhooks := ControllersAndWebhooksFromCtors(ctx, cmw, ctors...)
+	profilingHandler.Handle(trackerDumpPath, trackerDumpHandler(controllers))
 	WatchLoggingConfigOrDie(ctx, cmw, logger, atomicLevel, component)
 	WatchObservabilityConfigOrDie(ctx, cmw, profilingHandler, logger, component)
 
@@ -294,6 +299,22 @@ func MainWithConfig(ctx context.Context, component string, cfg *rest.Config, cto
 	}
 }
 
+// trackerDumpPath is the path on the profiling server where the references
+// held by each controller's tracker are served.
+const trackerDumpPath = "/debug/tracker"
+
+// trackerDumpHandler serves the references tracked by the given controllers,
+// keyed by controller name.
+func trackerDumpHandler(controllers []*controller.Impl) http.Handler {
+	trackers := make(map[string]tracker.Interface, len(controllers))
+	for _, c := range controllers {
+		if c.Tracker != nil {
+			trackers[c.Name] = c.Tracker
+		}
+	}
+	return tracker.NewDumpHandler(trackers)
+}
+
 func flush(logger *zap.SugaredLogger) {
 	logger.Sync()
 	metrics.FlushExporter()
@@ -88,3 +88,16 @@ func startNewPromSrv(e *prom.Exporter, host string, port int) *http.Server {
 	}
 	return curPromSrv
 }
+
+// PrometheusHandler serves the metrics of the Prometheus exporter, if the
+// Prometheus backend is configured, e.g. to expose them on another listener
+// than the exporter's own.
+func PrometheusHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if e, ok := getCurMetricsExporter().(*prom.Exporter); ok {
+			e.ServeHTTP(w, r)
+			return
+		}
+		http.NotFound(w, r)
+	})
+}
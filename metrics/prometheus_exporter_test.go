@@ -16,6 +16,8 @@ package metrics
 
 import (
 	"context"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
@@ -135,3 +137,34 @@ func expectPromSrv(t *testing.T, expectedAddr string) {
 		t.Errorf("metrics port addresses diff, got=%v, want=%v", got, want)
 	}
 }
+
+func TestPrometheusHandler(t *testing.T) {
+	prev := getCurMetricsExporter()
+	defer setCurMetricsExporter(prev)
+
+	setCurMetricsExporter(nil)
+	rec := httptest.NewRecorder()
+	PrometheusHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if got, want := rec.Code, http.StatusNotFound; got != want {
+		t.Errorf("status without the Prometheus backend = %d, want %d", got, want)
+	}
+
+	e, _, err := newPrometheusExporter(&metricsConfig{
+		domain:             "does not matter",
+		component:          testComponent,
+		backendDestination: prometheus,
+		prometheusPort:     9095,
+		prometheusHost:     "127.0.0.1",
+	}, TestLogger(t))
+	if err != nil {
+		t.Fatal("newPrometheusExporter() =", err)
+	}
+	defer resetCurPromSrv()
+	setCurMetricsExporter(e)
+
+	rec = httptest.NewRecorder()
+	PrometheusHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if got, want := rec.Code, http.StatusOK; got != want {
+		t.Errorf("status with the Prometheus backend = %d, want %d", got, want)
+	}
+}
@@ -45,7 +45,7 @@ const (
 // whether the handler is active
 type Handler struct {
 	enabled *atomic.Bool
-	handler http.Handler
+	handler *http.ServeMux
 	log     *zap.SugaredLogger
 }
 
@@ -69,6 +69,12 @@ func NewHandler(logger *zap.SugaredLogger, enableProfiling bool) *Handler {
 	}
 }
 
+// Handle registers an additional debug handler for the given pattern.
+// Like the profiling endpoints, it is only served while profiling is enabled.
+func (h *Handler) Handle(pattern string, handler http.Handler) {
+	h.handler.Handle(pattern, handler)
+}
+
 func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if h.enabled.Load() {
 		h.handler.ServeHTTP(w, r)
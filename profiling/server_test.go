@@ -101,3 +101,28 @@ func TestUpdateFromConfigMap(t *testing.T) {
 		})
 	}
 }
+
+func TestHandle(t *testing.T) {
+	handler := NewHandler(zap.NewNop().Sugar(), false)
+	handler.Handle("/debug/extra", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+
+	for _, tc := range []struct {
+		enabled  bool
+		wantCode int
+	}{{
+		enabled:  false,
+		wantCode: http.StatusNotFound,
+	}, {
+		enabled:  true,
+		wantCode: http.StatusTeapot,
+	}} {
+		handler.enabled.Store(tc.enabled)
+		rw := httptest.NewRecorder()
+		handler.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/debug/extra", nil))
+		if rw.Code != tc.wantCode {
+			t.Errorf("StatusCode (enabled=%v) = %d, want: %d", tc.enabled, rw.Code, tc.wantCode)
+		}
+	}
+}
@@ -63,6 +63,16 @@ func (n *FakeTracker) GetObservers(obj interface{}) []types.NamespacedName {
 	for key := range n.references[ref] {
 		keys = append(keys, key)
 	}
+
+	// Handle references by selector, owner or fields.
+	for r, objs := range n.references {
+		if r.Name != "" || !r.Matches(item) {
+			continue
+		}
+		for key := range objs {
+			keys = append(keys, key)
+		}
+	}
 	return keys
 }
 
@@ -148,6 +148,63 @@ func TestFakeTracker(t *testing.T) {
 	}
 }
 
+func TestFakeTrackerInexact(t *testing.T) {
+	observer := &Resource{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "ns",
+			Name:      "foo",
+		},
+	}
+	observerName := types.NamespacedName{
+		Namespace: observer.Namespace,
+		Name:      observer.Name,
+	}
+
+	owned := &Resource{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "fakeapi",
+			Kind:       "Fake",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "ns",
+			Name:      "owned",
+			OwnerReferences: []metav1.OwnerReference{{
+				UID: "owner-uid",
+			}},
+		},
+	}
+	other := &Resource{
+		TypeMeta: owned.TypeMeta,
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "ns",
+			Name:      "other",
+		},
+	}
+	ref := tracker.Reference{
+		APIVersion: owned.APIVersion,
+		Kind:       owned.Kind,
+		Namespace:  owned.Namespace,
+		OwnerUID:   "owner-uid",
+	}
+
+	trk := &FakeTracker{}
+	trk.TrackReference(ref, observer)
+	if !isTracking(trk, ref) {
+		t.Fatal("Tracker is not tracking", ref)
+	}
+	if !hasObserver(trk, owned, observerName) {
+		t.Fatalf("Object %v is not being observed by %v", owned, observerName)
+	}
+	if hasObserver(trk, other, observerName) {
+		t.Fatalf("Object %v wrongly being observed by %v", other, observerName)
+	}
+
+	trk.OnDeletedObserver(observer)
+	if hasObserver(trk, owned, observerName) {
+		t.Fatalf("Object %v is still being observed by %v", owned, observerName)
+	}
+}
+
 func isTracking(tracker *FakeTracker, ref1 tracker.Reference) bool {
 	for _, tracking := range tracker.References() {
 		if tracking == ref1 {
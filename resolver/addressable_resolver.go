@@ -20,8 +20,12 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
+
+	"go.uber.org/zap"
 
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/client-go/tools/cache"
 
 	"github.com/Yangfisher1/knative-common-pkg/client/injection/ducks/duck/v1/addressable"
@@ -35,6 +39,7 @@ import (
 	pkgapisduck "github.com/Yangfisher1/knative-common-pkg/apis/duck"
 	duckv1 "github.com/Yangfisher1/knative-common-pkg/apis/duck/v1"
 	duckv1beta1 "github.com/Yangfisher1/knative-common-pkg/apis/duck/v1beta1"
+	"github.com/Yangfisher1/knative-common-pkg/logging"
 	"github.com/Yangfisher1/knative-common-pkg/network"
 	"github.com/Yangfisher1/knative-common-pkg/tracker"
 )
@@ -48,19 +53,39 @@ type URIResolver struct {
 	tracker       tracker.Interface
 	listerFactory func(schema.GroupVersionResource) (cache.GenericLister, error)
 	resolvers     []RefResolverFunc
+	registry      *Registry
+	cache         *addressCache
+
+	// services is used to resolve the ports of referenced Services.
+	services duckv1.ServiceGetter
 }
 
 // NewURIResolverFromTracker constructs a new URIResolver with context, a tracker and an optional list of custom resolvers.
+// The custom resolvers are consulted before those registered with the Default Registry.
+// Resolved addresses are cached as configured by WithCacheConfig, until the
+// referenced objects change.
+// References to a port of a v1 Service are resolved with the ServiceGetter
+// attached by duckv1.WithServiceGetter.
+// The resolution latencies and failures are exported once the binary has
+// registered their views with RegisterMetrics.
 func NewURIResolverFromTracker(ctx context.Context, t tracker.Interface, resolvers ...RefResolverFunc) *URIResolver {
 	ret := &URIResolver{
 		tracker:   t,
 		resolvers: resolvers,
+		registry:  Default,
+		cache:     newAddressCache(GetCacheConfig(ctx)),
+		services:  duckv1.GetServiceGetter(ctx),
 	}
 
 	informerFactory := &pkgapisduck.CachedInformerFactory{
 		Delegate: &pkgapisduck.EnqueueInformerFactory{
-			Delegate:     addressable.Get(ctx),
-			EventHandler: controller.HandleAll(ret.tracker.OnChanged),
+			Delegate: addressable.Get(ctx),
+			EventHandler: controller.HandleAll(func(obj interface{}) {
+				// Drop stale addresses before the tracker triggers a
+				// reconcile that would read them.
+				ret.cache.evict(obj)
+				ret.tracker.OnChanged(obj)
+			}),
 		},
 	}
 
@@ -119,8 +144,24 @@ func (r *URIResolver) URIFromDestination(ctx context.Context, dest duckv1beta1.D
 
 // URIFromDestinationV1 resolves a v1.Destination into a URL.
 func (r *URIResolver) URIFromDestinationV1(ctx context.Context, dest duckv1.Destination, parent interface{}) (*apis.URL, error) {
+	addr, err := r.AddressableFromDestinationV1(ctx, dest, parent)
+	if err != nil {
+		return nil, err
+	}
+	return addr.URL, nil
+}
+
+// AddressableFromDestinationV1 resolves a v1.Destination into a full
+// Addressable, including its CACerts and audience.  When the Destination
+// references an object that publishes several addresses, the one named by
+// dest.Ref.Address is returned, or the first one if no name is given.
+// References handled by a RefResolverFunc resolve to a single URL, so naming
+// an address for them is an error.
+// CACerts and Audience set on the Destination take precedence over those
+// of the resolved address.
+func (r *URIResolver) AddressableFromDestinationV1(ctx context.Context, dest duckv1.Destination, parent interface{}) (*duckv1.Addressable, error) {
 	if dest.Ref != nil {
-		url, err := r.URIFromKReference(ctx, dest.Ref, parent)
+		addr, err := r.addressableFromKReference(ctx, dest.Ref, parent)
 		if err != nil {
 			return nil, err
 		}
@@ -128,9 +169,15 @@ func (r *URIResolver) URIFromDestinationV1(ctx context.Context, dest duckv1.Dest
 			if dest.URI.URL().IsAbs() {
 				return nil, errors.New("absolute URI is not allowed when Ref or [apiVersion, kind, name] exists")
 			}
-			return url.ResolveReference(dest.URI), nil
+			addr.URL = addr.URL.ResolveReference(dest.URI)
+		}
+		if dest.CACerts != nil {
+			addr.CACerts = dest.CACerts
 		}
-		return url, nil
+		if dest.Audience != nil {
+			addr.Audience = dest.Audience
+		}
+		return addr, nil
 	}
 
 	if dest.URI != nil {
@@ -138,41 +185,100 @@ func (r *URIResolver) URIFromDestinationV1(ctx context.Context, dest duckv1.Dest
 		if !dest.URI.URL().IsAbs() || dest.URI.Host == "" {
 			return nil, fmt.Errorf("URI is not absolute(both scheme and host should be non-empty): %q", dest.URI.String())
 		}
-		return dest.URI, nil
+		return &duckv1.Addressable{
+			URL:      dest.URI,
+			CACerts:  dest.CACerts,
+			Audience: dest.Audience,
+		}, nil
 	}
 
 	return nil, errors.New("destination missing Ref and URI, expected at least one")
 }
 
 func (r *URIResolver) URIFromKReference(ctx context.Context, ref *duckv1.KReference, parent interface{}) (*apis.URL, error) {
-	return r.URIFromObjectReference(ctx, &corev1.ObjectReference{Name: ref.Name, Namespace: ref.Namespace, APIVersion: ref.APIVersion, Kind: ref.Kind}, parent)
+	addr, err := r.addressableFromKReference(ctx, ref, parent)
+	if err != nil {
+		return nil, err
+	}
+	return addr.URL, nil
+}
+
+func (r *URIResolver) addressableFromKReference(ctx context.Context, ref *duckv1.KReference, parent interface{}) (*duckv1.Addressable, error) {
+	if ref.Port != nil && !ref.IsServiceReference() {
+		return nil, apierrs.NewBadRequest(fmt.Sprintf("port is only supported for v1 Services, got %+v", ref))
+	}
+	return r.addressableFromObjectReference(ctx, &corev1.ObjectReference{Name: ref.Name, Namespace: ref.Namespace, APIVersion: ref.APIVersion, Kind: ref.Kind}, ref.Address, ref.Port, parent)
 }
 
 // URIFromObjectReference resolves an ObjectReference to a URI string.
 func (r *URIResolver) URIFromObjectReference(ctx context.Context, ref *corev1.ObjectReference, parent interface{}) (*apis.URL, error) {
+	addr, err := r.addressableFromObjectReference(ctx, ref, nil, nil, parent)
+	if err != nil {
+		return nil, err
+	}
+	return addr.URL, nil
+}
+
+// addressableFromObjectReference resolves an ObjectReference to the
+// address with the given name, or the first address if name is nil.
+// For a v1 Service, port optionally selects the Service port to address.
+// The returned Addressable is a copy and may be modified by the caller.
+func (r *URIResolver) addressableFromObjectReference(ctx context.Context, ref *corev1.ObjectReference, name *string, port *intstr.IntOrString, parent interface{}) (*duckv1.Addressable, error) {
 	if ref == nil {
 		return nil, apierrs.NewBadRequest("ref is nil")
 	}
 
-	// try custom resolvers first
-	for _, resolver := range r.resolvers {
+	start := time.Now()
+	addr, err := r.resolve(ctx, ref, name, port, parent)
+	reportResolve(ref.GroupVersionKind(), time.Since(start), err)
+	return addr, err
+}
+
+func (r *URIResolver) resolve(ctx context.Context, ref *corev1.ObjectReference, name *string, port *intstr.IntOrString, parent interface{}) (*duckv1.Addressable, error) {
+	key := newCacheKey(ref, name, port)
+	if e, ok := r.cache.get(key); ok {
+		if err := r.track(ref, parent); err != nil {
+			return nil, err
+		}
+		return e.addr.DeepCopy(), nil
+	}
+
+	// try custom resolvers first, then those registered for the GroupKind.
+	// They don't know about ports, so skip them when one is requested.
+	var resolvers, registered []RefResolverFunc
+	if port == nil {
+		registered = r.registry.Get(ref.GroupVersionKind().GroupKind())
+		resolvers = make([]RefResolverFunc, 0, len(r.resolvers)+len(registered))
+		resolvers = append(append(resolvers, r.resolvers...), registered...)
+	}
+	for _, resolver := range resolvers {
 		handled, url, err := resolver(ctx, ref)
 		if handled {
-			return url, err
+			if err != nil {
+				return nil, err
+			}
+			if name != nil {
+				// They only resolve a single URL, so can't select an address.
+				return nil, apierrs.NewBadRequest(fmt.Sprintf("address %q can't be selected for %+v, which is resolved to a single URL", *name, ref))
+			}
+			addr := &duckv1.Addressable{URL: url}
+			if r.cache != nil && r.watch(ref, parent) == nil {
+				r.cache.put(key, addr)
+			}
+			return addr, nil
 		}
 
 		// when handled is false, both url and err are ignored.
 	}
+	if len(registered) > 0 {
+		logging.FromContext(ctx).Debugw("No registered resolver handled the reference, falling back to Addressable",
+			zap.Any("ref", ref))
+	}
 
-	gvr, _ := meta.UnsafeGuessKindToResource(ref.GroupVersionKind())
-	if err := r.tracker.TrackReference(tracker.Reference{
-		APIVersion: ref.APIVersion,
-		Kind:       ref.Kind,
-		Namespace:  ref.Namespace,
-		Name:       ref.Name,
-	}, parent); err != nil {
-		return nil, fmt.Errorf("failed to track reference %s %s/%s: %w", gvr.String(), ref.Namespace, ref.Name, err)
+	if err := r.track(ref, parent); err != nil {
+		return nil, err
 	}
+	gvr, _ := meta.UnsafeGuessKindToResource(ref.GroupVersionKind())
 
 	lister, err := r.listerFactory(gvr)
 	if err != nil {
@@ -192,22 +298,108 @@ func (r *URIResolver) URIFromObjectReference(ctx context.Context, ref *corev1.Ob
 			Host:   network.GetServiceHostname(ref.Name, ref.Namespace),
 			Path:   "",
 		}
-		return url, nil
+		if port != nil {
+			if url, err = r.serviceURL(ref, *port); err != nil {
+				return nil, err
+			}
+		}
+		addr := &duckv1.Addressable{URL: url}
+		r.cache.put(key, addr)
+		return addr, nil
 	}
 
 	addressable, ok := obj.(*duckv1.AddressableType)
 	if !ok {
 		return nil, apierrs.NewBadRequest(fmt.Sprintf("%+v (%T) is not an AddressableType", ref, ref))
 	}
-	if addressable.Status.Address == nil {
-		return nil, apierrs.NewBadRequest(fmt.Sprintf("address not set for %+v", ref))
+	addr, err := selectAddress(&addressable.Status, name)
+	if err != nil {
+		return nil, apierrs.NewBadRequest(fmt.Sprintf("%v for %+v", err, ref))
 	}
-	url := addressable.Status.Address.URL
+	url := addr.URL
 	if url == nil {
 		return nil, apierrs.NewBadRequest(fmt.Sprintf("URL missing in address of %+v", ref))
 	}
 	if url.Host == "" {
 		return nil, apierrs.NewBadRequest(fmt.Sprintf("hostname missing in address of %+v", ref))
 	}
+	r.cache.put(key, addr)
+	// The object comes from the informer cache, so hand out a copy.
+	return addr.DeepCopy(), nil
+}
+
+// serviceURL returns the URL of the given port of the referenced Service.
+// The scheme is picked from the port's appProtocol, and the port number is
+// omitted from the URL when it is the scheme's default.
+func (r *URIResolver) serviceURL(ref *corev1.ObjectReference, port intstr.IntOrString) (*apis.URL, error) {
+	if r.services == nil {
+		return nil, fmt.Errorf("unable to resolve port %q of Service %s/%s: no ServiceGetter available", port.String(), ref.Namespace, ref.Name)
+	}
+	svc, err := r.services.GetService(ref.Namespace, ref.Name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Service %s/%s: %w", ref.Namespace, ref.Name, err)
+	}
+	sp, ok := duckv1.FindServicePort(svc, port)
+	if !ok {
+		return nil, apierrs.NewBadRequest(fmt.Sprintf("port %q not found on Service %s/%s", port.String(), ref.Namespace, ref.Name))
+	}
+
+	url := &apis.URL{
+		Scheme: duckv1.ServicePortScheme(sp),
+		Host:   network.GetServiceHostname(ref.Name, ref.Namespace),
+	}
+	if !(url.Scheme == "http" && sp.Port == 80) && !(url.Scheme == "https" && sp.Port == 443) {
+		url.Host = fmt.Sprintf("%s:%d", url.Host, sp.Port)
+	}
 	return url, nil
 }
+
+// watch registers the parent as tracking the referenced object, and starts
+// the informer whose events evict the object's cached addresses, so that the
+// addresses resolved by a RefResolverFunc may be cached until the object
+// changes.
+func (r *URIResolver) watch(ref *corev1.ObjectReference, parent interface{}) error {
+	if err := r.track(ref, parent); err != nil {
+		return err
+	}
+	gvr, _ := meta.UnsafeGuessKindToResource(ref.GroupVersionKind())
+	_, err := r.listerFactory(gvr)
+	return err
+}
+
+// track registers the parent as tracking the referenced object.
+func (r *URIResolver) track(ref *corev1.ObjectReference, parent interface{}) error {
+	if err := r.tracker.TrackReference(tracker.Reference{
+		APIVersion: ref.APIVersion,
+		Kind:       ref.Kind,
+		Namespace:  ref.Namespace,
+		Name:       ref.Name,
+	}, parent); err != nil {
+		gvr, _ := meta.UnsafeGuessKindToResource(ref.GroupVersionKind())
+		return fmt.Errorf("failed to track reference %s %s/%s: %w", gvr.String(), ref.Namespace, ref.Name, err)
+	}
+	return nil
+}
+
+// selectAddress returns the address with the given name, or the first
+// address when name is nil.  As documented on AddressStatus, Address is
+// ignored when Addresses is present.
+func selectAddress(status *duckv1.AddressStatus, name *string) (*duckv1.Addressable, error) {
+	addresses := status.Addresses
+	if len(addresses) == 0 && status.Address != nil {
+		addresses = []duckv1.Addressable{*status.Address}
+	}
+	if name == nil {
+		if len(addresses) == 0 {
+			return nil, errors.New("address not set")
+		}
+		return &addresses[0], nil
+	}
+
+	for i := range addresses {
+		if a := &addresses[i]; a.Name != nil && *a.Name == *name {
+			return a, nil
+		}
+	}
+	return nil, fmt.Errorf("address %q not found", *name)
+}
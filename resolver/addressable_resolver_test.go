@@ -24,11 +24,15 @@ import (
 
 	"github.com/google/go-cmp/cmp"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/client-go/kubernetes/scheme"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
 
 	"github.com/Yangfisher1/knative-common-pkg/apis"
 	duckv1 "github.com/Yangfisher1/knative-common-pkg/apis/duck/v1"
@@ -36,6 +40,7 @@ import (
 	duckv1beta1 "github.com/Yangfisher1/knative-common-pkg/apis/duck/v1beta1"
 	"github.com/Yangfisher1/knative-common-pkg/client/injection/ducks/duck/v1/addressable"
 	fakedynamicclient "github.com/Yangfisher1/knative-common-pkg/injection/clients/dynamicclient/fake"
+	"github.com/Yangfisher1/knative-common-pkg/ptr"
 	"github.com/Yangfisher1/knative-common-pkg/resolver"
 	"github.com/Yangfisher1/knative-common-pkg/tracker"
 )
@@ -555,6 +560,17 @@ func TestGetURIDestinationV1(t *testing.T) {
 			dest:            duckv1.Destination{Ref: k8sServiceRef()},
 			customResolvers: []resolver.RefResolverFunc{sampleURIResolver, noopURIResolver},
 			wantURI:         "ref://" + addressableName + ".Service.v1",
+		}, "named address with sample resolver": {
+			dest: duckv1.Destination{Ref: &duckv1.KReference{
+				Kind:       "Service",
+				Name:       addressableName,
+				APIVersion: "v1",
+				Namespace:  testNS,
+				Address:    ptr.String("https"),
+			}},
+			customResolvers: []resolver.RefResolverFunc{sampleURIResolver},
+			wantErr: fmt.Sprintf(`address "https" can't be selected for %+v, which is resolved to a single URL`,
+				&corev1.ObjectReference{Kind: "Service", Namespace: testNS, Name: addressableName, APIVersion: "v1"}),
 		}, "happy with two sample resolvers, second one passes": {
 			dest:            duckv1.Destination{Ref: k8sServiceRef()},
 			customResolvers: []resolver.RefResolverFunc{noopURIResolver, sampleURIResolver},
@@ -588,6 +604,242 @@ func TestGetURIDestinationV1(t *testing.T) {
 	}
 }
 
+func TestAddressableFromDestinationV1(t *testing.T) {
+	httpName, httpsName, missingName, legacyName := "http", "https", "grpc", "legacy"
+	caCerts, audience := "addressable-ca-certs", "addressable-audience"
+	destCACerts, destAudience := "destination-ca-certs", "destination-audience"
+
+	tests := map[string]struct {
+		objects []runtime.Object
+		dest    duckv1.Destination
+		want    *duckv1.Addressable
+		wantErr string
+	}{"URI with CACerts and audience": {
+		dest: duckv1.Destination{
+			URI:      apis.HTTP("example.com"),
+			CACerts:  &destCACerts,
+			Audience: &destAudience,
+		},
+		want: &duckv1.Addressable{
+			URL:      apis.HTTP("example.com"),
+			CACerts:  &destCACerts,
+			Audience: &destAudience,
+		},
+	}, "ref with single address": {
+		objects: []runtime.Object{
+			getAddressable(),
+		},
+		dest: duckv1.Destination{Ref: addressableKnativeRef()},
+		want: &duckv1.Addressable{
+			URL: apis.HTTP("addressable.sink.svc.cluster.local"),
+		},
+	}, "ref with addresses uses the first": {
+		objects: []runtime.Object{
+			addressableWithAddresses(),
+		},
+		dest: duckv1.Destination{Ref: addressableKnativeRef()},
+		want: &duckv1.Addressable{
+			Name: &httpName,
+			URL:  apis.HTTP("addressable.sink.svc.cluster.local"),
+		},
+	}, "ref with named address": {
+		objects: []runtime.Object{
+			addressableWithAddresses(),
+		},
+		dest: duckv1.Destination{Ref: namedAddressRef(httpsName)},
+		want: &duckv1.Addressable{
+			Name: &httpsName,
+			URL: &apis.URL{
+				Scheme: "https",
+				Host:   "addressable.sink.svc.cluster.local",
+			},
+			CACerts:  &caCerts,
+			Audience: &audience,
+		},
+	}, "ref with named address and destination overrides": {
+		objects: []runtime.Object{
+			addressableWithAddresses(),
+		},
+		dest: duckv1.Destination{
+			Ref:      namedAddressRef(httpsName),
+			URI:      &apis.URL{Path: "/foo"},
+			CACerts:  &destCACerts,
+			Audience: &destAudience,
+		},
+		want: &duckv1.Addressable{
+			Name: &httpsName,
+			URL: &apis.URL{
+				Scheme: "https",
+				Host:   "addressable.sink.svc.cluster.local",
+				Path:   "/foo",
+			},
+			CACerts:  &destCACerts,
+			Audience: &destAudience,
+		},
+	}, "ref with address and addresses uses the first of addresses": {
+		objects: []runtime.Object{
+			addressableWithAddressAndAddresses(),
+		},
+		dest: duckv1.Destination{Ref: addressableKnativeRef()},
+		want: &duckv1.Addressable{
+			Name: &httpName,
+			URL:  apis.HTTP("addressable.sink.svc.cluster.local"),
+		},
+	}, "ref with address and addresses ignores the name of address": {
+		objects: []runtime.Object{
+			addressableWithAddressAndAddresses(),
+		},
+		dest: duckv1.Destination{Ref: namedAddressRef(legacyName)},
+		wantErr: fmt.Sprintf(`address "legacy" not found for %+v`,
+			&corev1.ObjectReference{Kind: addressableKind, Namespace: testNS, Name: addressableName, APIVersion: addressableAPIVersion}),
+	}, "ref with named address only in address": {
+		objects: []runtime.Object{
+			addressableWithNamedAddress(legacyName),
+		},
+		dest: duckv1.Destination{Ref: namedAddressRef(legacyName)},
+		want: &duckv1.Addressable{
+			Name: &legacyName,
+			URL:  apis.HTTP("legacy.sink.svc.cluster.local"),
+		},
+	}, "ref with missing named address": {
+		objects: []runtime.Object{
+			addressableWithAddresses(),
+		},
+		dest: duckv1.Destination{Ref: namedAddressRef(missingName)},
+		wantErr: fmt.Sprintf(`address "grpc" not found for %+v`,
+			&corev1.ObjectReference{Kind: addressableKind, Namespace: testNS, Name: addressableName, APIVersion: addressableAPIVersion}),
+	}}
+
+	for n, tc := range tests {
+		t.Run(n, func(t *testing.T) {
+			ctx, _ := fakedynamicclient.With(context.Background(), scheme.Scheme, tc.objects...)
+			ctx = addressable.WithDuck(ctx)
+			r := resolver.NewURIResolverFromTracker(ctx, tracker.New(func(types.NamespacedName) {}, 0))
+
+			// Run it twice since this should be idempotent. URI Resolver should
+			// not modify the cache's copy.
+			_, _ = r.AddressableFromDestinationV1(ctx, tc.dest, getAddressable())
+			got, gotErr := r.AddressableFromDestinationV1(ctx, tc.dest, getAddressable())
+
+			if gotErr != nil {
+				if tc.wantErr == "" {
+					t.Fatal("Unexpected error:", gotErr)
+				}
+				if got, want := gotErr.Error(), tc.wantErr; got != want {
+					t.Errorf("Unexpected error (-want, +got) =\n%s", cmp.Diff(want, got))
+				}
+				return
+			} else if tc.wantErr != "" {
+				t.Fatal("Expected error:", tc.wantErr)
+			}
+			if !cmp.Equal(got, tc.want) {
+				t.Errorf("Unexpected object (-want, +got) =\n%s", cmp.Diff(tc.want, got))
+			}
+
+			// Existing callers keep getting the selected URL.
+			uri, err := r.URIFromDestinationV1(ctx, tc.dest, getAddressable())
+			if err != nil {
+				t.Fatal("URIFromDestinationV1() =", err)
+			}
+			if got, want := uri.String(), tc.want.URL.String(); got != want {
+				t.Errorf("URIFromDestinationV1() = %s, wanted %s", got, want)
+			}
+		})
+	}
+}
+
+func TestAddressableFromDestinationV1ServicePort(t *testing.T) {
+	svc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Namespace: testNS, Name: addressableName},
+		Spec: corev1.ServiceSpec{
+			Ports: []corev1.ServicePort{{
+				Name: "http",
+				Port: 80,
+			}, {
+				Name: "admin",
+				Port: 8080,
+			}, {
+				Name:        "secure",
+				Port:        8443,
+				AppProtocol: ptr.String("https"),
+			}, {
+				Name: "https",
+				Port: 443,
+			}},
+		},
+	}
+	withPort := func(port intstr.IntOrString) *duckv1.KReference {
+		ref := k8sServiceRef()
+		ref.Port = &port
+		return ref
+	}
+
+	tests := map[string]struct {
+		ref     *duckv1.KReference
+		lister  bool
+		wantURI string
+		wantErr string
+	}{"no port": {
+		ref:     k8sServiceRef(),
+		wantURI: "http://testsink.testnamespace.svc.cluster.local",
+	}, "default http port by name": {
+		ref:     withPort(intstr.FromString("http")),
+		lister:  true,
+		wantURI: "http://testsink.testnamespace.svc.cluster.local",
+	}, "http port by number": {
+		ref:     withPort(intstr.FromInt(8080)),
+		lister:  true,
+		wantURI: "http://testsink.testnamespace.svc.cluster.local:8080",
+	}, "https from appProtocol": {
+		ref:     withPort(intstr.FromString("secure")),
+		lister:  true,
+		wantURI: "https://testsink.testnamespace.svc.cluster.local:8443",
+	}, "https from port name": {
+		ref:     withPort(intstr.FromInt(443)),
+		lister:  true,
+		wantURI: "https://testsink.testnamespace.svc.cluster.local",
+	}, "missing port": {
+		ref:     withPort(intstr.FromString("grpc")),
+		lister:  true,
+		wantErr: `port "grpc" not found on Service testnamespace/testsink`,
+	}, "no lister": {
+		ref:     withPort(intstr.FromString("http")),
+		wantErr: `unable to resolve port "http" of Service testnamespace/testsink: no ServiceGetter available`,
+	}}
+
+	for n, tc := range tests {
+		t.Run(n, func(t *testing.T) {
+			ctx, _ := fakedynamicclient.With(context.Background(), scheme.Scheme, getAddressableFromKRef(tc.ref))
+			ctx = addressable.WithDuck(ctx)
+			if tc.lister {
+				indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+				indexer.Add(svc)
+				lister := corev1listers.NewServiceLister(indexer)
+				ctx = duckv1.WithServiceGetter(ctx, duckv1.ServiceGetterFunc(func(namespace, name string) (*corev1.Service, error) {
+					return lister.Services(namespace).Get(name)
+				}))
+			}
+			r := resolver.NewURIResolverFromTracker(ctx, tracker.New(func(types.NamespacedName) {}, 0))
+
+			got, gotErr := r.AddressableFromDestinationV1(ctx, duckv1.Destination{Ref: tc.ref}, getAddressable())
+			if gotErr != nil {
+				if tc.wantErr == "" {
+					t.Fatal("Unexpected error:", gotErr)
+				}
+				if got, want := gotErr.Error(), tc.wantErr; got != want {
+					t.Errorf("Unexpected error (-want, +got) =\n%s", cmp.Diff(want, got))
+				}
+				return
+			} else if tc.wantErr != "" {
+				t.Fatal("Expected error:", tc.wantErr)
+			}
+			if got, want := got.URL.String(), tc.wantURI; got != want {
+				t.Errorf("URL = %s, wanted %s", got, want)
+			}
+		})
+	}
+}
+
 func TestURIFromObjectReferenceErrors(t *testing.T) {
 	tests := map[string]struct {
 		objects []runtime.Object
@@ -682,6 +934,61 @@ func addressableWithPathAndNoTrailingSlash() *unstructured.Unstructured {
 	}
 }
 
+func addressableWithAddresses() *unstructured.Unstructured {
+	return &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": addressableAPIVersion,
+			"kind":       addressableKind,
+			"metadata": map[string]interface{}{
+				"namespace": testNS,
+				"name":      addressableName,
+			},
+			"status": map[string]interface{}{
+				"addresses": []interface{}{
+					map[string]interface{}{
+						"name": "http",
+						"url":  addressableDNS,
+					},
+					map[string]interface{}{
+						"name":     "https",
+						"url":      "https://addressable.sink.svc.cluster.local",
+						"CACerts":  "addressable-ca-certs",
+						"audience": "addressable-audience",
+					},
+				},
+			},
+		},
+	}
+}
+
+func addressableWithNamedAddress(name string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": addressableAPIVersion,
+			"kind":       addressableKind,
+			"metadata": map[string]interface{}{
+				"namespace": testNS,
+				"name":      addressableName,
+			},
+			"status": map[string]interface{}{
+				"address": map[string]interface{}{
+					"name": name,
+					"url":  "http://legacy.sink.svc.cluster.local",
+				},
+			},
+		},
+	}
+}
+
+func addressableWithAddressAndAddresses() *unstructured.Unstructured {
+	u := addressableWithAddresses()
+	unstructured.SetNestedMap(u.Object, map[string]interface{}{
+		"name": "legacy",
+		"url":  "http://legacy.sink.svc.cluster.local",
+	}, "status", "address")
+	return u
+}
+
 func addressableNoStatus() *unstructured.Unstructured {
 	return &unstructured.Unstructured{
 		Object: map[string]interface{}{
@@ -776,6 +1083,12 @@ func addressableKnativeRef() *duckv1.KReference {
 	}
 }
 
+func namedAddressRef(name string) *duckv1.KReference {
+	ref := addressableKnativeRef()
+	ref.Address = &name
+	return ref
+}
+
 func addressableRef() *corev1.ObjectReference {
 	return &corev1.ObjectReference{
 		Kind:       addressableKind,
@@ -0,0 +1,176 @@
+/*
+Copyright 2023 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	duckv1 "github.com/Yangfisher1/knative-common-pkg/apis/duck/v1"
+	"github.com/Yangfisher1/knative-common-pkg/kmeta"
+	"github.com/Yangfisher1/knative-common-pkg/ptr"
+)
+
+// CacheConfig configures the cache of resolved addresses kept by a URIResolver.
+type CacheConfig struct {
+	// TTL is how long a resolved address may be served from the cache.
+	// A zero TTL disables the cache.
+	TTL time.Duration
+
+	// MaxEntries bounds the number of cached addresses.
+	MaxEntries int
+}
+
+type cacheConfigKey struct{}
+
+// WithCacheConfig attaches the given cache configuration to the context,
+// for use by the URIResolvers constructed from it.  By default no addresses
+// are cached.
+func WithCacheConfig(ctx context.Context, cfg CacheConfig) context.Context {
+	return context.WithValue(ctx, cacheConfigKey{}, cfg)
+}
+
+// GetCacheConfig returns the cache configuration attached to the context.
+func GetCacheConfig(ctx context.Context) CacheConfig {
+	cfg, _ := ctx.Value(cacheConfigKey{}).(CacheConfig)
+	return cfg
+}
+
+// cacheKey identifies a resolved address.
+type cacheKey struct {
+	apiVersion string
+	kind       string
+	namespace  string
+	name       string
+	address    string
+	port       string
+}
+
+func newCacheKey(ref *corev1.ObjectReference, address *string, port *intstr.IntOrString) cacheKey {
+	key := cacheKey{
+		apiVersion: ref.APIVersion,
+		kind:       ref.Kind,
+		namespace:  ref.Namespace,
+		name:       ref.Name,
+		address:    ptr.StringValue(address),
+	}
+	if port != nil {
+		key.port = port.String()
+	}
+	return key
+}
+
+type cacheEntry struct {
+	addr   *duckv1.Addressable
+	expiry time.Time
+}
+
+// addressCache is a small TTL cache of resolved addresses.  Addresses are
+// only cached while the informer of the referenced object is watching it,
+// and are evicted as soon as it sees a change to the object rather than
+// keyed by its generation, since status updates do not bump the generation.
+// Hits renew the tracker lease of the parent on the object.
+// A nil *addressCache caches nothing.
+type addressCache struct {
+	m          sync.Mutex
+	ttl        time.Duration
+	maxEntries int
+	entries    map[cacheKey]cacheEntry
+}
+
+func newAddressCache(cfg CacheConfig) *addressCache {
+	if cfg.TTL <= 0 || cfg.MaxEntries <= 0 {
+		return nil
+	}
+	return &addressCache{
+		ttl:        cfg.TTL,
+		maxEntries: cfg.MaxEntries,
+		entries:    make(map[cacheKey]cacheEntry, cfg.MaxEntries),
+	}
+}
+
+// get returns the unexpired entry for the key, if any.
+func (c *addressCache) get(key cacheKey) (cacheEntry, bool) {
+	if c == nil {
+		return cacheEntry{}, false
+	}
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		return cacheEntry{}, false
+	}
+	if time.Now().After(e.expiry) {
+		delete(c.entries, key)
+		return cacheEntry{}, false
+	}
+	return e, true
+}
+
+// put caches a copy of the given address.
+func (c *addressCache) put(key cacheKey, addr *duckv1.Addressable) {
+	if c == nil {
+		return
+	}
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	now := time.Now()
+	if _, ok := c.entries[key]; !ok && len(c.entries) >= c.maxEntries {
+		for k, e := range c.entries {
+			if now.After(e.expiry) {
+				delete(c.entries, k)
+			}
+		}
+		// Still full, so make room by dropping an arbitrary entry.
+		for k := range c.entries {
+			if len(c.entries) < c.maxEntries {
+				break
+			}
+			delete(c.entries, k)
+		}
+	}
+	c.entries[key] = cacheEntry{
+		addr:   addr.DeepCopy(),
+		expiry: now.Add(c.ttl),
+	}
+}
+
+// evict drops the entries for the given object.  It is registered as an
+// informer event handler, so entries are matched by namespace and name.
+func (c *addressCache) evict(obj interface{}) {
+	if c == nil {
+		return
+	}
+	item, err := kmeta.DeletionHandlingAccessor(obj)
+	if err != nil {
+		return
+	}
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	for k := range c.entries {
+		if k.namespace == item.GetNamespace() && k.name == item.GetName() {
+			delete(c.entries, k)
+		}
+	}
+}
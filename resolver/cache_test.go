@@ -0,0 +1,87 @@
+/*
+Copyright 2023 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"github.com/Yangfisher1/knative-common-pkg/apis"
+	duckv1 "github.com/Yangfisher1/knative-common-pkg/apis/duck/v1"
+	"github.com/Yangfisher1/knative-common-pkg/ptr"
+)
+
+func TestAddressCache(t *testing.T) {
+	if c := newAddressCache(CacheConfig{}); c != nil {
+		t.Fatal("newAddressCache() with zero config = non-nil, wanted nil")
+	}
+	// A nil cache is usable and caches nothing.
+	var nilCache *addressCache
+	nilCache.put(cacheKey{name: "foo"}, &duckv1.Addressable{})
+	if _, ok := nilCache.get(cacheKey{name: "foo"}); ok {
+		t.Error("nil cache get() = true, wanted false")
+	}
+
+	c := newAddressCache(CacheConfig{TTL: time.Minute, MaxEntries: 2})
+	ref := &corev1.ObjectReference{APIVersion: "v1", Kind: "Thing", Namespace: "ns", Name: "foo"}
+	key := newCacheKey(ref, nil, nil)
+	named := newCacheKey(ref, ptr.String("https"), nil)
+	if key == named {
+		t.Fatal("keys with and without an address name are equal")
+	}
+	if port := intstr.FromString("https"); key == newCacheKey(ref, nil, &port) {
+		t.Fatal("keys with and without a port are equal")
+	}
+
+	c.put(key, &duckv1.Addressable{URL: apis.HTTP("foo")})
+	e, ok := c.get(key)
+	if !ok {
+		t.Fatal("get() = false, wanted true")
+	}
+	if got, want := e.addr.URL.String(), "http://foo"; got != want {
+		t.Errorf("get() = %s, wanted %s", got, want)
+	}
+
+	// The cache is bounded.
+	c.put(named, &duckv1.Addressable{URL: apis.HTTP("foo-https")})
+	c.put(cacheKey{name: "bar"}, &duckv1.Addressable{URL: apis.HTTP("bar")})
+	if got := len(c.entries); got != 2 {
+		t.Errorf("len(entries) = %d, wanted 2", got)
+	}
+
+	// Informer events evict all the entries of the object.
+	c.put(key, &duckv1.Addressable{URL: apis.HTTP("foo")})
+	c.put(named, &duckv1.Addressable{URL: apis.HTTP("foo-https")})
+	c.evict(&duckv1.AddressableType{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "foo"}})
+	if _, ok := c.get(key); ok {
+		t.Error("get() after evict = true, wanted false")
+	}
+	if _, ok := c.get(named); ok {
+		t.Error("get(named) after evict = true, wanted false")
+	}
+
+	// Expired entries are not served.
+	c.put(key, &duckv1.Addressable{URL: apis.HTTP("foo")})
+	c.entries[key] = cacheEntry{addr: c.entries[key].addr, expiry: time.Now().Add(-time.Second)}
+	if _, ok := c.get(key); ok {
+		t.Error("get() of expired entry = true, wanted false")
+	}
+}
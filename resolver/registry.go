@@ -0,0 +1,59 @@
+/*
+Copyright 2023 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"sync"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// Registry holds the RefResolverFuncs to use for references of a
+// particular GroupKind.
+type Registry struct {
+	m         sync.RWMutex
+	resolvers map[schema.GroupKind][]RefResolverFunc
+}
+
+// Default is the Registry with which packages should register their
+// resolvers (typically from an init() function) to make them available to
+// every URIResolver in the process, e.g.
+//
+//	func init() {
+//		resolver.Default.Register(schema.GroupKind{Group: "serving.knative.dev", Kind: "Route"}, resolveRoute)
+//	}
+var Default = &Registry{}
+
+// Register adds a resolver for references of the given GroupKind.
+// Resolvers are consulted in the order in which they were registered.
+func (r *Registry) Register(gk schema.GroupKind, resolver RefResolverFunc) {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	if r.resolvers == nil {
+		r.resolvers = make(map[schema.GroupKind][]RefResolverFunc)
+	}
+	r.resolvers[gk] = append(r.resolvers[gk], resolver)
+}
+
+// Get returns the resolvers registered for the given GroupKind.
+func (r *Registry) Get(gk schema.GroupKind) []RefResolverFunc {
+	r.m.RLock()
+	defer r.m.RUnlock()
+
+	return r.resolvers[gk]
+}
@@ -0,0 +1,154 @@
+/*
+Copyright 2023 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes/scheme"
+
+	"github.com/Yangfisher1/knative-common-pkg/apis"
+	duckv1 "github.com/Yangfisher1/knative-common-pkg/apis/duck/v1"
+	"github.com/Yangfisher1/knative-common-pkg/client/injection/ducks/duck/v1/addressable"
+	fakedynamicclient "github.com/Yangfisher1/knative-common-pkg/injection/clients/dynamicclient/fake"
+	"github.com/Yangfisher1/knative-common-pkg/resolver"
+	"github.com/Yangfisher1/knative-common-pkg/tracker"
+)
+
+func TestRegistry(t *testing.T) {
+	gk := schema.GroupKind{Group: "registry.knative.dev", Kind: "Registered"}
+	unhandled := func(context.Context, *corev1.ObjectReference) (bool, *apis.URL, error) {
+		return false, nil, nil
+	}
+	handled := func(_ context.Context, ref *corev1.ObjectReference) (bool, *apis.URL, error) {
+		return true, apis.HTTP(ref.Name + ".registered.example.com"), nil
+	}
+
+	reg := &resolver.Registry{}
+	reg.Register(gk, unhandled)
+	reg.Register(gk, handled)
+	if got := len(reg.Get(gk)); got != 2 {
+		t.Errorf("len(Get()) = %d, wanted 2", got)
+	}
+	if got := len(reg.Get(schema.GroupKind{Kind: "Other"})); got != 0 {
+		t.Errorf("len(Get(Other)) = %d, wanted 0", got)
+	}
+
+	// Resolvers registered with the Default registry are used by every URIResolver.
+	resolver.Default.Register(gk, handled)
+
+	ctx, _ := fakedynamicclient.With(context.Background(), scheme.Scheme)
+	ctx = addressable.WithDuck(ctx)
+	r := resolver.NewURIResolverFromTracker(ctx, tracker.New(func(types.NamespacedName) {}, 0))
+
+	uri, err := r.URIFromDestinationV1(ctx, duckv1.Destination{
+		Ref: &duckv1.KReference{
+			APIVersion: "registry.knative.dev/v1",
+			Kind:       "Registered",
+			Namespace:  testNS,
+			Name:       "foo",
+		},
+	}, getAddressable())
+	if err != nil {
+		t.Fatal("URIFromDestinationV1() =", err)
+	}
+	if got, want := uri.String(), "http://foo.registered.example.com"; got != want {
+		t.Errorf("URIFromDestinationV1() = %s, wanted %s", got, want)
+	}
+}
+
+func TestCachedResolution(t *testing.T) {
+	var calls atomic.Int32
+	counting := func(_ context.Context, ref *corev1.ObjectReference) (bool, *apis.URL, error) {
+		calls.Add(1)
+		return true, apis.HTTP(ref.Name + ".example.com"), nil
+	}
+	dest := duckv1.Destination{Ref: addressableKnativeRef()}
+
+	tests := []struct {
+		name      string
+		cfg       resolver.CacheConfig
+		change    bool
+		wantCalls int32
+	}{{
+		name:      "cache disabled",
+		wantCalls: 2,
+	}, {
+		name:      "cache enabled",
+		cfg:       resolver.CacheConfig{TTL: time.Minute, MaxEntries: 10},
+		wantCalls: 1,
+	}, {
+		name:      "evicted when the object changes",
+		cfg:       resolver.CacheConfig{TTL: time.Minute, MaxEntries: 10},
+		change:    true,
+		wantCalls: 2,
+	}}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			calls.Store(0)
+			obj := getAddressable()
+			ctx, client := fakedynamicclient.With(context.Background(), scheme.Scheme, obj)
+			ctx = addressable.WithDuck(ctx)
+			ctx = resolver.WithCacheConfig(ctx, tc.cfg)
+			r := resolver.NewURIResolverFromTracker(ctx, tracker.New(func(types.NamespacedName) {}, 0), counting)
+
+			resolve := func() {
+				t.Helper()
+				uri, err := r.URIFromDestinationV1(ctx, dest, getAddressable())
+				if err != nil {
+					t.Fatal("URIFromDestinationV1() =", err)
+				}
+				if got, want := uri.String(), "http://"+addressableName+".example.com"; got != want {
+					t.Errorf("URIFromDestinationV1() = %s, wanted %s", got, want)
+				}
+				// Modifying the result must not affect the cached copy.
+				uri.Host = "mutated"
+			}
+
+			resolve()
+			if tc.change {
+				// The resolver's informer sees the change and evicts the
+				// cached address.
+				obj.SetLabels(map[string]string{"changed": "true"})
+				gvr := schema.GroupVersionResource{Group: "duck.knative.dev", Version: "v1", Resource: "sinks"}
+				if _, err := client.Resource(gvr).Namespace(testNS).Update(ctx, obj, metav1.UpdateOptions{}); err != nil {
+					t.Fatal("Update() =", err)
+				}
+				if err := wait.PollImmediate(10*time.Millisecond, 5*time.Second, func() (bool, error) {
+					resolve()
+					return calls.Load() > 1, nil
+				}); err != nil {
+					t.Fatal("The cached address was not evicted:", err)
+				}
+			} else {
+				resolve()
+			}
+			if got := calls.Load(); got != tc.wantCalls {
+				t.Errorf("resolver calls = %d, wanted %d", got, tc.wantCalls)
+			}
+		})
+	}
+}
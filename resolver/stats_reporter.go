@@ -0,0 +1,94 @@
+/*
+Copyright 2023 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/Yangfisher1/knative-common-pkg/metrics"
+)
+
+const (
+	resolveLatenciesName    = "resolve_latencies"
+	resolveFailureCountName = "resolve_failure_count"
+)
+
+var (
+	resolveLatenciesM = stats.Float64(
+		resolveLatenciesName,
+		"The time in milliseconds taken to resolve a reference into an address",
+		stats.UnitMilliseconds)
+	resolveFailureCountM = stats.Int64(
+		resolveFailureCountName,
+		"The number of references that failed to resolve",
+		stats.UnitDimensionless)
+
+	// Create the tag keys that will be used to add tags to our measurements.
+	// Tag keys must conform to the restrictions described in
+	// go.opencensus.io/tag/validate.go. Currently those restrictions are:
+	// - length between 1 and 255 inclusive
+	// - characters are printable US-ASCII
+	groupKey   = tag.MustNewKey("group")
+	versionKey = tag.MustNewKey("version")
+	kindKey    = tag.MustNewKey("kind")
+	successKey = tag.MustNewKey("success")
+)
+
+// RegisterMetrics registers the views of the resolution metrics, which the
+// URIResolvers export.
+func RegisterMetrics() {
+	if err := view.Register(&view.View{
+		Description: resolveLatenciesM.Description(),
+		Measure:     resolveLatenciesM,
+		Aggregation: view.Distribution(metrics.Buckets125(1, 10000)...), // [1 2 5 10 20 50 100 200 500 1000 2000 5000 10000]ms
+		TagKeys:     []tag.Key{groupKey, versionKey, kindKey, successKey},
+	}, &view.View{
+		Description: resolveFailureCountM.Description(),
+		Measure:     resolveFailureCountM,
+		Aggregation: view.Count(),
+		TagKeys:     []tag.Key{groupKey, versionKey, kindKey},
+	}); err != nil {
+		panic(err)
+	}
+}
+
+// reportResolve records the latency and outcome of resolving a reference
+// of the given GroupVersionKind.
+func reportResolve(gvk schema.GroupVersionKind, d time.Duration, err error) {
+	ctx, tagErr := tag.New(
+		context.Background(),
+		tag.Insert(groupKey, gvk.Group),
+		tag.Insert(versionKey, gvk.Version),
+		tag.Insert(kindKey, gvk.Kind),
+	)
+	if tagErr != nil {
+		return
+	}
+
+	metrics.Record(ctx, resolveLatenciesM.M(float64(d)/float64(time.Millisecond)),
+		stats.WithTags(tag.Insert(successKey, strconv.FormatBool(err == nil))))
+	if err != nil {
+		metrics.Record(ctx, resolveFailureCountM.M(1))
+	}
+}
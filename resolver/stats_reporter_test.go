@@ -0,0 +1,73 @@
+/*
+Copyright 2023 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/Yangfisher1/knative-common-pkg/metrics/metricstest"
+	_ "github.com/Yangfisher1/knative-common-pkg/metrics/testing"
+)
+
+func TestReportResolve(t *testing.T) {
+	tests := []struct {
+		name        string
+		kind        string
+		err         error
+		wantFailure int64
+	}{{
+		name: "success",
+		kind: "Resolved",
+	}, {
+		name:        "failure",
+		kind:        "Unresolved",
+		err:         errors.New("boom"),
+		wantFailure: 2,
+	}}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			resetMetrics()
+			gvk := schema.GroupVersionKind{Group: "stats.knative.dev", Version: "v1", Kind: tc.kind}
+			tags := map[string]string{
+				groupKey.Name():   gvk.Group,
+				versionKey.Name(): gvk.Version,
+				kindKey.Name():    gvk.Kind,
+			}
+
+			reportResolve(gvk, 20*time.Millisecond, tc.err)
+			reportResolve(gvk, 50*time.Millisecond, tc.err)
+
+			if tc.wantFailure > 0 {
+				metricstest.CheckCountData(t, resolveFailureCountName, tags, tc.wantFailure)
+			}
+			tags[successKey.Name()] = strconv.FormatBool(tc.err == nil)
+			metricstest.CheckDistributionData(t, resolveLatenciesName, tags, 2, 20, 50)
+		})
+	}
+}
+
+// opencensus metrics carry global state that need to be reset between unit tests
+func resetMetrics() {
+	metricstest.Unregister(resolveLatenciesName, resolveFailureCountName)
+	RegisterMetrics()
+}
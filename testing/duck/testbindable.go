@@ -44,6 +44,7 @@ type TestBindable struct {
 // Check that TestBindable may be validated and defaulted.
 var _ apis.Listable = (*TestBindable)(nil)
 var _ duck.Bindable = (*TestBindable)(nil)
+var _ duck.BindableConflictStatus = (*TestBindableStatus)(nil)
 
 // TestBindableSpec represents test resource spec.
 type TestBindableSpec struct {
@@ -158,6 +159,32 @@ func (tbs *TestBindableStatus) MarkBindingAvailable() {
 	tbs.clearLTT()
 }
 
+// TestBindableConflictFree is the condition surfacing the conflicts of a
+// TestBindable with other bindings.  It does not affect readiness.
+const TestBindableConflictFree apis.ConditionType = "ConflictFree"
+
+// MarkBindingConflict implements duck.BindableConflictStatus
+func (tbs *TestBindableStatus) MarkBindingConflict(message string) {
+	tbCondSet.Manage(tbs).SetCondition(apis.Condition{
+		Type:     TestBindableConflictFree,
+		Status:   corev1.ConditionFalse,
+		Severity: apis.ConditionSeverityWarning,
+		Reason:   "BindingConflict",
+		Message:  message,
+	})
+	tbs.clearLTT()
+}
+
+// MarkBindingNoConflict implements duck.BindableConflictStatus
+func (tbs *TestBindableStatus) MarkBindingNoConflict() {
+	tbCondSet.Manage(tbs).SetCondition(apis.Condition{
+		Type:     TestBindableConflictFree,
+		Status:   corev1.ConditionTrue,
+		Severity: apis.ConditionSeverityWarning,
+	})
+	tbs.clearLTT()
+}
+
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 
 // TestBindableList is a list of TestBindable resources
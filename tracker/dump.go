@@ -0,0 +1,171 @@
+/*
+Copyright 2023 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracker
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// Edge describes a single observer tracking a reference.
+type Edge struct {
+	// Observer is the key of the object doing the tracking.
+	Observer types.NamespacedName `json:"observer"`
+
+	// Reference is the tracked reference.  For inexact references
+	// only the APIVersion, Kind and Namespace are set.
+	Reference Reference `json:"reference"`
+
+	// Selector is the string form of the label selector used by
+	// inexact references.
+	Selector string `json:"selector,omitempty"`
+
+	// OwnerUID is the owner UID used by inexact references.
+	OwnerUID types.UID `json:"ownerUID,omitempty"`
+
+	// FieldSelector is the string form of the field selector used by
+	// inexact references.
+	FieldSelector string `json:"fieldSelector,omitempty"`
+
+	// Expiry is the time at which the observer's lease on the
+	// reference expires unless it is renewed.
+	Expiry time.Time `json:"expiry"`
+}
+
+// Dumper is implemented by trackers that can list the references they hold.
+type Dumper interface {
+	// Dump returns all the observer-to-reference edges that the tracker
+	// holds, including those whose lease has expired but which have
+	// not yet been cleaned up.
+	Dump() []Edge
+}
+
+// Check that impl implements Dumper.
+var _ Dumper = (*impl)(nil)
+
+// Dump implements Dumper.
+func (i *impl) Dump() []Edge {
+	i.m.Lock()
+	defer i.m.Unlock()
+
+	edges := make([]Edge, 0, len(i.exact)+len(i.inexact))
+	for ref, s := range i.exact {
+		for key, expiry := range s {
+			edges = append(edges, Edge{
+				Observer:  key,
+				Reference: ref,
+				Expiry:    expiry,
+			})
+		}
+	}
+	for ref, ms := range i.inexact {
+		for mk, m := range ms {
+			edges = append(edges, Edge{
+				Observer:      mk.key,
+				Reference:     ref,
+				Selector:      mk.selector,
+				OwnerUID:      mk.ownerUID,
+				FieldSelector: mk.fields,
+				Expiry:        m.expiry,
+			})
+		}
+	}
+
+	sort.Slice(edges, func(a, b int) bool {
+		ea, eb := edges[a], edges[b]
+		if ea.Observer != eb.Observer {
+			return ea.Observer.String() < eb.Observer.String()
+		}
+		ra, rb := ea.Reference, eb.Reference
+		if ra.APIVersion != rb.APIVersion {
+			return ra.APIVersion < rb.APIVersion
+		}
+		if ra.Kind != rb.Kind {
+			return ra.Kind < rb.Kind
+		}
+		if ra.Namespace != rb.Namespace {
+			return ra.Namespace < rb.Namespace
+		}
+		if ra.Name != rb.Name {
+			return ra.Name < rb.Name
+		}
+		if ea.Selector != eb.Selector {
+			return ea.Selector < eb.Selector
+		}
+		if ea.OwnerUID != eb.OwnerUID {
+			return ea.OwnerUID < eb.OwnerUID
+		}
+		return ea.FieldSelector < eb.FieldSelector
+	})
+	return edges
+}
+
+// ExpiringWithin filters the edges to those whose lease expires within
+// the given duration of now.
+func ExpiringWithin(edges []Edge, now time.Time, d time.Duration) []Edge {
+	deadline := now.Add(d)
+	var ret []Edge
+	for _, e := range edges {
+		if !e.Expiry.After(deadline) {
+			ret = append(ret, e)
+		}
+	}
+	return ret
+}
+
+// NewDumpHandler returns an http.Handler that serves the edges of the given
+// named trackers as JSON, keyed by tracker name.  Trackers that do not
+// implement Dumper are skipped.
+//
+// The optional "expiring" query parameter takes a duration (e.g. "30s") and
+// limits the output to edges whose lease expires within that duration.
+func NewDumpHandler(trackers map[string]Interface) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var within time.Duration
+		if v := r.URL.Query().Get("expiring"); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				http.Error(w, "invalid expiring duration: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			within = d
+		}
+
+		now := time.Now()
+		out := make(map[string][]Edge, len(trackers))
+		for name, t := range trackers {
+			d, ok := t.(Dumper)
+			if !ok {
+				continue
+			}
+			edges := d.Dump()
+			if within > 0 {
+				edges = ExpiringWithin(edges, now, within)
+			}
+			out[name] = edges
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(out); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
@@ -0,0 +1,159 @@
+/*
+Copyright 2023 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracker
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	. "github.com/Yangfisher1/knative-common-pkg/testing"
+)
+
+func TestDump(t *testing.T) {
+	trk := New(func(types.NamespacedName) {}, time.Minute)
+
+	observer := &Resource{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "ns",
+			Name:      "observer",
+		},
+	}
+	byName := Reference{
+		APIVersion: "ref.knative.dev/v1alpha1",
+		Kind:       "Thing1",
+		Namespace:  "ns",
+		Name:       "foo",
+	}
+	bySelector := Reference{
+		APIVersion: "ref.knative.dev/v1alpha1",
+		Kind:       "Thing2",
+		Namespace:  "ns",
+		Selector: &metav1.LabelSelector{
+			MatchLabels: map[string]string{"foo": "bar"},
+		},
+	}
+	for _, ref := range []Reference{bySelector, byName} {
+		if err := trk.TrackReference(ref, observer); err != nil {
+			t.Fatal("TrackReference() =", err)
+		}
+	}
+
+	got := trk.(Dumper).Dump()
+	want := []Edge{{
+		Observer:  types.NamespacedName{Namespace: "ns", Name: "observer"},
+		Reference: byName,
+	}, {
+		Observer: types.NamespacedName{Namespace: "ns", Name: "observer"},
+		Reference: Reference{
+			APIVersion: bySelector.APIVersion,
+			Kind:       bySelector.Kind,
+			Namespace:  bySelector.Namespace,
+		},
+		Selector: "foo=bar",
+	}}
+	if !cmp.Equal(got, want, cmpopts.IgnoreFields(Edge{}, "Expiry")) {
+		t.Error("Dump (-want, +got):", cmp.Diff(want, got, cmpopts.IgnoreFields(Edge{}, "Expiry")))
+	}
+	for _, e := range got {
+		if e.Expiry.Before(time.Now()) {
+			t.Errorf("Expiry = %v, wanted a time in the future", e.Expiry)
+		}
+	}
+
+	trk.OnDeletedObserver(observer)
+	if got := trk.(Dumper).Dump(); len(got) != 0 {
+		t.Errorf("Dump() after OnDeletedObserver = %v, wanted empty", got)
+	}
+}
+
+func TestExpiringWithin(t *testing.T) {
+	now := time.Now()
+	soon := Edge{Observer: types.NamespacedName{Name: "soon"}, Expiry: now.Add(time.Second)}
+	later := Edge{Observer: types.NamespacedName{Name: "later"}, Expiry: now.Add(time.Hour)}
+
+	got := ExpiringWithin([]Edge{soon, later}, now, time.Minute)
+	if want := []Edge{soon}; !cmp.Equal(got, want) {
+		t.Error("ExpiringWithin (-want, +got):", cmp.Diff(want, got))
+	}
+}
+
+func TestDumpHandler(t *testing.T) {
+	trk := New(func(types.NamespacedName) {}, time.Hour)
+	observer := &Resource{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "ns",
+			Name:      "observer",
+		},
+	}
+	if err := trk.TrackReference(Reference{
+		APIVersion: "ref.knative.dev/v1alpha1",
+		Kind:       "Thing1",
+		Namespace:  "ns",
+		Name:       "foo",
+	}, observer); err != nil {
+		t.Fatal("TrackReference() =", err)
+	}
+	h := NewDumpHandler(map[string]Interface{"thing": trk})
+
+	tests := []struct {
+		name      string
+		query     string
+		wantCode  int
+		wantEdges int
+	}{{
+		name:      "all edges",
+		wantCode:  http.StatusOK,
+		wantEdges: 1,
+	}, {
+		name:      "nothing expiring soon",
+		query:     "?expiring=1m",
+		wantCode:  http.StatusOK,
+		wantEdges: 0,
+	}, {
+		name:     "bad duration",
+		query:    "?expiring=soon",
+		wantCode: http.StatusBadRequest,
+	}}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/debug/tracker"+tc.query, nil))
+			if w.Code != tc.wantCode {
+				t.Fatalf("StatusCode = %d, wanted %d", w.Code, tc.wantCode)
+			}
+			if tc.wantCode != http.StatusOK {
+				return
+			}
+			var got map[string][]Edge
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatal("Unmarshal() =", err)
+			}
+			if len(got["thing"]) != tc.wantEdges {
+				t.Errorf("len(edges) = %d, wanted %d", len(got["thing"]), tc.wantEdges)
+			}
+		})
+	}
+}
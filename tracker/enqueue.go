@@ -25,7 +25,9 @@ import (
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/validation"
 
@@ -40,11 +42,30 @@ import (
 // When OnChanged is called by the informer for a particular
 // GroupVersionKind, the provided callback is called with the "key"
 // of each object actively watching the changed object.
-func New(callback func(types.NamespacedName), lease time.Duration) Interface {
-	return &impl{
+//
+// The returned Interface also implements Dumper.
+func New(callback func(types.NamespacedName), lease time.Duration, opts ...Option) Interface {
+	i := &impl{
 		leaseDuration: lease,
 		cb:            callback,
 	}
+	for _, opt := range opts {
+		opt(i)
+	}
+	return i
+}
+
+// Option configures optional behaviour of the tracker returned by New.
+type Option func(*impl)
+
+// WithMetrics enables exporting the number of exact and inexact references
+// held per GroupVersionKind, and the number of callbacks fired, tagged with
+// the given tracker name, once their views are registered by RegisterMetrics.
+// The trackers of controllers enable it through controller.WithTrackerMetrics.
+func WithMetrics(name string) Option {
+	return func(i *impl) {
+		i.reporter = &statsReporter{name: name}
+	}
 }
 
 type impl struct {
@@ -53,7 +74,8 @@ type impl struct {
 	// keys for objects watching it.
 	exact map[Reference]set
 	// inexact maps from a partial object reference (no name/selector) to
-	// a map from watcher keys to the compiled selector and expiry.
+	// a map from watcher keys and selectors to the compiled selector and
+	// expiry.
 	inexact map[Reference]matchers
 
 	// The amount of time that an object may watch another
@@ -61,6 +83,11 @@ type impl struct {
 	leaseDuration time.Duration
 
 	cb func(types.NamespacedName)
+
+	// counts holds the number of references per GroupVersionKind,
+	// it is only maintained when reporter is non-nil.
+	counts   map[schema.GroupVersionKind]counts
+	reporter *statsReporter
 }
 
 // Check that impl implements Interface.
@@ -69,14 +96,34 @@ var _ Interface = (*impl)(nil)
 // set is a map from keys to expirations
 type set map[types.NamespacedName]time.Time
 
-// matchers maps the tracker's key to the matcher.
-type matchers map[types.NamespacedName]matcher
+// counts is the number of exact and inexact (observer, reference) pairs.
+type counts struct {
+	exact, inexact int
+}
 
-// matcher holds the selector and expiry for matching tracked objects.
+// matchers maps the tracker's key and selectors to the matcher.
+type matchers map[matcherKey]matcher
+
+// matcherKey identifies an inexact reference of the tracker's key, so that
+// it may track several of the same kind in the same namespace.
+type matcherKey struct {
+	key      types.NamespacedName
+	selector string
+	ownerUID types.UID
+	fields   string
+}
+
+// matcher holds the selectors and expiry for matching tracked objects.
 type matcher struct {
 	// The selector to complete the match.
 	selector labels.Selector
 
+	// The UID of an owner the tracked object must reference, if any.
+	ownerUID types.UID
+
+	// The field selector to complete the match, if any.
+	fields fields.Selector
+
 	// When this lease expires.
 	expiry time.Time
 }
@@ -100,21 +147,31 @@ func (i *impl) TrackReference(ref Reference, obj interface{}) error {
 	if ref.Namespace != "" {
 		invalidFields["Namespace"] = validation.IsDNS1123Label(ref.Namespace)
 	}
-	var selector labels.Selector
+	inexact := ref.Selector != nil || ref.OwnerUID != "" || ref.FieldSelector != ""
+	m := matcher{selector: labels.Everything(), ownerUID: ref.OwnerUID}
 	fieldErrors := []string{}
 	switch {
-	case ref.Selector != nil && ref.Name != "":
-		fieldErrors = append(fieldErrors, "cannot provide both Name and Selector")
+	case inexact && ref.Name != "":
+		fieldErrors = append(fieldErrors, "cannot provide both Name and Selector, OwnerUID or FieldSelector")
 	case ref.Name != "":
 		invalidFields["Name"] = validation.IsDNS1123Subdomain(ref.Name)
-	case ref.Selector != nil:
-		ls, err := metav1.LabelSelectorAsSelector(ref.Selector)
-		if err != nil {
-			invalidFields["Selector"] = []string{err.Error()}
+	case inexact:
+		if ref.Selector != nil {
+			ls, err := metav1.LabelSelectorAsSelector(ref.Selector)
+			if err != nil {
+				invalidFields["Selector"] = []string{err.Error()}
+			}
+			m.selector = ls
+		}
+		if ref.FieldSelector != "" {
+			fs, err := fields.ParseSelector(ref.FieldSelector)
+			if err != nil {
+				invalidFields["FieldSelector"] = []string{err.Error()}
+			}
+			m.fields = fs
 		}
-		selector = ls
 	default:
-		fieldErrors = append(fieldErrors, "must provide either Name or Selector")
+		fieldErrors = append(fieldErrors, "must provide either Name or Selector, OwnerUID or FieldSelector")
 	}
 	for k, v := range invalidFields {
 		for _, msg := range v {
@@ -141,6 +198,9 @@ func (i *impl) TrackReference(ref Reference, obj interface{}) error {
 			cb(key)
 		}
 	}(i.cb) // read i.cb with the lock held
+	defer func() {
+		i.reporter.reportCallbacks(ref.GroupVersionKind(), len(keys))
+	}()
 	defer i.m.Unlock()
 	if i.exact == nil {
 		i.exact = make(map[Reference]set)
@@ -150,13 +210,17 @@ func (i *impl) TrackReference(ref Reference, obj interface{}) error {
 	}
 
 	// If the reference uses Name then it is an exact match.
-	if selector == nil {
+	if !inexact {
 		l, ok := i.exact[ref]
 		if !ok {
 			l = set{}
 		}
 
-		if expiry, ok := l[key]; !ok || isExpired(expiry) {
+		expiry, ok := l[key]
+		if !ok {
+			i.adjust(ref.GroupVersionKind(), 1, 0)
+		}
+		if !ok || isExpired(expiry) {
 			// When covering an uncovered key, immediately call the
 			// registered callback to ensure that the following pattern
 			// doesn't create problems:
@@ -177,19 +241,27 @@ func (i *impl) TrackReference(ref Reference, obj interface{}) error {
 		return nil
 	}
 
-	// Otherwise, it is an inexact match by selector.
+	// Otherwise, it is an inexact match by selector, owner or fields.
 	partialRef := Reference{
 		APIVersion: ref.APIVersion,
 		Kind:       ref.Kind,
 		Namespace:  ref.Namespace,
-		// Exclude the selector.
+		// Exclude the selectors.
 	}
 	l, ok := i.inexact[partialRef]
 	if !ok {
 		l = matchers{}
 	}
 
-	if m, ok := l[key]; !ok || isExpired(m.expiry) {
+	mk := matcherKey{key: key, selector: m.selector.String(), ownerUID: m.ownerUID}
+	if m.fields != nil {
+		mk.fields = m.fields.String()
+	}
+	prev, ok := l[mk]
+	if !ok {
+		i.adjust(ref.GroupVersionKind(), 0, 1)
+	}
+	if !ok || isExpired(prev.expiry) {
 		// When covering an uncovered key, immediately call the
 		// registered callback to ensure that the following pattern
 		// doesn't create problems:
@@ -204,10 +276,8 @@ func (i *impl) TrackReference(ref Reference, obj interface{}) error {
 		keys = append(keys, key)
 	}
 	// Overwrite the key with a new expiration.
-	l[key] = matcher{
-		selector: selector,
-		expiry:   time.Now().Add(i.leaseDuration),
-	}
+	m.expiry = time.Now().Add(i.leaseDuration)
+	l[mk] = m
 
 	i.inexact[partialRef] = l
 	return nil
@@ -217,6 +287,26 @@ func isExpired(expiry time.Time) bool {
 	return time.Now().After(expiry)
 }
 
+// adjust updates the reference counts for the given GroupVersionKind
+// and reports them.  It must be called with the lock held.
+func (i *impl) adjust(gvk schema.GroupVersionKind, exact, inexact int) {
+	if i.reporter == nil {
+		return
+	}
+	if i.counts == nil {
+		i.counts = make(map[schema.GroupVersionKind]counts)
+	}
+	c := i.counts[gvk]
+	c.exact += exact
+	c.inexact += inexact
+	if c.exact == 0 && c.inexact == 0 {
+		delete(i.counts, gvk)
+	} else {
+		i.counts[gvk] = c
+	}
+	i.reporter.reportReferences(gvk, c)
+}
+
 // OnChanged implements Interface.
 func (i *impl) OnChanged(obj interface{}) {
 	observers := i.GetObservers(obj)
@@ -224,6 +314,12 @@ func (i *impl) OnChanged(obj interface{}) {
 	for _, observer := range observers {
 		i.cb(observer)
 	}
+
+	if i.reporter != nil && len(observers) > 0 {
+		if item, err := kmeta.DeletionHandlingAccessor(obj); err == nil {
+			i.reporter.reportCallbacks(item.GroupVersionKind(), len(observers))
+		}
+	}
 }
 
 // GetObservers implements Interface.
@@ -253,6 +349,7 @@ func (i *impl) GetObservers(obj interface{}) []types.NamespacedName {
 			// If the expiration has lapsed, then delete the key.
 			if isExpired(expiry) {
 				delete(s, key)
+				i.adjust(ref.GroupVersionKind(), -1, 0)
 				continue
 			}
 			keys = append(keys, key)
@@ -266,19 +363,23 @@ func (i *impl) GetObservers(obj interface{}) []types.NamespacedName {
 	ref.Name = ""
 	ms, ok := i.inexact[ref]
 	if ok {
-		ls := labels.Set(item.GetLabels())
-		for key, m := range ms {
+		of := &objectFields{obj: item}
+		// Keys tracking several matching references are only returned once.
+		matched := make(map[types.NamespacedName]struct{}, len(ms))
+		for mk, m := range ms {
 			// If the expiration has lapsed, then delete the key.
 			if isExpired(m.expiry) {
-				delete(ms, key)
+				delete(ms, mk)
+				i.adjust(ref.GroupVersionKind(), 0, -1)
 				continue
 			}
-			if m.selector.Matches(ls) {
-				keys = append(keys, key)
+			if _, ok := matched[mk.key]; !ok && m.matches(item, of) {
+				matched[mk.key] = struct{}{}
+				keys = append(keys, mk.key)
 			}
 		}
-		if len(s) == 0 {
-			delete(i.exact, ref)
+		if len(ms) == 0 {
+			delete(i.inexact, ref)
 		}
 	}
 
@@ -299,7 +400,10 @@ func (i *impl) OnDeletedObserver(obj interface{}) {
 
 	// Remove exact matches.
 	for ref, matchers := range i.exact {
-		delete(matchers, key)
+		if _, ok := matchers[key]; ok {
+			delete(matchers, key)
+			i.adjust(ref.GroupVersionKind(), -1, 0)
+		}
 		if len(matchers) == 0 {
 			delete(i.exact, ref)
 		}
@@ -307,9 +411,14 @@ func (i *impl) OnDeletedObserver(obj interface{}) {
 
 	// Remove inexact matches.
 	for ref, matchers := range i.inexact {
-		delete(matchers, key)
+		for mk := range matchers {
+			if mk.key == key {
+				delete(matchers, mk)
+				i.adjust(ref.GroupVersionKind(), 0, -1)
+			}
+		}
 		if len(matchers) == 0 {
-			delete(i.exact, ref)
+			delete(i.inexact, ref)
 		}
 	}
 }
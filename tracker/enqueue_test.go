@@ -456,6 +456,25 @@ func TestBadReferences(t *testing.T) {
 			},
 		},
 		match: "a bad key",
+	}, {
+		name: "Name and OwnerUID",
+		objRef: Reference{
+			APIVersion: "apps/v1",
+			Kind:       "Deployment",
+			Namespace:  "default",
+			Name:       "foo",
+			OwnerUID:   "a-uid",
+		},
+		match: "both Name and Selector, OwnerUID or FieldSelector",
+	}, {
+		name: "bad field selector",
+		objRef: Reference{
+			APIVersion:    "apps/v1",
+			Kind:          "Deployment",
+			Namespace:     "default",
+			FieldSelector: "spec.nodeName",
+		},
+		match: "FieldSelector: invalid selector",
 	}}
 
 	for _, test := range tests {
@@ -810,3 +829,56 @@ func TestHappyPathsByBoth(t *testing.T) {
 		}
 	}
 }
+
+func TestInexactReferencesAreDropped(t *testing.T) {
+	thing := &Resource{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "ref.knative.dev/v1alpha1",
+			Kind:       "Thing1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "ns",
+			Name:      "foo",
+			Labels:    map[string]string{"foo": "bar"},
+		},
+	}
+	ref := Reference{
+		APIVersion: "ref.knative.dev/v1alpha1",
+		Kind:       "Thing1",
+		Namespace:  "ns",
+		Selector:   &metav1.LabelSelector{MatchLabels: map[string]string{"foo": "bar"}},
+	}
+	observer := &Resource{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "observer"}}
+
+	t.Run("expired", func(t *testing.T) {
+		trk := New(func(types.NamespacedName) {}, time.Millisecond).(*impl)
+		if err := trk.TrackReference(ref, observer); err != nil {
+			t.Fatal("TrackReference() =", err)
+		}
+		time.Sleep(2 * time.Millisecond)
+
+		if got := trk.GetObservers(thing); len(got) != 0 {
+			t.Errorf("GetObservers() = %v, wanted none", got)
+		}
+		if got := len(trk.inexact); got != 0 {
+			t.Errorf("len(inexact) = %d, wanted the expired references dropped", got)
+		}
+	})
+
+	t.Run("observer deleted", func(t *testing.T) {
+		trk := New(func(types.NamespacedName) {}, time.Minute).(*impl)
+		if err := trk.TrackReference(ref, observer); err != nil {
+			t.Fatal("TrackReference() =", err)
+		}
+		// Looking up the observers keeps the references that are still
+		// leased.
+		if got := trk.GetObservers(thing); len(got) != 1 {
+			t.Errorf("GetObservers() = %v, wanted the observer", got)
+		}
+
+		trk.OnDeletedObserver(observer)
+		if got := len(trk.inexact); got != 0 {
+			t.Errorf("len(inexact) = %d, wanted the deleted observer's references dropped", got)
+		}
+	})
+}
@@ -22,6 +22,7 @@ import (
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/validation"
@@ -46,7 +47,7 @@ type Reference struct {
 	Namespace string `json:"namespace,omitempty"`
 
 	// Name of the referent.
-	// Mutually exclusive with Selector.
+	// Mutually exclusive with Selector, OwnerUID and FieldSelector.
 	// +optional
 	Name string `json:"name,omitempty"`
 
@@ -54,6 +55,18 @@ type Reference struct {
 	// Mutually exclusive with Name.
 	// +optional
 	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+
+	// OwnerUID selects the referents that have an ownerReference
+	// with this UID.
+	// Mutually exclusive with Name.
+	// +optional
+	OwnerUID types.UID `json:"ownerUID,omitempty"`
+
+	// FieldSelector selects the referents by their fields, using the
+	// syntax of Kubernetes field selectors, e.g. "spec.nodeName=node-1".
+	// Mutually exclusive with Name.
+	// +optional
+	FieldSelector string `json:"fieldSelector,omitempty"`
 }
 
 // Interface defines the interface through which an object can register
@@ -135,6 +148,12 @@ func (ref *Reference) ValidateObjectReference(ctx context.Context) *apis.FieldEr
 	if ref.Selector != nil {
 		errs = errs.Also(apis.ErrDisallowedFields("selector"))
 	}
+	if ref.OwnerUID != "" {
+		errs = errs.Also(apis.ErrDisallowedFields("ownerUID"))
+	}
+	if ref.FieldSelector != "" {
+		errs = errs.Also(apis.ErrDisallowedFields("fieldSelector"))
+	}
 
 	return errs
 }
@@ -159,13 +178,30 @@ func (ref *Reference) Validate(ctx context.Context) *apis.FieldError {
 		errs = errs.Also(apis.ErrInvalidValue(strings.Join(verrs, ", "), "namespace"))
 	}
 
+	var inexact []string
+	if ref.Selector != nil {
+		inexact = append(inexact, "selector")
+	}
+	if ref.OwnerUID != "" {
+		inexact = append(inexact, "ownerUID")
+	}
+	if ref.FieldSelector != "" {
+		inexact = append(inexact, "fieldSelector")
+	}
+
 	switch {
-	case ref.Selector != nil && ref.Name != "":
-		errs = errs.Also(apis.ErrMultipleOneOf("selector", "name"))
-	case ref.Selector != nil:
-		_, err := metav1.LabelSelectorAsSelector(ref.Selector)
-		if err != nil {
-			errs = errs.Also(apis.ErrInvalidValue(err.Error(), "selector"))
+	case len(inexact) > 0 && ref.Name != "":
+		errs = errs.Also(apis.ErrMultipleOneOf(append(inexact, "name")...))
+	case len(inexact) > 0:
+		if ref.Selector != nil {
+			if _, err := metav1.LabelSelectorAsSelector(ref.Selector); err != nil {
+				errs = errs.Also(apis.ErrInvalidValue(err.Error(), "selector"))
+			}
+		}
+		if ref.FieldSelector != "" {
+			if _, err := fields.ParseSelector(ref.FieldSelector); err != nil {
+				errs = errs.Also(apis.ErrInvalidValue(err.Error(), "fieldSelector"))
+			}
 		}
 
 	case ref.Name != "":
@@ -173,7 +209,7 @@ func (ref *Reference) Validate(ctx context.Context) *apis.FieldError {
 			errs = errs.Also(apis.ErrInvalidValue(strings.Join(verrs, ", "), "name"))
 		}
 	default:
-		errs = errs.Also(apis.ErrMissingOneOf("selector", "name"))
+		errs = errs.Also(apis.ErrMissingOneOf("selector", "ownerUID", "fieldSelector", "name"))
 	}
 
 	return errs
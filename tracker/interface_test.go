@@ -109,6 +109,17 @@ func TestValidateObjectReference(t *testing.T) {
 			Selector:   &metav1.LabelSelector{},
 		},
 		want: apis.ErrDisallowedFields("selector"),
+	}, {
+		name: "with owner and field selector too",
+		ref: Reference{
+			APIVersion:    "apps/v1",
+			Kind:          "Deployment",
+			Namespace:     "default",
+			Name:          "nginx",
+			OwnerUID:      "a-uid",
+			FieldSelector: "spec.nodeName=node-1",
+		},
+		want: apis.ErrDisallowedFields("fieldSelector", "ownerUID"),
 	}}
 
 	for _, test := range tests {
@@ -134,7 +145,7 @@ func TestValidate(t *testing.T) {
 	}{{
 		name: "empty reference",
 		want: apis.ErrMissingField("apiVersion", "kind", "namespace").Also(
-			apis.ErrMissingOneOf("name", "selector")),
+			apis.ErrMissingOneOf("fieldSelector", "name", "ownerUID", "selector")),
 	}, {
 		name: "good reference",
 		ref: Reference{
@@ -216,6 +227,47 @@ func TestValidate(t *testing.T) {
 			},
 		},
 		want: apis.ErrInvalidValue(`key: Invalid value: "a b c": name part must consist of alphanumeric characters, '-', '_' or '.', and must start and end with an alphanumeric character (e.g. 'MyName',  or 'my.name',  or '123-abc', regex used for validation is '([A-Za-z0-9][-A-Za-z0-9_.]*)?[A-Za-z0-9]')`, "selector"),
+	}, {
+		name: "with just owner",
+		ref: Reference{
+			APIVersion: "apps/v1",
+			Kind:       "Deployment",
+			Namespace:  "default",
+			OwnerUID:   "a-uid",
+		},
+	}, {
+		name: "with owner, field selector and selector",
+		ref: Reference{
+			APIVersion: "v1",
+			Kind:       "Pod",
+			Namespace:  "default",
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					"foo": "bar",
+				},
+			},
+			OwnerUID:      "a-uid",
+			FieldSelector: "spec.nodeName=node-1",
+		},
+	}, {
+		name: "with owner and name",
+		ref: Reference{
+			APIVersion: "apps/v1",
+			Kind:       "Deployment",
+			Namespace:  "default",
+			Name:       "nginx",
+			OwnerUID:   "a-uid",
+		},
+		want: apis.ErrMultipleOneOf("name", "ownerUID"),
+	}, {
+		name: "with invalid field selector",
+		ref: Reference{
+			APIVersion:    "v1",
+			Kind:          "Pod",
+			Namespace:     "default",
+			FieldSelector: "spec.nodeName",
+		},
+		want: apis.ErrInvalidValue(`invalid selector: 'spec.nodeName'; can't understand 'spec.nodeName'`, "fieldSelector"),
 	}}
 
 	for _, test := range tests {
@@ -0,0 +1,123 @@
+/*
+Copyright 2023 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracker
+
+import (
+	"fmt"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/Yangfisher1/knative-common-pkg/kmeta"
+)
+
+// Matches returns whether the given object is referenced by the Reference.
+// The object must have the referenced APIVersion, Kind and Namespace, and
+// either the referenced Name, or satisfy each of Selector, OwnerUID and
+// FieldSelector that is set.  Invalid references match nothing.
+func (ref *Reference) Matches(obj interface{}) bool {
+	item, err := kmeta.DeletionHandlingAccessor(obj)
+	if err != nil {
+		return false
+	}
+	or := kmeta.ObjectReference(item)
+	if or.APIVersion != ref.APIVersion || or.Kind != ref.Kind || or.Namespace != ref.Namespace {
+		return false
+	}
+	if ref.Name != "" {
+		return ref.Selector == nil && ref.OwnerUID == "" && ref.FieldSelector == "" &&
+			or.Name == ref.Name
+	}
+	if ref.Selector == nil && ref.OwnerUID == "" && ref.FieldSelector == "" {
+		return false
+	}
+
+	m := matcher{selector: labels.Everything(), ownerUID: ref.OwnerUID}
+	if ref.Selector != nil {
+		if m.selector, err = metav1.LabelSelectorAsSelector(ref.Selector); err != nil {
+			return false
+		}
+	}
+	if ref.FieldSelector != "" {
+		if m.fields, err = fields.ParseSelector(ref.FieldSelector); err != nil {
+			return false
+		}
+	}
+	return m.matches(item, &objectFields{obj: item})
+}
+
+// matches returns whether the given object satisfies the matcher's
+// selectors, ignoring its expiry.
+func (m *matcher) matches(item kmeta.Accessor, of *objectFields) bool {
+	if !m.selector.Matches(labels.Set(item.GetLabels())) {
+		return false
+	}
+	if m.ownerUID != "" && !isOwnedBy(item, m.ownerUID) {
+		return false
+	}
+	if m.fields != nil && !m.fields.Matches(of.set(m.fields)) {
+		return false
+	}
+	return true
+}
+
+// isOwnedBy returns whether the object has an ownerReference with the given UID.
+func isOwnedBy(item kmeta.Accessor, uid types.UID) bool {
+	for _, or := range item.GetOwnerReferences() {
+		if or.UID == uid {
+			return true
+		}
+	}
+	return false
+}
+
+// objectFields resolves field selector paths (e.g. spec.nodeName) against
+// an object, converting it to its unstructured form at most once.
+type objectFields struct {
+	obj     interface{}
+	content map[string]interface{}
+}
+
+// set returns the values of the fields referenced by the selector.
+// Fields that are absent from the object are omitted.
+func (of *objectFields) set(sel fields.Selector) fields.Set {
+	if of.content == nil {
+		if u, ok := of.obj.(*unstructured.Unstructured); ok {
+			of.content = u.Object
+		} else if c, err := runtime.DefaultUnstructuredConverter.ToUnstructured(of.obj); err == nil {
+			of.content = c
+		} else {
+			of.content = map[string]interface{}{}
+		}
+	}
+
+	reqs := sel.Requirements()
+	set := make(fields.Set, len(reqs))
+	for _, r := range reqs {
+		v, found, err := unstructured.NestedFieldNoCopy(of.content, strings.Split(r.Field, ".")...)
+		if err != nil || !found {
+			continue
+		}
+		set[r.Field] = fmt.Sprint(v)
+	}
+	return set
+}
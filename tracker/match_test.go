@@ -0,0 +1,260 @@
+/*
+Copyright 2023 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	. "github.com/Yangfisher1/knative-common-pkg/testing"
+)
+
+func TestReferenceMatches(t *testing.T) {
+	thing := &Resource{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "ref.knative.dev/v1alpha1",
+			Kind:       "Thing1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "ns",
+			Name:      "foo",
+			Labels:    map[string]string{"foo": "bar"},
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: "ref.knative.dev/v1alpha1",
+				Kind:       "Parent",
+				Name:       "parent",
+				UID:        "parent-uid",
+			}},
+		},
+		Spec: ResourceSpec{
+			FieldWithValidation: "magic",
+		},
+	}
+	base := Reference{
+		APIVersion: "ref.knative.dev/v1alpha1",
+		Kind:       "Thing1",
+		Namespace:  "ns",
+	}
+
+	tests := []struct {
+		name string
+		ref  func(Reference) Reference
+		want bool
+	}{{
+		name: "by name",
+		ref:  func(r Reference) Reference { r.Name = "foo"; return r },
+		want: true,
+	}, {
+		name: "by other name",
+		ref:  func(r Reference) Reference { r.Name = "bar"; return r },
+	}, {
+		name: "by owner",
+		ref:  func(r Reference) Reference { r.OwnerUID = "parent-uid"; return r },
+		want: true,
+	}, {
+		name: "by other owner",
+		ref:  func(r Reference) Reference { r.OwnerUID = "other-uid"; return r },
+	}, {
+		name: "by field",
+		ref:  func(r Reference) Reference { r.FieldSelector = "spec.fieldWithValidation=magic"; return r },
+		want: true,
+	}, {
+		name: "by metadata field",
+		ref:  func(r Reference) Reference { r.FieldSelector = "metadata.name=foo"; return r },
+		want: true,
+	}, {
+		name: "by other field value",
+		ref:  func(r Reference) Reference { r.FieldSelector = "spec.fieldWithValidation=mundane"; return r },
+	}, {
+		name: "by missing field",
+		ref:  func(r Reference) Reference { r.FieldSelector = "spec.nodeName=node-1"; return r },
+	}, {
+		name: "by selector, owner and field",
+		ref: func(r Reference) Reference {
+			r.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{"foo": "bar"}}
+			r.OwnerUID = "parent-uid"
+			r.FieldSelector = "spec.fieldWithValidation!=mundane"
+			return r
+		},
+		want: true,
+	}, {
+		name: "by selector and other owner",
+		ref: func(r Reference) Reference {
+			r.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{"foo": "bar"}}
+			r.OwnerUID = "other-uid"
+			return r
+		},
+	}, {
+		name: "by owner in other namespace",
+		ref:  func(r Reference) Reference { r.Namespace = "other"; r.OwnerUID = "parent-uid"; return r },
+	}, {
+		name: "no name or selectors",
+		ref:  func(r Reference) Reference { return r },
+	}, {
+		name: "invalid field selector",
+		ref:  func(r Reference) Reference { r.FieldSelector = "spec.fieldWithValidation"; return r },
+	}}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ref := tc.ref(base)
+			if got := ref.Matches(thing); got != tc.want {
+				t.Errorf("Matches() = %v, wanted %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestHappyPathsByOwnerAndFields(t *testing.T) {
+	calls := 0
+	f := func(key types.NamespacedName) {
+		calls++
+	}
+
+	trk := New(f, time.Minute)
+
+	thing := &Resource{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "ref.knative.dev/v1alpha1",
+			Kind:       "Thing1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "ns",
+			Name:      "foo",
+			OwnerReferences: []metav1.OwnerReference{{
+				UID: "owner-uid",
+			}},
+		},
+		Spec: ResourceSpec{
+			FieldWithValidation: "magic",
+		},
+	}
+	byOwner := &Resource{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "by-owner"}}
+	byField := &Resource{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "by-field"}}
+	byOtherField := &Resource{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "by-other-field"}}
+
+	for obj, ref := range map[*Resource]Reference{
+		byOwner: {
+			APIVersion: "ref.knative.dev/v1alpha1",
+			Kind:       "Thing1",
+			Namespace:  "ns",
+			OwnerUID:   "owner-uid",
+		},
+		byField: {
+			APIVersion:    "ref.knative.dev/v1alpha1",
+			Kind:          "Thing1",
+			Namespace:     "ns",
+			FieldSelector: "spec.fieldWithValidation=magic",
+		},
+		byOtherField: {
+			APIVersion:    "ref.knative.dev/v1alpha1",
+			Kind:          "Thing1",
+			Namespace:     "ns",
+			FieldSelector: "spec.fieldWithValidation=mundane",
+		},
+	} {
+		if err := trk.TrackReference(ref, obj); err != nil {
+			t.Fatal("TrackReference() =", err)
+		}
+	}
+	// New registrations should result in an immediate callback.
+	if got, want := calls, 3; got != want {
+		t.Fatalf("TrackReference() = %v, wanted %v", got, want)
+	}
+
+	got := map[types.NamespacedName]bool{}
+	for _, key := range trk.GetObservers(thing) {
+		got[key] = true
+	}
+	want := map[types.NamespacedName]bool{
+		{Namespace: "ns", Name: "by-owner"}: true,
+		{Namespace: "ns", Name: "by-field"}: true,
+	}
+	if len(got) != len(want) {
+		t.Fatalf("GetObservers() = %v, wanted %v", got, want)
+	}
+	for key := range want {
+		if !got[key] {
+			t.Errorf("GetObservers() = %v, wanted %v", got, want)
+		}
+	}
+
+	trk.OnChanged(thing)
+	if got, want := calls, 5; got != want {
+		t.Fatalf("OnChanged() = %v, wanted %v", got, want)
+	}
+}
+
+func TestSeveralInexactReferences(t *testing.T) {
+	trk := New(func(types.NamespacedName) {}, time.Minute)
+
+	byOwner := &Resource{
+		TypeMeta: metav1.TypeMeta{APIVersion: "ref.knative.dev/v1alpha1", Kind: "Thing1"},
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:       "ns",
+			Name:            "owned",
+			OwnerReferences: []metav1.OwnerReference{{UID: "owner-uid"}},
+		},
+	}
+	byField := &Resource{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "ref.knative.dev/v1alpha1", Kind: "Thing1"},
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "magic"},
+		Spec:       ResourceSpec{FieldWithValidation: "magic"},
+	}
+	both := byField.DeepCopy()
+	both.Name, both.OwnerReferences = "both", byOwner.OwnerReferences
+
+	// The observer tracks objects of the same kind and namespace both by
+	// owner and by field, and is notified of the changes to either.
+	observer := &Resource{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "observer"}}
+	for _, ref := range []Reference{{
+		APIVersion: "ref.knative.dev/v1alpha1",
+		Kind:       "Thing1",
+		Namespace:  "ns",
+		OwnerUID:   "owner-uid",
+	}, {
+		APIVersion:    "ref.knative.dev/v1alpha1",
+		Kind:          "Thing1",
+		Namespace:     "ns",
+		FieldSelector: "spec.fieldWithValidation=magic",
+	}} {
+		if err := trk.TrackReference(ref, observer); err != nil {
+			t.Fatal("TrackReference() =", err)
+		}
+	}
+
+	want := []types.NamespacedName{{Namespace: "ns", Name: "observer"}}
+	for _, obj := range []*Resource{byOwner, byField, both} {
+		if got := trk.GetObservers(obj); !cmp.Equal(got, want) {
+			t.Errorf("GetObservers(%s) = %v, wanted %v", obj.Name, got, want)
+		}
+	}
+	if got, want := len(trk.(Dumper).Dump()), 2; got != want {
+		t.Errorf("len(Dump()) = %d, wanted %d", got, want)
+	}
+
+	trk.OnDeletedObserver(observer)
+	for _, obj := range []*Resource{byOwner, byField} {
+		if got := trk.GetObservers(obj); len(got) != 0 {
+			t.Errorf("GetObservers(%s) after OnDeletedObserver = %v, wanted none", obj.Name, got)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+/*
+Copyright 2023 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracker
+
+import (
+	"context"
+
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/Yangfisher1/knative-common-pkg/metrics"
+)
+
+const (
+	exactReferencesName   = "tracker_exact_references"
+	inexactReferencesName = "tracker_inexact_references"
+	callbackCountName     = "tracker_callback_count"
+)
+
+var (
+	exactReferencesM = stats.Int64(
+		exactReferencesName,
+		"The number of observers tracking a reference by name",
+		stats.UnitDimensionless)
+	inexactReferencesM = stats.Int64(
+		inexactReferencesName,
+		"The number of observers tracking references by selector",
+		stats.UnitDimensionless)
+	callbackCountM = stats.Int64(
+		callbackCountName,
+		"The number of times the tracker callback was invoked",
+		stats.UnitDimensionless)
+
+	// Create the tag keys that will be used to add tags to our measurements.
+	// Tag keys must conform to the restrictions described in
+	// go.opencensus.io/tag/validate.go. Currently those restrictions are:
+	// - length between 1 and 255 inclusive
+	// - characters are printable US-ASCII
+	trackerNameKey = tag.MustNewKey("tracker")
+	groupKey       = tag.MustNewKey("group")
+	versionKey     = tag.MustNewKey("version")
+	kindKey        = tag.MustNewKey("kind")
+)
+
+// RegisterMetrics registers the views of the tracker metrics, which the
+// trackers created with WithMetrics export.
+func RegisterMetrics() {
+	tagKeys := []tag.Key{trackerNameKey, groupKey, versionKey, kindKey}
+
+	if err := view.Register(
+		&view.View{
+			Description: exactReferencesM.Description(),
+			Measure:     exactReferencesM,
+			Aggregation: view.LastValue(),
+			TagKeys:     tagKeys,
+		},
+		&view.View{
+			Description: inexactReferencesM.Description(),
+			Measure:     inexactReferencesM,
+			Aggregation: view.LastValue(),
+			TagKeys:     tagKeys,
+		},
+		&view.View{
+			Description: callbackCountM.Description(),
+			Measure:     callbackCountM,
+			Aggregation: view.Count(),
+			TagKeys:     tagKeys,
+		},
+	); err != nil {
+		panic(err)
+	}
+}
+
+// statsReporter records the tracker metrics for a single named tracker.
+// A nil *statsReporter records nothing.
+type statsReporter struct {
+	name string
+}
+
+func (r *statsReporter) tagged(gvk schema.GroupVersionKind) context.Context {
+	ctx, err := tag.New(
+		context.Background(),
+		tag.Insert(trackerNameKey, r.name),
+		tag.Insert(groupKey, gvk.Group),
+		tag.Insert(versionKey, gvk.Version),
+		tag.Insert(kindKey, gvk.Kind),
+	)
+	if err != nil {
+		// Fall back to untagged measurements rather than dropping them.
+		return context.Background()
+	}
+	return ctx
+}
+
+// reportReferences records the current number of exact and inexact
+// references for the given GroupVersionKind.
+func (r *statsReporter) reportReferences(gvk schema.GroupVersionKind, c counts) {
+	if r == nil {
+		return
+	}
+	metrics.RecordBatch(r.tagged(gvk),
+		exactReferencesM.M(int64(c.exact)),
+		inexactReferencesM.M(int64(c.inexact)))
+}
+
+// reportCallbacks records n invocations of the tracker callback that
+// were triggered by an object of the given GroupVersionKind.
+func (r *statsReporter) reportCallbacks(gvk schema.GroupVersionKind, n int) {
+	if r == nil {
+		return
+	}
+	ctx := r.tagged(gvk)
+	for i := 0; i < n; i++ {
+		metrics.Record(ctx, callbackCountM.M(1))
+	}
+}
@@ -0,0 +1,85 @@
+/*
+Copyright 2023 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracker
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/Yangfisher1/knative-common-pkg/metrics/metricstest"
+	_ "github.com/Yangfisher1/knative-common-pkg/metrics/testing"
+	. "github.com/Yangfisher1/knative-common-pkg/testing"
+)
+
+func TestTrackerMetrics(t *testing.T) {
+	RegisterMetrics()
+	trk := New(func(types.NamespacedName) {}, time.Minute, WithMetrics("metrics-test"))
+
+	thing := &Resource{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "ref.knative.dev/v1alpha1",
+			Kind:       "Thing1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "ns",
+			Name:      "foo",
+			Labels:    map[string]string{"foo": "bar"},
+		},
+	}
+	wantTags := map[string]string{
+		trackerNameKey.Name(): "metrics-test",
+		groupKey.Name():       "ref.knative.dev",
+		versionKey.Name():     "v1alpha1",
+		kindKey.Name():        "Thing1",
+	}
+
+	for _, name := range []string{"first", "second"} {
+		if err := trk.TrackReference(Reference{
+			APIVersion: "ref.knative.dev/v1alpha1",
+			Kind:       "Thing1",
+			Namespace:  "ns",
+			Name:       "foo",
+		}, &Resource{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: name}}); err != nil {
+			t.Fatal("TrackReference() =", err)
+		}
+	}
+	if err := trk.TrackReference(Reference{
+		APIVersion: "ref.knative.dev/v1alpha1",
+		Kind:       "Thing1",
+		Namespace:  "ns",
+		Selector: &metav1.LabelSelector{
+			MatchLabels: map[string]string{"foo": "bar"},
+		},
+	}, &Resource{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "third"}}); err != nil {
+		t.Fatal("TrackReference() =", err)
+	}
+
+	metricstest.CheckLastValueData(t, exactReferencesName, wantTags, 2)
+	metricstest.CheckLastValueData(t, inexactReferencesName, wantTags, 1)
+	// One catch-up callback per new registration.
+	metricstest.CheckCountData(t, callbackCountName, wantTags, 3)
+
+	trk.OnChanged(thing)
+	metricstest.CheckCountData(t, callbackCountName, wantTags, 6)
+
+	trk.OnDeletedObserver(&Resource{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "first"}})
+	metricstest.CheckLastValueData(t, exactReferencesName, wantTags, 1)
+	metricstest.CheckLastValueData(t, inexactReferencesName, wantTags, 1)
+}
@@ -26,6 +26,7 @@ import (
 	"github.com/Yangfisher1/knative-common-pkg/apis"
 	"github.com/Yangfisher1/knative-common-pkg/logging"
 	"github.com/Yangfisher1/knative-common-pkg/logging/logkey"
+	"go.opencensus.io/trace"
 	"go.uber.org/zap"
 	admissionv1 "k8s.io/api/admission/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
@@ -74,7 +75,7 @@ func MakeErrorStatus(reason string, args ...interface{}) *admissionv1.AdmissionR
 	}
 }
 
-func admissionHandler(rootLogger *zap.SugaredLogger, stats StatsReporter, c AdmissionController, synced <-chan struct{}) http.HandlerFunc {
+func admissionHandler(rootLogger *zap.SugaredLogger, stats StatsReporter, audit *auditor, cache *responseCache, c AdmissionController, synced <-chan struct{}) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if _, ok := c.(StatelessAdmissionController); ok {
 			// Stateless admission controllers do not require Informers to have
@@ -91,7 +92,7 @@ func admissionHandler(rootLogger *zap.SugaredLogger, stats StatsReporter, c Admi
 
 		var review admissionv1.AdmissionReview
 		if err := json.NewDecoder(r.Body).Decode(&review); err != nil {
-			http.Error(w, fmt.Sprint("could not decode body:", err), http.StatusBadRequest)
+			decodeError(w, err)
 			return
 		}
 
@@ -105,7 +106,15 @@ func admissionHandler(rootLogger *zap.SugaredLogger, stats StatsReporter, c Admi
 			logkey.UserInfo, review.Request.UserInfo.Username,
 		)
 
-		ctx := logging.WithLogger(r.Context(), logger)
+		ctx, span := trace.StartSpan(r.Context(), "AdmissionReview")
+		defer span.End()
+		span.AddAttributes(
+			trace.StringAttribute("uid", string(review.Request.UID)),
+			trace.StringAttribute("kind", review.Request.Kind.String()),
+			trace.StringAttribute("operation", string(review.Request.Operation)),
+		)
+
+		ctx = logging.WithLogger(ctx, logger)
 		ctx = apis.WithHTTPRequest(ctx, r)
 
 		response := admissionv1.AdmissionReview{
@@ -115,7 +124,13 @@ func admissionHandler(rootLogger *zap.SugaredLogger, stats StatsReporter, c Admi
 			TypeMeta: review.TypeMeta,
 		}
 
-		reviewResponse := c.Admit(ctx, review.Request)
+		reviewResponse := cache.admit(ctx, c, review.Request)
+		if !reviewResponse.Allowed && reviewResponse.Result != nil {
+			span.SetStatus(trace.Status{
+				Code:    trace.StatusCodeUnknown,
+				Message: reviewResponse.Result.Message,
+			})
+		}
 		var patchType string
 		if reviewResponse.PatchType != nil {
 			patchType = string(*reviewResponse.PatchType)
@@ -139,10 +154,13 @@ func admissionHandler(rootLogger *zap.SugaredLogger, stats StatsReporter, c Admi
 			return
 		}
 
+		latency := time.Since(ttStart)
 		if stats != nil {
 			// Only report valid requests
-			stats.ReportRequest(review.Request, response.Response, time.Since(ttStart))
+			stats.ReportRequest(review.Request, response.Response, latency)
+			reportPatch(review.Request, response.Response.Patch)
 		}
+		audit.audit(c.Path(), review.Request, response.Response, latency)
 	}
 }
 
@@ -0,0 +1,122 @@
+/*
+Copyright 2023 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// AdmissionWebhookOptions declares the fields of the entry of a
+// {Mutating,Validating}WebhookConfiguration that an admission controller
+// reconciles.  Fields left nil are not reconciled, so whatever is set on the
+// webhook configuration (e.g. by its YAML) is preserved.
+type AdmissionWebhookOptions struct {
+	// NamespaceSelector and ObjectSelector select the objects sent to the
+	// webhook.  The knative.dev keyed expressions the admission controller
+	// needs, e.g. to honor webhooks.knative.dev/exclude, are still merged in.
+	NamespaceSelector *metav1.LabelSelector
+	ObjectSelector    *metav1.LabelSelector
+
+	TimeoutSeconds *int32
+	FailurePolicy  *admissionregistrationv1.FailurePolicyType
+	MatchPolicy    *admissionregistrationv1.MatchPolicyType
+	SideEffects    *admissionregistrationv1.SideEffectClass
+
+	// Rules overrides the operations and scope of the rules generated for
+	// each kind handled by the admission controller, across all its versions.
+	Rules map[schema.GroupKind]RuleOptions
+}
+
+// RuleOptions declares the operations and scope of a webhook rule.
+type RuleOptions struct {
+	// Operations replaces the admission controller's default operations,
+	// when set.
+	Operations []admissionregistrationv1.OperationType
+	// Scope restricts the rule to cluster or namespace scoped resources.
+	Scope *admissionregistrationv1.ScopeType
+}
+
+// Operations returns the operations declared for the kind's rule, or def.
+func (o *AdmissionWebhookOptions) Operations(gk schema.GroupKind, def ...admissionregistrationv1.OperationType) []admissionregistrationv1.OperationType {
+	if o != nil {
+		if ro, ok := o.Rules[gk]; ok && len(ro.Operations) > 0 {
+			return append([]admissionregistrationv1.OperationType(nil), ro.Operations...)
+		}
+	}
+	return def
+}
+
+// Scope returns the scope declared for the kind's rule, or def.
+func (o *AdmissionWebhookOptions) Scope(gk schema.GroupKind, def *admissionregistrationv1.ScopeType) *admissionregistrationv1.ScopeType {
+	if o != nil {
+		if ro, ok := o.Rules[gk]; ok && ro.Scope != nil {
+			scope := *ro.Scope
+			return &scope
+		}
+	}
+	return def
+}
+
+// ApplyToMutatingWebhook sets the declared fields on the webhook entry.
+func (o *AdmissionWebhookOptions) ApplyToMutatingWebhook(wh *admissionregistrationv1.MutatingWebhook) {
+	if o == nil {
+		return
+	}
+	o.apply(&wh.NamespaceSelector, &wh.ObjectSelector, &wh.TimeoutSeconds, &wh.FailurePolicy, &wh.MatchPolicy, &wh.SideEffects)
+}
+
+// ApplyToValidatingWebhook sets the declared fields on the webhook entry.
+func (o *AdmissionWebhookOptions) ApplyToValidatingWebhook(wh *admissionregistrationv1.ValidatingWebhook) {
+	if o == nil {
+		return
+	}
+	o.apply(&wh.NamespaceSelector, &wh.ObjectSelector, &wh.TimeoutSeconds, &wh.FailurePolicy, &wh.MatchPolicy, &wh.SideEffects)
+}
+
+func (o *AdmissionWebhookOptions) apply(
+	namespaceSelector, objectSelector **metav1.LabelSelector,
+	timeoutSeconds **int32,
+	failurePolicy **admissionregistrationv1.FailurePolicyType,
+	matchPolicy **admissionregistrationv1.MatchPolicyType,
+	sideEffects **admissionregistrationv1.SideEffectClass,
+) {
+	// Copy everything, so that the webhook never aliases the options.
+	if o.NamespaceSelector != nil {
+		*namespaceSelector = o.NamespaceSelector.DeepCopy()
+	}
+	if o.ObjectSelector != nil {
+		*objectSelector = o.ObjectSelector.DeepCopy()
+	}
+	if o.TimeoutSeconds != nil {
+		v := *o.TimeoutSeconds
+		*timeoutSeconds = &v
+	}
+	if o.FailurePolicy != nil {
+		v := *o.FailurePolicy
+		*failurePolicy = &v
+	}
+	if o.MatchPolicy != nil {
+		v := *o.MatchPolicy
+		*matchPolicy = &v
+	}
+	if o.SideEffects != nil {
+		v := *o.SideEffects
+		*sideEffects = &v
+	}
+}
@@ -0,0 +1,115 @@
+/*
+Copyright 2023 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestAdmissionWebhookOptionsRules(t *testing.T) {
+	gk := schema.GroupKind{Group: "pkg.knative.dev", Kind: "Resource"}
+	other := schema.GroupKind{Group: "pkg.knative.dev", Kind: "Other"}
+	cluster := admissionregistrationv1.ClusterScope
+	namespaced := admissionregistrationv1.NamespacedScope
+	defaults := []admissionregistrationv1.OperationType{admissionregistrationv1.Create, admissionregistrationv1.Update}
+
+	var none *AdmissionWebhookOptions
+	if got := none.Operations(gk, defaults...); !cmp.Equal(got, defaults) {
+		t.Errorf("nil Operations() = %v, want %v", got, defaults)
+	}
+	if got := none.Scope(gk, &namespaced); got != &namespaced {
+		t.Errorf("nil Scope() = %v, want the default", got)
+	}
+
+	o := &AdmissionWebhookOptions{
+		Rules: map[schema.GroupKind]RuleOptions{
+			gk: {
+				Operations: []admissionregistrationv1.OperationType{admissionregistrationv1.Delete},
+				Scope:      &cluster,
+			},
+		},
+	}
+	if got, want := o.Operations(gk, defaults...), []admissionregistrationv1.OperationType{admissionregistrationv1.Delete}; !cmp.Equal(got, want) {
+		t.Errorf("Operations() = %v, want %v", got, want)
+	}
+	if got := o.Scope(gk, &namespaced); got == nil || *got != cluster {
+		t.Errorf("Scope() = %v, want %v", got, cluster)
+	}
+	if got := o.Operations(other, defaults...); !cmp.Equal(got, defaults) {
+		t.Errorf("Operations(undeclared) = %v, want %v", got, defaults)
+	}
+	if got := o.Scope(other, nil); got != nil {
+		t.Errorf("Scope(undeclared) = %v, want nil", *got)
+	}
+}
+
+func TestAdmissionWebhookOptionsApply(t *testing.T) {
+	ignore := admissionregistrationv1.Ignore
+	equivalent := admissionregistrationv1.Equivalent
+	none := admissionregistrationv1.SideEffectClassNone
+	timeout := int32(5)
+	selector := &metav1.LabelSelector{MatchLabels: map[string]string{"foo": "bar"}}
+
+	o := &AdmissionWebhookOptions{
+		ObjectSelector: selector,
+		FailurePolicy:  &ignore,
+		SideEffects:    &none,
+	}
+	ctx := WithAdmissionWebhookOptions(context.Background(), *o)
+	if got := GetAdmissionWebhookOptions(ctx); !cmp.Equal(got, o) {
+		t.Error("GetAdmissionWebhookOptions (-want, +got):", cmp.Diff(o, got))
+	}
+	if got := GetAdmissionWebhookOptions(context.Background()); got != nil {
+		t.Errorf("GetAdmissionWebhookOptions() = %v, want nil", got)
+	}
+
+	vwh := &admissionregistrationv1.ValidatingWebhook{
+		TimeoutSeconds: &timeout,
+		MatchPolicy:    &equivalent,
+	}
+	o.ApplyToValidatingWebhook(vwh)
+	want := &admissionregistrationv1.ValidatingWebhook{
+		ObjectSelector: selector,
+		FailurePolicy:  &ignore,
+		SideEffects:    &none,
+		// Undeclared fields are preserved.
+		TimeoutSeconds: &timeout,
+		MatchPolicy:    &equivalent,
+	}
+	if !cmp.Equal(vwh, want) {
+		t.Error("ApplyToValidatingWebhook (-want, +got):", cmp.Diff(want, vwh))
+	}
+	if vwh.ObjectSelector == selector || vwh.FailurePolicy == &ignore {
+		t.Error("ApplyToValidatingWebhook() aliased the options")
+	}
+
+	mwh := &admissionregistrationv1.MutatingWebhook{}
+	o.ApplyToMutatingWebhook(mwh)
+	if !cmp.Equal(mwh.ObjectSelector, selector) || mwh.SideEffects == nil || *mwh.SideEffects != none {
+		t.Errorf("ApplyToMutatingWebhook() = %+v", mwh)
+	}
+
+	// Nil options leave the webhook alone.
+	var nilOptions *AdmissionWebhookOptions
+	nilOptions.ApplyToMutatingWebhook(mwh)
+}
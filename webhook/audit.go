@@ -0,0 +1,392 @@
+/*
+Copyright 2023 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// AuditDecision is the outcome of an admission request.
+type AuditDecision string
+
+const (
+	// AuditDecisionAllowed is recorded for admitted requests that were not
+	// mutated.
+	AuditDecisionAllowed AuditDecision = "allowed"
+
+	// AuditDecisionMutated is recorded for admitted requests that were
+	// mutated with a JSON patch.
+	AuditDecisionMutated AuditDecision = "mutated"
+
+	// AuditDecisionDenied is recorded for denied requests.
+	AuditDecisionDenied AuditDecision = "denied"
+)
+
+// redacted replaces redacted values in AuditRecords.
+const redacted = "REDACTED"
+
+// AuditRecord is the structured record of an admission request.
+type AuditRecord struct {
+	Time        time.Time                   `json:"time"`
+	UID         types.UID                   `json:"uid"`
+	Path        string                      `json:"path"`
+	User        authenticationv1.UserInfo   `json:"user"`
+	Kind        metav1.GroupVersionKind     `json:"kind"`
+	Resource    metav1.GroupVersionResource `json:"resource"`
+	SubResource string                      `json:"subResource,omitempty"`
+	Namespace   string                      `json:"namespace,omitempty"`
+	Name        string                      `json:"name,omitempty"`
+	Operation   Operation                   `json:"operation"`
+	DryRun      bool                        `json:"dryRun,omitempty"`
+
+	Decision AuditDecision `json:"decision"`
+	// Reason is the reason of the denial, if denied.
+	Reason   string   `json:"reason,omitempty"`
+	Warnings []string `json:"warnings,omitempty"`
+	// PatchBytes is the size of the JSON patch, if mutated.
+	PatchBytes     int     `json:"patchBytes"`
+	LatencySeconds float64 `json:"latencySeconds"`
+
+	// Object and OldObject are only recorded when enabled through
+	// AuditOptions.IncludeObjects.  The payload of Secrets is redacted.
+	Object    json.RawMessage `json:"object,omitempty"`
+	OldObject json.RawMessage `json:"oldObject,omitempty"`
+}
+
+// DefaultAuditQueueSize is the default number of AuditRecords queued for the
+// AuditWriter.
+const DefaultAuditQueueSize = 1000
+
+// AuditWriter writes AuditRecords to durable storage.  It must be safe for
+// concurrent use.  Records are written in the background, after the
+// admission response has been returned.
+type AuditWriter interface {
+	WriteAudit(context.Context, *AuditRecord) error
+}
+
+// AuditOptions configures the admission audit log.
+type AuditOptions struct {
+	// Writer is where AuditRecords are written.  Auditing is disabled
+	// without a Writer.
+	Writer AuditWriter
+
+	// SampleRates holds the fraction of requests to record, between 0 and 1,
+	// by decision.  Requests with decisions without a sample rate are
+	// always recorded, so e.g. {AuditDecisionAllowed: 0.01} records 1% of
+	// the admitted requests and all the mutated and denied ones.
+	SampleRates map[AuditDecision]float64
+
+	// IncludeObjects records the objects of the requests, with the payload
+	// of Secrets redacted.
+	IncludeObjects bool
+
+	// QueueSize is the number of AuditRecords queued for the Writer, past
+	// which records are dropped and counted in the audit_dropped_count
+	// metric rather than delaying admission.  Defaults to
+	// DefaultAuditQueueSize.
+	QueueSize int
+}
+
+// auditor records admission requests to an AuditWriter, through a bounded
+// queue drained in the background.  A nil auditor records nothing.
+type auditor struct {
+	opts   AuditOptions
+	logger *zap.SugaredLogger
+
+	// random returns a number in [0, 1) to sample with.
+	random func() float64
+
+	queue    chan *AuditRecord
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+func newAuditor(logger *zap.SugaredLogger, opts *AuditOptions) *auditor {
+	if opts == nil || opts.Writer == nil {
+		return nil
+	}
+	size := opts.QueueSize
+	if size <= 0 {
+		size = DefaultAuditQueueSize
+	}
+	a := &auditor{
+		opts:   *opts,
+		logger: logger,
+		random: rand.Float64,
+		queue:  make(chan *AuditRecord, size),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go a.run()
+	return a
+}
+
+// run writes the queued records until the auditor is flushed, then writes
+// those left in the queue.
+func (a *auditor) run() {
+	defer close(a.done)
+	for {
+		select {
+		case record := <-a.queue:
+			a.write(record)
+		case <-a.stop:
+			for {
+				select {
+				case record := <-a.queue:
+					a.write(record)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (a *auditor) write(record *AuditRecord) {
+	// The request is over by now, so its context is of no use.
+	if err := a.opts.Writer.WriteAudit(context.Background(), record); err != nil {
+		a.logger.Errorw("Failed to write the admission audit record", zap.Error(err))
+	}
+}
+
+// flush stops the auditor, and waits for the queued records to be written or
+// for the context to be done.  Records audited afterwards are dropped.
+func (a *auditor) flush(ctx context.Context) error {
+	if a == nil {
+		return nil
+	}
+	a.stopOnce.Do(func() { close(a.stop) })
+	select {
+	case <-a.done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("failed to flush the admission audit records: %w", ctx.Err())
+	}
+}
+
+// audit queues the record of the request for the auditor's AuditWriter if it
+// is sampled, or drops it if the queue is full.
+func (a *auditor) audit(path string, req *admissionv1.AdmissionRequest, resp *admissionv1.AdmissionResponse, latency time.Duration) {
+	if a == nil {
+		return
+	}
+
+	decision := AuditDecisionAllowed
+	switch {
+	case !resp.Allowed:
+		decision = AuditDecisionDenied
+	case !isEmptyPatch(resp.Patch):
+		decision = AuditDecisionMutated
+	}
+	if rate, ok := a.opts.SampleRates[decision]; ok && a.random() >= rate {
+		return
+	}
+
+	record := &AuditRecord{
+		Time:           time.Now().UTC(),
+		UID:            req.UID,
+		Path:           path,
+		User:           req.UserInfo,
+		Kind:           req.Kind,
+		Resource:       req.Resource,
+		SubResource:    req.SubResource,
+		Namespace:      req.Namespace,
+		Name:           req.Name,
+		Operation:      req.Operation,
+		DryRun:         req.DryRun != nil && *req.DryRun,
+		Decision:       decision,
+		Warnings:       resp.Warnings,
+		PatchBytes:     len(resp.Patch),
+		LatencySeconds: latency.Seconds(),
+	}
+	if decision == AuditDecisionDenied && resp.Result != nil {
+		record.Reason = resp.Result.Message
+	}
+	if a.opts.IncludeObjects {
+		record.Object = redact(req.Kind, req.Object.Raw)
+		record.OldObject = redact(req.Kind, req.OldObject.Raw)
+	}
+
+	select {
+	case <-a.stop:
+		// The queue is no longer drained.
+		reportAuditDropped(decision)
+		return
+	default:
+	}
+	select {
+	case a.queue <- record:
+	default:
+		reportAuditDropped(decision)
+	}
+}
+
+// redact returns the object, with the payload redacted if it is a Secret.
+func redact(kind metav1.GroupVersionKind, raw []byte) json.RawMessage {
+	if len(raw) == 0 {
+		return nil
+	}
+	if kind.Group != "" || kind.Kind != "Secret" {
+		return raw
+	}
+
+	var secret map[string]interface{}
+	if err := json.Unmarshal(raw, &secret); err != nil {
+		// Never record what we could not redact.
+		return nil
+	}
+	for _, field := range []string{"data", "stringData"} {
+		if data, ok := secret[field].(map[string]interface{}); ok {
+			for k := range data {
+				data[k] = redacted
+			}
+		}
+	}
+	// kubectl apply stores the whole Secret in an annotation.
+	if meta, ok := secret["metadata"].(map[string]interface{}); ok {
+		if annotations, ok := meta["annotations"].(map[string]interface{}); ok {
+			if _, ok := annotations["kubectl.kubernetes.io/last-applied-configuration"]; ok {
+				annotations["kubectl.kubernetes.io/last-applied-configuration"] = redacted
+			}
+		}
+	}
+	b, err := json.Marshal(secret)
+	if err != nil {
+		return nil
+	}
+	return b
+}
+
+// loggerAuditWriter writes AuditRecords to a logger.
+type loggerAuditWriter struct {
+	logger *zap.Logger
+}
+
+// NewLoggerAuditWriter returns an AuditWriter that logs AuditRecords at info
+// level as the "audit" field, which the JSON encoder renders as structured
+// JSON.
+func NewLoggerAuditWriter(logger *zap.SugaredLogger) AuditWriter {
+	return &loggerAuditWriter{logger: logger.Desugar()}
+}
+
+// WriteAudit implements AuditWriter
+func (w *loggerAuditWriter) WriteAudit(_ context.Context, record *AuditRecord) error {
+	w.logger.Info("Admission audit", zap.Any("audit", record))
+	return nil
+}
+
+// StreamAuditWriter writes AuditRecords to a stream, e.g. a file, as JSON
+// lines.
+type StreamAuditWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+var _ AuditWriter = (*StreamAuditWriter)(nil)
+
+// NewStreamAuditWriter returns an AuditWriter that writes AuditRecords to w
+// as JSON lines.
+func NewStreamAuditWriter(w io.Writer) *StreamAuditWriter {
+	return &StreamAuditWriter{w: w}
+}
+
+// NewFileAuditWriter returns an AuditWriter that appends AuditRecords to the
+// named file as JSON lines, creating it if needed.  The file should be closed
+// with Close once the webhook is done.
+func NewFileAuditWriter(name string) (*StreamAuditWriter, error) {
+	f, err := os.OpenFile(name, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the audit log: %w", err)
+	}
+	return NewStreamAuditWriter(f), nil
+}
+
+// WriteAudit implements AuditWriter
+func (w *StreamAuditWriter) WriteAudit(_ context.Context, record *AuditRecord) error {
+	b, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	_, err = w.w.Write(append(b, '\n'))
+	return err
+}
+
+// Close closes the underlying stream, if it can be closed.
+func (w *StreamAuditWriter) Close() error {
+	if c, ok := w.w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// httpAuditWriter posts AuditRecords to an HTTP endpoint.
+type httpAuditWriter struct {
+	url    string
+	client *http.Client
+}
+
+// NewHTTPAuditWriter returns an AuditWriter that POSTs each AuditRecord as
+// JSON to url.  Records are written one at a time in the background, so the
+// client's timeout bounds how long each one holds up the queue, and how long
+// the webhook takes to flush the queue on shutdown.  If client is nil, a
+// client with a five second timeout is used.
+func NewHTTPAuditWriter(url string, client *http.Client) AuditWriter {
+	if client == nil {
+		client = &http.Client{Timeout: 5 * time.Second}
+	}
+	return &httpAuditWriter{url: url, client: client}
+}
+
+// WriteAudit implements AuditWriter
+func (w *httpAuditWriter) WriteAudit(ctx context.Context, record *AuditRecord) error {
+	b, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("audit endpoint returned %s", resp.Status)
+	}
+	return nil
+}
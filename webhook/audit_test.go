@@ -0,0 +1,391 @@
+/*
+Copyright 2023 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	. "github.com/Yangfisher1/knative-common-pkg/logging/testing"
+	"github.com/Yangfisher1/knative-common-pkg/metrics/metricstest"
+)
+
+type recordingAuditWriter struct {
+	mu      sync.Mutex
+	records []*AuditRecord
+}
+
+func (w *recordingAuditWriter) WriteAudit(_ context.Context, record *AuditRecord) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.records = append(w.records, record)
+	return nil
+}
+
+func TestAdmissionAudit(t *testing.T) {
+	jsonPatch := admissionv1.PatchTypeJSONPatch
+	secretKind := metav1.GroupVersionKind{Version: "v1", Kind: "Secret"}
+	resourceKind := metav1.GroupVersionKind{Group: "pkg.knative.dev", Version: "v1alpha1", Kind: "Resource"}
+	dryRun := true
+
+	tests := []struct {
+		name          string
+		opts          AuditOptions
+		random        float64
+		request       *admissionv1.AdmissionRequest
+		response      *admissionv1.AdmissionResponse
+		want          *AuditRecord
+		wantObject    string
+		wantOldObject string
+	}{{
+		name: "allowed",
+		request: &admissionv1.AdmissionRequest{
+			UID:       "uid",
+			Kind:      resourceKind,
+			Namespace: "ns",
+			Name:      "name",
+			Operation: admissionv1.Create,
+			UserInfo:  authenticationv1.UserInfo{Username: "jane"},
+			DryRun:    &dryRun,
+		},
+		response: &admissionv1.AdmissionResponse{Allowed: true, Warnings: []string{"careful"}},
+		want: &AuditRecord{
+			UID:       "uid",
+			Path:      "/audited",
+			User:      authenticationv1.UserInfo{Username: "jane"},
+			Kind:      resourceKind,
+			Namespace: "ns",
+			Name:      "name",
+			Operation: admissionv1.Create,
+			DryRun:    true,
+			Decision:  AuditDecisionAllowed,
+			Warnings:  []string{"careful"},
+		},
+	}, {
+		name:    "mutated",
+		request: &admissionv1.AdmissionRequest{Kind: resourceKind, Operation: admissionv1.Update},
+		response: &admissionv1.AdmissionResponse{
+			Allowed:   true,
+			PatchType: &jsonPatch,
+			Patch:     []byte(`[{"op":"add","path":"/a","value":"b"}]`),
+		},
+		want: &AuditRecord{
+			Path:       "/audited",
+			Kind:       resourceKind,
+			Operation:  admissionv1.Update,
+			Decision:   AuditDecisionMutated,
+			PatchBytes: 38,
+		},
+	}, {
+		name:    "empty patch",
+		request: &admissionv1.AdmissionRequest{Kind: resourceKind, Operation: admissionv1.Update},
+		response: &admissionv1.AdmissionResponse{
+			Allowed:   true,
+			PatchType: &jsonPatch,
+			Patch:     []byte(`[]`),
+		},
+		want: &AuditRecord{
+			Path:       "/audited",
+			Kind:       resourceKind,
+			Operation:  admissionv1.Update,
+			Decision:   AuditDecisionAllowed,
+			PatchBytes: 2,
+		},
+	}, {
+		name:     "denied",
+		request:  &admissionv1.AdmissionRequest{Kind: resourceKind, Operation: admissionv1.Create},
+		response: MakeErrorStatus("nope: %s", "bad"),
+		want: &AuditRecord{
+			Path:      "/audited",
+			Kind:      resourceKind,
+			Operation: admissionv1.Create,
+			Decision:  AuditDecisionDenied,
+			Reason:    "nope: bad",
+		},
+	}, {
+		name:     "sampled in",
+		opts:     AuditOptions{SampleRates: map[AuditDecision]float64{AuditDecisionAllowed: 0.5}},
+		random:   0.4,
+		request:  &admissionv1.AdmissionRequest{Kind: resourceKind, Operation: admissionv1.Create},
+		response: &admissionv1.AdmissionResponse{Allowed: true},
+		want: &AuditRecord{
+			Path:      "/audited",
+			Kind:      resourceKind,
+			Operation: admissionv1.Create,
+			Decision:  AuditDecisionAllowed,
+		},
+	}, {
+		name:     "sampled out",
+		opts:     AuditOptions{SampleRates: map[AuditDecision]float64{AuditDecisionAllowed: 0.5}},
+		random:   0.5,
+		request:  &admissionv1.AdmissionRequest{Kind: resourceKind, Operation: admissionv1.Create},
+		response: &admissionv1.AdmissionResponse{Allowed: true},
+	}, {
+		name:     "other decisions are not sampled",
+		opts:     AuditOptions{SampleRates: map[AuditDecision]float64{AuditDecisionAllowed: 0}},
+		random:   0.99,
+		request:  &admissionv1.AdmissionRequest{Kind: resourceKind, Operation: admissionv1.Create},
+		response: MakeErrorStatus("nope"),
+		want: &AuditRecord{
+			Path:      "/audited",
+			Kind:      resourceKind,
+			Operation: admissionv1.Create,
+			Decision:  AuditDecisionDenied,
+			Reason:    "nope",
+		},
+	}, {
+		name: "objects",
+		opts: AuditOptions{IncludeObjects: true},
+		request: &admissionv1.AdmissionRequest{
+			Kind:      resourceKind,
+			Operation: admissionv1.Update,
+			Object:    runtimeRaw(`{"spec":{"field":"new"}}`),
+			OldObject: runtimeRaw(`{"spec":{"field":"old"}}`),
+		},
+		response: &admissionv1.AdmissionResponse{Allowed: true},
+		want: &AuditRecord{
+			Path:      "/audited",
+			Kind:      resourceKind,
+			Operation: admissionv1.Update,
+			Decision:  AuditDecisionAllowed,
+		},
+		wantObject:    `{"spec":{"field":"new"}}`,
+		wantOldObject: `{"spec":{"field":"old"}}`,
+	}, {
+		name: "redacted secret",
+		opts: AuditOptions{IncludeObjects: true},
+		request: &admissionv1.AdmissionRequest{
+			Kind:      secretKind,
+			Operation: admissionv1.Create,
+			Object: runtimeRaw(`{"metadata":{"name":"s","annotations":{` +
+				`"kubectl.kubernetes.io/last-applied-configuration":"{\"data\":{\"password\":\"aHVudGVyMg==\"}}"}},` +
+				`"data":{"password":"aHVudGVyMg=="},"stringData":{"token":"hunter2"}}`),
+		},
+		response: &admissionv1.AdmissionResponse{Allowed: true},
+		want: &AuditRecord{
+			Path:      "/audited",
+			Kind:      secretKind,
+			Operation: admissionv1.Create,
+			Decision:  AuditDecisionAllowed,
+		},
+		wantObject: `{"data":{"password":"REDACTED"},"metadata":{"annotations":{` +
+			`"kubectl.kubernetes.io/last-applied-configuration":"REDACTED"},"name":"s"},"stringData":{"token":"REDACTED"}}`,
+	}}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			writer := &recordingAuditWriter{}
+			opts := tc.opts
+			opts.Writer = writer
+			audit := newAuditor(TestLogger(t), &opts)
+			audit.random = func() float64 { return tc.random }
+
+			c := &fixedAdmissionController{path: "/audited", response: tc.response}
+			synced := make(chan struct{})
+			close(synced)
+			handler := admissionHandler(TestLogger(t), nil, audit, nil, c, synced)
+
+			body, err := json.Marshal(&admissionv1.AdmissionReview{Request: tc.request})
+			if err != nil {
+				t.Fatal("Failed to marshal admission review:", err)
+			}
+			req := httptest.NewRequest(http.MethodPost, "/audited", bytes.NewReader(body))
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+			if err := audit.flush(context.Background()); err != nil {
+				t.Fatal("flush() =", err)
+			}
+
+			if tc.want == nil {
+				if len(writer.records) != 0 {
+					t.Errorf("Got %d records, want none", len(writer.records))
+				}
+				return
+			}
+			if len(writer.records) != 1 {
+				t.Fatalf("Got %d records, want 1", len(writer.records))
+			}
+			got := writer.records[0]
+			if got.Time.IsZero() || got.LatencySeconds <= 0 {
+				t.Errorf("Time = %v, LatencySeconds = %v, want them set", got.Time, got.LatencySeconds)
+			}
+			if got, want := string(got.Object), tc.wantObject; got != want {
+				t.Errorf("Object = %s, want %s", got, want)
+			}
+			if got, want := string(got.OldObject), tc.wantOldObject; got != want {
+				t.Errorf("OldObject = %s, want %s", got, want)
+			}
+			ignore := cmpopts.IgnoreFields(AuditRecord{}, "Time", "LatencySeconds", "Object", "OldObject")
+			if !cmp.Equal(got, tc.want, ignore) {
+				t.Error("AuditRecord (-want, +got):", cmp.Diff(tc.want, got, ignore))
+			}
+		})
+	}
+}
+
+func TestNilAuditor(t *testing.T) {
+	if a := newAuditor(TestLogger(t), nil); a != nil {
+		t.Errorf("newAuditor(nil) = %v, want nil", a)
+	}
+	if a := newAuditor(TestLogger(t), &AuditOptions{}); a != nil {
+		t.Errorf("newAuditor(no writer) = %v, want nil", a)
+	}
+	// A nil auditor records nothing, and must not panic.
+	var a *auditor
+	a.audit("/", &admissionv1.AdmissionRequest{}, &admissionv1.AdmissionResponse{}, 0)
+	if err := a.flush(context.Background()); err != nil {
+		t.Error("flush() =", err)
+	}
+}
+
+// blockingAuditWriter records AuditRecords once unblocked.
+type blockingAuditWriter struct {
+	recordingAuditWriter
+	started chan struct{}
+	unblock chan struct{}
+}
+
+func (w *blockingAuditWriter) WriteAudit(ctx context.Context, record *AuditRecord) error {
+	w.started <- struct{}{}
+	<-w.unblock
+	return w.recordingAuditWriter.WriteAudit(ctx, record)
+}
+
+func TestAuditQueueOverflow(t *testing.T) {
+	resetMetrics()
+	writer := &blockingAuditWriter{
+		started: make(chan struct{}, 10),
+		unblock: make(chan struct{}),
+	}
+	a := newAuditor(TestLogger(t), &AuditOptions{Writer: writer, QueueSize: 2})
+	audit := func() {
+		a.audit("/", &admissionv1.AdmissionRequest{}, &admissionv1.AdmissionResponse{Allowed: true}, 0)
+	}
+
+	// The first record holds up the writer, the next two fill the queue and
+	// the last two are dropped, without blocking admission.
+	audit()
+	<-writer.started
+	for i := 0; i < 4; i++ {
+		audit()
+	}
+	metricstest.CheckCountData(t, auditDroppedCountName,
+		map[string]string{auditDecisionKey.Name(): string(AuditDecisionAllowed)}, 2)
+
+	// The queue is not written past the deadline of the flush.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := a.flush(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("flush() = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	// Once unblocked, the queued records are written.
+	close(writer.unblock)
+	if err := a.flush(context.Background()); err != nil {
+		t.Fatal("flush() =", err)
+	}
+	if got, want := len(writer.records), 3; got != want {
+		t.Errorf("Got %d records, want %d", got, want)
+	}
+
+	// Records audited after the flush are dropped.
+	audit()
+	metricstest.CheckCountData(t, auditDroppedCountName,
+		map[string]string{auditDecisionKey.Name(): string(AuditDecisionAllowed)}, 3)
+}
+
+func TestFileAuditWriter(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "audit.log")
+	w, err := NewFileAuditWriter(name)
+	if err != nil {
+		t.Fatal("NewFileAuditWriter() =", err)
+	}
+	for _, d := range []AuditDecision{AuditDecisionAllowed, AuditDecisionDenied} {
+		if err := w.WriteAudit(context.Background(), &AuditRecord{Decision: d}); err != nil {
+			t.Fatal("WriteAudit() =", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal("Close() =", err)
+	}
+
+	b, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal("ReadFile() =", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Got %d lines, want 2: %s", len(lines), b)
+	}
+	for i, want := range []AuditDecision{AuditDecisionAllowed, AuditDecisionDenied} {
+		var got AuditRecord
+		if err := json.Unmarshal([]byte(lines[i]), &got); err != nil {
+			t.Fatalf("Unmarshal(%s) = %v", lines[i], err)
+		}
+		if got.Decision != want {
+			t.Errorf("line %d Decision = %q, want %q", i, got.Decision, want)
+		}
+	}
+}
+
+func TestHTTPAuditWriter(t *testing.T) {
+	var got AuditRecord
+	status := http.StatusNoContent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("Got %s with Content-Type %q", r.Method, r.Header.Get("Content-Type"))
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Error("Failed to decode the audit record:", err)
+		}
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	w := NewHTTPAuditWriter(server.URL, nil)
+	if err := w.WriteAudit(context.Background(), &AuditRecord{UID: "uid", Decision: AuditDecisionMutated}); err != nil {
+		t.Fatal("WriteAudit() =", err)
+	}
+	if got.UID != "uid" || got.Decision != AuditDecisionMutated {
+		t.Errorf("Posted %#v", got)
+	}
+
+	status = http.StatusInternalServerError
+	if err := w.WriteAudit(context.Background(), &AuditRecord{}); err == nil {
+		t.Error("WriteAudit() = nil, wanted an error from the failing endpoint")
+	}
+}
+
+func runtimeRaw(s string) runtime.RawExtension {
+	return runtime.RawExtension{Raw: []byte(s)}
+}
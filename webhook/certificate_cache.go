@@ -0,0 +1,121 @@
+/*
+Copyright 2023 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"strings"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+	corelisters "k8s.io/client-go/listers/core/v1"
+)
+
+// certificateCache serves the certificate held by the webhook's secret.
+// The certificate is parsed once per resourceVersion of the secret rather
+// than on every TLS handshake.
+type certificateCache struct {
+	logger *zap.SugaredLogger
+	lister corelisters.SecretNamespaceLister
+	name   string
+
+	// keyName and crtName are the keys of the secret's data that hold
+	// the server key and certificate.
+	keyName string
+	crtName string
+
+	cached atomic.Pointer[cachedCertificate]
+}
+
+type cachedCertificate struct {
+	resourceVersion string
+	cert            *tls.Certificate
+}
+
+// GetCertificate implements tls.Config.GetCertificate.
+//
+// If we return (nil, error) the client sees - 'tls: internal error"
+// If we return (nil, nil) the client sees - 'tls: no certificates configured'
+//
+// We'll return (nil, nil) when we don't find a certificate
+func (c *certificateCache) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	secret, err := c.lister.Get(c.name)
+	if err != nil {
+		c.logger.Errorw("failed to fetch secret", zap.Error(err))
+		reportNoCertificate(reasonSecretMissing)
+		return nil, nil
+	}
+
+	// Objects without a resourceVersion (e.g. in tests) are never cached,
+	// since there is no telling when they change.
+	if cc := c.cached.Load(); cc != nil && cc.resourceVersion != "" && cc.resourceVersion == secret.ResourceVersion {
+		return cc.cert, nil
+	}
+
+	serverKey, ok := secret.Data[c.keyName]
+	if !ok {
+		c.logger.Warn("server key missing")
+		reportNoCertificate(reasonKeyMissing)
+		return nil, nil
+	}
+	serverCert, ok := secret.Data[c.crtName]
+	if !ok {
+		c.logger.Warn("server cert missing")
+		reportNoCertificate(reasonCertMissing)
+		return nil, nil
+	}
+	cert, err := tls.X509KeyPair(serverCert, serverKey)
+	if err != nil {
+		c.logger.Errorw("invalid server key pair", zap.Error(err))
+		reportNoCertificate(reasonInvalidCertificate)
+		return nil, err
+	}
+	// Parse the leaf once here instead of on every handshake.  No OCSP
+	// staple is attached.
+	if cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0]); err != nil {
+		c.logger.Errorw("invalid server cert", zap.Error(err))
+		reportNoCertificate(reasonInvalidCertificate)
+		return nil, err
+	}
+
+	c.cached.Store(&cachedCertificate{
+		resourceVersion: secret.ResourceVersion,
+		cert:            &cert,
+	})
+	reportCertificateConfigured(true)
+	return &cert, nil
+}
+
+// serverErrorLog forwards the messages the http.Server logs to the logger,
+// counting the TLS handshake failures among them.
+type serverErrorLog struct {
+	logger *zap.SugaredLogger
+}
+
+func (l serverErrorLog) Write(p []byte) (int, error) {
+	msg := strings.TrimSpace(string(p))
+	if strings.Contains(msg, "TLS handshake error") {
+		// These are frequent (e.g. from TCP probes), so they are only
+		// logged at debug level and counted.
+		reportHandshakeFailure(reasonHandshake)
+		l.logger.Debug(msg)
+	} else {
+		l.logger.Warn(msg)
+	}
+	return len(p), nil
+}
@@ -0,0 +1,132 @@
+/*
+Copyright 2023 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"log"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+
+	logtesting "github.com/Yangfisher1/knative-common-pkg/logging/testing"
+	"github.com/Yangfisher1/knative-common-pkg/metrics/metricstest"
+	_ "github.com/Yangfisher1/knative-common-pkg/metrics/testing"
+	certresources "github.com/Yangfisher1/knative-common-pkg/webhook/certificates/resources"
+)
+
+func TestCertificateCache(t *testing.T) {
+	setup()
+	ctx := logtesting.TestContextWithLogger(t)
+	const ns, name = "ns", "webhook-certs"
+
+	secret, err := certresources.MakeSecret(ctx, name, ns, "webhook")
+	if err != nil {
+		t.Fatal("MakeSecret() =", err)
+	}
+	secret.ResourceVersion = "1"
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	indexer.Add(secret)
+
+	c := &certificateCache{
+		logger:  logtesting.TestLogger(t),
+		lister:  corelisters.NewSecretLister(indexer).Secrets(ns),
+		name:    name,
+		keyName: certresources.ServerKey,
+		crtName: certresources.ServerCert,
+	}
+
+	first, err := c.GetCertificate(nil)
+	if err != nil || first == nil {
+		t.Fatalf("GetCertificate() = %v, %v", first, err)
+	}
+	if first.Leaf == nil {
+		t.Error("GetCertificate().Leaf = nil, wanted the parsed leaf")
+	}
+	metricstest.CheckLastValueData(t, certificateConfiguredName, map[string]string{}, 1)
+
+	// The parsed certificate is reused while the secret is unchanged.
+	if second, err := c.GetCertificate(nil); err != nil || second != first {
+		t.Errorf("GetCertificate() = %p, %v, wanted the cached %p", second, err, first)
+	}
+
+	// A new resourceVersion invalidates the cache.
+	renewed, err := certresources.MakeSecret(ctx, name, ns, "webhook")
+	if err != nil {
+		t.Fatal("MakeSecret() =", err)
+	}
+	renewed.ResourceVersion = "2"
+	indexer.Update(renewed)
+	third, err := c.GetCertificate(nil)
+	if err != nil || third == nil || third == first {
+		t.Fatalf("GetCertificate() = %p, %v, wanted a new certificate", third, err)
+	}
+	if got, want := third.Leaf.SerialNumber, first.Leaf.SerialNumber; got.Cmp(want) == 0 {
+		t.Error("GetCertificate() returned the certificate of the previous resourceVersion")
+	}
+
+	// Without a certificate, handshakes fail and are counted.
+	setup()
+	indexer.Update(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: name, ResourceVersion: "3"},
+	})
+	if cert, err := c.GetCertificate(nil); cert != nil || err != nil {
+		t.Errorf("GetCertificate() = %v, %v, wanted nil, nil", cert, err)
+	}
+	metricstest.CheckCountData(t, tlsHandshakeFailureCountName, map[string]string{reasonKey.Name(): reasonKeyMissing}, 1)
+	metricstest.CheckLastValueData(t, certificateConfiguredName, map[string]string{}, 0)
+
+	setup()
+	indexer.Delete(renewed)
+	if cert, err := c.GetCertificate(nil); cert != nil || err != nil {
+		t.Errorf("GetCertificate() = %v, %v, wanted nil, nil", cert, err)
+	}
+	metricstest.CheckCountData(t, tlsHandshakeFailureCountName, map[string]string{reasonKey.Name(): reasonSecretMissing}, 1)
+
+	// Invalid certificates fail the handshake, and are not configured.
+	renewed.ResourceVersion = "4"
+	indexer.Add(renewed)
+	if _, err := c.GetCertificate(nil); err != nil {
+		t.Fatal("GetCertificate() =", err)
+	}
+	metricstest.CheckLastValueData(t, certificateConfiguredName, map[string]string{}, 1)
+	setup()
+	indexer.Update(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: name, ResourceVersion: "5"},
+		Data: map[string][]byte{
+			certresources.ServerKey:  []byte("invalid"),
+			certresources.ServerCert: []byte("invalid"),
+		},
+	})
+	if _, err := c.GetCertificate(nil); err == nil {
+		t.Error("GetCertificate() = nil, wanted an error")
+	}
+	metricstest.CheckCountData(t, tlsHandshakeFailureCountName, map[string]string{reasonKey.Name(): reasonInvalidCertificate}, 1)
+	metricstest.CheckLastValueData(t, certificateConfiguredName, map[string]string{}, 0)
+}
+
+func TestServerErrorLog(t *testing.T) {
+	setup()
+	l := log.New(serverErrorLog{logger: logtesting.TestLogger(t)}, "", 0)
+	l.Print("http: TLS handshake error from 10.0.0.1:1234: EOF")
+	l.Print("http: TLS handshake error from 10.0.0.1:1235: remote error: tls: bad certificate")
+	l.Print("http: Accept error: too many open files")
+
+	metricstest.CheckCountData(t, tlsHandshakeFailureCountName, map[string]string{reasonKey.Name(): reasonHandshake}, 2)
+}
@@ -17,9 +17,12 @@ limitations under the License.
 package certificates
 
 import (
+	"bytes"
 	"context"
+	"crypto"
 	"crypto/tls"
 	"crypto/x509"
+	"encoding/pem"
 	"time"
 
 	"github.com/Yangfisher1/knative-common-pkg/controller"
@@ -27,16 +30,21 @@ import (
 	pkgreconciler "github.com/Yangfisher1/knative-common-pkg/reconciler"
 	certresources "github.com/Yangfisher1/knative-common-pkg/webhook/certificates/resources"
 	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
 	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/record"
 )
 
 const (
 	// Time used for updating a certificate before it expires.
-	oneDay = 24 * time.Hour
+	oneDay = certresources.DefaultServerCertRenewBefore
+
+	// Time used for rotating the CA before it expires.
+	thirtyDays = certresources.DefaultCARenewBefore
 )
 
 type reconciler struct {
@@ -46,6 +54,19 @@ type reconciler struct {
 	secretlister corelisters.SecretLister
 	key          types.NamespacedName
 	serviceName  string
+	// caKey names the secret holding the CA.
+	caKey types.NamespacedName
+
+	// Certificate lifetimes and renewal thresholds.  Zero values select
+	// the defaults.
+	lifetimes             certresources.Lifetimes
+	caRenewBefore         time.Duration
+	serverCertRenewBefore time.Duration
+
+	// external is whether the secret is managed by an external certificate
+	// manager, in which case problems are reported through the recorder.
+	external bool
+	recorder record.EventRecorder
 }
 
 var _ controller.Reconciler = (*reconciler)(nil)
@@ -62,47 +83,230 @@ func (r *reconciler) Reconcile(ctx context.Context, key string) error {
 
 func (r *reconciler) reconcileCertificate(ctx context.Context) error {
 	logger := logging.FromContext(ctx)
+	ctx = certresources.WithLifetimes(ctx, r.lifetimes)
 
 	secret, err := r.secretlister.Secrets(r.key.Namespace).Get(r.key.Name)
 	if apierrors.IsNotFound(err) {
 		// The secret should be created explicitly by a higher-level system
 		// that's responsible for install/updates.  We simply populate the
 		// secret information.
+		if r.external {
+			logger.Warnf("Certificate secret %q does not exist", r.key.Name)
+			reportProblem(problemMissing)
+		}
 		return nil
 	} else if err != nil {
 		logger.Errorf("Error accessing certificate secret %q: %v", r.key.Name, err)
 		return err
 	}
+	if r.external {
+		return r.reconcileExternalCertificate(ctx, secret)
+	}
+
+	caSecret, err := r.secretlister.Secrets(r.caKey.Namespace).Get(r.caKey.Name)
+	if apierrors.IsNotFound(err) {
+		caSecret = nil
+	} else if err != nil {
+		logger.Errorf("Error accessing CA secret %q: %v", r.caKey.Name, err)
+		return err
+	}
+	var caData, newCAData map[string][]byte
+	if caSecret != nil {
+		caData = caSecret.Data
+	} else if _, ok := secret.Data[certresources.CAKey]; ok {
+		// Move the CA out of secrets made when it was kept alongside the
+		// server certificate.
+		caData = secret.Data
+		newCAData = map[string][]byte{
+			certresources.CAKey:  secret.Data[certresources.CAKey],
+			certresources.CACert: secret.Data[certresources.CACert],
+		}
+	}
+
+	now := time.Now()
+	var data map[string][]byte
+	regenerate := true
+	if serverCert := r.serverCert(ctx, secret.Data); serverCert != nil {
+		serverDue := !now.Add(durationOrDefault(r.serverCertRenewBefore, oneDay)).Before(serverCert.NotAfter)
+		if ca := signingCA(caData, secret.Data); ca != nil {
+			if newCAData != nil {
+				// Keep only the CA signing the certificates, not the bundle.
+				newCAData[certresources.CACert] = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.Raw})
+			}
+			var rotatedCA map[string][]byte
+			data, rotatedCA, err = r.rotate(ctx, secret.Data, caData, ca, serverDue, now)
+			if err != nil {
+				return err
+			}
+			if rotatedCA != nil {
+				newCAData = rotatedCA
+			}
+			if data == nil && newCAData == nil {
+				return nil
+			}
+			regenerate = false
+		} else if !serverDue {
+			// Secrets made before CAs were rotated separately don't have
+			// the CA key, so they are regenerated entirely once the server
+			// certificate is about to expire.
+			return nil
+		}
+	}
+	// Don't modify the informer copy.
+	secret = secret.DeepCopy()
+
+	if regenerate {
+		// The secret can't be renewed in place, so synthesize a new one and update the secret.
+		newSecret, newCASecret, err := certresources.MakeSecrets(ctx, r.key.Name, r.key.Namespace, r.serviceName, r.caKey)
+		if err != nil {
+			return err
+		}
+		// Keep trusting the previous CA while it is valid, since the new one
+		// takes a while to reach every client.
+		data = make(map[string][]byte, len(newSecret.Data))
+		for k, v := range newSecret.Data {
+			data[k] = v
+		}
+		data[certresources.CACert] = certresources.MakeCABundle(data[certresources.CACert], secret.Data[certresources.CACert], now)
+		newCAData = newCASecret.Data
+	} else if data == nil {
+		data = secret.Data
+	}
+
+	// Store the CA first, so that the key of the CA which signed the server
+	// certificate is never lost.
+	if newCAData != nil {
+		if err := r.updateCA(ctx, caSecret, newCAData); err != nil {
+			return err
+		}
+	}
+	secret.Data = make(map[string][]byte, len(data))
+	for k, v := range data {
+		if k != certresources.CAKey {
+			secret.Data[k] = v
+		}
+	}
+	_, err = r.client.CoreV1().Secrets(secret.Namespace).Update(ctx, secret, metav1.UpdateOptions{})
+	return err
+}
+
+// updateCA stores the CA data in the CA secret, creating it if needed.
+func (r *reconciler) updateCA(ctx context.Context, caSecret *corev1.Secret, data map[string][]byte) error {
+	if caSecret == nil {
+		_, err := r.client.CoreV1().Secrets(r.caKey.Namespace).Create(ctx, &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      r.caKey.Name,
+				Namespace: r.caKey.Namespace,
+			},
+			Data: data,
+		}, metav1.CreateOptions{})
+		return err
+	}
+	caSecret = caSecret.DeepCopy()
+	caSecret.Data = data
+	_, err := r.client.CoreV1().Secrets(caSecret.Namespace).Update(ctx, caSecret, metav1.UpdateOptions{})
+	return err
+}
 
-	if _, haskey := secret.Data[certresources.ServerKey]; !haskey {
+// serverCert returns the server certificate of the secret, or nil when one
+// of the secret's keys is missing or invalid.
+func (r *reconciler) serverCert(ctx context.Context, data map[string][]byte) *x509.Certificate {
+	logger := logging.FromContext(ctx)
+	if _, haskey := data[certresources.ServerKey]; !haskey {
 		logger.Infof("Certificate secret %q is missing key %q", r.key.Name, certresources.ServerKey)
-	} else if _, haskey := secret.Data[certresources.ServerCert]; !haskey {
+	} else if _, haskey := data[certresources.ServerCert]; !haskey {
 		logger.Infof("Certificate secret %q is missing key %q", r.key.Name, certresources.ServerCert)
-	} else if _, haskey := secret.Data[certresources.CACert]; !haskey {
+	} else if _, haskey := data[certresources.CACert]; !haskey {
 		logger.Infof("Certificate secret %q is missing key %q", r.key.Name, certresources.CACert)
 	} else {
 		// Check the expiration date of the certificate to see if it needs to be updated
-		cert, err := tls.X509KeyPair(secret.Data[certresources.ServerCert], secret.Data[certresources.ServerKey])
+		cert, err := tls.X509KeyPair(data[certresources.ServerCert], data[certresources.ServerKey])
 		if err != nil {
 			logger.Warnw("Error creating pem from certificate and key", zap.Error(err))
-		} else {
-			certData, err := x509.ParseCertificate(cert.Certificate[0])
-			if err != nil {
-				logger.Errorw("Error parsing certificate", zap.Error(err))
-			} else if time.Now().Add(oneDay).Before(certData.NotAfter) {
-				return nil
-			}
+			return nil
 		}
+		certData, err := x509.ParseCertificate(cert.Certificate[0])
+		if err != nil {
+			logger.Errorw("Error parsing certificate", zap.Error(err))
+			return nil
+		}
+		return certData
 	}
-	// Don't modify the informer copy.
-	secret = secret.DeepCopy()
+	return nil
+}
+
+// rotate renews the server certificate and the CA in the given secret data
+// when they are about to expire, and drops expired CAs from the CA bundle.
+// It returns the new data of the secret and of the CA secret, or nil for
+// those that don't need to change.
+//
+// The CA and the server certificate are renewed separately.  A new CA is
+// added to the bundle ahead of the previous one but only used to sign the
+// next server certificate, which gives clients time to pick up the bundle
+// before they see a certificate signed by the new CA.
+func (r *reconciler) rotate(ctx context.Context, data, caData map[string][]byte, ca *x509.Certificate, serverDue bool, now time.Time) (map[string][]byte, map[string][]byte, error) {
+	logger := logging.FromContext(ctx)
+	lifetimes := certresources.GetLifetimes(ctx)
+	caDue := !now.Add(durationOrDefault(r.caRenewBefore, thirtyDays)).Before(ca.NotAfter)
+
+	ret := make(map[string][]byte, len(data))
+	for k, v := range data {
+		ret[k] = v
+	}
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.Raw})
+	if serverDue {
+		logger.Infof("Renewing the server certificate in secret %q", r.key.Name)
+		// Sign with the current CA, which clients already trust.
+		serverKey, serverCert, err := certresources.CreateServerCert(ctx, r.serviceName, r.key.Namespace,
+			caData[certresources.CAKey], caPEM, now.Add(lifetimes.ServerCert))
+		if err != nil {
+			return nil, nil, err
+		}
+		ret[certresources.ServerKey], ret[certresources.ServerCert] = serverKey, serverCert
+	}
+	var retCA map[string][]byte
+	if caDue {
+		logger.Infof("Rotating the CA in secret %q", r.caKey.Name)
+		caKey, caCert, err := certresources.CreateCACert(ctx, r.serviceName, r.key.Namespace, now.Add(lifetimes.CA))
+		if err != nil {
+			return nil, nil, err
+		}
+		retCA = map[string][]byte{
+			certresources.CAKey:  caKey,
+			certresources.CACert: caCert,
+		}
+		caPEM = caCert
+	}
+	ret[certresources.CACert] = certresources.MakeCABundle(caPEM, data[certresources.CACert], now)
+
+	if !serverDue && !caDue && bytes.Equal(ret[certresources.CACert], data[certresources.CACert]) {
+		return nil, nil, nil
+	}
+	return ret, retCA, nil
+}
 
-	// One of the secret's keys is missing, so synthesize a new one and update the secret.
-	newSecret, err := certresources.MakeSecret(ctx, r.key.Name, r.key.Namespace, r.serviceName)
+// signingCA returns the certificate of the CA whose key is in the CA data,
+// provided the CA bundle of the secret data trusts it, or nil.
+func signingCA(caData, data map[string][]byte) *x509.Certificate {
+	key, err := certresources.ParsePrivateKey(caData[certresources.CAKey])
 	if err != nil {
-		return err
+		return nil
 	}
-	secret.Data = newSecret.Data
-	_, err = r.client.CoreV1().Secrets(secret.Namespace).Update(ctx, secret, metav1.UpdateOptions{})
-	return err
+	certs, err := certresources.ParseCertificates(data[certresources.CACert])
+	if err != nil {
+		return nil
+	}
+	for _, cert := range certs {
+		if pub, ok := cert.PublicKey.(interface{ Equal(crypto.PublicKey) bool }); ok && pub.Equal(key.Public()) {
+			return cert
+		}
+	}
+	return nil
+}
+
+func durationOrDefault(d, def time.Duration) time.Duration {
+	if d <= 0 {
+		return def
+	}
+	return d
 }
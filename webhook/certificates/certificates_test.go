@@ -18,6 +18,7 @@ package certificates
 
 import (
 	"context"
+	"crypto/x509"
 	"errors"
 	"testing"
 	"time"
@@ -36,7 +37,10 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/wait"
+	fakekubeclientset "k8s.io/client-go/kubernetes/fake"
+	corelisters "k8s.io/client-go/listers/core/v1"
 	clientgotesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
 
 	. "github.com/Yangfisher1/knative-common-pkg/reconciler/testing"
 	. "github.com/Yangfisher1/knative-common-pkg/webhook/testing"
@@ -47,24 +51,31 @@ func TestReconcile(t *testing.T) {
 		secretName  = "webhook-secret"
 		serviceName = "webhook-service"
 	)
-	secret, err := certresources.MakeSecret(context.Background(),
-		secretName, system.Namespace(), serviceName)
+	caKey := types.NamespacedName{Namespace: system.Namespace(), Name: certresources.CASecretName(secretName)}
+	secret, caSecret, err := certresources.MakeSecrets(context.Background(),
+		secretName, system.Namespace(), serviceName, caKey)
 	if err != nil {
-		t.Fatal("MakeSecret() =", err)
+		t.Fatal("MakeSecrets() =", err)
 	}
 
-	// Mutate the MakeSecret to return our secret deterministically.
-	certresources.MakeSecret = func(ctx context.Context, name, namespace, serviceName string) (*corev1.Secret, error) {
-		return secret, nil
+	// Mutate the MakeSecrets to return our secrets deterministically.
+	certresources.MakeSecrets = func(ctx context.Context, name, namespace, serviceName string, ca types.NamespacedName) (*corev1.Secret, *corev1.Secret, error) {
+		return secret, caSecret, nil
 	}
 	defer func() {
-		certresources.MakeSecret = certresources.MakeSecretInternal
+		certresources.MakeSecrets = certresources.MakeSecretsInternal
 	}()
 
 	// The key to use, which for this singleton reconciler doesn't matter (although the
 	// namespace matters for namespace validation).
 	key := system.Namespace() + "/does not matter"
 
+	// The previous CA is kept in the bundle of the regenerated secret.
+	expiring := secretWithCertData(t, time.Now().Add(23*time.Hour))
+	renewed := secret.DeepCopy()
+	renewed.Data[certresources.CACert] = append(append([]byte(nil),
+		secret.Data[certresources.CACert]...), expiring.Data[certresources.CACert]...)
+
 	table := TableTest{{
 		Name:    "well formed secret exists",
 		Key:     key,
@@ -86,6 +97,7 @@ func TestReconcile(t *testing.T) {
 				certresources.CACert:     []byte("present"),
 			},
 		}},
+		WantCreates: []runtime.Object{caSecret},
 		WantUpdates: []clientgotesting.UpdateActionImpl{{
 			Object: secret,
 		}},
@@ -103,6 +115,7 @@ func TestReconcile(t *testing.T) {
 				certresources.CACert: []byte("present"),
 			},
 		}},
+		WantCreates: []runtime.Object{caSecret},
 		WantUpdates: []clientgotesting.UpdateActionImpl{{
 			Object: secret,
 		}},
@@ -120,6 +133,7 @@ func TestReconcile(t *testing.T) {
 				// certresources.CACert: []byte("missing"),
 			},
 		}},
+		WantCreates: []runtime.Object{caSecret},
 		WantUpdates: []clientgotesting.UpdateActionImpl{{
 			Object: secret,
 		}},
@@ -127,15 +141,35 @@ func TestReconcile(t *testing.T) {
 		Name: "certificate expiring soon",
 		Key:  key,
 		// 23 hours  falls inside of the grace period of 1 day so the secret will be updated.
-		Objects: []runtime.Object{secretWithCertData(t, time.Now().Add(23*time.Hour))},
+		Objects:     []runtime.Object{expiring},
+		WantCreates: []runtime.Object{caSecret},
 		WantUpdates: []clientgotesting.UpdateActionImpl{{
-			Object: secret,
+			Object: renewed,
 		}},
 	}, {
 		Name: "certificate not expiring soon",
 		Key:  key,
 		// 25 hours falls outside of the grace period of 1 day so the secret will not be updated.
 		Objects: []runtime.Object{secretWithCertData(t, time.Now().Add(25*time.Hour))},
+	}, {
+		Name: "CA secret exists",
+		Key:  key,
+		Objects: []runtime.Object{&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      secretName,
+				Namespace: system.Namespace(),
+			},
+		}, &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      caKey.Name,
+				Namespace: caKey.Namespace,
+			},
+		}},
+		WantUpdates: []clientgotesting.UpdateActionImpl{{
+			Object: caSecret,
+		}, {
+			Object: secret,
+		}},
 	}}
 
 	table.Test(t, MakeFactory(func(ctx context.Context, listers *Listers, cmw configmap.Watcher) controller.Reconciler {
@@ -146,11 +180,89 @@ func TestReconcile(t *testing.T) {
 				Namespace: system.Namespace(),
 				Name:      secretName,
 			},
+			caKey:       caKey,
 			serviceName: serviceName,
 		}
 	}))
 }
 
+func TestReconcileExternal(t *testing.T) {
+	const secretName = "webhook-secret"
+	key := system.Namespace() + "/does not matter"
+
+	externalSecret := func(expiration time.Time, omit ...string) *corev1.Secret {
+		s := secretWithCertData(t, expiration)
+		s.Type = corev1.SecretTypeTLS
+		s.Data = map[string][]byte{
+			certresources.TLSKey:    s.Data[certresources.ServerKey],
+			certresources.TLSCert:   s.Data[certresources.ServerCert],
+			certresources.TLSCACert: s.Data[certresources.CACert],
+		}
+		for _, k := range omit {
+			delete(s.Data, k)
+		}
+		return s
+	}
+	expiring := time.Now().Add(23 * time.Hour).Truncate(time.Second)
+
+	// The secret is never written, so none of the rows want updates.
+	table := TableTest{{
+		Name:    "valid certificate",
+		Key:     key,
+		Objects: []runtime.Object{externalSecret(time.Now().Add(7 * 24 * time.Hour))},
+		// The reconciler requeues to check the expiry again.
+		WantErr: true,
+	}, {
+		Name: "secret does not exist",
+		Key:  key,
+	}, {
+		Name:    "missing CA cert",
+		Key:     key,
+		Objects: []runtime.Object{externalSecret(time.Now().Add(7*24*time.Hour), certresources.TLSCACert)},
+		WantEvents: []string{
+			Eventf(corev1.EventTypeWarning, "CertificateMissing", `Certificate secret is missing key "ca.crt"`),
+		},
+	}, {
+		Name: "invalid certificate",
+		Key:  key,
+		Objects: []runtime.Object{&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      secretName,
+				Namespace: system.Namespace(),
+			},
+			Data: map[string][]byte{
+				certresources.TLSKey:    []byte("invalid"),
+				certresources.TLSCert:   []byte("invalid"),
+				certresources.TLSCACert: []byte("invalid"),
+			},
+		}},
+		WantEvents: []string{
+			Eventf(corev1.EventTypeWarning, "CertificateInvalid", "Certificate secret holds an invalid key pair: tls: failed to find any PEM data in certificate input"),
+		},
+	}, {
+		Name:    "certificate expiring soon",
+		Key:     key,
+		Objects: []runtime.Object{externalSecret(expiring)},
+		WantEvents: []string{
+			Eventf(corev1.EventTypeWarning, "CertificateExpiring", "Certificate expires at %s", expiring.UTC().Format(time.RFC3339)),
+		},
+	}}
+
+	table.Test(t, MakeFactory(func(ctx context.Context, listers *Listers, cmw configmap.Watcher) controller.Reconciler {
+		return &reconciler{
+			client:       kubeclient.Get(ctx),
+			secretlister: listers.GetSecretLister(),
+			key: types.NamespacedName{
+				Namespace: system.Namespace(),
+				Name:      secretName,
+			},
+			serviceName: "webhook-service",
+			external:    true,
+			recorder:    controller.GetEventRecorder(ctx),
+		}
+	}))
+}
+
 func TestReconcileMakeSecretFailure(t *testing.T) {
 	secretName, serviceName := "webhook-secret", "webhook-service"
 	secret, err := certresources.MakeSecret(context.Background(),
@@ -159,12 +271,14 @@ func TestReconcileMakeSecretFailure(t *testing.T) {
 		t.Fatal("MakeSecret() =", err)
 	}
 
-	// Mutate the MakeSecret to return our secret deterministically.
-	certresources.MakeSecret = func(ctx context.Context, name, namespace, serviceName string) (*corev1.Secret, error) {
-		return nil, errors.New("this is an error")
+	caKey := types.NamespacedName{Namespace: system.Namespace(), Name: certresources.CASecretName(secretName)}
+
+	// Mutate the MakeSecrets to fail deterministically.
+	certresources.MakeSecrets = func(ctx context.Context, name, namespace, serviceName string, ca types.NamespacedName) (*corev1.Secret, *corev1.Secret, error) {
+		return nil, nil, errors.New("this is an error")
 	}
 	defer func() {
-		certresources.MakeSecret = certresources.MakeSecretInternal
+		certresources.MakeSecrets = certresources.MakeSecretsInternal
 	}()
 
 	// The key to use, which for this singleton reconciler doesn't matter (although the
@@ -215,6 +329,7 @@ func TestReconcileMakeSecretFailure(t *testing.T) {
 				Namespace: system.Namespace(),
 				Name:      secretName,
 			},
+			caKey:       caKey,
 			serviceName: serviceName,
 		}
 	}))
@@ -268,3 +383,195 @@ func secretWithCertData(t *testing.T, expiration time.Time) *corev1.Secret {
 		},
 	}
 }
+
+func TestRotate(t *testing.T) {
+	const (
+		secretName  = "webhook-secret"
+		serviceName = "webhook-service"
+	)
+	ctx := context.Background()
+	now := time.Now()
+	caKey := types.NamespacedName{Namespace: system.Namespace(), Name: "webhook-ca"}
+
+	// makeSecrets returns a secret whose server certificate is signed by a
+	// CA, both expiring at the given times, and whose bundle also holds the
+	// given previous CAs, and the CA secret holding the CA.
+	makeSecrets := func(caExpiry, serverExpiry time.Time, previous ...[]byte) (*corev1.Secret, *corev1.Secret) {
+		caPrivateKey, caCert, err := certresources.CreateCACert(ctx, serviceName, system.Namespace(), caExpiry)
+		if err != nil {
+			t.Fatal("CreateCACert() =", err)
+		}
+		serverKey, serverCert, err := certresources.CreateServerCert(ctx, serviceName, system.Namespace(), caPrivateKey, caCert, serverExpiry)
+		if err != nil {
+			t.Fatal("CreateServerCert() =", err)
+		}
+		bundle := caCert
+		for _, p := range previous {
+			bundle = append(append([]byte(nil), bundle...), p...)
+		}
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: secretName, Namespace: system.Namespace()},
+			Data: map[string][]byte{
+				certresources.ServerKey:  serverKey,
+				certresources.ServerCert: serverCert,
+				certresources.CACert:     bundle,
+			},
+		}, &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: caKey.Name, Namespace: caKey.Namespace},
+			Data: map[string][]byte{
+				certresources.CACert: caCert,
+				certresources.CAKey:  caPrivateKey,
+			},
+		}
+	}
+	_, expiredCA, err := certresources.CreateCACert(ctx, serviceName, system.Namespace(), now.Add(-time.Hour))
+	if err != nil {
+		t.Fatal("CreateCACert() =", err)
+	}
+
+	tests := []struct {
+		name    string
+		secrets func() (*corev1.Secret, *corev1.Secret)
+		// legacy is whether the CA key is kept in the secret, as it was
+		// before the CA secret.
+		legacy bool
+		// wantCAs is the number of CAs in the updated bundle, or 0 if
+		// the secret must not be updated.
+		wantCAs       int
+		wantNewServer bool
+		wantNewCA     bool
+	}{{
+		name: "nothing due",
+		secrets: func() (*corev1.Secret, *corev1.Secret) {
+			return makeSecrets(now.Add(365*oneDay), now.Add(7*oneDay))
+		},
+	}, {
+		name: "server certificate due",
+		secrets: func() (*corev1.Secret, *corev1.Secret) {
+			return makeSecrets(now.Add(365*oneDay), now.Add(time.Hour))
+		},
+		wantCAs:       1,
+		wantNewServer: true,
+	}, {
+		name: "CA due",
+		secrets: func() (*corev1.Secret, *corev1.Secret) {
+			return makeSecrets(now.Add(20*oneDay), now.Add(7*oneDay))
+		},
+		wantCAs:   2,
+		wantNewCA: true,
+	}, {
+		name: "CA and server certificate due",
+		secrets: func() (*corev1.Secret, *corev1.Secret) {
+			return makeSecrets(now.Add(20*oneDay), now.Add(time.Hour))
+		},
+		wantCAs:       2,
+		wantNewServer: true,
+		wantNewCA:     true,
+	}, {
+		name: "expired previous CA",
+		secrets: func() (*corev1.Secret, *corev1.Secret) {
+			return makeSecrets(now.Add(365*oneDay), now.Add(7*oneDay), expiredCA)
+		},
+		wantCAs: 1,
+	}, {
+		name: "CA key in the secret",
+		secrets: func() (*corev1.Secret, *corev1.Secret) {
+			return makeSecrets(now.Add(365*oneDay), now.Add(7*oneDay))
+		},
+		legacy:  true,
+		wantCAs: 1,
+	}}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			secret, caSecret := tc.secrets()
+			objects := []runtime.Object{secret, caSecret}
+			if tc.legacy {
+				secret.Data[certresources.CAKey] = caSecret.Data[certresources.CAKey]
+				objects = objects[:1]
+			}
+			client := fakekubeclientset.NewSimpleClientset(objects...)
+			indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+			for _, obj := range objects {
+				indexer.Add(obj)
+			}
+			r := &reconciler{
+				client:       client,
+				secretlister: corelisters.NewSecretLister(indexer),
+				key:          types.NamespacedName{Namespace: system.Namespace(), Name: secretName},
+				caKey:        caKey,
+				serviceName:  serviceName,
+			}
+			if err := r.reconcileCertificate(ctx); err != nil {
+				t.Fatal("reconcileCertificate() =", err)
+			}
+
+			var updated, updatedCA *corev1.Secret
+			for _, action := range client.Actions() {
+				var obj runtime.Object
+				switch a := action.(type) {
+				case clientgotesting.UpdateAction:
+					obj = a.GetObject()
+				case clientgotesting.CreateAction:
+					obj = a.GetObject()
+				default:
+					continue
+				}
+				if s := obj.(*corev1.Secret); s.Name == caKey.Name {
+					updatedCA = s
+				} else {
+					updated = s
+				}
+			}
+			if tc.wantCAs == 0 {
+				if updated != nil || updatedCA != nil {
+					t.Fatal("Unexpected update:", updated, updatedCA)
+				}
+				return
+			}
+			if updated == nil {
+				t.Fatal("Secret was not updated")
+			}
+			if _, ok := updated.Data[certresources.CAKey]; ok {
+				t.Error("The CA key was kept in the secret")
+			}
+			if gotNewCA := updatedCA != nil && !tc.legacy; gotNewCA != tc.wantNewCA {
+				t.Errorf("CA rotated = %v, wanted %v", gotNewCA, tc.wantNewCA)
+			}
+			caData := caSecret.Data
+			if updatedCA != nil {
+				caData = updatedCA.Data
+			}
+			if tc.legacy && string(caData[certresources.CAKey]) != string(caSecret.Data[certresources.CAKey]) {
+				t.Error("The CA key was not moved to the CA secret")
+			}
+
+			old, got := secret.Data, updated.Data
+			cas, err := certresources.ParseCertificates(got[certresources.CACert])
+			if err != nil {
+				t.Fatal("ParseCertificates() =", err)
+			}
+			if len(cas) != tc.wantCAs {
+				t.Errorf("Got %d CAs in the bundle, wanted %d", len(cas), tc.wantCAs)
+			}
+			if gotNew := string(got[certresources.ServerCert]) != string(old[certresources.ServerCert]); gotNew != tc.wantNewServer {
+				t.Errorf("Server certificate renewed = %v, wanted %v", gotNew, tc.wantNewServer)
+			}
+			if ca := signingCA(caData, got); ca == nil || !ca.Equal(cas[0]) {
+				t.Error("The CA key doesn't match the first CA of the bundle")
+			}
+
+			// The server certificate is signed by a CA that was already
+			// trusted before the update.
+			pool := x509.NewCertPool()
+			pool.AppendCertsFromPEM(old[certresources.CACert])
+			serverCerts, err := certresources.ParseCertificates(got[certresources.ServerCert])
+			if err != nil {
+				t.Fatal("ParseCertificates() =", err)
+			}
+			if _, err := serverCerts[0].Verify(x509.VerifyOptions{Roots: pool}); err != nil {
+				t.Error("Verify() =", err)
+			}
+		})
+	}
+}
@@ -29,6 +29,7 @@ import (
 	pkgreconciler "github.com/Yangfisher1/knative-common-pkg/reconciler"
 	"github.com/Yangfisher1/knative-common-pkg/system"
 	"github.com/Yangfisher1/knative-common-pkg/webhook"
+	certresources "github.com/Yangfisher1/knative-common-pkg/webhook/certificates/resources"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/cache"
 )
@@ -46,10 +47,19 @@ func NewController(
 	secretInformer := secretinformer.Get(ctx)
 	options := webhook.GetOptions(ctx)
 
+	logger := logging.FromContext(ctx)
+
 	key := types.NamespacedName{
 		Namespace: system.Namespace(),
 		Name:      options.SecretName,
 	}
+	caKey := types.NamespacedName{
+		Namespace: system.Namespace(),
+		Name:      options.CASecretName,
+	}
+	if caKey.Name == "" {
+		caKey.Name = certresources.CASecretName(options.SecretName)
+	}
 
 	wh := &reconciler{
 		LeaderAwareFuncs: pkgreconciler.LeaderAwareFuncs{
@@ -60,14 +70,25 @@ func NewController(
 			},
 		},
 		key:         key,
+		caKey:       caKey,
 		serviceName: options.ServiceName,
+		lifetimes: certresources.Lifetimes{
+			CA:         options.CALifetime,
+			ServerCert: options.ServerCertLifetime,
+		},
+		caRenewBefore:         options.CARenewBefore,
+		serverCertRenewBefore: options.ServerCertRenewBefore,
+		external:              options.ExternallyManagedCertificates,
 
 		client:       client,
 		secretlister: secretInformer.Lister(),
 	}
 
 	const queueName = "WebhookCertificates"
-	c := controller.NewContext(ctx, wh, controller.ControllerOptions{WorkQueueName: queueName, Logger: logging.FromContext(ctx).Named(queueName)})
+	c := controller.NewContext(ctx, wh, controller.ControllerOptions{WorkQueueName: queueName, Logger: logger.Named(queueName)})
+	if wh.external {
+		wh.recorder = controller.GetOrCreateEventRecorder(ctx, client.CoreV1(), queueName)
+	}
 
 	// Reconcile when the cert bundle changes.
 	secretInformer.Informer().AddEventHandler(cache.FilteringResourceEventHandler{
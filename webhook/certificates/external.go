@@ -0,0 +1,86 @@
+/*
+Copyright 2023 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package certificates
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"time"
+
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/Yangfisher1/knative-common-pkg/controller"
+	"github.com/Yangfisher1/knative-common-pkg/logging"
+	certresources "github.com/Yangfisher1/knative-common-pkg/webhook/certificates/resources"
+)
+
+// Reasons of the events about externally managed certificates.
+const (
+	certificateMissingReason  = "CertificateMissing"
+	certificateInvalidReason  = "CertificateInvalid"
+	certificateExpiringReason = "CertificateExpiring"
+)
+
+// reconcileExternalCertificate checks the externally managed certificate in
+// the secret.  The secret is never written: missing, invalid or expiring
+// material is surfaced through events and metrics for its manager to act on.
+func (r *reconciler) reconcileExternalCertificate(ctx context.Context, secret *corev1.Secret) error {
+	logger := logging.FromContext(ctx)
+
+	for _, key := range []string{certresources.TLSKey, certresources.TLSCert, certresources.TLSCACert} {
+		if _, haskey := secret.Data[key]; !haskey {
+			logger.Warnf("Certificate secret %q is missing key %q", r.key.Name, key)
+			r.recorder.Eventf(secret, corev1.EventTypeWarning, certificateMissingReason,
+				"Certificate secret is missing key %q", key)
+			reportProblem(problemMissing)
+			return nil
+		}
+	}
+
+	cert, err := tls.X509KeyPair(secret.Data[certresources.TLSCert], secret.Data[certresources.TLSKey])
+	if err != nil {
+		logger.Warnw("Error creating pem from certificate and key", zap.Error(err))
+		r.recorder.Eventf(secret, corev1.EventTypeWarning, certificateInvalidReason,
+			"Certificate secret holds an invalid key pair: %v", err)
+		reportProblem(problemInvalid)
+		return nil
+	}
+	certData, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		logger.Errorw("Error parsing certificate", zap.Error(err))
+		r.recorder.Eventf(secret, corev1.EventTypeWarning, certificateInvalidReason,
+			"Certificate secret holds an invalid certificate: %v", err)
+		reportProblem(problemInvalid)
+		return nil
+	}
+
+	remaining := time.Until(certData.NotAfter)
+	reportExpiration(remaining)
+	warnBefore := durationOrDefault(r.serverCertRenewBefore, oneDay)
+	if remaining <= warnBefore {
+		logger.Warnf("Certificate in secret %q expires at %s", r.key.Name, certData.NotAfter.Format(time.RFC3339))
+		r.recorder.Eventf(secret, corev1.EventTypeWarning, certificateExpiringReason,
+			"Certificate expires at %s", certData.NotAfter.Format(time.RFC3339))
+		reportProblem(problemExpiring)
+		return nil
+	}
+	// Nothing signals that the certificate is about to expire, so check
+	// again then in case the secret wasn't renewed by that time.
+	return controller.NewRequeueAfter(remaining - warnBefore)
+}
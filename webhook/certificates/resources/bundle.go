@@ -0,0 +1,79 @@
+/*
+Copyright 2023 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/pem"
+	"time"
+)
+
+// ParseCertificates parses the PEM-encoded certificates in data, in
+// order.  Blocks of other types are skipped.
+func ParseCertificates(data []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for {
+		var p *pem.Block
+		p, data = pem.Decode(data)
+		if p == nil {
+			return certs, nil
+		}
+		if p.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(p.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+}
+
+// MakeCABundle returns a PEM bundle of the current CA certificate followed
+// by the certificates of the previous bundle that are still valid at now.
+// Clients that trust the bundle thus accept server certificates signed by
+// either CA while the previous one overlaps the current one.  Invalid data
+// in the previous bundle is ignored.
+func MakeCABundle(current, previous []byte, now time.Time) []byte {
+	bundle := append([]byte(nil), current...)
+	currentCerts, _ := ParseCertificates(current)
+	for {
+		var p *pem.Block
+		p, previous = pem.Decode(previous)
+		if p == nil {
+			return bundle
+		}
+		if p.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(p.Bytes)
+		if err != nil || now.After(cert.NotAfter) || containsCert(currentCerts, cert) {
+			continue
+		}
+		bundle = append(bundle, pem.EncodeToMemory(p)...)
+	}
+}
+
+func containsCert(certs []*x509.Certificate, cert *x509.Certificate) bool {
+	for _, c := range certs {
+		if bytes.Equal(c.Raw, cert.Raw) {
+			return true
+		}
+	}
+	return false
+}
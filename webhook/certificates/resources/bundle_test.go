@@ -0,0 +1,83 @@
+/*
+Copyright 2023 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"crypto/x509"
+	"testing"
+	"time"
+
+	. "github.com/Yangfisher1/knative-common-pkg/logging/testing"
+)
+
+func TestMakeCABundle(t *testing.T) {
+	ctx := TestContextWithLogger(t)
+	now := time.Now()
+
+	_, oldCA, err := CreateCACert(ctx, "svc", "ns", now.Add(time.Hour))
+	if err != nil {
+		t.Fatal("CreateCACert() =", err)
+	}
+	newKey, newCA, err := CreateCACert(ctx, "svc", "ns", now.Add(48*time.Hour))
+	if err != nil {
+		t.Fatal("CreateCACert() =", err)
+	}
+
+	// The previous CA follows the current one until it expires.
+	bundle := MakeCABundle(newCA, oldCA, now)
+	if got := mustParse(t, bundle); len(got) != 2 || string(got[0].Raw) != string(mustParse(t, newCA)[0].Raw) {
+		t.Fatalf("MakeCABundle() = %d certs, wanted the new CA followed by the old one", len(got))
+	}
+
+	// Rebuilding the bundle doesn't duplicate the current CA.
+	if got := MakeCABundle(newCA, bundle, now); string(got) != string(bundle) {
+		t.Errorf("MakeCABundle() with the current bundle = %s, wanted %s", got, bundle)
+	}
+
+	// Expired and invalid certificates are dropped.
+	if got := MakeCABundle(newCA, bundle, now.Add(2*time.Hour)); string(got) != string(newCA) {
+		t.Errorf("MakeCABundle() after expiry = %s, wanted %s", got, newCA)
+	}
+	if got := MakeCABundle(newCA, []byte("present"), now); string(got) != string(newCA) {
+		t.Errorf("MakeCABundle() with garbage = %s, wanted %s", got, newCA)
+	}
+
+	// Server certificates signed by the new CA verify against the bundle,
+	// and don't outlive the CA.
+	_, serverCert, err := CreateServerCert(ctx, "svc", "ns", newKey, newCA, now.Add(72*time.Hour))
+	if err != nil {
+		t.Fatal("CreateServerCert() =", err)
+	}
+	cert := mustParse(t, serverCert)[0]
+	if want := mustParse(t, newCA)[0].NotAfter; !cert.NotAfter.Equal(want) {
+		t.Errorf("NotAfter = %v, wanted %v", cert.NotAfter, want)
+	}
+	pool := x509.NewCertPool()
+	pool.AppendCertsFromPEM(bundle)
+	if _, err := cert.Verify(x509.VerifyOptions{Roots: pool, DNSName: "svc.ns.svc"}); err != nil {
+		t.Error("Verify() =", err)
+	}
+}
+
+func mustParse(t *testing.T, data []byte) []*x509.Certificate {
+	t.Helper()
+	certs, err := ParseCertificates(data)
+	if err != nil {
+		t.Fatal("ParseCertificates() =", err)
+	}
+	return certs
+}
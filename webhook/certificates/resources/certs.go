@@ -18,6 +18,7 @@ package resources
 
 import (
 	"context"
+	"crypto"
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
@@ -25,6 +26,7 @@ import (
 	"crypto/x509/pkix"
 	"encoding/pem"
 	"errors"
+	"fmt"
 	"math/big"
 	"time"
 
@@ -142,40 +144,113 @@ func createCA(ctx context.Context, name, namespace string, notAfter time.Time) (
 // client to verify the server authentication chain. notAfter specifies
 // the expiration date.
 func CreateCerts(ctx context.Context, name, namespace string, notAfter time.Time) (serverKey, serverCert, caCert []byte, err error) {
-	logger := logging.FromContext(ctx)
 	// First create a CA certificate and private key
 	caKey, caCertificate, caCertificatePEM, err := createCA(ctx, name, namespace, notAfter)
 	if err != nil {
 		return nil, nil, nil, err
 	}
 
-	// Then create the private key for the serving cert
+	servKeyPEM, servCertPEM, err := createServerCert(ctx, name, namespace, caKey, caCertificate, notAfter)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return servKeyPEM, servCertPEM, caCertificatePEM, nil
+}
+
+// CreateCACert creates a self-signed CA certificate that expires at
+// notAfter, and returns it along with its private key.  The key can
+// later be passed to CreateServerCert to sign server certificates, so
+// that the CA outlives them.
+func CreateCACert(ctx context.Context, name, namespace string, notAfter time.Time) (caKey, caCert []byte, err error) {
+	logger := logging.FromContext(ctx)
+	privateKey, _, caCertificatePEM, err := createCA(ctx, name, namespace, notAfter)
+	if err != nil {
+		return nil, nil, err
+	}
+	caKey, err = encodePrivateKey(privateKey)
+	if err != nil {
+		logger.Errorw("error marshaling private key", zap.Error(err))
+		return nil, nil, err
+	}
+	return caKey, caCertificatePEM, nil
+}
+
+// CreateServerCert creates a certificate and key for the server, signed
+// by the given CA.  The certificate expires at notAfter, or when the CA
+// does if that is earlier.
+func CreateServerCert(ctx context.Context, name, namespace string, caKey, caCert []byte, notAfter time.Time) (serverKey, serverCert []byte, err error) {
+	privateKey, err := ParsePrivateKey(caKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse the CA key: %w", err)
+	}
+	certs, err := ParseCertificates(caCert)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse the CA cert: %w", err)
+	}
+	if len(certs) == 0 {
+		return nil, nil, errors.New("no CA cert found")
+	}
+	if certs[0].NotAfter.Before(notAfter) {
+		notAfter = certs[0].NotAfter
+	}
+	return createServerCert(ctx, name, namespace, privateKey, certs[0], notAfter)
+}
+
+func createServerCert(ctx context.Context, name, namespace string, caKey crypto.Signer, caCertificate *x509.Certificate, notAfter time.Time) (serverKey, serverCert []byte, err error) {
+	logger := logging.FromContext(ctx)
+
+	// Create the private key for the serving cert
 	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
 	if err != nil {
 		logger.Errorw("error generating random key", zap.Error(err))
-		return nil, nil, nil, err
+		return nil, nil, err
 	}
 	publicKey := privateKey.Public()
 
 	servCertTemplate, err := createServerCertTemplate(name, namespace, notAfter)
 	if err != nil {
 		logger.Errorw("failed to create the server certificate template", zap.Error(err))
-		return nil, nil, nil, err
+		return nil, nil, err
 	}
 
 	// create a certificate which wraps the server's public key, sign it with the CA private key
 	_, servCertPEM, err := createCert(servCertTemplate, caCertificate, publicKey, caKey)
 	if err != nil {
 		logger.Errorw("error signing server certificate template", zap.Error(err))
-		return nil, nil, nil, err
+		return nil, nil, err
 	}
-	privKeyBytes, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	servKeyPEM, err := encodePrivateKey(privateKey)
 	if err != nil {
 		logger.Errorw("error marshaling private key", zap.Error(err))
-		return nil, nil, nil, err
+		return nil, nil, err
 	}
-	servKeyPEM := pem.EncodeToMemory(&pem.Block{
+	return servKeyPEM, servCertPEM, nil
+}
+
+func encodePrivateKey(key *ecdsa.PrivateKey) ([]byte, error) {
+	privKeyBytes, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{
 		Type: "PRIVATE KEY", Bytes: privKeyBytes,
-	})
-	return servKeyPEM, servCertPEM, caCertificatePEM, nil
+	}), nil
+}
+
+// ParsePrivateKey parses a PEM-encoded PKCS #8 private key, as produced
+// by CreateCACert and CreateServerCert.
+func ParsePrivateKey(data []byte) (crypto.Signer, error) {
+	p, _ := pem.Decode(data)
+	if p == nil || p.Type != "PRIVATE KEY" {
+		return nil, errors.New("no PEM-encoded private key found")
+	}
+	key, err := x509.ParsePKCS8PrivateKey(p.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type %T", key)
+	}
+	return signer, nil
 }
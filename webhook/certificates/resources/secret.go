@@ -18,10 +18,12 @@ package resources
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 )
 
 const (
@@ -30,24 +32,101 @@ const (
 	// ServerCert is the name of the key associated with the secret's public key.
 	ServerCert = "server-cert.pem"
 	// CACert is the name of the key associated with the certificate of the CA for
-	// the keypair.
+	// the keypair.  While the CA is being rotated, the previous CA certificate
+	// follows the current one, so that clients given this bundle trust either.
 	CACert = "ca-cert.pem"
+	// CAKey is the name of the key associated with the private key of the CA,
+	// which is used to sign new server certificates.  It is kept in the CA
+	// secret, along with the CACert of the current CA only, rather than in
+	// the secret served by the webhook.
+	CAKey = "ca-key.pem"
 
-	oneWeek = 7 * 24 * time.Hour
+	// TLSCert, TLSKey and TLSCACert are the names of the keys holding the
+	// server certificate, its private key and the CA certificate in secrets
+	// of type kubernetes.io/tls, as issued by external certificate managers.
+	TLSCert   = corev1.TLSCertKey
+	TLSKey    = corev1.TLSPrivateKeyKey
+	TLSCACert = "ca.crt"
+
+	// DefaultCALifetime is how long generated CA certificates are valid by default.
+	DefaultCALifetime = 365 * 24 * time.Hour
+	// DefaultServerCertLifetime is how long generated server certificates are
+	// valid by default.
+	DefaultServerCertLifetime = 7 * 24 * time.Hour
+
+	// DefaultCARenewBefore is how long before its expiry the CA is rotated by
+	// default.
+	DefaultCARenewBefore = 30 * 24 * time.Hour
+	// DefaultServerCertRenewBefore is how long before its expiry the server
+	// certificate is renewed by default.
+	DefaultServerCertRenewBefore = 24 * time.Hour
 )
 
+// CASecretName returns the default name of the secret holding the CA of the
+// certificates of the secret with the given name.
+func CASecretName(name string) string {
+	return name + "-ca"
+}
+
+// Lifetimes configures how long the certificates generated by MakeSecret
+// are valid.  Zero values select the defaults.
+type Lifetimes struct {
+	CA         time.Duration
+	ServerCert time.Duration
+}
+
+type lifetimesKey struct{}
+
+// WithLifetimes attaches the certificate lifetimes to use by MakeSecret to
+// the context.
+func WithLifetimes(ctx context.Context, l Lifetimes) context.Context {
+	return context.WithValue(ctx, lifetimesKey{}, l)
+}
+
+// GetLifetimes returns the certificate lifetimes attached to the context,
+// with defaults filled in.
+func GetLifetimes(ctx context.Context) Lifetimes {
+	l, _ := ctx.Value(lifetimesKey{}).(Lifetimes)
+	if l.CA <= 0 {
+		l.CA = DefaultCALifetime
+	}
+	if l.ServerCert <= 0 {
+		l.ServerCert = DefaultServerCertLifetime
+	}
+	return l
+}
+
 // MakeSecret synthesizes a Kubernetes Secret object with the keys specified by
 // ServerKey, ServerCert, and CACert populated with a fresh certificate.
+// The certificates are valid for the Lifetimes attached to the context.
 // This is mutable to make deterministic testing possible.
 var MakeSecret = MakeSecretInternal
 
 // MakeSecretInternal is only public so MakeSecret can be restored in testing.  Use MakeSecret.
 func MakeSecretInternal(ctx context.Context, name, namespace, serviceName string) (*corev1.Secret, error) {
-	serverKey, serverCert, caCert, err := CreateCerts(ctx, serviceName, namespace, time.Now().Add(oneWeek))
+	secret, _, err := MakeSecretsInternal(ctx, name, namespace, serviceName,
+		types.NamespacedName{Namespace: namespace, Name: CASecretName(name)})
+	return secret, err
+}
+
+// MakeSecrets synthesizes the secret made by MakeSecret, and the CA secret
+// with the keys specified by CACert and CAKey populated with the CA that
+// signed its certificate.
+// This is mutable to make deterministic testing possible.
+var MakeSecrets = MakeSecretsInternal
+
+// MakeSecretsInternal is only public so MakeSecrets can be restored in testing.  Use MakeSecrets.
+func MakeSecretsInternal(ctx context.Context, name, namespace, serviceName string, ca types.NamespacedName) (secret, caSecret *corev1.Secret, err error) {
+	lifetimes, now := GetLifetimes(ctx), time.Now()
+	caKey, caCert, err := CreateCACert(ctx, serviceName, namespace, now.Add(lifetimes.CA))
+	if err != nil {
+		return nil, nil, err
+	}
+	serverKey, serverCert, err := CreateServerCert(ctx, serviceName, namespace, caKey, caCert, now.Add(lifetimes.ServerCert))
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	return &corev1.Secret{
+	secret = &corev1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      name,
 			Namespace: namespace,
@@ -57,5 +136,31 @@ func MakeSecretInternal(ctx context.Context, name, namespace, serviceName string
 			ServerCert: serverCert,
 			CACert:     caCert,
 		},
-	}, nil
+	}
+	caSecret = &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      ca.Name,
+			Namespace: ca.Namespace,
+		},
+		Data: map[string][]byte{
+			CACert: caCert,
+			CAKey:  caKey,
+		},
+	}
+	return secret, caSecret, nil
+}
+
+// CABundle returns the CA certificates with which to verify the webhook's
+// server certificate, from a secret made by MakeSecret or from a secret of
+// type kubernetes.io/tls.
+func CABundle(secret *corev1.Secret) ([]byte, error) {
+	key := CACert
+	if _, ok := secret.Data[TLSCert]; ok {
+		key = TLSCACert
+	}
+	caCert, ok := secret.Data[key]
+	if !ok {
+		return nil, fmt.Errorf("secret %q is missing %q key", secret.Name, key)
+	}
+	return caCert, nil
 }
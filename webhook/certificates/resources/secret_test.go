@@ -18,6 +18,11 @@ package resources
 
 import (
 	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 
 	. "github.com/Yangfisher1/knative-common-pkg/logging/testing"
 )
@@ -34,4 +39,97 @@ func TestMakeSecret(t *testing.T) {
 			t.Errorf("secret.Data[%q] is missing", key)
 		}
 	}
+	if _, ok := secret.Data[CAKey]; ok {
+		t.Errorf("secret.Data[%q] is present", CAKey)
+	}
+}
+
+func TestMakeSecrets(t *testing.T) {
+	ctx := TestContextWithLogger(t)
+	ca := types.NamespacedName{Namespace: "ca-ns", Name: "foo-ca"}
+	secret, caSecret, err := MakeSecrets(ctx, "foo", "ns", "bar", ca)
+	if err != nil {
+		t.Fatal("MakeSecrets() =", err)
+	}
+
+	if got := (types.NamespacedName{Namespace: caSecret.Namespace, Name: caSecret.Name}); got != ca {
+		t.Errorf("CA secret = %v, wanted %v", got, ca)
+	}
+	for _, key := range []string{CACert, CAKey} {
+		if _, ok := caSecret.Data[key]; !ok {
+			t.Errorf("caSecret.Data[%q] is missing", key)
+		}
+	}
+	if got, want := string(secret.Data[CACert]), string(caSecret.Data[CACert]); got != want {
+		t.Errorf("secret.Data[%q] = %q, wanted the CA certificate %q", CACert, got, want)
+	}
+}
+
+func TestMakeSecretLifetimes(t *testing.T) {
+	ctx := WithLifetimes(TestContextWithLogger(t), Lifetimes{
+		CA:         48 * time.Hour,
+		ServerCert: time.Hour,
+	})
+	secret, err := MakeSecret(ctx, "foo", "ns", "bar")
+	if err != nil {
+		t.Fatal("MakeSecret() =", err)
+	}
+
+	for key, want := range map[string]time.Duration{
+		CACert:     48 * time.Hour,
+		ServerCert: time.Hour,
+	} {
+		certs, err := ParseCertificates(secret.Data[key])
+		if err != nil || len(certs) != 1 {
+			t.Fatalf("ParseCertificates(%s) = %d certs, %v", key, len(certs), err)
+		}
+		if got := certs[0].NotAfter.Sub(certs[0].NotBefore).Round(time.Minute); got != want {
+			t.Errorf("%s lifetime = %v, wanted %v", key, got, want)
+		}
+	}
+}
+
+func TestCABundle(t *testing.T) {
+	tests := map[string]struct {
+		data    map[string][]byte
+		want    string
+		wantErr string
+	}{
+		"generated layout": {
+			data: map[string][]byte{ServerCert: []byte("cert"), CACert: []byte("ca")},
+			want: "ca",
+		},
+		"kubernetes.io/tls layout": {
+			data: map[string][]byte{TLSCert: []byte("cert"), TLSCACert: []byte("ca")},
+			want: "ca",
+		},
+		"missing generated CA": {
+			data:    map[string][]byte{ServerCert: []byte("cert")},
+			wantErr: `secret "foo" is missing "ca-cert.pem" key`,
+		},
+		"missing external CA": {
+			data:    map[string][]byte{TLSCert: []byte("cert"), CACert: []byte("ca")},
+			wantErr: `secret "foo" is missing "ca.crt" key`,
+		},
+	}
+	for n, tc := range tests {
+		t.Run(n, func(t *testing.T) {
+			got, err := CABundle(&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "foo"},
+				Data:       tc.data,
+			})
+			if tc.wantErr != "" {
+				if err == nil || err.Error() != tc.wantErr {
+					t.Fatalf("CABundle() = %v, wanted error %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal("CABundle() =", err)
+			}
+			if string(got) != tc.want {
+				t.Errorf("CABundle() = %s, wanted %s", got, tc.want)
+			}
+		})
+	}
 }
@@ -0,0 +1,87 @@
+/*
+Copyright 2023 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package certificates
+
+import (
+	"context"
+	"time"
+
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+
+	"github.com/Yangfisher1/knative-common-pkg/metrics"
+)
+
+const (
+	certificateExpirationName   = "certificate_expiration_seconds"
+	certificateProblemCountName = "certificate_problem_count"
+
+	problemMissing  = "missing"
+	problemInvalid  = "invalid"
+	problemExpiring = "expiring"
+)
+
+var (
+	certificateExpirationM = stats.Float64(
+		certificateExpirationName,
+		"The number of seconds until the externally managed webhook certificate expires",
+		stats.UnitSeconds)
+	certificateProblemCountM = stats.Int64(
+		certificateProblemCountName,
+		"The number of times the externally managed webhook certificate was found missing, invalid or expiring",
+		stats.UnitDimensionless)
+
+	// Create the tag keys that will be used to add tags to our measurements.
+	// Tag keys must conform to the restrictions described in
+	// go.opencensus.io/tag/validate.go. Currently those restrictions are:
+	// - length between 1 and 255 inclusive
+	// - characters are printable US-ASCII
+	problemKey = tag.MustNewKey("problem")
+)
+
+// RegisterMetrics registers the views of the externally managed
+// certificate metrics.  Webhooks managing their certificates externally
+// call it alongside webhook.RegisterMetrics, which sharedmain calls for them.
+func RegisterMetrics() {
+	if err := view.Register(&view.View{
+		Description: certificateExpirationM.Description(),
+		Measure:     certificateExpirationM,
+		Aggregation: view.LastValue(),
+	}, &view.View{
+		Description: certificateProblemCountM.Description(),
+		Measure:     certificateProblemCountM,
+		Aggregation: view.Count(),
+		TagKeys:     []tag.Key{problemKey},
+	}); err != nil {
+		panic(err)
+	}
+}
+
+// reportExpiration records how long the certificate remains valid.
+func reportExpiration(remaining time.Duration) {
+	metrics.Record(context.Background(), certificateExpirationM.M(remaining.Seconds()))
+}
+
+// reportProblem counts a problem with the certificate.
+func reportProblem(problem string) {
+	ctx, err := tag.New(context.Background(), tag.Insert(problemKey, problem))
+	if err != nil {
+		return
+	}
+	metrics.Record(ctx, certificateProblemCountM.M(1))
+}
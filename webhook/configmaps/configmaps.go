@@ -53,12 +53,14 @@ type reconciler struct {
 	key          types.NamespacedName
 	path         string
 	constructors map[string]reflect.Value
+	schemas      configmap.Schemas
 
 	client       kubernetes.Interface
 	vwhlister    admissionlisters.ValidatingWebhookConfigurationLister
 	secretlister corelisters.SecretLister
 
-	secretName string
+	secretName     string
+	webhookOptions *webhook.AdmissionWebhookOptions
 }
 
 var _ controller.Reconciler = (*reconciler)(nil)
@@ -80,9 +82,9 @@ func (ac *reconciler) Reconcile(ctx context.Context, key string) error {
 		return err
 	}
 
-	caCert, ok := secret.Data[certresources.CACert]
-	if !ok {
-		return fmt.Errorf("secret %q is missing %q key", ac.secretName, certresources.CACert)
+	caCert, err := certresources.CABundle(secret)
+	if err != nil {
+		return err
 	}
 
 	return ac.reconcileValidatingWebhook(ctx, caCert)
@@ -103,29 +105,32 @@ func (ac *reconciler) Admit(ctx context.Context, request *admissionv1.AdmissionR
 		return &admissionv1.AdmissionResponse{Allowed: true}
 	}
 
-	if err := ac.validate(ctx, request); err != nil {
+	warnings, err := ac.validate(ctx, request)
+	if err != nil {
 		return webhook.MakeErrorStatus("validation failed: %v", err)
 	}
 
 	return &admissionv1.AdmissionResponse{
-		Allowed: true,
+		Allowed:  true,
+		Warnings: warnings,
 	}
 }
 
 func (ac *reconciler) reconcileValidatingWebhook(ctx context.Context, caCert []byte) error {
 	logger := logging.FromContext(ctx)
 
+	gk := corev1.SchemeGroupVersion.WithKind("ConfigMap").GroupKind()
 	ruleScope := admissionregistrationv1.NamespacedScope
 	rules := []admissionregistrationv1.RuleWithOperations{{
-		Operations: []admissionregistrationv1.OperationType{
+		Operations: ac.webhookOptions.Operations(gk,
 			admissionregistrationv1.Create,
 			admissionregistrationv1.Update,
-		},
+		),
 		Rule: admissionregistrationv1.Rule{
 			APIGroups:   []string{""},
 			APIVersions: []string{"v1"},
 			Resources:   []string{"configmaps/*"},
-			Scope:       &ruleScope,
+			Scope:       ac.webhookOptions.Scope(gk, &ruleScope),
 		},
 	}}
 
@@ -149,6 +154,7 @@ func (ac *reconciler) reconcileValidatingWebhook(ctx context.Context, caCert []b
 			continue
 		}
 		webhook.Webhooks[i].Rules = rules
+		ac.webhookOptions.ApplyToValidatingWebhook(&webhook.Webhooks[i])
 		webhook.Webhooks[i].ClientConfig.CABundle = caCert
 		if webhook.Webhooks[i].ClientConfig.Service == nil {
 			return errors.New("missing service reference for webhook: " + wh.Name)
@@ -171,7 +177,7 @@ func (ac *reconciler) reconcileValidatingWebhook(ctx context.Context, caCert []b
 	return nil
 }
 
-func (ac *reconciler) validate(ctx context.Context, req *admissionv1.AdmissionRequest) error {
+func (ac *reconciler) validate(ctx context.Context, req *admissionv1.AdmissionRequest) ([]string, error) {
 	logger := logging.FromContext(ctx)
 	kind := req.Kind
 	newBytes := req.Object.Raw
@@ -186,27 +192,37 @@ func (ac *reconciler) validate(ctx context.Context, req *admissionv1.AdmissionRe
 	resourceGVK := corev1.SchemeGroupVersion.WithKind("ConfigMap")
 	if gvk != resourceGVK {
 		logger.Error("Unhandled kind: ", gvk)
-		return fmt.Errorf("unhandled kind: %v", gvk)
+		return nil, fmt.Errorf("unhandled kind: %v", gvk)
 	}
 
 	var newObj corev1.ConfigMap
 	if len(newBytes) != 0 {
 		if err := json.Unmarshal(newBytes, &newObj); err != nil {
-			return fmt.Errorf("cannot decode incoming new object: %w", err)
+			return nil, fmt.Errorf("cannot decode incoming new object: %w", err)
 		}
 	}
 
+	var warnings []string
+
 	if constructor, ok := ac.constructors[newObj.Name]; ok {
 		// Only validate example data if this is a configMap we know about.
 		exampleData, hasExampleData := newObj.Data[configmap.ExampleKey]
 		exampleChecksum, hasExampleChecksumAnnotation := newObj.Annotations[configmap.ExampleChecksumAnnotation]
 		if hasExampleData && hasExampleChecksumAnnotation &&
 			exampleChecksum != configmap.Checksum(exampleData) {
-			return fmt.Errorf(
+			return nil, fmt.Errorf(
 				"the update modifies a key in %q which is probably not what you want. Instead, copy the respective setting to the top-level of the ConfigMap, directly below %q",
 				configmap.ExampleKey, "data")
 		}
 
+		if schema, ok := ac.schemas[newObj.Name]; ok {
+			w, err := ac.validateSchema(req, schema, &newObj)
+			if err != nil {
+				return nil, err
+			}
+			warnings = w
+		}
+
 		inputs := []reflect.Value{
 			reflect.ValueOf(&newObj),
 		}
@@ -215,11 +231,34 @@ func (ac *reconciler) validate(ctx context.Context, req *admissionv1.AdmissionRe
 		errVal := outputs[1]
 
 		if !errVal.IsNil() {
-			return errVal.Interface().(error)
+			return nil, errVal.Interface().(error)
 		}
 	}
 
-	return nil
+	return warnings, nil
+}
+
+// validateSchema validates the ConfigMap against its Schema, and returns the
+// warnings of the Schema followed, for updates, by the changes to the
+// effective settings.
+func (ac *reconciler) validateSchema(req *admissionv1.AdmissionRequest, schema configmap.Schema, newObj *corev1.ConfigMap) ([]string, error) {
+	warnings, err := schema.Validate(newObj.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Operation == admissionv1.Update && len(req.OldObject.Raw) != 0 {
+		var oldObj corev1.ConfigMap
+		if err := json.Unmarshal(req.OldObject.Raw, &oldObj); err != nil {
+			return nil, fmt.Errorf("cannot decode incoming old object: %w", err)
+		}
+		warnings = append(warnings, schema.Diff(oldObj.Data, newObj.Data)...)
+	}
+
+	for i, w := range warnings {
+		warnings[i] = fmt.Sprintf("%s: %s", newObj.Name, w)
+	}
+	return warnings, nil
 }
 
 func (ac *reconciler) registerConfig(name string, constructor interface{}) {
@@ -23,6 +23,8 @@ import (
 	"strconv"
 	"testing"
 
+	"github.com/google/go-cmp/cmp"
+
 	// Injection stuff
 	_ "github.com/Yangfisher1/knative-common-pkg/client/injection/kube/client/fake"
 	_ "github.com/Yangfisher1/knative-common-pkg/client/injection/kube/informers/admissionregistration/v1/validatingwebhookconfiguration/fake"
@@ -344,3 +346,94 @@ func configMapRequest(
 	req.Resource.Group = ""
 	return req
 }
+
+func TestAdmitConfigMapWithSchema(t *testing.T) {
+	ctx, _ := SetupFakeContext(t)
+	ctx = webhook.WithOptions(ctx, webhook.Options{
+		SecretName: "webhook-secret",
+	})
+	ctx = WithSchemas(ctx, configmap.Schemas{
+		testConfigName: {
+			Keys: []configmap.Key{{
+				Name: "value",
+				Type: configmap.FloatType,
+			}, {
+				Name:       "old-value",
+				Deprecated: `use "value" instead`,
+			}, {
+				Name:    "mode",
+				Values:  []string{"fast", "slow"},
+				Default: "slow",
+			}},
+		},
+	})
+	ac := NewAdmissionController(ctx, testConfigValidationName, testConfigValidationPath,
+		validations).Reconciler.(*reconciler)
+
+	tests := []struct {
+		name         string
+		op           admissionv1.Operation
+		old          map[string]string
+		data         map[string]string
+		wantWarnings []string
+		wantErr      string
+	}{{
+		name: "valid",
+		op:   admissionv1.Create,
+		data: map[string]string{"value": "1.5"},
+	}, {
+		name: "unknown and deprecated keys",
+		op:   admissionv1.Create,
+		data: map[string]string{"value": "1.5", "valeu": "1", "old-value": "1"},
+		wantWarnings: []string{
+			`test-config: key "old-value" is deprecated: use "value" instead`,
+			`test-config: unknown key "valeu", did you mean "value"?`,
+		},
+	}, {
+		name:    "invalid value",
+		op:      admissionv1.Create,
+		data:    map[string]string{"value": "1.5", "mode": "medium"},
+		wantErr: `invalid value "medium" for "mode"`,
+	}, {
+		name:    "constructor still validates",
+		op:      admissionv1.Create,
+		data:    map[string]string{"value": "2.5"},
+		wantErr: "out of range",
+	}, {
+		name: "update",
+		op:   admissionv1.Update,
+		old:  map[string]string{"value": "1"},
+		data: map[string]string{"value": "1.5", "mode": "fast"},
+		wantWarnings: []string{
+			`test-config: mode changed from "slow" to "fast"`,
+			`test-config: value changed from "1" to "1.5"`,
+		},
+	}}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cm := createConfigMap("")
+			cm.Data = tc.data
+			req := configMapRequest(t, cm, tc.op)
+			if tc.old != nil {
+				old := createConfigMap("")
+				old.Data = tc.old
+				b, err := json.Marshal(old)
+				if err != nil {
+					t.Fatal("Failed to marshal resource:", err)
+				}
+				req.OldObject.Raw = b
+			}
+
+			resp := ac.Admit(TestContextWithLogger(t), req)
+			if tc.wantErr != "" {
+				ExpectFailsWith(t, resp, tc.wantErr)
+				return
+			}
+			ExpectAllowed(t, resp)
+			if !cmp.Equal(resp.Warnings, tc.wantWarnings) {
+				t.Error("Warnings (-want, +got):", cmp.Diff(tc.wantWarnings, resp.Warnings))
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+/*
+Copyright 2023 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package configmaps
+
+import (
+	"context"
+
+	"github.com/Yangfisher1/knative-common-pkg/configmap"
+)
+
+// schemasKey is used as the key for associating configmap.Schemas with a
+// context.Context.
+type schemasKey struct{}
+
+// WithSchemas associates the Schemas of the ConfigMaps validated by the
+// admission controller constructed with the returned context.
+func WithSchemas(ctx context.Context, schemas configmap.Schemas) context.Context {
+	return context.WithValue(ctx, schemasKey{}, schemas)
+}
+
+// GetSchemas retrieves the Schemas associated with the given context via
+// WithSchemas (above), or nil.
+func GetSchemas(ctx context.Context) configmap.Schemas {
+	v := ctx.Value(schemasKey{})
+	if v == nil {
+		return nil
+	}
+	return v.(configmap.Schemas)
+}
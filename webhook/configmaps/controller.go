@@ -31,6 +31,7 @@ import (
 	"github.com/Yangfisher1/knative-common-pkg/controller"
 	"github.com/Yangfisher1/knative-common-pkg/system"
 	"github.com/Yangfisher1/knative-common-pkg/webhook"
+	"go.uber.org/zap"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/cache"
 )
@@ -49,6 +50,11 @@ func NewAdmissionController(
 
 	key := types.NamespacedName{Name: name}
 
+	schemas := GetSchemas(ctx)
+	if err := schemas.Check(); err != nil {
+		logging.FromContext(ctx).Fatalw("Invalid ConfigMap schemas", zap.Error(err))
+	}
+
 	wh := &reconciler{
 		LeaderAwareFuncs: pkgreconciler.LeaderAwareFuncs{
 			// Have this reconciler enqueue our singleton whenever it becomes leader.
@@ -62,8 +68,11 @@ func NewAdmissionController(
 		path: path,
 
 		constructors: make(map[string]reflect.Value),
+		schemas:      schemas,
 		secretName:   options.SecretName,
 
+		webhookOptions: webhook.GetAdmissionWebhookOptions(ctx),
+
 		client:       client,
 		vwhlister:    vwhInformer.Lister(),
 		secretlister: secretInformer.Lister(),
@@ -28,10 +28,13 @@ import (
 
 	"github.com/Yangfisher1/knative-common-pkg/configmap"
 	"github.com/Yangfisher1/knative-common-pkg/controller"
+	"github.com/Yangfisher1/knative-common-pkg/logging"
 	"github.com/Yangfisher1/knative-common-pkg/ptr"
 	"github.com/Yangfisher1/knative-common-pkg/system"
 	"github.com/Yangfisher1/knative-common-pkg/webhook"
 	certresources "github.com/Yangfisher1/knative-common-pkg/webhook/certificates/resources"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -359,3 +362,21 @@ func TestNew(t *testing.T) {
 		t.Error("Queue length was never 1")
 	}
 }
+
+func TestNewInvalidSchema(t *testing.T) {
+	ctx, _ := SetupFakeContext(t)
+	ctx = webhook.WithOptions(ctx, webhook.Options{})
+	ctx = WithSchemas(ctx, configmap.Schemas{
+		"config-test": {Keys: []configmap.Key{{Name: "enabled", Type: configmap.BoolType, Min: "false"}}},
+	})
+	// Panic rather than exit on fatal errors.
+	logger := logging.FromContext(ctx).Desugar().WithOptions(zap.OnFatal(zapcore.WriteThenPanic))
+	ctx = logging.WithLogger(ctx, logger.Sugar())
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("NewAdmissionController() did not fail on an invalid schema")
+		}
+	}()
+	NewAdmissionController(ctx, "foo", "/bar", validations)
+}
@@ -37,3 +37,23 @@ func GetOptions(ctx context.Context) *Options {
 	}
 	return v.(*Options)
 }
+
+// admissionWebhookOptionsKey is used as the key for associating
+// AdmissionWebhookOptions with a context.Context.
+type admissionWebhookOptionsKey struct{}
+
+// WithAdmissionWebhookOptions associates the AdmissionWebhookOptions of the
+// admission controller constructed with the returned context.
+func WithAdmissionWebhookOptions(ctx context.Context, opt AdmissionWebhookOptions) context.Context {
+	return context.WithValue(ctx, admissionWebhookOptionsKey{}, &opt)
+}
+
+// GetAdmissionWebhookOptions retrieves the AdmissionWebhookOptions associated
+// with the given context via WithAdmissionWebhookOptions (above), or nil.
+func GetAdmissionWebhookOptions(ctx context.Context) *AdmissionWebhookOptions {
+	v := ctx.Value(admissionWebhookOptionsKey{})
+	if v == nil {
+		return nil
+	}
+	return v.(*AdmissionWebhookOptions)
+}
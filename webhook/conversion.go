@@ -21,11 +21,14 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/Yangfisher1/knative-common-pkg/apis"
 	"github.com/Yangfisher1/knative-common-pkg/logging"
+	"go.opencensus.io/trace"
 	"go.uber.org/zap"
 	apixv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 // ConversionController provides the interface for different conversion controllers
@@ -37,14 +40,15 @@ type ConversionController interface {
 	Convert(context.Context, *apixv1.ConversionRequest) *apixv1.ConversionResponse
 }
 
-func conversionHandler(rootLogger *zap.SugaredLogger, _ StatsReporter, c ConversionController) http.HandlerFunc {
+func conversionHandler(rootLogger *zap.SugaredLogger, stats StatsReporter, c ConversionController) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		logger := rootLogger
 		logger.Infof("Webhook ServeHTTP request=%#v", r)
 
+		ttStart := time.Now()
 		var review apixv1.ConversionReview
 		if err := json.NewDecoder(r.Body).Decode(&review); err != nil {
-			http.Error(w, fmt.Sprint("could not decode body:", err), http.StatusBadRequest)
+			decodeError(w, err)
 			return
 		}
 
@@ -53,7 +57,15 @@ func conversionHandler(rootLogger *zap.SugaredLogger, _ StatsReporter, c Convers
 			zap.String("desiredAPIVersion", review.Request.DesiredAPIVersion),
 		)
 
-		ctx := logging.WithLogger(r.Context(), logger)
+		ctx, span := trace.StartSpan(r.Context(), "ConversionReview")
+		defer span.End()
+		span.AddAttributes(
+			trace.StringAttribute("uid", string(review.Request.UID)),
+			trace.StringAttribute("desiredAPIVersion", review.Request.DesiredAPIVersion),
+			trace.Int64Attribute("objects", int64(len(review.Request.Objects))),
+		)
+
+		ctx = logging.WithLogger(ctx, logger)
 		ctx = apis.WithHTTPRequest(ctx, r)
 
 		response := apixv1.ConversionReview{
@@ -63,16 +75,21 @@ func conversionHandler(rootLogger *zap.SugaredLogger, _ StatsReporter, c Convers
 			TypeMeta: review.TypeMeta,
 			Response: c.Convert(ctx, review.Request),
 		}
+		if response.Response.Result.Status == metav1.StatusFailure {
+			span.SetStatus(trace.Status{
+				Code:    trace.StatusCodeUnknown,
+				Message: response.Response.Result.Message,
+			})
+		}
 
 		if err := json.NewEncoder(w).Encode(response); err != nil {
 			http.Error(w, fmt.Sprint("could not encode response:", err), http.StatusInternalServerError)
 			return
 		}
 
-		// TODO(dprotaso) - figure out what metrics we want reported
-		// if stats != nil {
-		// 	// Only report valid requests
-		// 	stats.ReportRequest(review.Request, response.Response, time.Since(ttStart))
-		// }
+		if stats != nil {
+			// Only report valid requests
+			stats.ReportConversionRequest(review.Request, response.Response, time.Since(ttStart))
+		}
 	}
 }
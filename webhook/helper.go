@@ -24,8 +24,8 @@ import (
 
 // EnsureLabelSelectorExpressions merges the current label selector's MatchExpressions
 // with the ones wanted.
-// It keeps all non-knative keys intact, removes all knative-keys no longer wanted and
-// adds all knative-keys not yet there.
+// It keeps all non-knative keys and the MatchLabels intact, removes all knative-keys
+// no longer wanted and adds all knative-keys not yet there.
 func EnsureLabelSelectorExpressions(
 	current *metav1.LabelSelector,
 	want *metav1.LabelSelector) *metav1.LabelSelector {
@@ -34,7 +34,7 @@ func EnsureLabelSelectorExpressions(
 		return want
 	}
 
-	if len(current.MatchExpressions) == 0 {
+	if len(current.MatchExpressions) == 0 && len(current.MatchLabels) == 0 {
 		return want
 	}
 
@@ -44,6 +44,7 @@ func EnsureLabelSelectorExpressions(
 	}
 
 	return &metav1.LabelSelector{
+		MatchLabels: current.MatchLabels,
 		MatchExpressions: ensureLabelSelectorRequirements(
 			current.MatchExpressions, wantExpressions),
 	}
@@ -117,6 +117,18 @@ func TestEnsureLabelSelectorExpressions(t *testing.T) {
 			MatchExpressions: []metav1.LabelSelectorRequirement{
 				knativeExpression, fooExpression},
 		},
+	}, {
+		name: "keep match labels",
+		current: &metav1.LabelSelector{
+			MatchLabels: map[string]string{"foo": "bar"},
+		},
+		want: &metav1.LabelSelector{
+			MatchExpressions: []metav1.LabelSelectorRequirement{knativeExpression},
+		},
+		expect: &metav1.LabelSelector{
+			MatchLabels:      map[string]string{"foo": "bar"},
+			MatchExpressions: []metav1.LabelSelectorRequirement{knativeExpression},
+		},
 	}}
 
 	for _, tc := range tests {
@@ -0,0 +1,38 @@
+/*
+Copyright 2023 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package json
+
+import "context"
+
+// limitsKey is used as the key for associating Limits with a
+// context.Context.
+type limitsKey struct{}
+
+// WithLimits associates the Limits of the objects decoded by the admission
+// controllers constructed with the returned context.
+func WithLimits(ctx context.Context, limits Limits) context.Context {
+	return context.WithValue(ctx, limitsKey{}, limits)
+}
+
+// GetLimits retrieves the Limits associated with the given context via
+// WithLimits (above), or no limits.
+func GetLimits(ctx context.Context) Limits {
+	if v, ok := ctx.Value(limitsKey{}).(Limits); ok {
+		return v
+	}
+	return Limits{}
+}
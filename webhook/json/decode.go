@@ -19,15 +19,11 @@ package json
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 )
 
-var (
-	emptyMeta  = []byte(`:{}`)
-	metaPrefix = []byte(`{"metadata"`)
-	metaSuffix = []byte(`}`)
-)
-
 var (
 	// Unmarshal is an alias for json.Unmarshal
 	Unmarshal = json.Unmarshal
@@ -36,92 +32,88 @@ var (
 	Marshal = json.Marshal
 )
 
+var (
+	// ErrTooLarge is returned, wrapped, when an object exceeds the maximum
+	// size of its Limits.
+	ErrTooLarge = errors.New("object is too large")
+
+	// ErrTooDeep is returned, wrapped, when an object exceeds the maximum
+	// nesting depth of its Limits.
+	ErrTooDeep = errors.New("object is nested too deeply")
+)
+
+// Limits bound the objects that are decoded.
+type Limits struct {
+	// MaxBytes is the maximum size of an object in bytes, unbounded if zero.
+	MaxBytes int64
+
+	// MaxDepth is the maximum nesting depth of the objects and arrays of an
+	// object, the object itself being at depth 1, unbounded if zero.
+	MaxDepth int
+}
+
 // Decode will parse the json byte array to the target object. When
 // unknown fields are _not_ allowed we still accept unknown
 // fields in the Object's metadata
 //
 // See https://github.com/knative/serving/issues/11448 for details
 func Decode(bites []byte, target interface{}, disallowUnknownFields bool) error {
-	if !disallowUnknownFields {
+	return DecodeWithLimits(bites, target, disallowUnknownFields, Limits{})
+}
+
+// DecodeWithLimits is like Decode, but rejects objects exceeding the limits.
+// When unknown fields are not allowed, all of them are reported at once in an
+// *apis.FieldError.
+func DecodeWithLimits(bites []byte, target interface{}, disallowUnknownFields bool, limits Limits) error {
+	if limits.MaxBytes > 0 && int64(len(bites)) > limits.MaxBytes {
+		return fmt.Errorf("%w: %d bytes exceed the maximum of %d", ErrTooLarge, len(bites), limits.MaxBytes)
+	}
+	if !disallowUnknownFields && limits.MaxDepth == 0 {
 		return json.Unmarshal(bites, target)
 	}
+	return DecodeReader(bytes.NewReader(bites), target, disallowUnknownFields, limits)
+}
 
-	// If we don't allow unknown fields we skip validating fields in the metadata
-	// block since that is opaque to us and validated by the API server
-	start, end, err := findMetadataOffsets(bites)
-	if err != nil {
-		return err
-	} else if start == -1 || end == -1 {
-		// If for some reason the json does not have metadata continue with normal parsing
-		dec := json.NewDecoder(bytes.NewReader(bites))
-		dec.DisallowUnknownFields()
-		return dec.Decode(target)
+// DecodeReader is like DecodeWithLimits, but reads the object from the
+// reader.  The object is checked against the limits and for unknown fields in
+// a single pass as it is read, so that reading stops as soon as it exceeds
+// the limits, before anything is decoded into the target.
+func DecodeReader(r io.Reader, target interface{}, disallowUnknownFields bool, limits Limits) error {
+	s := &scanner{maxBytes: limits.MaxBytes, maxDepth: limits.MaxDepth}
+	if disallowUnknownFields {
+		// The object's metadata is exempt from the check of the fields,
+		// since it is opaque to us and validated by the API server.
+		s.root = typeOf(target)
 	}
-
-	before := bites[:start]
-	metadata := bites[start:end]
-	after := bites[end:]
-
-	// Parse everything but skip metadata
-	dec := json.NewDecoder(io.MultiReader(
-		bytes.NewReader(before),
-		bytes.NewReader(emptyMeta),
-		bytes.NewReader(after),
-	))
-
-	dec.DisallowUnknownFields()
-	if err := dec.Decode(target); err != nil {
+	dec := json.NewDecoder(&scanningReader{r: r, s: s})
+	if err := dec.Decode(target); err == io.EOF {
+		return io.ErrUnexpectedEOF
+	} else if err != nil {
 		return err
 	}
+	if end := dec.InputOffset(); !isEOF(dec) {
+		return fmt.Errorf("invalid data after the object at offset %d", end)
+	}
+	return s.unknownFields()
+}
 
-	// Now we parse just the metadata
-	dec = json.NewDecoder(io.MultiReader(
-		bytes.NewReader(metaPrefix),
-		bytes.NewReader(metadata),
-		bytes.NewReader(metaSuffix),
-	))
+// isEOF returns whether only whitespace is left to decode.
+func isEOF(dec *json.Decoder) bool {
+	_, err := dec.Token()
+	return err == io.EOF
+}
 
-	return dec.Decode(target)
+// scanningReader feeds the scanner the bytes read from r, withholding them
+// from the decoder once the scanner fails.
+type scanningReader struct {
+	r io.Reader
+	s *scanner
 }
 
-func findMetadataOffsets(bites []byte) (start, end int64, err error) {
-	start, end = -1, -1
-	level := 0
-
-	var (
-		dec = json.NewDecoder(bytes.NewReader(bites))
-		t   json.Token
-	)
-
-	for {
-		t, err = dec.Token()
-		if err == io.EOF { //nolint
-			break
-		}
-		if err != nil {
-			return
-		}
-
-		switch v := t.(type) {
-		case json.Delim:
-			if v == '{' {
-				level++
-			} else if v == '}' {
-				level--
-			}
-		case string:
-			if v == "metadata" && level == 1 {
-				start = dec.InputOffset()
-				x := struct{}{}
-				if err = dec.Decode(&x); err != nil {
-					return -1, -1, err
-				}
-				end = dec.InputOffset()
-
-				// we exit early to stop processing the rest of the object
-				return
-			}
-		}
+func (sr *scanningReader) Read(p []byte) (int, error) {
+	n, err := sr.r.Read(p)
+	if serr := sr.s.scan(p[:n]); serr != nil {
+		return 0, serr
 	}
-	return -1, -1, nil
+	return n, err
 }
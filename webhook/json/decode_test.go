@@ -20,7 +20,10 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"io"
+	"strings"
 	"testing"
+	"testing/iotest"
 
 	"github.com/Yangfisher1/knative-common-pkg/apis"
 	"github.com/Yangfisher1/knative-common-pkg/webhook/resourcesemantics"
@@ -180,10 +183,8 @@ func TestDecode_AllowUnknownFields(t *testing.T) {
 	}
 }
 
-// Note: this test is paired with the failingFixture and knows
-// that the implementation of Decode parses the json in two passes
-// the first being with an empty metadata '{}' - the second being the real
-// input
+// Note: this test is paired with the failingFixture, whose metadata
+// fails to unmarshal unless it is empty.
 func TestDecode_UnmarshalMetadataFailed(t *testing.T) {
 	input := `{ "metadata":{"name":"some-name", "namespace":"some-namespace"} }`
 	err := Decode([]byte(input), &failingFixture{}, true)
@@ -192,6 +193,231 @@ func TestDecode_UnmarshalMetadataFailed(t *testing.T) {
 	}
 }
 
+func TestDecode_UnknownFields(t *testing.T) {
+	cases := []struct {
+		name    string
+		input   string
+		want    *nestedFixture
+		wantErr string
+	}{{
+		name: "all known",
+		input: `{
+			"apiVersion": "v1", "kind": "Nested",
+			"metadata": {"name": "some-name", "bomba": "boom"},
+			"spec": {
+				"items": [{"name": "a"}, {"NAME": "b"}],
+				"byName": {"c": {"name": "c"}},
+				"raw": {"anything": ["goes"]},
+				"any": {"anything": "goes"}
+			}
+		}`,
+		want: &nestedFixture{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Nested"},
+			ObjectMeta: metav1.ObjectMeta{Name: "some-name"},
+			Spec: nestedSpec{
+				Items:  []nestedItem{{Name: "a"}, {Name: "b"}},
+				ByName: map[string]nestedItem{"c": {Name: "c"}},
+				Raw:    runtime.RawExtension{Raw: []byte(`{"anything": ["goes"]}`)},
+				Any:    map[string]interface{}{"anything": "goes"},
+			},
+		},
+	}, {
+		name: "all unknown fields are reported",
+		input: `{
+			"bomba": "boom",
+			"spec": {
+				"items": [{"name": "a"}, {"name": "b", "size": 1}],
+				"byName": {"c": {"\u0063olor": "red"}},
+				"metadata": {}
+			}
+		}`,
+		wantErr: "must not set the field(s): bomba, spec.byName.c.color, spec.items[1].size, spec.metadata",
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := &nestedFixture{}
+			err := Decode([]byte(tc.input), got, true)
+			if tc.wantErr != "" {
+				var fe *apis.FieldError
+				if !errors.As(err, &fe) {
+					t.Fatalf("Decode() = %v, wanted an *apis.FieldError", err)
+				}
+				if err.Error() != tc.wantErr {
+					t.Errorf("Decode() = %q, wanted %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal("Decode() =", err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Error("unexpected diff (-want, +got):", diff)
+			}
+		})
+	}
+}
+
+func TestDecodeWithLimits(t *testing.T) {
+	input := `{"metadata":{"labels":{"key":"value"}},"spec":{"items":[{"name":"a"}]}}`
+
+	cases := []struct {
+		name                  string
+		limits                Limits
+		disallowUnknownFields bool
+		wantErr               error
+		wantMsg               string
+	}{{
+		name:   "within the limits",
+		limits: Limits{MaxBytes: int64(len(input)), MaxDepth: 4},
+	}, {
+		name:                  "within the limits, unknown fields disallowed",
+		limits:                Limits{MaxBytes: int64(len(input)), MaxDepth: 4},
+		disallowUnknownFields: true,
+	}, {
+		name:    "too large",
+		limits:  Limits{MaxBytes: int64(len(input)) - 1},
+		wantErr: ErrTooLarge,
+		wantMsg: "object is too large: 71 bytes exceed the maximum of 70",
+	}, {
+		name:    "too deep",
+		limits:  Limits{MaxDepth: 3},
+		wantErr: ErrTooDeep,
+		wantMsg: "object is nested too deeply: spec.items[0] exceeds the maximum depth of 3",
+	}, {
+		name:                  "too deep, unknown fields disallowed",
+		limits:                Limits{MaxDepth: 2},
+		disallowUnknownFields: true,
+		wantErr:               ErrTooDeep,
+		wantMsg:               "object is nested too deeply: metadata.labels exceeds the maximum depth of 2",
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := &nestedFixture{}
+			err := DecodeWithLimits([]byte(input), got, tc.disallowUnknownFields, tc.limits)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("DecodeWithLimits() = %v, wanted %v", err, tc.wantErr)
+			}
+			if err != nil {
+				if err.Error() != tc.wantMsg {
+					t.Errorf("DecodeWithLimits() = %q, wanted %q", err, tc.wantMsg)
+				}
+				return
+			}
+			want := &nestedFixture{
+				ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"key": "value"}},
+				Spec:       nestedSpec{Items: []nestedItem{{Name: "a"}}},
+			}
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Error("unexpected diff (-want, +got):", diff)
+			}
+		})
+	}
+}
+
+// countingReader counts the bytes read from r.
+type countingReader struct {
+	r io.Reader
+	n int
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	cr.n += n
+	return n, err
+}
+
+func TestDecodeReader(t *testing.T) {
+	deep := `{"spec":{"items":[{"name":"a"}]},"metadata":{"name":"` + strings.Repeat("x", 1024) + `"}}`
+
+	cases := []struct {
+		name     string
+		input    string
+		limits   Limits
+		wantErr  error
+		wantMsg  string
+		maxRead  int
+		wantItem string
+	}{{
+		name:     "within the limits",
+		input:    `{"spec":{"items":[{"name":"a"}, {"n\u0061me":"b"}]}}`,
+		limits:   Limits{MaxBytes: 1024, MaxDepth: 4},
+		wantItem: "b",
+	}, {
+		name:    "too large",
+		input:   deep,
+		limits:  Limits{MaxBytes: 64},
+		wantErr: ErrTooLarge,
+		wantMsg: "object is too large: it exceeds the maximum of 64 bytes",
+		maxRead: 65,
+	}, {
+		name:    "too deep",
+		input:   deep,
+		limits:  Limits{MaxDepth: 3},
+		wantErr: ErrTooDeep,
+		wantMsg: "object is nested too deeply: spec.items[0] exceeds the maximum depth of 3",
+		maxRead: len(`{"spec":{"items":[{`),
+	}, {
+		name:    "unknown fields",
+		input:   `{"spec":{"items":[{"name":"a"},{"n\u0061me":"b","size":1}],"byName":{"c":{"color":"red"}}},"bomba":1}`,
+		wantMsg: "must not set the field(s): bomba, spec.byName.c.color, spec.items[1].size",
+	}, {
+		name:    "trailing data",
+		input:   `{"spec":{}} {}`,
+		wantMsg: "invalid data after the object at offset 11",
+	}, {
+		name:    "empty",
+		wantErr: io.ErrUnexpectedEOF,
+		wantMsg: io.ErrUnexpectedEOF.Error(),
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Read a byte at a time, to scan keys, strings and escapes split
+			// across reads.
+			r := &countingReader{r: iotest.OneByteReader(strings.NewReader(tc.input))}
+			got := &nestedFixture{}
+			err := DecodeReader(r, got, true, tc.limits)
+			if tc.wantErr != nil && !errors.Is(err, tc.wantErr) {
+				t.Fatalf("DecodeReader() = %v, wanted %v", err, tc.wantErr)
+			}
+			if tc.wantMsg != "" {
+				if err == nil || err.Error() != tc.wantMsg {
+					t.Errorf("DecodeReader() = %v, wanted %q", err, tc.wantMsg)
+				}
+				if tc.maxRead > 0 && r.n > tc.maxRead {
+					t.Errorf("Read %d bytes, wanted reading to stop after %d", r.n, tc.maxRead)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal("DecodeReader() =", err)
+			}
+			if items := got.Spec.Items; len(items) != 2 || items[1].Name != tc.wantItem {
+				t.Errorf("Items = %v, wanted the second named %q", items, tc.wantItem)
+			}
+		})
+	}
+}
+
+type nestedFixture struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+	Spec              nestedSpec `json:"spec"`
+}
+
+type nestedSpec struct {
+	Items  []nestedItem          `json:"items,omitempty"`
+	ByName map[string]nestedItem `json:"byName,omitempty"`
+	Raw    runtime.RawExtension  `json:"raw,omitempty"`
+	Any    interface{}           `json:"any,omitempty"`
+}
+
+type nestedItem struct {
+	Name string `json:"name"`
+}
+
 type fixture struct {
 	// Our decoder doesn't support `inline` that's a sig.k8s.io/yaml feature
 	// So we skip parsing this property
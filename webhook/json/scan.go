@@ -0,0 +1,333 @@
+/*
+Copyright 2023 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package json
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/Yangfisher1/knative-common-pkg/apis"
+)
+
+var unmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+// typeOf returns the type checked for the target of Unmarshal.
+func typeOf(target interface{}) reflect.Type {
+	return resolve(reflect.TypeOf(target))
+}
+
+// resolve returns the type whose fields are checked for values decoded into
+// t, dereferencing pointers, or nil when they aren't checked, e.g. for
+// interface{} or json.Unmarshalers.
+func resolve(t reflect.Type) reflect.Type {
+	for t != nil && t.Kind() == reflect.Pointer {
+		if t.Implements(unmarshalerType) {
+			return nil
+		}
+		t = t.Elem()
+	}
+	if t == nil || t.Implements(unmarshalerType) || reflect.PointerTo(t).Implements(unmarshalerType) {
+		return nil
+	}
+	switch t.Kind() {
+	case reflect.Struct, reflect.Map, reflect.Slice, reflect.Array:
+		return t
+	default:
+		return nil
+	}
+}
+
+// fieldCache maps struct types to their fields, by JSON name.
+var fieldCache sync.Map // map[reflect.Type]map[string]reflect.Type
+
+// fieldsOf returns the fields of the struct type by JSON name, including the
+// fields of embedded structs, which the fields of the embedding struct shadow.
+func fieldsOf(t reflect.Type) map[string]reflect.Type {
+	if f, ok := fieldCache.Load(t); ok {
+		return f.(map[string]reflect.Type)
+	}
+	fields := make(map[string]reflect.Type, t.NumField())
+	collectFields(t, fields)
+	fieldCache.Store(t, fields)
+	return fields
+}
+
+func collectFields(t reflect.Type, fields map[string]reflect.Type) {
+	var embedded []reflect.Type
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				embedded = append(embedded, ft)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields[name] = f.Type
+	}
+	for _, et := range embedded {
+		promoted := make(map[string]reflect.Type, et.NumField())
+		collectFields(et, promoted)
+		for name, ft := range promoted {
+			if _, ok := fields[name]; !ok {
+				fields[name] = ft
+			}
+		}
+	}
+}
+
+// field returns the type of the field of the struct type with the JSON name,
+// which, as with Unmarshal, is preferably an exact match but may differ in
+// case.
+func field(t reflect.Type, name []byte) (reflect.Type, bool) {
+	fields := fieldsOf(t)
+	if ft, ok := fields[string(name)]; ok {
+		return ft, true
+	}
+	for n, ft := range fields {
+		if bytes.EqualFold([]byte(n), name) {
+			return ft, true
+		}
+	}
+	return nil, false
+}
+
+// pathElement is a field name or, when index isn't negative, an array index.
+type pathElement struct {
+	name  []byte
+	index int
+}
+
+func fieldElement(name []byte) pathElement {
+	return pathElement{name: name, index: -1}
+}
+
+// frame is an object or array being scanned.
+type frame struct {
+	array bool
+	depth int
+
+	// t is the type the object is decoded into, or elem the type of the
+	// elements of the array, nil when their fields aren't checked.
+	t    reflect.Type
+	elem reflect.Type
+
+	// member is the type of the value of the object member being scanned,
+	// and inMember whether its name was pushed onto the path.
+	member   reflect.Type
+	inMember bool
+	wantKey  bool
+}
+
+// scanner is fed the JSON being decoded as it is read, bounding its size and
+// the nesting depth of its values, and collecting the fields unknown to the
+// types they are decoded into.  It keeps just enough state to follow the
+// JSON, leaving the reporting of syntax errors to the decoder.
+type scanner struct {
+	maxBytes int64
+	maxDepth int
+	root     reflect.Type
+
+	n        int64
+	stack    []frame
+	path     []pathElement
+	inString bool
+	escaped  bool
+	inKey    bool
+	key      []byte
+	unknown  []string
+}
+
+func (s *scanner) top() *frame {
+	if len(s.stack) == 0 {
+		return nil
+	}
+	return &s.stack[len(s.stack)-1]
+}
+
+// scan scans the next bytes of the JSON.
+func (s *scanner) scan(p []byte) error {
+	s.n += int64(len(p))
+	if s.maxBytes > 0 && s.n > s.maxBytes {
+		return fmt.Errorf("%w: it exceeds the maximum of %d bytes", ErrTooLarge, s.maxBytes)
+	}
+	for _, c := range p {
+		if s.inString {
+			s.stringByte(c)
+			continue
+		}
+		f := s.top()
+		switch c {
+		case '"':
+			s.inString = true
+			if f != nil && !f.array && f.wantKey {
+				s.inKey, s.key = true, s.key[:0]
+			}
+		case '{', '[':
+			if err := s.open(c == '['); err != nil {
+				return err
+			}
+		case ':':
+			if f != nil && !f.array && f.wantKey {
+				s.member(f)
+			}
+		case ',':
+			switch {
+			case f == nil:
+			case f.array:
+				s.path[len(s.path)-1].index++
+			case f.inMember:
+				s.path = s.path[:len(s.path)-1]
+				f.inMember, f.wantKey = false, true
+			}
+		case '}', ']':
+			if f == nil {
+				continue
+			}
+			if f.array || f.inMember {
+				s.path = s.path[:len(s.path)-1]
+			}
+			s.stack = s.stack[:len(s.stack)-1]
+		}
+	}
+	return nil
+}
+
+// stringByte scans the byte of a string, collecting those of object keys.
+func (s *scanner) stringByte(c byte) {
+	switch {
+	case s.escaped:
+		s.escaped = false
+	case c == '\\':
+		s.escaped = true
+	case c == '"':
+		s.inString = false
+		s.inKey = false
+		return
+	}
+	if s.inKey {
+		s.key = append(s.key, c)
+	}
+}
+
+// open scans the start of an object or array, whose parent is at the top of
+// the stack.
+func (s *scanner) open(array bool) error {
+	t, depth := s.root, 1
+	if f := s.top(); f != nil {
+		t, depth = f.member, f.depth+1
+		if f.array {
+			t = f.elem
+		}
+	}
+	if s.maxDepth > 0 && depth > s.maxDepth {
+		return fmt.Errorf("%w: %s exceeds the maximum depth of %d", ErrTooDeep, s.pathString(), s.maxDepth)
+	}
+	f := frame{array: array, depth: depth, wantKey: !array}
+	if array {
+		if t != nil && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) {
+			f.elem = resolve(t.Elem())
+		}
+		s.path = append(s.path, pathElement{index: 0})
+	} else {
+		f.t = t
+	}
+	s.stack = append(s.stack, f)
+	return nil
+}
+
+// member scans the start of the value of the object member whose key was
+// just scanned.
+func (s *scanner) member(f *frame) {
+	name := s.keyName()
+	f.member, f.inMember, f.wantKey = nil, true, false
+	switch {
+	case f.t == nil:
+	case f.depth == 1 && string(name) == "metadata":
+		// The metadata is exempt from the check of the fields.
+	case f.t.Kind() == reflect.Struct:
+		ft, ok := field(f.t, name)
+		if !ok {
+			s.unknown = append(s.unknown, s.pathString(fieldElement(name)))
+		}
+		f.member = resolve(ft)
+	case f.t.Kind() == reflect.Map:
+		f.member = resolve(f.t.Elem())
+	}
+	s.path = append(s.path, fieldElement(name))
+}
+
+// keyName returns the name of the key just scanned, unescaping it if needed.
+func (s *scanner) keyName() []byte {
+	name := append([]byte(nil), s.key...)
+	if bytes.IndexByte(name, '\\') < 0 {
+		return name
+	}
+	var unescaped string
+	quoted := append(append([]byte{'"'}, name...), '"')
+	if err := json.Unmarshal(quoted, &unescaped); err != nil {
+		// The decoder reports the syntax error.
+		return name
+	}
+	return []byte(unescaped)
+}
+
+// pathString returns the current path, followed by the extra elements.
+func (s *scanner) pathString(extra ...pathElement) string {
+	var b strings.Builder
+	for _, e := range append(s.path[:len(s.path):len(s.path)], extra...) {
+		if e.index >= 0 {
+			b.WriteString("[" + strconv.Itoa(e.index) + "]")
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteByte('.')
+		}
+		b.Write(e.name)
+	}
+	if b.Len() == 0 {
+		return "the object"
+	}
+	return b.String()
+}
+
+// unknownFields returns the error reporting the unknown fields, if any.
+func (s *scanner) unknownFields() error {
+	if len(s.unknown) == 0 {
+		return nil
+	}
+	return apis.ErrDisallowedFields(s.unknown...)
+}
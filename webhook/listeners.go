@@ -0,0 +1,98 @@
+/*
+Copyright 2023 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/Yangfisher1/knative-common-pkg/metrics"
+	"github.com/Yangfisher1/knative-common-pkg/profiling"
+)
+
+const (
+	// DefaultMaxRequestBodyBytes is the default limit of the size of the
+	// bodies of admission and conversion requests.  It leaves room for
+	// both the old and new objects of an update, each of up to etcd's
+	// 1.5MiB limit.
+	DefaultMaxRequestBodyBytes int64 = 6 << 20
+
+	// DefaultReadTimeout is the default timeout for reading admission and
+	// conversion requests.  The API server waits for webhooks for at most 30
+	// seconds.
+	DefaultReadTimeout = 30 * time.Second
+
+	// DefaultWriteTimeout is the default timeout for writing admission and
+	// conversion responses.
+	DefaultWriteTimeout = 30 * time.Second
+
+	// LivenessPath and ReadinessPath are the paths of the probes served on
+	// Options.HealthPort.
+	LivenessPath  = "/healthz"
+	ReadinessPath = "/readyz"
+)
+
+// healthHandler serves the liveness and readiness probes of the webhook.
+func (wh *Webhook) healthHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(LivenessPath, func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc(ReadinessPath, func(w http.ResponseWriter, _ *http.Request) {
+		select {
+		case <-wh.informersSynced:
+		default:
+			http.Error(w, "informers not synced", http.StatusServiceUnavailable)
+			return
+		}
+		if wh.draining.Load() {
+			http.Error(w, "shutting down", http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	return mux
+}
+
+// debugHandler serves pprof profiles and Prometheus metrics.
+func debugHandler(logger *zap.SugaredLogger) http.Handler {
+	h := profiling.NewHandler(logger, true)
+	h.Handle("/metrics", metrics.PrometheusHandler())
+	return h
+}
+
+// limitRequestBody limits the size of request bodies to maxBytes.
+func limitRequestBody(maxBytes int64, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+		h.ServeHTTP(w, r)
+	})
+}
+
+// decodeError reports a failure to decode the request body.
+func decodeError(w http.ResponseWriter, err error) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		http.Error(w, fmt.Sprintf("request body exceeds %d bytes", tooLarge.Limit), http.StatusRequestEntityTooLarge)
+		return
+	}
+	http.Error(w, fmt.Sprint("could not decode body:", err), http.StatusBadRequest)
+}
@@ -0,0 +1,138 @@
+/*
+Copyright 2023 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	logtesting "github.com/Yangfisher1/knative-common-pkg/logging/testing"
+)
+
+func TestHealthHandler(t *testing.T) {
+	synced := make(chan struct{})
+	wh := &Webhook{informersSynced: synced}
+	h := wh.healthHandler()
+
+	probe := func(path string) int {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec.Code
+	}
+
+	if got, want := probe(LivenessPath), http.StatusOK; got != want {
+		t.Errorf("liveness = %d, want %d", got, want)
+	}
+	if got, want := probe(ReadinessPath), http.StatusServiceUnavailable; got != want {
+		t.Errorf("readiness before informers synced = %d, want %d", got, want)
+	}
+
+	close(synced)
+	if got, want := probe(ReadinessPath), http.StatusOK; got != want {
+		t.Errorf("readiness after informers synced = %d, want %d", got, want)
+	}
+
+	wh.draining.Store(true)
+	if got, want := probe(ReadinessPath), http.StatusServiceUnavailable; got != want {
+		t.Errorf("readiness while draining = %d, want %d", got, want)
+	}
+	if got, want := probe(LivenessPath), http.StatusOK; got != want {
+		t.Errorf("liveness while draining = %d, want %d", got, want)
+	}
+}
+
+func TestRequestBodyLimit(t *testing.T) {
+	c := &fixedAdmissionController{path: "/limited", response: &admissionv1.AdmissionResponse{}}
+	synced := make(chan struct{})
+	close(synced)
+	h := limitRequestBody(16, admissionHandler(logtesting.TestLogger(t), nil, nil, nil, c, synced))
+
+	rec := httptest.NewRecorder()
+	body := `{"request":{"uid":"` + strings.Repeat("x", 32) + `"}}`
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/limited", strings.NewReader(body)))
+	if got, want := rec.Code, http.StatusRequestEntityTooLarge; got != want {
+		t.Errorf("status = %d, want %d: %s", got, want, rec.Body.String())
+	}
+}
+
+func TestRunSeparateListeners(t *testing.T) {
+	ports := make([]int, 3)
+	for i := range ports {
+		port, err := newTestPort()
+		if err != nil {
+			t.Fatal("newTestPort() =", err)
+		}
+		ports[i] = port
+	}
+
+	opts := newDefaultOptions()
+	opts.SecretName = ""
+	opts.Port, opts.HealthPort, opts.DebugPort = ports[0], ports[1], ports[2]
+	_, wh, cancel := newNonRunningTestWebhook(t, opts)
+	defer cancel()
+
+	stopCh := make(chan struct{})
+	errCh := make(chan error, 1)
+	go func() { errCh <- wh.Run(stopCh) }()
+
+	// Don't keep connections to the servers alive, so that they shut down
+	// without waiting for idle connections.
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	get := func(port int, path string) int {
+		resp, err := client.Get(fmt.Sprintf("http://localhost:%d%s", port, path))
+		if err != nil {
+			return 0
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	waitFor := func(port int, path string, want int) {
+		t.Helper()
+		var got int
+		if err := wait.PollImmediate(10*time.Millisecond, 5*time.Second, func() (bool, error) {
+			got = get(port, path)
+			return got == want, nil
+		}); err != nil {
+			t.Fatalf("GET %s on port %d = %d, want %d", path, port, got, want)
+		}
+	}
+
+	waitFor(opts.HealthPort, LivenessPath, http.StatusOK)
+	waitFor(opts.HealthPort, ReadinessPath, http.StatusServiceUnavailable)
+	wh.InformersHaveSynced()
+	waitFor(opts.HealthPort, ReadinessPath, http.StatusOK)
+	waitFor(opts.DebugPort, "/debug/pprof/", http.StatusOK)
+
+	close(stopCh)
+	waitFor(opts.HealthPort, ReadinessPath, http.StatusServiceUnavailable)
+
+	select {
+	case err := <-errCh:
+		if err != nil {
+			t.Error("Run() =", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("Timed out waiting for Run to return")
+	}
+}
@@ -0,0 +1,291 @@
+/*
+Copyright 2023 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package psbinding
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	duckv1 "github.com/Yangfisher1/knative-common-pkg/apis/duck/v1"
+)
+
+// PrioritizedBindable may be implemented by Bindables to control the order in
+// which they are applied to their subjects.  Bindables are applied in
+// increasing priority, so that higher priorities win conflicts.  Bindables
+// that don't implement it have priority 0, and ties are applied in
+// namespace, name and kind order.
+type PrioritizedBindable interface {
+	Bindable
+
+	// BindingPriority returns the priority of the Bindable.
+	BindingPriority() int
+}
+
+// FieldOwnerBindable may be implemented by Bindables to declare the fields of
+// their subjects that they own.  Conflicts are otherwise only detected when
+// Bindables actually write different values to the same fields.
+type FieldOwnerBindable interface {
+	Bindable
+
+	// OwnedFields returns the fields of the subject owned by the Bindable.
+	OwnedFields() OwnedFields
+}
+
+// OwnedFields are fields of the pod template of a PodSpecable.
+type OwnedFields struct {
+	// Env are the names of the environment variables owned, in all containers.
+	Env []string
+
+	// Volumes are the names of the volumes owned.
+	Volumes []string
+
+	// VolumeMounts are the mount paths of the volume mounts owned, in all
+	// containers.
+	VolumeMounts []string
+}
+
+// Conflict is a field of a subject written by several Bindables.
+type Conflict struct {
+	// Field describes the field, e.g. `env "FOO" of container "user"`.
+	Field string
+
+	// Bindables are the Bindables writing the field, in the order they were
+	// applied, so the last one wins.
+	Bindables []Bindable
+}
+
+// String implements fmt.Stringer
+func (c Conflict) String() string {
+	names := make([]string, 0, len(c.Bindables))
+	for _, fb := range c.Bindables {
+		names = append(names, bindableName(fb))
+	}
+	return fmt.Sprintf("%s is written by %s, the last one wins", c.Field, strings.Join(names, ", "))
+}
+
+// involves returns whether fb is one of the conflicting Bindables.
+func (c Conflict) involves(fb Bindable) bool {
+	for _, other := range c.Bindables {
+		if sameBindable(fb, other) {
+			return true
+		}
+	}
+	return false
+}
+
+func bindableName(fb Bindable) string {
+	return fmt.Sprintf("%s %s/%s", fb.GetGroupVersionKind().Kind, fb.GetNamespace(), fb.GetName())
+}
+
+func sameBindable(a, b Bindable) bool {
+	return a.GetGroupVersionKind().GroupKind() == b.GetGroupVersionKind().GroupKind() &&
+		a.GetNamespace() == b.GetNamespace() && a.GetName() == b.GetName()
+}
+
+func priority(fb Bindable) int {
+	if pb, ok := fb.(PrioritizedBindable); ok {
+		return pb.BindingPriority()
+	}
+	return 0
+}
+
+// sortBindables sorts the Bindables in the order they are applied.
+func sortBindables(fbs []Bindable) {
+	sort.SliceStable(fbs, func(i, j int) bool {
+		lhs, rhs := fbs[i], fbs[j]
+		if pl, pr := priority(lhs), priority(rhs); pl != pr {
+			return pl < pr
+		}
+		if lhs.GetNamespace() != rhs.GetNamespace() {
+			return lhs.GetNamespace() < rhs.GetNamespace()
+		}
+		if lhs.GetName() != rhs.GetName() {
+			return lhs.GetName() < rhs.GetName()
+		}
+		return lhs.GetGroupVersionKind().String() < rhs.GetGroupVersionKind().String()
+	})
+}
+
+// applyBindables applies the Bindables to the subject in order, doing or
+// undoing them according to their deletion state, and returns the fields
+// written by more than one of them.
+func applyBindables(ctx context.Context, withContext BindableContext, fbs []Bindable, s bindingSubject) ([]Conflict, error) {
+	writers := make(map[string][]Bindable)
+	for _, fb := range fbs {
+		// Callback into the user's code to setup the context with additional
+		// information needed to perform the mutation.
+		bindingContext := ctx
+		if withContext != nil {
+			var err error
+			if bindingContext, err = withContext(ctx, fb); err != nil {
+				return nil, err
+			}
+		}
+
+		written := sets.NewString()
+		s.bind(func(ps *duckv1.WithPod) {
+			before := ps.DeepCopy()
+			if fb.GetDeletionTimestamp() != nil {
+				fb.Undo(bindingContext, ps)
+			} else {
+				fb.Do(bindingContext, ps)
+				if fo, ok := fb.(FieldOwnerBindable); ok {
+					written.Insert(ownedFields(fo.OwnedFields(), ps)...)
+				}
+			}
+			written.Insert(writtenFields(before, ps)...)
+		})
+
+		for _, field := range written.UnsortedList() {
+			writers[field] = append(writers[field], fb)
+		}
+	}
+
+	var conflicts []Conflict
+	for field, fbs := range writers {
+		if len(fbs) > 1 {
+			conflicts = append(conflicts, Conflict{Field: field, Bindables: fbs})
+		}
+	}
+	sort.Slice(conflicts, func(i, j int) bool {
+		return conflicts[i].Field < conflicts[j].Field
+	})
+	return conflicts, nil
+}
+
+func envField(container, name string) string {
+	return fmt.Sprintf("env %q of container %q", name, container)
+}
+
+func volumeField(name string) string {
+	return fmt.Sprintf("volume %q", name)
+}
+
+func volumeMountField(container, path string) string {
+	return fmt.Sprintf("volume mount %q of container %q", path, container)
+}
+
+func allContainers(ps *duckv1.WithPod) []corev1.Container {
+	spec := ps.Spec.Template.Spec
+	containers := append(append([]corev1.Container(nil), spec.InitContainers...), spec.Containers...)
+	for _, ec := range spec.EphemeralContainers {
+		containers = append(containers, corev1.Container(ec.EphemeralContainerCommon))
+	}
+	return containers
+}
+
+// ownedFields lists the fields of the subject described by the OwnedFields.
+func ownedFields(of OwnedFields, ps *duckv1.WithPod) []string {
+	var fields []string
+	for _, c := range allContainers(ps) {
+		for _, name := range of.Env {
+			fields = append(fields, envField(c.Name, name))
+		}
+		for _, path := range of.VolumeMounts {
+			fields = append(fields, volumeMountField(c.Name, path))
+		}
+	}
+	for _, name := range of.Volumes {
+		fields = append(fields, volumeField(name))
+	}
+	return fields
+}
+
+// writtenFields lists the env vars, volumes and volume mounts that differ
+// between the subjects.
+func writtenFields(before, after *duckv1.WithPod) []string {
+	var fields []string
+	diff := func(lhs, rhs map[string]interface{}, field func(string) string) {
+		for k, l := range lhs {
+			if r, ok := rhs[k]; !ok || !equality.Semantic.DeepEqual(l, r) {
+				fields = append(fields, field(k))
+			}
+		}
+		for k := range rhs {
+			if _, ok := lhs[k]; !ok {
+				fields = append(fields, field(k))
+			}
+		}
+	}
+
+	beforeContainers := make(map[string]corev1.Container)
+	for _, c := range allContainers(before) {
+		beforeContainers[c.Name] = c
+	}
+	for _, c := range allContainers(after) {
+		name := c.Name
+		b := beforeContainers[name]
+		diff(envByName(b.Env), envByName(c.Env), func(k string) string { return envField(name, k) })
+		diff(mountsByPath(b.VolumeMounts), mountsByPath(c.VolumeMounts), func(k string) string { return volumeMountField(name, k) })
+	}
+	diff(volumesByName(before.Spec.Template.Spec.Volumes), volumesByName(after.Spec.Template.Spec.Volumes), volumeField)
+	return fields
+}
+
+func envByName(env []corev1.EnvVar) map[string]interface{} {
+	m := make(map[string]interface{}, len(env))
+	for _, ev := range env {
+		m[ev.Name] = ev
+	}
+	return m
+}
+
+func mountsByPath(mounts []corev1.VolumeMount) map[string]interface{} {
+	m := make(map[string]interface{}, len(mounts))
+	for _, vm := range mounts {
+		m[vm.MountPath] = vm
+	}
+	return m
+}
+
+func volumesByName(volumes []corev1.Volume) map[string]interface{} {
+	m := make(map[string]interface{}, len(volumes))
+	for _, v := range volumes {
+		m[v.Name] = v
+	}
+	return m
+}
+
+// appliesTo returns whether the Bindable's subject reference covers the
+// resource of the given kind.
+func appliesTo(fb Bindable, gk schema.GroupKind, ps metav1.Object) (bool, error) {
+	ref := fb.GetSubject()
+	gv, err := schema.ParseGroupVersion(ref.APIVersion)
+	if err != nil {
+		return false, err
+	}
+	if gv.Group != gk.Group || ref.Kind != gk.Kind || ref.Namespace != ps.GetNamespace() {
+		return false, nil
+	}
+	if ref.Name != "" {
+		return ref.Name == ps.GetName(), nil
+	}
+	selector, err := metav1.LabelSelectorAsSelector(ref.Selector)
+	if err != nil {
+		return false, err
+	}
+	return selector.Matches(labels.Set(ps.GetLabels())), nil
+}
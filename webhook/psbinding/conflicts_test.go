@@ -0,0 +1,325 @@
+/*
+Copyright 2023 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package psbinding
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	jsonpatch "gomodules.xyz/jsonpatch/v2"
+	admissionv1 "k8s.io/api/admission/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/Yangfisher1/knative-common-pkg/apis"
+	"github.com/Yangfisher1/knative-common-pkg/apis/duck"
+	duckv1 "github.com/Yangfisher1/knative-common-pkg/apis/duck/v1"
+	duckv1alpha1 "github.com/Yangfisher1/knative-common-pkg/apis/duck/v1alpha1"
+	"github.com/Yangfisher1/knative-common-pkg/tracker"
+
+	. "github.com/Yangfisher1/knative-common-pkg/testing/duck"
+	. "github.com/Yangfisher1/knative-common-pkg/webhook/testing"
+)
+
+// prioritizedBindable is a TestBindable with a priority and owned fields.
+type prioritizedBindable struct {
+	*TestBindable
+	priority int
+	owned    OwnedFields
+}
+
+func (pb *prioritizedBindable) BindingPriority() int {
+	return pb.priority
+}
+
+func (pb *prioritizedBindable) OwnedFields() OwnedFields {
+	return pb.owned
+}
+
+// unreportedBindable is a TestBindable whose status can't report conflicts.
+type unreportedBindable struct {
+	*TestBindable
+}
+
+func (ub *unreportedBindable) GetBindingStatus() duck.BindableStatus {
+	return struct{ duck.BindableStatus }{ub.TestBindable.GetBindingStatus()}
+}
+
+func testBinding(name, foo string) *TestBindable {
+	return &TestBindable{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "ns",
+			Name:      name,
+		},
+		Spec: TestBindableSpec{
+			BindingSpec: duckv1alpha1.BindingSpec{
+				Subject: tracker.Reference{
+					APIVersion: "apps/v1",
+					Kind:       "Deployment",
+					Namespace:  "ns",
+					Name:       "subject",
+				},
+			},
+			Foo: foo,
+		},
+	}
+}
+
+func testSubject() *duckv1.WithPod {
+	return &duckv1.WithPod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "ns",
+			Name:      "subject",
+		},
+		Spec: duckv1.WithPodSpec{
+			Template: duckv1.PodSpecable{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{
+						Name:  "user",
+						Image: "busybox",
+					}},
+				},
+			},
+		},
+	}
+}
+
+func TestSortBindables(t *testing.T) {
+	a, b, c := testBinding("a", ""), testBinding("b", ""), testBinding("c", "")
+	fbs := []Bindable{
+		&prioritizedBindable{TestBindable: a, priority: 10},
+		c,
+		&prioritizedBindable{TestBindable: b, priority: -1},
+		a,
+	}
+	sortBindables(fbs)
+
+	var got []string
+	for _, fb := range fbs {
+		got = append(got, fb.GetName())
+	}
+	if want := []string{"b", "a", "c", "a"}; !cmp.Equal(got, want) {
+		t.Errorf("sortBindables() = %v, want %v", got, want)
+	}
+}
+
+func TestApplyBindables(t *testing.T) {
+	fooOfUser := envField("user", "FOO")
+
+	tests := []struct {
+		name      string
+		fbs       []Bindable
+		wantFoo   string
+		wantField []string
+	}{{
+		name:    "single binding",
+		fbs:     []Bindable{testBinding("a", "x")},
+		wantFoo: "x",
+	}, {
+		name:    "same value",
+		fbs:     []Bindable{testBinding("a", "x"), testBinding("b", "x")},
+		wantFoo: "x",
+	}, {
+		name:      "different values",
+		fbs:       []Bindable{testBinding("a", "x"), testBinding("b", "y")},
+		wantFoo:   "y",
+		wantField: []string{fooOfUser},
+	}, {
+		name: "declared owner",
+		fbs: []Bindable{
+			testBinding("a", "x"),
+			&prioritizedBindable{TestBindable: testBinding("b", "x"), owned: OwnedFields{Env: []string{"FOO"}}},
+		},
+		wantFoo:   "x",
+		wantField: []string{fooOfUser},
+	}, {
+		name: "declared volumes and mounts",
+		fbs: []Bindable{
+			&prioritizedBindable{TestBindable: testBinding("a", "x"), owned: OwnedFields{Volumes: []string{"v"}, VolumeMounts: []string{"/v"}}},
+			&prioritizedBindable{TestBindable: testBinding("b", "x"), owned: OwnedFields{Volumes: []string{"v"}, VolumeMounts: []string{"/v"}}},
+		},
+		wantFoo:   "x",
+		wantField: []string{volumeField("v"), volumeMountField("user", "/v")},
+	}}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ps := testSubject()
+			conflicts, err := applyBindables(context.Background(), nil, tc.fbs, withPodSubject{ps})
+			if err != nil {
+				t.Fatal("applyBindables() =", err)
+			}
+			if got := ps.Spec.Template.Spec.Containers[0].Env; len(got) != 1 || got[0].Value != tc.wantFoo {
+				t.Errorf("Env = %v, want FOO=%s", got, tc.wantFoo)
+			}
+			var got []string
+			for _, c := range conflicts {
+				got = append(got, c.Field)
+				if len(c.Bindables) != len(tc.fbs) {
+					t.Errorf("Conflict on %s involves %d bindables, want %d", c.Field, len(c.Bindables), len(tc.fbs))
+				}
+			}
+			if !cmp.Equal(got, tc.wantField) {
+				t.Errorf("Conflicting fields = %v, want %v", got, tc.wantField)
+			}
+		})
+	}
+}
+
+func TestAdmitOrderAndConflicts(t *testing.T) {
+	// The higher priority binding is applied last, whatever its name.
+	low := testBinding("z-low", "low")
+	high := &prioritizedBindable{TestBindable: testBinding("a-high", "high"), priority: 1}
+
+	ac := &Reconciler{}
+	ib := newIndexBuilder()
+	key := exactKey{Group: "apps", Kind: "Deployment", Namespace: "ns", Name: "subject"}
+	ib.associate(key, high).associate(key, low)
+	ib.build(&ac.index)
+
+	d := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "subject"},
+		Spec: appsv1.DeploymentSpec{
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "user", Image: "busybox"}},
+				},
+			},
+		},
+	}
+	b, err := json.Marshal(d)
+	if err != nil {
+		t.Fatal("Unable to serialize deployment:", err)
+	}
+	resp := ac.Admit(context.Background(), &admissionv1.AdmissionRequest{
+		Operation: admissionv1.Create,
+		Kind:      metav1.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"},
+		Namespace: "ns",
+		Object:    runtime.RawExtension{Raw: b},
+	})
+	ExpectAllowed(t, resp)
+	ExpectPatches(t, resp.Patch, []jsonpatch.JsonPatchOperation{{
+		Operation: "add",
+		Path:      "/spec/template/spec/containers/0/env",
+		Value: []interface{}{map[string]interface{}{
+			"name":  "FOO",
+			"value": "high",
+		}},
+	}})
+
+	want := []string{`binding conflict: env "FOO" of container "user" is written by ` +
+		`TestBindable ns/z-low, TestBindable ns/a-high, the last one wins`}
+	if !cmp.Equal(resp.Warnings, want) {
+		t.Error("Warnings (-want, +got):", cmp.Diff(want, resp.Warnings))
+	}
+
+	// The index is left as it was.
+	if got := ac.index.exact.get(key); got[0] != Bindable(high) {
+		t.Error("Admit() reordered the index")
+	}
+}
+
+func TestReportConflicts(t *testing.T) {
+	gk := schema.GroupKind{Group: "apps", Kind: "Deployment"}
+
+	tests := []struct {
+		name        string
+		fb          Bindable
+		others      []Bindable
+		wantStatus  corev1.ConditionStatus
+		wantMessage string
+		wantEvent   bool
+	}{{
+		name:       "no other bindings",
+		fb:         testBinding("a", "x"),
+		others:     []Bindable{testBinding("a", "x")},
+		wantStatus: corev1.ConditionTrue,
+	}, {
+		name:       "compatible bindings",
+		fb:         testBinding("a", "x"),
+		others:     []Bindable{testBinding("a", "x"), testBinding("b", "x")},
+		wantStatus: corev1.ConditionTrue,
+	}, {
+		name: "binding of another subject",
+		fb:   testBinding("a", "x"),
+		others: func() []Bindable {
+			other := testBinding("b", "y")
+			other.Spec.Subject.Name = "other"
+			return []Bindable{other}
+		}(),
+		wantStatus: corev1.ConditionTrue,
+	}, {
+		name:       "conflicting bindings",
+		fb:         testBinding("a", "x"),
+		others:     []Bindable{testBinding("b", "y")},
+		wantStatus: corev1.ConditionFalse,
+		wantMessage: `Deployment subject: env "FOO" of container "user" is written by ` +
+			`TestBindable ns/a, TestBindable ns/b, the last one wins`,
+	}, {
+		name:      "conflicting bindings without conflict status",
+		fb:        &unreportedBindable{testBinding("a", "x")},
+		others:    []Bindable{testBinding("b", "y")},
+		wantEvent: true,
+	}}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := record.NewFakeRecorder(10)
+			r := &BaseReconciler{
+				Recorder: recorder,
+				ListAll: func() ([]Bindable, error) {
+					return tc.others, nil
+				},
+			}
+			if err := r.reportConflicts(context.Background(), tc.fb, gk, []bindingSubject{withPodSubject{testSubject()}}); err != nil {
+				t.Fatal("reportConflicts() =", err)
+			}
+
+			if tc.wantEvent {
+				select {
+				case event := <-recorder.Events:
+					if !strings.Contains(event, "BindingConflict") {
+						t.Errorf("Event = %q, wanted a BindingConflict", event)
+					}
+				default:
+					t.Error("No event recorded")
+				}
+				return
+			}
+
+			status := tc.fb.(*TestBindable).Status
+			cond := status.GetCondition(TestBindableConflictFree)
+			if cond == nil {
+				t.Fatal("No ConflictFree condition")
+			}
+			if cond.Status != tc.wantStatus || cond.Message != tc.wantMessage {
+				t.Errorf("ConflictFree = %s %q, want %s %q", cond.Status, cond.Message, tc.wantStatus, tc.wantMessage)
+			}
+			// Conflicts don't affect readiness.
+			if ready := status.GetCondition(apis.ConditionReady); ready != nil {
+				t.Errorf("Ready = %v, wanted it untouched", ready)
+			}
+		})
+	}
+}
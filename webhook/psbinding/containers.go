@@ -0,0 +1,81 @@
+/*
+Copyright 2023 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package psbinding
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+
+	duckv1 "github.com/Yangfisher1/knative-common-pkg/apis/duck/v1"
+)
+
+// ContainerSelection selects the containers of a subject that a Binding
+// mutates, beyond its regular containers.
+type ContainerSelection struct {
+	// InitContainers selects the init containers.
+	InitContainers bool
+
+	// EphemeralContainers selects the ephemeral containers, which only
+	// bare Pods have.
+	EphemeralContainers bool
+}
+
+// DefaultContainerSelection is the ContainerSelection of contexts without one:
+// the regular and init containers.
+var DefaultContainerSelection = ContainerSelection{InitContainers: true}
+
+// containerSelectionKey is used as the key for associating a
+// ContainerSelection with a context.Context.
+type containerSelectionKey struct{}
+
+// WithContainerSelection returns a context passing the ContainerSelection
+// to Do and Undo, e.g. from the BindableContext of a Binding.
+func WithContainerSelection(ctx context.Context, cs ContainerSelection) context.Context {
+	return context.WithValue(ctx, containerSelectionKey{}, cs)
+}
+
+// GetContainerSelection returns the ContainerSelection of the context, or
+// DefaultContainerSelection.
+func GetContainerSelection(ctx context.Context) ContainerSelection {
+	if cs, ok := ctx.Value(containerSelectionKey{}).(ContainerSelection); ok {
+		return cs
+	}
+	return DefaultContainerSelection
+}
+
+// ForEachContainer calls f with each of the subject's containers selected by
+// the context's ContainerSelection, so that Do and Undo may mutate them.
+func ForEachContainer(ctx context.Context, ps *duckv1.WithPod, f func(*corev1.Container)) {
+	cs := GetContainerSelection(ctx)
+	spec := &ps.Spec.Template.Spec
+	if cs.InitContainers {
+		for i := range spec.InitContainers {
+			f(&spec.InitContainers[i])
+		}
+	}
+	for i := range spec.Containers {
+		f(&spec.Containers[i])
+	}
+	if cs.EphemeralContainers {
+		for i := range spec.EphemeralContainers {
+			c := corev1.Container(spec.EphemeralContainers[i].EphemeralContainerCommon)
+			f(&c)
+			spec.EphemeralContainers[i].EphemeralContainerCommon = corev1.EphemeralContainerCommon(c)
+		}
+	}
+}
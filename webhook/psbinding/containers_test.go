@@ -0,0 +1,94 @@
+/*
+Copyright 2023 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package psbinding
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+
+	duckv1 "github.com/Yangfisher1/knative-common-pkg/apis/duck/v1"
+)
+
+func TestForEachContainer(t *testing.T) {
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want []string
+	}{{
+		name: "default",
+		ctx:  context.Background(),
+		want: []string{"init", "user"},
+	}, {
+		name: "regular containers only",
+		ctx:  WithContainerSelection(context.Background(), ContainerSelection{}),
+		want: []string{"user"},
+	}, {
+		name: "all containers",
+		ctx: WithContainerSelection(context.Background(), ContainerSelection{
+			InitContainers:      true,
+			EphemeralContainers: true,
+		}),
+		want: []string{"init", "user", "debug"},
+	}}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ps := &duckv1.WithPod{
+				Spec: duckv1.WithPodSpec{
+					Template: duckv1.PodSpecable{
+						Spec: corev1.PodSpec{
+							InitContainers: []corev1.Container{{Name: "init"}},
+							Containers:     []corev1.Container{{Name: "user"}},
+							EphemeralContainers: []corev1.EphemeralContainer{{
+								EphemeralContainerCommon: corev1.EphemeralContainerCommon{Name: "debug"},
+							}},
+						},
+					},
+				},
+			}
+
+			var got []string
+			ForEachContainer(tc.ctx, ps, func(c *corev1.Container) {
+				got = append(got, c.Name)
+				c.Image = "bound"
+			})
+			if !cmp.Equal(got, tc.want) {
+				t.Errorf("ForEachContainer() visited %v, want %v", got, tc.want)
+			}
+
+			// The visited containers, and only those, are mutated.
+			var mutated []string
+			spec := ps.Spec.Template.Spec
+			for _, c := range append(spec.InitContainers, spec.Containers...) {
+				if c.Image == "bound" {
+					mutated = append(mutated, c.Name)
+				}
+			}
+			for _, ec := range spec.EphemeralContainers {
+				if ec.Image == "bound" {
+					mutated = append(mutated, ec.Name)
+				}
+			}
+			if !cmp.Equal(mutated, tc.want) {
+				t.Errorf("ForEachContainer() mutated %v, want %v", mutated, tc.want)
+			}
+		})
+	}
+}
@@ -84,6 +84,9 @@ func NewAdmissionController(
 	mwhInformer := mwhinformer.Get(ctx)
 	secretInformer := secretinformer.Get(ctx)
 	options := webhook.GetOptions(ctx)
+	if wo := webhook.GetAdmissionWebhookOptions(ctx); wo != nil {
+		reconcilerOptions = append([]ReconcilerOption{WithAdmissionWebhookOptions(*wo)}, reconcilerOptions...)
+	}
 
 	// Construct the reconciler for the mutating webhook configuration.
 	wh := NewReconciler(name, path, options.SecretName, client, mwhInformer.Lister(), secretInformer.Lister(), withContext, reconcilerOptions...)
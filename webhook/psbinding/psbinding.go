@@ -18,13 +18,11 @@ package psbinding
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"sort"
 	"strings"
 
 	"github.com/Yangfisher1/knative-common-pkg/apis/duck"
-	duckv1 "github.com/Yangfisher1/knative-common-pkg/apis/duck/v1"
 	"github.com/Yangfisher1/knative-common-pkg/controller"
 	"github.com/Yangfisher1/knative-common-pkg/logging"
 	"github.com/Yangfisher1/knative-common-pkg/ptr"
@@ -56,6 +54,14 @@ func WithSelector(s metav1.LabelSelector) ReconcilerOption {
 	}
 }
 
+// WithAdmissionWebhookOptions specifies the fields of the webhook to reconcile,
+// beyond its rules and client configuration.
+func WithAdmissionWebhookOptions(o webhook.AdmissionWebhookOptions) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.webhookOptions = &o
+	}
+}
+
 func NewReconciler(
 	name, path, secretName string,
 	client kubernetes.Interface,
@@ -113,7 +119,8 @@ type Reconciler struct {
 	// respective tasks.
 	WithContext BindableContext
 
-	selector metav1.LabelSelector
+	selector       metav1.LabelSelector
+	webhookOptions *webhook.AdmissionWebhookOptions
 
 	index index
 }
@@ -121,6 +128,7 @@ type Reconciler struct {
 var _ controller.Reconciler = (*Reconciler)(nil)
 var _ pkgreconciler.LeaderAware = (*Reconciler)(nil)
 var _ webhook.AdmissionController = (*Reconciler)(nil)
+var _ webhook.SideEffectingAdmissionController = (*Reconciler)(nil)
 
 // We need to specifically exclude our deployment(s) from consideration, but this provides a way
 // of excluding other things as well.
@@ -153,9 +161,9 @@ func (ac *Reconciler) Reconcile(ctx context.Context, key string) error {
 		logging.FromContext(ctx).Errorw("Error fetching secret", zap.Error(err))
 		return err
 	}
-	caCert, ok := secret.Data[certresources.CACert]
-	if !ok {
-		return fmt.Errorf("secret %q is missing %q key", ac.SecretName, certresources.CACert)
+	caCert, err := certresources.CABundle(secret)
+	if err != nil {
+		return err
 	}
 
 	// Reconcile the webhook configuration.
@@ -167,6 +175,13 @@ func (ac *Reconciler) Path() string {
 	return ac.HandlerPath
 }
 
+// HasSideEffects implements SideEffectingAdmissionController.  The patches
+// depend on the Bindables selecting the subjects, which change independently
+// of the requests, so the responses are never cached.
+func (ac *Reconciler) HasSideEffects(*admissionv1.AdmissionRequest) bool {
+	return true
+}
+
 // Admit implements AdmissionController
 func (ac *Reconciler) Admit(ctx context.Context, request *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
 	switch request.Operation {
@@ -176,8 +191,13 @@ func (ac *Reconciler) Admit(ctx context.Context, request *admissionv1.AdmissionR
 		return &admissionv1.AdmissionResponse{Allowed: true}
 	}
 
-	orig := &duckv1.WithPod{}
-	if err := json.Unmarshal(request.Object.Raw, orig); err != nil {
+	// Decode the subject according to its shape, e.g. CronJobs bind their
+	// job template.
+	gk := schema.GroupKind{Group: request.Kind.Group, Kind: request.Kind.Kind}
+	_, endDecode := webhook.StartPhase(ctx, request, webhook.PhaseDecode)
+	orig, err := decodeSubject(gk, request.Object.Raw)
+	endDecode(err)
+	if err != nil {
 		return webhook.MakeErrorStatus("unable to decode object: %v", err)
 	}
 
@@ -186,48 +206,46 @@ func (ac *Reconciler) Admit(ctx context.Context, request *admissionv1.AdmissionR
 		Group:     request.Kind.Group,
 		Kind:      request.Kind.Kind,
 		Namespace: request.Namespace,
-		Name:      orig.Name},
-		labels.Set(orig.Labels))
+		Name:      orig.GetName()},
+		labels.Set(orig.GetLabels()))
 	if len(fbs) == 0 {
 		// This doesn't apply!
 		return &admissionv1.AdmissionResponse{Allowed: true}
 	}
 
-	// Copy the subject state.
-	mutated := orig.DeepCopy()
-
-	// Apply the Bindables to the copy of the subject state. If conflicts occur, for example because multiple Bindables
-	// make incompatible changes, the reconciler will attempt to correct the state later.
-	for _, fb := range fbs {
-		var bindingContext context.Context
-		// Callback into the user's code to setup the context with additional
-		// information needed to perform the mutation.
-		if ac.WithContext != nil {
-			var err error
-			bindingContext, err = ac.WithContext(ctx, fb)
-			if err != nil {
-				return webhook.MakeErrorStatus("unable to setup binding context: %v", err)
-			}
-		} else {
-			bindingContext = ctx
-		}
+	// Apply the Bindables in a deterministic order, copying the slice so that
+	// sorting doesn't modify the index.
+	fbs = append([]Bindable(nil), fbs...)
+	sortBindables(fbs)
+
+	// Apply the Bindables to a copy of the subject state, according to their
+	// deletion state.
+	mutated := copySubject(orig)
+	bindCtx, endBind := webhook.StartPhase(ctx, request, webhook.PhaseBind)
+	conflicts, err := applyBindables(bindCtx, ac.WithContext, fbs, mutated)
+	endBind(err)
+	if err != nil {
+		return webhook.MakeErrorStatus("unable to setup binding context: %v", err)
+	}
 
-		// Mutate the copy of the subject state according to the deletion state of the Bindable.
-		if fb.GetDeletionTimestamp() != nil {
-			fb.Undo(bindingContext, mutated)
-		} else {
-			fb.Do(bindingContext, mutated)
-		}
+	// Surface conflicts, e.g. when several Bindables set the same env var,
+	// as warnings.  The BaseReconciler reports them in the Bindables' status.
+	var warnings []string
+	for _, c := range conflicts {
+		warnings = append(warnings, "binding conflict: "+c.String())
 	}
 
 	// Synthesize a patch from the changes and return it in our AdmissionResponse
-	patchBytes, err := duck.CreateBytePatch(orig, mutated)
+	_, endPatch := webhook.StartPhase(ctx, request, webhook.PhasePatch)
+	patchBytes, err := duck.CreateBytePatch(orig.object(), mutated.object())
+	endPatch(err)
 	if err != nil {
 		return webhook.MakeErrorStatus("unable to create patch with binding: %v", err)
 	}
 	return &admissionv1.AdmissionResponse{
-		Patch:   patchBytes,
-		Allowed: true,
+		Patch:    patchBytes,
+		Allowed:  true,
+		Warnings: warnings,
 		PatchType: func() *admissionv1.PatchType {
 			pt := admissionv1.PatchTypeJSONPatch
 			return &pt
@@ -301,14 +319,15 @@ func (ac *Reconciler) reconcileMutatingWebhook(ctx context.Context, caCert []byt
 		plural := strings.ToLower(flect.Pluralize(gk.Kind))
 
 		rules = append(rules, admissionregistrationv1.RuleWithOperations{
-			Operations: []admissionregistrationv1.OperationType{
+			Operations: ac.webhookOptions.Operations(gk,
 				admissionregistrationv1.Create,
 				admissionregistrationv1.Update,
-			},
+			),
 			Rule: admissionregistrationv1.Rule{
 				APIGroups:   []string{gk.Group},
 				APIVersions: versions.List(),
 				Resources:   []string{plural + "/*"},
+				Scope:       ac.webhookOptions.Scope(gk, nil),
 			},
 		})
 	}
@@ -340,12 +359,18 @@ func (ac *Reconciler) reconcileMutatingWebhook(ctx context.Context, caCert []byt
 			continue
 		}
 		cur := &current.Webhooks[i]
+		cur.MatchPolicy = &matchPolicy
+		ac.webhookOptions.ApplyToMutatingWebhook(cur)
+
 		selector := webhook.EnsureLabelSelectorExpressions(cur.NamespaceSelector, &ac.selector)
+		objectSelector := selector
+		if ac.webhookOptions != nil && ac.webhookOptions.ObjectSelector != nil {
+			objectSelector = webhook.EnsureLabelSelectorExpressions(cur.ObjectSelector, &ac.selector)
+		}
 
-		cur.MatchPolicy = &matchPolicy
 		cur.Rules = rules
 		cur.NamespaceSelector = selector
-		cur.ObjectSelector = selector // 1.15+ only
+		cur.ObjectSelector = objectSelector // 1.15+ only
 		cur.ClientConfig.CABundle = caCert
 		if cur.ClientConfig.Service == nil {
 			return fmt.Errorf("missing service reference for webhook: %s", wh.Name)
@@ -21,6 +21,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"reflect"
+	"strings"
 
 	"github.com/Yangfisher1/knative-common-pkg/apis"
 	"github.com/Yangfisher1/knative-common-pkg/apis/duck"
@@ -36,6 +37,7 @@ import (
 	"k8s.io/apimachinery/pkg/api/equality"
 	apierrs "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/sets"
@@ -85,6 +87,12 @@ type BaseReconciler struct {
 	// encounter.
 	Factory duck.InformerFactory
 
+	// SubjectFactories optionally produces the listers of the subjects not
+	// shaped like duckv1.WithPod, keyed by their GroupKind, e.g. a
+	// duck.TypedInformerFactory of duckv1.CronJob for batch CronJobs.
+	// Factory is used for other subjects.
+	SubjectFactories map[schema.GroupKind]duck.InformerFactory
+
 	// The tracker builds an index of what resources are watching other
 	// resources so that we can immediately react to changes to changes in
 	// tracked resources.
@@ -99,6 +107,12 @@ type BaseReconciler struct {
 
 	// Sub-resources reconciler. Used to reconcile Binding related resources
 	SubResourcesReconciler SubResourcesReconcilerInterface
+
+	// ListAll optionally enumerates the Bindables that may apply to the
+	// same subjects, so that the conflicts between them are reported in
+	// the status of the Bindables implementing duck.BindableConflictStatus,
+	// or else as events.
+	ListAll ListAll
 }
 
 // Check that our Reconciler implements controller.Reconciler
@@ -329,11 +343,16 @@ func (r *BaseReconciler) ReconcileSubject(ctx context.Context, fb Bindable, muta
 		logging.FromContext(ctx).Errorf("Error parsing GroupVersion %v: %v", subject.APIVersion, err)
 		return err
 	}
+	gk := gv.WithKind(subject.Kind).GroupKind()
 	gvr := apis.KindToResource(gv.WithKind(subject.Kind))
 
 	// Use the GVR of the subject(s) to get ahold of a lister that we can
 	// use to fetch our PodSpecable resources.
-	_, lister, err := r.Factory.Get(ctx, gvr)
+	factory := r.Factory
+	if f, ok := r.SubjectFactories[gk]; ok {
+		factory = f
+	}
+	_, lister, err := factory.Get(ctx, gvr)
 	if err != nil {
 		logging.FromContext(ctx).Errorf("Error getting a lister for resource '%+v': %v", gvr, err)
 		fb.GetBindingStatus().MarkBindingUnavailable("SubjectUnavailable", err.Error())
@@ -341,7 +360,7 @@ func (r *BaseReconciler) ReconcileSubject(ctx context.Context, fb Bindable, muta
 	}
 
 	// Based on the type of subject reference, build up a list of referents.
-	var referents []*duckv1.WithPod
+	var psObjs []runtime.Object
 	if subject.Name != "" {
 		// If name is specified, then fetch it from the lister and turn
 		// it into a singleton list.
@@ -356,7 +375,7 @@ func (r *BaseReconciler) ReconcileSubject(ctx context.Context, fb Bindable, muta
 		if err != nil {
 			return err
 		}
-		referents = append(referents, psObj.(*duckv1.WithPod))
+		psObjs = append(psObjs, psObj)
 	} else {
 		// Otherwise, the subject is referenced by selector, so compile
 		// the selector and pass it to the lister.
@@ -364,7 +383,7 @@ func (r *BaseReconciler) ReconcileSubject(ctx context.Context, fb Bindable, muta
 		if err != nil {
 			return err
 		}
-		psObjs, err := lister.ByNamespace(subject.Namespace).List(selector)
+		psObjs, err = lister.ByNamespace(subject.Namespace).List(selector)
 		if err != nil {
 			return fmt.Errorf("error fetching Pod Speccable %v: %w", subject, err)
 		}
@@ -372,9 +391,21 @@ func (r *BaseReconciler) ReconcileSubject(ctx context.Context, fb Bindable, muta
 		if err != nil {
 			return err
 		}
-		// Type cast the returned resources into our referent list.
-		for _, psObj := range psObjs {
-			referents = append(referents, psObj.(*duckv1.WithPod))
+	}
+	// Adapt the returned resources into our referent list, according to
+	// their shape.
+	referents := make([]bindingSubject, 0, len(psObjs))
+	for _, psObj := range psObjs {
+		ps, err := asSubject(psObj)
+		if err != nil {
+			return err
+		}
+		referents = append(referents, ps)
+	}
+
+	if r.ListAll != nil && fb.GetDeletionTimestamp() == nil {
+		if err := r.reportConflicts(ctx, fb, gk, referents); err != nil {
+			return err
 		}
 	}
 
@@ -387,23 +418,31 @@ func (r *BaseReconciler) ReconcileSubject(ctx context.Context, fb Bindable, muta
 		}
 	}
 
+	// The containers of existing Pods are immutable, so bare Pods are only
+	// bound by the webhook, when they are created.
+	if gk == podGK {
+		referents = nil
+	}
+
 	// For each of the referents, apply the mutation.
 	eg := errgroup.Group{}
 	for _, ps := range referents {
-		ps := ps
+		ps := copySubject(ps)
 		eg.Go(func() error {
 			// Do the binding to the pod specable.
-			orig := ps.DeepCopy()
-			mutation(ctx, ps)
+			orig := copySubject(ps)
+			ps.bind(func(wp *duckv1.WithPod) {
+				mutation(ctx, wp)
+			})
 
 			// If nothing changed, then bail early.
-			if equality.Semantic.DeepEqual(orig, ps) {
+			if equality.Semantic.DeepEqual(orig.object(), ps.object()) {
 				return nil
 			}
 
 			// If we encountered changes, then synthesize and apply
 			// a patch.
-			patchBytes, err := duck.CreateBytePatch(orig, ps)
+			patchBytes, err := duck.CreateBytePatch(orig.object(), ps.object())
 			if err != nil {
 				return err
 			}
@@ -411,10 +450,10 @@ func (r *BaseReconciler) ReconcileSubject(ctx context.Context, fb Bindable, muta
 			// TODO(mattmoor): This might fail because a binding changed after
 			// a Job started or completed, which can be fine.  Consider treating
 			// certain error codes as acceptable.
-			_, err = r.DynamicClient.Resource(gvr).Namespace(ps.Namespace).Patch(
-				ctx, ps.Name, types.JSONPatchType, patchBytes, metav1.PatchOptions{})
+			_, err = r.DynamicClient.Resource(gvr).Namespace(ps.GetNamespace()).Patch(
+				ctx, ps.GetName(), types.JSONPatchType, patchBytes, metav1.PatchOptions{})
 			if err != nil {
-				return fmt.Errorf("failed binding subject %s: %w", ps.Name, err)
+				return fmt.Errorf("failed binding subject %s: %w", ps.GetName(), err)
 			}
 			return nil
 		})
@@ -429,6 +468,56 @@ func (r *BaseReconciler) ReconcileSubject(ctx context.Context, fb Bindable, muta
 	return nil
 }
 
+// reportConflicts reports the conflicts of the Binding with the other
+// Bindables applying to its subjects.
+func (r *BaseReconciler) reportConflicts(ctx context.Context, fb Bindable, gk schema.GroupKind, referents []bindingSubject) error {
+	all, err := r.ListAll()
+	if err != nil {
+		return err
+	}
+
+	var messages []string
+	for _, ps := range referents {
+		fbs := []Bindable{fb}
+		for _, other := range all {
+			if sameBindable(fb, other) {
+				continue
+			}
+			if ok, err := appliesTo(other, gk, ps); err != nil {
+				return err
+			} else if ok {
+				fbs = append(fbs, other)
+			}
+		}
+		if len(fbs) == 1 {
+			continue
+		}
+
+		sortBindables(fbs)
+		conflicts, err := applyBindables(ctx, r.WithContext, fbs, copySubject(ps))
+		if err != nil {
+			return err
+		}
+		for _, c := range conflicts {
+			if c.involves(fb) {
+				messages = append(messages, fmt.Sprintf("%s %s: %s", gk.Kind, ps.GetName(), c))
+			}
+		}
+	}
+
+	status, ok := fb.GetBindingStatus().(duck.BindableConflictStatus)
+	switch {
+	case len(messages) == 0 && ok:
+		status.MarkBindingNoConflict()
+	case len(messages) == 0:
+	case ok:
+		status.MarkBindingConflict(strings.Join(messages, "; "))
+	default:
+		r.Recorder.Event(fb, corev1.EventTypeWarning, "BindingConflict", strings.Join(messages, "; "))
+	}
+	return nil
+}
+
 // UpdateStatus updates the status of the resource.  Caller is responsible for
 // checking for semantic differences before calling.
 func (r *BaseReconciler) UpdateStatus(ctx context.Context, desired Bindable) error {
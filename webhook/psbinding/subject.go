@@ -0,0 +1,155 @@
+/*
+Copyright 2023 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package psbinding
+
+import (
+	"encoding/json"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	duckv1 "github.com/Yangfisher1/knative-common-pkg/apis/duck/v1"
+)
+
+var (
+	cronJobGK = schema.GroupKind{Group: "batch", Kind: "CronJob"}
+	podGK     = schema.GroupKind{Kind: "Pod"}
+)
+
+// bindingSubject adapts the shape of the resources that Bindables apply to,
+// e.g. Deployments, CronJobs or bare Pods, to the duckv1.WithPod that they
+// mutate.
+type bindingSubject interface {
+	metav1.Object
+
+	// object returns the resource itself.
+	object() runtime.Object
+
+	// bind applies the mutation to a WithPod view of the resource, and
+	// writes the mutated view back.
+	bind(mutation func(*duckv1.WithPod))
+}
+
+// newSubject returns an empty subject of the shape of the given kind.
+func newSubject(gk schema.GroupKind) bindingSubject {
+	switch gk {
+	case cronJobGK:
+		return cronJobSubject{&duckv1.CronJob{}}
+	case podGK:
+		return podSubject{&duckv1.Pod{}}
+	default:
+		// Deployments, Jobs, ReplicaSets, etc.
+		return withPodSubject{&duckv1.WithPod{}}
+	}
+}
+
+// decodeSubject decodes a resource of the given kind.
+func decodeSubject(gk schema.GroupKind, raw []byte) (bindingSubject, error) {
+	s := newSubject(gk)
+	if err := json.Unmarshal(raw, s.object()); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// asSubject wraps a resource listed by a duck.InformerFactory.
+func asSubject(obj runtime.Object) (bindingSubject, error) {
+	switch o := obj.(type) {
+	case *duckv1.WithPod:
+		return withPodSubject{o}, nil
+	case *duckv1.CronJob:
+		return cronJobSubject{o}, nil
+	case *duckv1.Pod:
+		return podSubject{o}, nil
+	default:
+		return nil, fmt.Errorf("unsupported subject type %T", obj)
+	}
+}
+
+// copySubject deep copies the subject.
+func copySubject(s bindingSubject) bindingSubject {
+	cp, _ := asSubject(s.object().DeepCopyObject())
+	return cp
+}
+
+type withPodSubject struct {
+	*duckv1.WithPod
+}
+
+func (s withPodSubject) object() runtime.Object {
+	return s.WithPod
+}
+
+func (s withPodSubject) bind(mutation func(*duckv1.WithPod)) {
+	mutation(s.WithPod)
+}
+
+// cronJobSubject binds the pod template of the CronJob's job template.
+type cronJobSubject struct {
+	*duckv1.CronJob
+}
+
+func (s cronJobSubject) object() runtime.Object {
+	return s.CronJob
+}
+
+func (s cronJobSubject) bind(mutation func(*duckv1.WithPod)) {
+	view := &duckv1.WithPod{
+		TypeMeta:   s.TypeMeta,
+		ObjectMeta: s.ObjectMeta,
+		Spec: duckv1.WithPodSpec{
+			Template: duckv1.PodSpecable(s.Spec.JobTemplate.Spec.Template),
+		},
+	}
+	mutation(view)
+	s.ObjectMeta = view.ObjectMeta
+	s.Spec.JobTemplate.Spec.Template = corev1.PodTemplateSpec(view.Spec.Template)
+}
+
+// podSubject binds the Pod itself, whose labels and annotations stand for
+// those of the pod template.
+type podSubject struct {
+	*duckv1.Pod
+}
+
+func (s podSubject) object() runtime.Object {
+	return s.Pod
+}
+
+func (s podSubject) bind(mutation func(*duckv1.WithPod)) {
+	view := &duckv1.WithPod{
+		TypeMeta:   s.TypeMeta,
+		ObjectMeta: s.ObjectMeta,
+		Spec: duckv1.WithPodSpec{
+			Template: duckv1.PodSpecable{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels:      s.Labels,
+					Annotations: s.Annotations,
+				},
+				Spec: s.Spec,
+			},
+		},
+	}
+	mutation(view)
+	s.ObjectMeta = view.ObjectMeta
+	s.Labels = view.Spec.Template.Labels
+	s.Annotations = view.Spec.Template.Annotations
+	s.Spec = view.Spec.Template.Spec
+}
@@ -0,0 +1,162 @@
+/*
+Copyright 2023 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package psbinding
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	jsonpatch "gomodules.xyz/jsonpatch/v2"
+	admissionv1 "k8s.io/api/admission/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	duckv1 "github.com/Yangfisher1/knative-common-pkg/apis/duck/v1"
+
+	. "github.com/Yangfisher1/knative-common-pkg/webhook/testing"
+)
+
+func TestAdmitSubjectShapes(t *testing.T) {
+	podSpec := corev1.PodSpec{
+		InitContainers: []corev1.Container{{Name: "init", Image: "busybox"}},
+		Containers:     []corev1.Container{{Name: "user", Image: "busybox"}},
+	}
+	fooEnv := []interface{}{map[string]interface{}{
+		"name":  "FOO",
+		"value": "bar",
+	}}
+
+	tests := []struct {
+		name   string
+		kind   metav1.GroupVersionKind
+		object interface{}
+		want   []jsonpatch.JsonPatchOperation
+	}{{
+		name: "job",
+		kind: metav1.GroupVersionKind{Group: "batch", Version: "v1", Kind: "Job"},
+		object: &batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "subject"},
+			Spec: batchv1.JobSpec{
+				Template: corev1.PodTemplateSpec{Spec: podSpec},
+			},
+		},
+		want: []jsonpatch.JsonPatchOperation{{
+			Operation: "add",
+			Path:      "/spec/template/spec/initContainers/0/env",
+			Value:     fooEnv,
+		}, {
+			Operation: "add",
+			Path:      "/spec/template/spec/containers/0/env",
+			Value:     fooEnv,
+		}},
+	}, {
+		name: "cronjob",
+		kind: metav1.GroupVersionKind{Group: "batch", Version: "v1", Kind: "CronJob"},
+		object: &batchv1.CronJob{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "subject"},
+			Spec: batchv1.CronJobSpec{
+				Schedule: "@daily",
+				JobTemplate: batchv1.JobTemplateSpec{
+					Spec: batchv1.JobSpec{
+						Template: corev1.PodTemplateSpec{Spec: podSpec},
+					},
+				},
+			},
+		},
+		want: []jsonpatch.JsonPatchOperation{{
+			Operation: "add",
+			Path:      "/spec/jobTemplate/spec/template/spec/initContainers/0/env",
+			Value:     fooEnv,
+		}, {
+			Operation: "add",
+			Path:      "/spec/jobTemplate/spec/template/spec/containers/0/env",
+			Value:     fooEnv,
+		}},
+	}, {
+		name: "pod",
+		kind: metav1.GroupVersionKind{Version: "v1", Kind: "Pod"},
+		object: &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "subject"},
+			Spec:       podSpec,
+		},
+		want: []jsonpatch.JsonPatchOperation{{
+			Operation: "add",
+			Path:      "/spec/initContainers/0/env",
+			Value:     fooEnv,
+		}, {
+			Operation: "add",
+			Path:      "/spec/containers/0/env",
+			Value:     fooEnv,
+		}},
+	}}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fb := testBinding("binding", "bar")
+			fb.Spec.Subject.APIVersion = metav1.GroupVersion{Group: tc.kind.Group, Version: tc.kind.Version}.String()
+			fb.Spec.Subject.Kind = tc.kind.Kind
+
+			ac := &Reconciler{}
+			newIndexBuilder().associate(exactKey{
+				Group:     tc.kind.Group,
+				Kind:      tc.kind.Kind,
+				Namespace: "ns",
+				Name:      "subject",
+			}, fb).build(&ac.index)
+
+			b, err := json.Marshal(tc.object)
+			if err != nil {
+				t.Fatal("Unable to serialize subject:", err)
+			}
+			resp := ac.Admit(context.Background(), &admissionv1.AdmissionRequest{
+				Operation: admissionv1.Create,
+				Kind:      tc.kind,
+				Namespace: "ns",
+				Object:    runtime.RawExtension{Raw: b},
+			})
+			ExpectAllowed(t, resp)
+			ExpectPatches(t, resp.Patch, tc.want)
+		})
+	}
+}
+
+func TestPodSubjectMetadata(t *testing.T) {
+	s := podSubject{&duckv1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "pod",
+			Labels: map[string]string{"app": "a"},
+		},
+	}}
+	s.bind(func(ps *duckv1.WithPod) {
+		if got := ps.Spec.Template.Labels["app"]; got != "a" {
+			t.Errorf("template label app = %q, want the Pod's", got)
+		}
+		ps.Spec.Template.Annotations = map[string]string{"bound": "true"}
+	})
+	if got := s.Annotations["bound"]; got != "true" {
+		t.Errorf("Pod annotations = %v, want the template's", s.Annotations)
+	}
+
+	cp := copySubject(s)
+	cp.SetName("copy")
+	if s.GetName() != "pod" {
+		t.Error("copySubject() aliased the subject")
+	}
+}
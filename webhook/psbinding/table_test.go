@@ -1036,6 +1036,130 @@ func TestWebhookReconcile(t *testing.T) {
 	}))
 }
 
+func TestWebhookReconcileWithAdmissionWebhookOptions(t *testing.T) {
+	name, path := "foo.bar.baz", "/blah"
+	secretName := "webhook-secret"
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      secretName,
+			Namespace: system.Namespace(),
+		},
+		Data: map[string][]byte{
+			certresources.CACert: []byte("present"),
+		},
+	}
+
+	exact := admissionregistrationv1.Exact
+	failurePolicy := admissionregistrationv1.Fail
+	namespaced := admissionregistrationv1.NamespacedScope
+	opts := webhook.AdmissionWebhookOptions{
+		ObjectSelector: &metav1.LabelSelector{
+			MatchLabels: map[string]string{"bind": "true"},
+		},
+		TimeoutSeconds: ptr.Int32(3),
+		FailurePolicy:  &failurePolicy,
+		MatchPolicy:    &exact,
+		Rules: map[schema.GroupKind]webhook.RuleOptions{
+			{Group: "random.knative.dev", Kind: "Knoodle"}: {
+				Operations: []admissionregistrationv1.OperationType{admissionregistrationv1.Create},
+				Scope:      &namespaced,
+			},
+		},
+	}
+
+	key := system.Namespace() + "/does not matter"
+
+	table := TableTest{{
+		Name: "declared fields are reconciled",
+		Key:  key,
+		Objects: []runtime.Object{secret,
+			&TestBindable{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "foo",
+					Name:      "bar",
+				},
+				Spec: TestBindableSpec{
+					BindingSpec: duckv1alpha1.BindingSpec{
+						Subject: tracker.Reference{
+							APIVersion: "random.knative.dev/v2beta3",
+							Kind:       "Knoodle",
+							Namespace:  "foo",
+							Name:       "baz",
+						},
+					},
+				},
+			},
+			&admissionregistrationv1.MutatingWebhookConfiguration{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: name,
+				},
+				Webhooks: []admissionregistrationv1.MutatingWebhook{{
+					Name: name,
+					ClientConfig: admissionregistrationv1.WebhookClientConfig{
+						Service: &admissionregistrationv1.ServiceReference{
+							Namespace: system.Namespace(),
+							Name:      "webhook",
+						},
+					},
+				}},
+			},
+		},
+		WantUpdates: []clientgotesting.UpdateActionImpl{{
+			Object: &admissionregistrationv1.MutatingWebhookConfiguration{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: name,
+				},
+				Webhooks: []admissionregistrationv1.MutatingWebhook{{
+					Name: name,
+					ClientConfig: admissionregistrationv1.WebhookClientConfig{
+						Service: &admissionregistrationv1.ServiceReference{
+							Namespace: system.Namespace(),
+							Name:      "webhook",
+							Path:      ptr.String(path),
+						},
+						CABundle: []byte("present"),
+					},
+					Rules: []admissionregistrationv1.RuleWithOperations{{
+						Operations: []admissionregistrationv1.OperationType{"CREATE"},
+						Rule: admissionregistrationv1.Rule{
+							APIGroups:   []string{"random.knative.dev"},
+							APIVersions: []string{"v2beta3"},
+							Resources:   []string{"knoodles/*"},
+							Scope:       &namespaced,
+						},
+					}},
+					MatchPolicy:       &exact,
+					FailurePolicy:     &failurePolicy,
+					TimeoutSeconds:    ptr.Int32(3),
+					NamespaceSelector: &ExclusionSelector,
+					// The exclusion is merged into the declared object selector.
+					ObjectSelector: &metav1.LabelSelector{
+						MatchLabels:      map[string]string{"bind": "true"},
+						MatchExpressions: ExclusionSelector.MatchExpressions,
+					},
+					ReinvocationPolicy: ptrReinvocationPolicyType(admissionregistrationv1.IfNeededReinvocationPolicy),
+				}},
+			},
+		}},
+	}}
+
+	table.Test(t, MakeFactory(func(ctx context.Context, listers *Listers, cmw configmap.Watcher) controller.Reconciler {
+		r := NewReconciler(name, path, secretName, kubeclient.Get(ctx), listers.GetMutatingWebhookConfigurationLister(), listers.GetSecretLister(), nil,
+			WithAdmissionWebhookOptions(opts))
+		r.ListAll = func() ([]Bindable, error) {
+			bl := make([]Bindable, 0)
+			for _, elt := range listers.GetDuckObjects() {
+				if b, ok := elt.(Bindable); ok {
+					bl = append(bl, b)
+				}
+			}
+			return bl, nil
+		}
+		return r
+	}))
+}
+
 func TestNew(t *testing.T) {
 	ctx, _ := SetupFakeContext(t)
 	ctx = webhook.WithOptions(ctx, webhook.Options{})
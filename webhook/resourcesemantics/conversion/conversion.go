@@ -53,13 +53,23 @@ func (r *reconciler) Convert(
 
 	result := make([]runtime.RawExtension, 0, len(req.Objects))
 
-	for _, obj := range req.Objects {
+	// Keep converting past failures so that the response details all the
+	// objects that failed, while the message remains that of the first.
+	for i, obj := range req.Objects {
 		converted, err := r.convert(ctx, obj, req.DesiredAPIVersion)
 		if err != nil {
-			logging.FromContext(ctx).Errorw("Conversion failed", zap.Error(err))
-			res.Result.Status = metav1.StatusFailure
-			res.Result.Message = err.Error()
-			break
+			logging.FromContext(ctx).Errorw("Conversion failed", zap.Int("index", i), zap.Error(err))
+			if res.Result.Status != metav1.StatusFailure {
+				res.Result.Status = metav1.StatusFailure
+				res.Result.Message = err.Error()
+				res.Result.Details = &metav1.StatusDetails{}
+			}
+			res.Result.Details.Causes = append(res.Result.Details.Causes, metav1.StatusCause{
+				Type:    conversionFailedCause,
+				Message: describeObject(obj) + ": " + err.Error(),
+				Field:   fmt.Sprintf("objects[%d]", i),
+			})
+			continue
 		}
 
 		result = append(result, converted)
@@ -69,6 +79,27 @@ func (r *reconciler) Convert(
 	return res
 }
 
+// conversionFailedCause is the type of the causes of a failed
+// ConversionResponse, one per object that failed to convert.
+const conversionFailedCause metav1.CauseType = "ConversionFailed"
+
+// describeObject returns the kind and namespaced name of the object, as far
+// as they can be parsed.
+func describeObject(in runtime.RawExtension) string {
+	var obj metav1.PartialObjectMetadata
+	if err := json.Unmarshal(in.Raw, &obj); err != nil {
+		return "object"
+	}
+	desc := "object"
+	if obj.Kind != "" {
+		desc = obj.Kind
+	}
+	if obj.Name != "" {
+		desc += fmt.Sprintf(" %q", obj.Namespace+"/"+obj.Name)
+	}
+	return desc
+}
+
 func (r *reconciler) convert(
 	ctx context.Context,
 	inRaw runtime.RawExtension,
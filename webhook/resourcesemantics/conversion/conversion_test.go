@@ -255,7 +255,7 @@ func TestConversionErrorBadGVK(t *testing.T) {
 			}
 
 			cmpOpts := []cmp.Option{
-				cmpopts.IgnoreFields(metav1.Status{}, "Message"),
+				cmpopts.IgnoreFields(metav1.Status{}, "Message", "Details"),
 				cmpopts.EquateEmpty(),
 			}
 
@@ -294,6 +294,13 @@ func TestConversionUnknownInputGVK(t *testing.T) {
 		Result: metav1.Status{
 			Message: "no conversion support for type [kind=Resource group=some.api.group.dev]",
 			Status:  metav1.StatusFailure,
+			Details: &metav1.StatusDetails{
+				Causes: []metav1.StatusCause{{
+					Type:    conversionFailedCause,
+					Message: "Resource: no conversion support for type [kind=Resource group=some.api.group.dev]",
+					Field:   "objects[0]",
+				}},
+			},
 		},
 	}
 
@@ -303,6 +310,53 @@ func TestConversionUnknownInputGVK(t *testing.T) {
 	}
 }
 
+func TestConversionReportsEveryFailure(t *testing.T) {
+	unknownObj := &unstructured.Unstructured{}
+	unknownObj.SetGroupVersionKind(schema.GroupVersionKind{
+		Group:   "some.api.group.dev",
+		Version: "v1",
+		Kind:    "Resource",
+	})
+	unknownObj.SetNamespace("ns")
+	unknownObj.SetName("unknown")
+
+	ctx, conversion := newConversion(t)
+
+	req := &apixv1.ConversionRequest{
+		UID:               "some-uid",
+		DesiredAPIVersion: testAPIVersion("v1"),
+		Objects: []runtime.RawExtension{
+			toRaw(t, internal.NewV2("bing")),
+			toRaw(t, unknownObj),
+			{Raw: []byte("{}")},
+		},
+	}
+
+	got := conversion.Convert(ctx, req)
+	if got.Result.Status != metav1.StatusFailure {
+		t.Fatalf("Status = %q, wanted %q", got.Result.Status, metav1.StatusFailure)
+	}
+	if got, want := got.Result.Message, "no conversion support for type [kind=Resource group=some.api.group.dev]"; got != want {
+		t.Errorf("Message = %q, wanted the first failure %q", got, want)
+	}
+	if got.Result.Details == nil {
+		t.Fatal("Details = nil, wanted a cause per failed object")
+	}
+	causes := got.Result.Details.Causes
+	if len(causes) != 2 {
+		t.Fatalf("Got %d causes, wanted 2: %v", len(causes), causes)
+	}
+	if got, want := causes[0].Field, "objects[1]"; got != want {
+		t.Errorf("Field = %q, wanted %q", got, want)
+	}
+	if got, want := causes[0].Message, `Resource "ns/unknown": no conversion support`; !strings.HasPrefix(got, want) {
+		t.Errorf("Message = %q, wanted prefix %q", got, want)
+	}
+	if got, want := causes[1].Field, "objects[2]"; got != want {
+		t.Errorf("Field = %q, wanted %q", got, want)
+	}
+}
+
 func TestConversionInvalidTypeMeta(t *testing.T) {
 	ctx, conversion := newConversionWithKinds(t, nil)
 
@@ -322,7 +376,7 @@ func TestConversionInvalidTypeMeta(t *testing.T) {
 	}
 
 	cmpOpts := []cmp.Option{
-		cmpopts.IgnoreFields(metav1.Status{}, "Message"),
+		cmpopts.IgnoreFields(metav1.Status{}, "Message", "Details"),
 		cmpopts.EquateEmpty(),
 	}
 
@@ -355,7 +409,7 @@ func TestConversionFailureToUnmarshalInput(t *testing.T) {
 	}
 
 	cmpOpts := []cmp.Option{
-		cmpopts.IgnoreFields(metav1.Status{}, "Message"),
+		cmpopts.IgnoreFields(metav1.Status{}, "Message", "Details"),
 		cmpopts.EquateEmpty(),
 	}
 
@@ -390,7 +444,7 @@ func TestConversionFailureToMarshalOutput(t *testing.T) {
 	}
 
 	cmpOpts := []cmp.Option{
-		cmpopts.IgnoreFields(metav1.Status{}, "Message"),
+		cmpopts.IgnoreFields(metav1.Status{}, "Message", "Details"),
 		cmpopts.EquateEmpty(),
 	}
 
@@ -445,7 +499,7 @@ func TestConversionFailureToConvert(t *testing.T) {
 			}
 
 			cmpOpts := []cmp.Option{
-				cmpopts.IgnoreFields(metav1.Status{}, "Message"),
+				cmpopts.IgnoreFields(metav1.Status{}, "Message", "Details"),
 				rawOpt,
 			}
 
@@ -497,6 +551,13 @@ func TestConversionFailureInvalidDesiredAPIVersion(t *testing.T) {
 				Result: metav1.Status{
 					Message: fmt.Sprintf("desired API version %q is not valid", test.version),
 					Status:  metav1.StatusFailure,
+					Details: &metav1.StatusDetails{
+						Causes: []metav1.StatusCause{{
+							Type:    conversionFailedCause,
+							Message: fmt.Sprintf("Resource: desired API version %q is not valid", test.version),
+							Field:   "objects[0]",
+						}},
+					},
 				},
 			}
 
@@ -562,7 +623,7 @@ func TestConversionMissingZygotes(t *testing.T) {
 			}
 
 			cmpOpts := []cmp.Option{
-				cmpopts.IgnoreFields(metav1.Status{}, "Message"),
+				cmpopts.IgnoreFields(metav1.Status{}, "Message", "Details"),
 				cmpopts.EquateEmpty(),
 			}
 
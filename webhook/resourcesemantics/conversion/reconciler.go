@@ -75,9 +75,9 @@ func (r *reconciler) Reconcile(ctx context.Context, key string) error {
 		return err
 	}
 
-	cacert, ok := secret.Data[certresources.CACert]
-	if !ok {
-		return fmt.Errorf("secret %q is missing %q key", r.secretName, certresources.CACert)
+	cacert, err := certresources.CABundle(secret)
+	if err != nil {
+		return err
 	}
 
 	return r.reconcileCRD(ctx, cacert, key)
@@ -30,9 +30,12 @@ import (
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/cache"
 
+	duckv1 "github.com/Yangfisher1/knative-common-pkg/apis/duck/v1"
+	"github.com/Yangfisher1/knative-common-pkg/apis/feature"
 	"github.com/Yangfisher1/knative-common-pkg/controller"
 	"github.com/Yangfisher1/knative-common-pkg/system"
 	"github.com/Yangfisher1/knative-common-pkg/webhook"
+	"github.com/Yangfisher1/knative-common-pkg/webhook/json"
 	"github.com/Yangfisher1/knative-common-pkg/webhook/resourcesemantics"
 )
 
@@ -82,7 +85,11 @@ func NewAdmissionController(
 
 		withContext:           wc,
 		disallowUnknownFields: disallowUnknownFields,
+		decodeLimits:          json.GetLimits(ctx),
+		features:              feature.GetStore(ctx),
+		services:              duckv1.GetServiceGetter(ctx),
 		secretName:            options.SecretName,
+		webhookOptions:        webhook.GetAdmissionWebhookOptions(ctx),
 
 		client:       client,
 		mwhlister:    mwhInformer.Lister(),
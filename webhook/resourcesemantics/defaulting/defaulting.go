@@ -25,7 +25,6 @@ import (
 
 	"github.com/gobuffalo/flect"
 	"go.uber.org/zap"
-	"gomodules.xyz/jsonpatch/v2"
 	admissionv1 "k8s.io/api/admission/v1"
 	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
 	corev1 "k8s.io/api/core/v1"
@@ -39,6 +38,8 @@ import (
 
 	"github.com/Yangfisher1/knative-common-pkg/apis"
 	"github.com/Yangfisher1/knative-common-pkg/apis/duck"
+	duckv1 "github.com/Yangfisher1/knative-common-pkg/apis/duck/v1"
+	"github.com/Yangfisher1/knative-common-pkg/apis/feature"
 	"github.com/Yangfisher1/knative-common-pkg/controller"
 	"github.com/Yangfisher1/knative-common-pkg/kmp"
 	"github.com/Yangfisher1/knative-common-pkg/logging"
@@ -64,13 +65,21 @@ type reconciler struct {
 	callbacks map[schema.GroupVersionKind]Callback
 
 	withContext func(context.Context) context.Context
+	features    *feature.Store
+
+	// services is attached to the context of the resources' SetDefaults
+	// and Validate, to check the ports of the Services they reference.
+	services duckv1.ServiceGetter
 
 	client       kubernetes.Interface
 	mwhlister    admissionlisters.MutatingWebhookConfigurationLister
 	secretlister corelisters.SecretLister
 
 	disallowUnknownFields bool
+	decodeLimits          json.Limits
 	secretName            string
+
+	webhookOptions *webhook.AdmissionWebhookOptions
 }
 
 // CallbackFunc is the function to be invoked.
@@ -84,6 +93,9 @@ type Callback struct {
 	// supportedVerbs are the verbs supported for the callback.
 	// The function will only be called on these actions.
 	supportedVerbs map[webhook.Operation]struct{}
+
+	// sideEffects is whether the function has side effects.
+	sideEffects bool
 }
 
 // NewCallback creates a new callback function to be invoked on supported verbs.
@@ -104,10 +116,19 @@ func NewCallback(function func(context.Context, *unstructured.Unstructured) erro
 	return Callback{function: function, supportedVerbs: m}
 }
 
+// WithSideEffects returns a copy of the callback declaring that its function
+// has side effects, so that the responses to the requests it is invoked on
+// are never cached.
+func (c Callback) WithSideEffects() Callback {
+	c.sideEffects = true
+	return c
+}
+
 var _ controller.Reconciler = (*reconciler)(nil)
 var _ pkgreconciler.LeaderAware = (*reconciler)(nil)
 var _ webhook.AdmissionController = (*reconciler)(nil)
 var _ webhook.StatelessAdmissionController = (*reconciler)(nil)
+var _ webhook.SideEffectingAdmissionController = (*reconciler)(nil)
 
 // Reconcile implements controller.Reconciler
 func (ac *reconciler) Reconcile(ctx context.Context, key string) error {
@@ -123,9 +144,9 @@ func (ac *reconciler) Reconcile(ctx context.Context, key string) error {
 		logger.Errorw("Error fetching secret", zap.Error(err))
 		return err
 	}
-	caCert, ok := secret.Data[certresources.CACert]
-	if !ok {
-		return fmt.Errorf("secret %q is missing %q key", ac.secretName, certresources.CACert)
+	caCert, err := certresources.CABundle(secret)
+	if err != nil {
+		return err
 	}
 
 	// Reconcile the webhook configuration.
@@ -137,8 +158,29 @@ func (ac *reconciler) Path() string {
 	return ac.path
 }
 
+// HasSideEffects implements SideEffectingAdmissionController
+func (ac *reconciler) HasSideEffects(req *admissionv1.AdmissionRequest) bool {
+	gvk := schema.GroupVersionKind{
+		Group:   req.Kind.Group,
+		Version: req.Kind.Version,
+		Kind:    req.Kind.Kind,
+	}
+	c, ok := ac.callbacks[gvk]
+	if !ok || !c.sideEffects {
+		return false
+	}
+	_, supported := c.supportedVerbs[req.Operation]
+	return supported
+}
+
 // Admit implements AdmissionController
 func (ac *reconciler) Admit(ctx context.Context, request *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	// Attach the feature flags and ServiceGetter first, so that the context
+	// function may use or override them.
+	ctx = ac.features.ToContext(ctx)
+	if ac.services != nil {
+		ctx = duckv1.WithServiceGetter(ctx, ac.services)
+	}
 	if ac.withContext != nil {
 		ctx = ac.withContext(ctx)
 	}
@@ -185,14 +227,15 @@ func (ac *reconciler) reconcileMutatingWebhook(ctx context.Context, caCert []byt
 		plural := strings.ToLower(flect.Pluralize(gvk.Kind))
 
 		rules = append(rules, admissionregistrationv1.RuleWithOperations{
-			Operations: []admissionregistrationv1.OperationType{
+			Operations: ac.webhookOptions.Operations(gvk.GroupKind(),
 				admissionregistrationv1.Create,
 				admissionregistrationv1.Update,
-			},
+			),
 			Rule: admissionregistrationv1.Rule{
 				APIGroups:   []string{gvk.Group},
 				APIVersions: []string{gvk.Version},
 				Resources:   []string{plural, plural + "/status"},
+				Scope:       ac.webhookOptions.Scope(gvk.GroupKind(), nil),
 			},
 		})
 	}
@@ -231,6 +274,7 @@ func (ac *reconciler) reconcileMutatingWebhook(ctx context.Context, caCert []byt
 		cur := &current.Webhooks[i]
 		cur.Rules = rules
 
+		ac.webhookOptions.ApplyToMutatingWebhook(cur)
 		cur.NamespaceSelector = webhook.EnsureLabelSelectorExpressions(
 			cur.NamespaceSelector,
 			&metav1.LabelSelector{
@@ -291,36 +335,15 @@ func (ac *reconciler) mutate(ctx context.Context, req *admissionv1.AdmissionRequ
 		return json.Marshal(patches)
 	}
 
-	// nil values denote absence of `old` (create) or `new` (delete) objects.
-	var oldObj, newObj resourcesemantics.GenericCRD
-
-	if len(newBytes) != 0 {
-		newObj = handler.DeepCopyObject().(resourcesemantics.GenericCRD)
-		err := json.Decode(newBytes, newObj, ac.disallowUnknownFields)
-		if err != nil {
-			return nil, fmt.Errorf("cannot decode incoming new object: %w", err)
-		}
-	}
-	if len(oldBytes) != 0 {
-		oldObj = handler.DeepCopyObject().(resourcesemantics.GenericCRD)
-		err := json.Decode(oldBytes, oldObj, ac.disallowUnknownFields)
-		if err != nil {
-			return nil, fmt.Errorf("cannot decode incoming old object: %w", err)
-		}
+	_, endDecode := webhook.StartPhase(ctx, req, webhook.PhaseDecode)
+	oldObj, newObj, err := ac.decode(handler, oldBytes, newBytes)
+	endDecode(err)
+	if err != nil {
+		return nil, err
 	}
-	var patches duck.JSONPatch
-
-	var err error
-	// Skip this step if the type we're dealing with is a duck type, since it is inherently
-	// incomplete and this will patch away all of the unspecified fields.
-	if _, ok := newObj.(duck.Populatable); !ok {
-		// Add these before defaulting fields, otherwise defaulting may cause an illegal patch
-		// because it expects the round tripped through Golang fields to be present already.
-		rtp, err := roundTripPatch(newBytes, newObj)
-		if err != nil {
-			return nil, fmt.Errorf("cannot create patch for round tripped newBytes: %w", err)
-		}
-		patches = append(patches, rtp...)
+	// None of the validators will accept a nil value for newObj.
+	if newObj == nil {
+		return nil, errMissingNewObject
 	}
 
 	// Set up the context for defaulting and validation
@@ -328,7 +351,9 @@ func (ac *reconciler) mutate(ctx context.Context, req *admissionv1.AdmissionRequ
 		// Copy the old object and set defaults so that we don't reject our own
 		// defaulting done earlier in the webhook.
 		oldObj = oldObj.DeepCopyObject().(resourcesemantics.GenericCRD)
-		oldObj.SetDefaults(ctx)
+		defaultsCtx, endDefaults := webhook.StartPhase(ctx, req, webhook.PhaseDefaults)
+		oldObj.SetDefaults(defaultsCtx)
+		endDefaults(nil)
 
 		s, ok := oldObj.(apis.HasSpec)
 		if ok {
@@ -345,17 +370,25 @@ func (ac *reconciler) mutate(ctx context.Context, req *admissionv1.AdmissionRequ
 	}
 	ctx = apis.WithUserInfo(ctx, &req.UserInfo)
 
-	// Default the new object.
-	if patches, err = setDefaults(ctx, patches, newObj); err != nil {
-		logger.Errorw("Failed the resource specific defaulter", zap.Error(err))
-		// Return the error message as-is to give the defaulter callback
-		// discretion over (our portion of) the message that the user sees.
+	// Default the new object, and annotate it with the user info. The patch is
+	// computed against the raw object, so that only the paths changed here are
+	// patched.
+	before, err := toJSONValue(newObj)
+	if err != nil {
 		return nil, err
 	}
-
-	if patches, err = ac.setUserInfoAnnotations(ctx, patches, newObj, req.Resource.Group); err != nil {
-		logger.Errorw("Failed the resource user info annotator", zap.Error(err))
-		return nil, err
+	defaultsCtx, endDefaults := webhook.StartPhase(ctx, req, webhook.PhaseDefaults)
+	newObj.SetDefaults(defaultsCtx)
+	endDefaults(nil)
+	if s, ok := newObj.(apis.HasSpec); ok {
+		setUserInfoAnnotations(ctx, s, req.Resource.Group)
+	}
+	_, endPatch := webhook.StartPhase(ctx, req, webhook.PhasePatch)
+	patches, err := defaultingPatch(newBytes, before, newObj)
+	endPatch(err)
+	if err != nil {
+		logger.Errorw("Failed to patch the defaulted resource", zap.Error(err))
+		return nil, fmt.Errorf("cannot create patch for defaulted object: %w", err)
 	}
 
 	if patches, err = ac.callback(ctx, gvk, req, false /* shouldSetUserInfo */, patches); err != nil {
@@ -365,31 +398,35 @@ func (ac *reconciler) mutate(ctx context.Context, req *admissionv1.AdmissionRequ
 		return nil, err
 	}
 
-	// None of the validators will accept a nil value for newObj.
-	if newObj == nil {
-		return nil, errMissingNewObject
-	}
 	return json.Marshal(patches)
 }
 
-func (ac *reconciler) setUserInfoAnnotations(ctx context.Context, patches duck.JSONPatch, new resourcesemantics.GenericCRD, groupName string) (duck.JSONPatch, error) {
-	if new == nil {
-		return patches, nil
+// decode decodes the old and new objects of a request into the type of the
+// handler, leaving them nil when absent.
+func (ac *reconciler) decode(handler resourcesemantics.GenericCRD, oldBytes, newBytes []byte) (oldObj, newObj resourcesemantics.GenericCRD, err error) {
+	if len(newBytes) != 0 {
+		newObj = handler.DeepCopyObject().(resourcesemantics.GenericCRD)
+		if err := json.DecodeWithLimits(newBytes, newObj, ac.disallowUnknownFields, ac.decodeLimits); err != nil {
+			return nil, nil, fmt.Errorf("cannot decode incoming new object: %w", err)
+		}
 	}
-	nh, ok := new.(apis.HasSpec)
-	if !ok {
-		return patches, nil
+	if len(oldBytes) != 0 {
+		oldObj = handler.DeepCopyObject().(resourcesemantics.GenericCRD)
+		if err := json.DecodeWithLimits(oldBytes, oldObj, ac.disallowUnknownFields, ac.decodeLimits); err != nil {
+			return nil, nil, fmt.Errorf("cannot decode incoming old object: %w", err)
+		}
 	}
+	return oldObj, newObj, nil
+}
 
-	b, a := new.DeepCopyObject().(apis.HasSpec), nh
-
-	setUserInfoAnnotations(ctx, nh, groupName)
-
-	patch, err := duck.CreatePatch(b, a)
+// defaultingPatch returns the patch of the raw object applying the changes
+// made to the defaulted object.
+func defaultingPatch(raw []byte, before interface{}, defaulted resourcesemantics.GenericCRD) (duck.JSONPatch, error) {
+	after, err := toJSONValue(defaulted)
 	if err != nil {
 		return nil, err
 	}
-	return append(patches, patch...), nil
+	return rawPatch(raw, before, after)
 }
 
 func (ac *reconciler) callback(ctx context.Context, gvk schema.GroupVersionKind, req *admissionv1.AdmissionRequest, shouldSetUserInfo bool, patches duck.JSONPatch) (duck.JSONPatch, error) {
@@ -411,7 +448,10 @@ func (ac *reconciler) callback(ctx context.Context, gvk schema.GroupVersionKind,
 	after := &unstructured.Unstructured{}
 
 	// Get unstructured object.
-	if err := json.Unmarshal(newBytes, before); err != nil {
+	_, endDecode := webhook.StartPhase(ctx, req, webhook.PhaseDecode)
+	err := json.Unmarshal(newBytes, before)
+	endDecode(err)
+	if err != nil {
 		return nil, fmt.Errorf("cannot decode object: %w", err)
 	}
 	// Copy before in after unstructured objects.
@@ -430,7 +470,10 @@ func (ac *reconciler) callback(ctx context.Context, gvk schema.GroupVersionKind,
 	ctx = apis.WithUserInfo(ctx, &req.UserInfo)
 
 	// Call callback passing after.
-	if err := callback.function(ctx, after); err != nil {
+	callbackCtx, endCallback := webhook.StartPhase(ctx, req, webhook.PhaseCallback)
+	err = callback.function(callbackCtx, after)
+	endCallback(err)
+	if err != nil {
 		return patches, err
 	}
 
@@ -439,41 +482,12 @@ func (ac *reconciler) callback(ctx context.Context, gvk schema.GroupVersionKind,
 	}
 
 	// Create patches.
+	_, endPatch := webhook.StartPhase(ctx, req, webhook.PhasePatch)
 	patch, err := duck.CreatePatch(before.Object, after.Object)
+	endPatch(err)
 	return append(patches, patch...), err
 }
 
-// roundTripPatch generates the JSONPatch that corresponds to round tripping the given bytes through
-// the Golang type (JSON -> Golang type -> JSON). Because it is not always true that
-// bytes == json.Marshal(json.Unmarshal(bytes)).
-//
-// For example, if bytes did not contain a 'spec' field and the Golang type specifies its 'spec'
-// field without omitempty, then by round tripping through the Golang type, we would have added
-// `'spec': {}`.
-func roundTripPatch(bytes []byte, unmarshalled interface{}) (duck.JSONPatch, error) {
-	if unmarshalled == nil {
-		return duck.JSONPatch{}, nil
-	}
-	marshaledBytes, err := json.Marshal(unmarshalled)
-	if err != nil {
-		return nil, fmt.Errorf("cannot marshal interface: %w", err)
-	}
-	return jsonpatch.CreatePatch(bytes, marshaledBytes)
-}
-
-// setDefaults simply leverages apis.Defaultable to set defaults.
-func setDefaults(ctx context.Context, patches duck.JSONPatch, crd resourcesemantics.GenericCRD) (duck.JSONPatch, error) {
-	before, after := crd.DeepCopyObject(), crd
-	after.SetDefaults(ctx)
-
-	patch, err := duck.CreatePatch(before, after)
-	if err != nil {
-		return nil, err
-	}
-
-	return append(patches, patch...), nil
-}
-
 func ptrReinvocationPolicyType(r admissionregistrationv1.ReinvocationPolicyType) *admissionregistrationv1.ReinvocationPolicyType {
 	return &r
 }
@@ -34,6 +34,7 @@ import (
 	_ "github.com/Yangfisher1/knative-common-pkg/injection/clients/namespacedkube/informers/core/v1/secret/fake"
 	"github.com/Yangfisher1/knative-common-pkg/ptr"
 
+	"go.opencensus.io/trace"
 	"gomodules.xyz/jsonpatch/v2"
 	admissionv1 "k8s.io/api/admission/v1"
 	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
@@ -43,6 +44,7 @@ import (
 	fakekubeclientset "k8s.io/client-go/kubernetes/fake"
 
 	"github.com/Yangfisher1/knative-common-pkg/apis"
+	"github.com/Yangfisher1/knative-common-pkg/apis/feature"
 	"github.com/Yangfisher1/knative-common-pkg/system"
 	"github.com/Yangfisher1/knative-common-pkg/webhook"
 
@@ -154,6 +156,33 @@ func TestDeleteAllowed(t *testing.T) {
 	}
 }
 
+func TestFeatureFlags(t *testing.T) {
+	ctx, _ := SetupFakeContext(t)
+	ctx = webhook.WithOptions(ctx, webhook.Options{
+		SecretName: "webhook-secret",
+	})
+	store := feature.NewStore(TestLogger(t), feature.Flags{"alpha": feature.Enabled})
+	ctx = feature.WithStore(ctx, store)
+
+	var got feature.Flags
+	ac := NewAdmissionController(
+		ctx, testResourceValidationName, testResourceValidationPath,
+		handlers, func(ctx context.Context) context.Context {
+			got = feature.FromContext(ctx)
+			return ctx
+		}, true, callbacks).Reconciler.(*reconciler)
+
+	req := &admissionv1.AdmissionRequest{
+		Operation: admissionv1.Delete,
+	}
+	if resp := ac.Admit(TestContextWithLogger(t), req); !resp.Allowed {
+		t.Fatal("Unexpected denial of delete")
+	}
+	if !got.IsEnabled("alpha") {
+		t.Errorf("The flags of the context = %v, want alpha enabled", got)
+	}
+}
+
 func TestConnectAllowed(t *testing.T) {
 	_, ac := newNonRunningTestResourceAdmissionController(t)
 
@@ -217,7 +246,7 @@ func TestUnknownFieldFails(t *testing.T) {
 	req.Object.Raw = marshaled
 
 	ExpectFailsWith(t, ac.Admit(TestContextWithLogger(t), req),
-		`mutation failed: cannot decode incoming new object: json: unknown field "foo"`)
+		`mutation failed: cannot decode incoming new object: must not set the field(s): spec.foo`)
 }
 
 func TestUnknownMetadataFieldSucceeds(t *testing.T) {
@@ -912,14 +941,14 @@ func TestValidCreateResourceSucceedsWithRoundTripAndDefaultPatch(t *testing.T) {
 	_, ac := newNonRunningTestResourceAdmissionController(t)
 	resp := ac.Admit(TestContextWithLogger(t), req)
 	ExpectAllowed(t, resp)
+	// The spec added by the round trip is only patched in along with its
+	// defaults.
 	ExpectPatches(t, resp.Patch, []jsonpatch.JsonPatchOperation{{
 		Operation: "add",
 		Path:      "/spec",
-		Value:     map[string]interface{}{},
-	}, {
-		Operation: "add",
-		Path:      "/spec/fieldWithDefault",
-		Value:     "I'm a default.",
+		Value: map[string]interface{}{
+			"fieldWithDefault": "I'm a default.",
+		},
 	}})
 }
 
@@ -966,6 +995,10 @@ func newTestResourceAdmissionController(t *testing.T) webhook.AdmissionControlle
 }
 
 func resourceCallback(ctx context.Context, uns *unstructured.Unstructured) error {
+	if trace.FromContext(ctx) == nil {
+		return errors.New("callback called outside of its span")
+	}
+
 	var resource Resource
 	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(uns.UnstructuredContent(), &resource); err != nil {
 		return err
@@ -1016,3 +1049,54 @@ func podCallback(ctx context.Context, u *unstructured.Unstructured) error {
 
 	return nil
 }
+
+func TestHasSideEffects(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "pkg.knative.dev", Version: "v1alpha1", Kind: "Resource"}
+	other := schema.GroupVersionKind{Group: "pkg.knative.dev", Version: "v1alpha1", Kind: "Other"}
+	ac := &reconciler{
+		callbacks: map[schema.GroupVersionKind]Callback{
+			gvk:   NewCallback(resourceCallback, webhook.Create).WithSideEffects(),
+			other: NewCallback(resourceCallback, webhook.Create),
+		},
+	}
+
+	tests := []struct {
+		name      string
+		kind      schema.GroupVersionKind
+		operation admissionv1.Operation
+		want      bool
+	}{{
+		name:      "side effects on the operation",
+		kind:      gvk,
+		operation: admissionv1.Create,
+		want:      true,
+	}, {
+		name:      "operation not supported by the callback",
+		kind:      gvk,
+		operation: admissionv1.Update,
+	}, {
+		name:      "callback without side effects",
+		kind:      other,
+		operation: admissionv1.Create,
+	}, {
+		name:      "no callback",
+		kind:      schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"},
+		operation: admissionv1.Create,
+	}}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := &admissionv1.AdmissionRequest{
+				Kind: metav1.GroupVersionKind{
+					Group:   tc.kind.Group,
+					Version: tc.kind.Version,
+					Kind:    tc.kind.Kind,
+				},
+				Operation: tc.operation,
+			}
+			if got := ac.HasSideEffects(req); got != tc.want {
+				t.Errorf("HasSideEffects() = %t, wanted %t", got, tc.want)
+			}
+		})
+	}
+}
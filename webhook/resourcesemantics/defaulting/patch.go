@@ -0,0 +1,210 @@
+/*
+Copyright 2023 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package defaulting
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gomodules.xyz/jsonpatch/v2"
+	"k8s.io/apimachinery/pkg/api/equality"
+
+	"github.com/Yangfisher1/knative-common-pkg/apis/duck"
+	"github.com/Yangfisher1/knative-common-pkg/webhook/json"
+)
+
+// toJSONValue returns the generic JSON representation of the object, as
+// decoded into interface{}.
+func toJSONValue(obj interface{}) (interface{}, error) {
+	b, err := json.Marshal(obj)
+	if err != nil {
+		return nil, fmt.Errorf("cannot marshal interface: %w", err)
+	}
+	var v interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// rawPatch returns the patch of the raw JSON of an object which applies the
+// changes made to its Go representation, from before to after.
+//
+// Patching the raw JSON, rather than the Go representation, leaves the fields
+// the Go type doesn't know about, e.g. from a newer version of the CRD, and
+// the paths that weren't changed untouched.  Since removing the former is
+// never intended, patches removing or replacing them are rejected.
+func rawPatch(raw []byte, before, after interface{}) (duck.JSONPatch, error) {
+	var original interface{}
+	if err := json.Unmarshal(raw, &original); err != nil {
+		return nil, fmt.Errorf("cannot decode object: %w", err)
+	}
+
+	merged, err := json.Marshal(applyChanges(original, before, after))
+	if err != nil {
+		return nil, fmt.Errorf("cannot marshal interface: %w", err)
+	}
+	patch, err := jsonpatch.CreatePatch(raw, merged)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, op := range patch {
+		if op.Operation != "remove" && op.Operation != "replace" {
+			continue
+		}
+		if field, ok := unknownField(lookup(original, op.Path), lookup(before, op.Path)); ok {
+			return nil, fmt.Errorf("refusing to remove %s%s, which is unknown to the type of the object", op.Path, field)
+		}
+	}
+	return patch, nil
+}
+
+// unknownField returns the path, relative to the raw value, of a field that
+// isn't in the known value, if any.
+func unknownField(raw, known interface{}) (string, bool) {
+	switch r := raw.(type) {
+	case map[string]interface{}:
+		k, _ := known.(map[string]interface{})
+		for name, rv := range r {
+			if field, ok := unknownField(rv, k[name]); ok {
+				return "/" + escape(name) + field, true
+			}
+		}
+	case []interface{}:
+		k, _ := known.([]interface{})
+		for i, rv := range r {
+			if i >= len(k) {
+				return "/" + strconv.Itoa(i), true
+			}
+			if field, ok := unknownField(rv, k[i]); ok {
+				return "/" + strconv.Itoa(i) + field, true
+			}
+		}
+	default:
+		// Explicit nulls and, with omitempty, zero values are dropped by the
+		// round trip; other values the type doesn't know are unknown.
+		if known == nil && !isZero(r) {
+			return "", true
+		}
+	}
+	return "", false
+}
+
+// isZero returns whether the raw scalar is null or a zero value.
+func isZero(raw interface{}) bool {
+	switch r := raw.(type) {
+	case bool:
+		return !r
+	case float64:
+		return r == 0
+	case string:
+		return r == ""
+	}
+	return raw == nil
+}
+
+func escape(token string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1")
+}
+
+// applyChanges applies the changes from before to after to the raw value,
+// recursing into the maps and the elements of the arrays that were changed.
+func applyChanges(raw, before, after interface{}) interface{} {
+	if equality.Semantic.DeepEqual(before, after) {
+		return raw
+	}
+
+	switch a := after.(type) {
+	case map[string]interface{}:
+		b, ok := before.(map[string]interface{})
+		if !ok {
+			return after
+		}
+		// Fields added by the round trip through the Go type, e.g. `spec: {}`,
+		// are only added to the raw value when something was set in them.
+		r, ok := raw.(map[string]interface{})
+		if !ok {
+			r = make(map[string]interface{}, len(a))
+		}
+		merged := make(map[string]interface{}, len(r))
+		for k, v := range r {
+			merged[k] = v
+		}
+		for k, av := range a {
+			bv, hadValue := b[k]
+			switch {
+			case !hadValue:
+				merged[k] = av
+			case !equality.Semantic.DeepEqual(av, bv):
+				merged[k] = applyChanges(r[k], bv, av)
+			}
+		}
+		for k := range b {
+			if _, ok := a[k]; !ok {
+				delete(merged, k)
+			}
+		}
+		return merged
+
+	case []interface{}:
+		b, ok := before.([]interface{})
+		if !ok {
+			return after
+		}
+		r, ok := raw.([]interface{})
+		if !ok || len(r) != len(b) {
+			return after
+		}
+		merged := make([]interface{}, 0, len(a))
+		for i, av := range a {
+			if i < len(b) {
+				merged = append(merged, applyChanges(r[i], b[i], av))
+			} else {
+				merged = append(merged, av)
+			}
+		}
+		return merged
+
+	default:
+		return after
+	}
+}
+
+// lookup returns the value at the JSON pointer path in the value, or nil.
+func lookup(value interface{}, path string) interface{} {
+	if path == "" {
+		return value
+	}
+	for _, token := range strings.Split(strings.TrimPrefix(path, "/"), "/") {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		switch v := value.(type) {
+		case map[string]interface{}:
+			value = v[token]
+		case []interface{}:
+			i, err := strconv.Atoi(token)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil
+			}
+			value = v[i]
+		default:
+			return nil
+		}
+	}
+	return value
+}
@@ -0,0 +1,146 @@
+/*
+Copyright 2023 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package defaulting
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"gomodules.xyz/jsonpatch/v2"
+
+	. "github.com/Yangfisher1/knative-common-pkg/webhook/testing"
+)
+
+func TestRawPatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		before  string
+		after   string
+		want    []jsonpatch.JsonPatchOperation
+		wantErr string
+	}{{
+		name:   "nothing defaulted",
+		raw:    `{"spec":{"replicas":1.0,"newField":"x"}}`,
+		before: `{"metadata":{},"spec":{"replicas":1}}`,
+		after:  `{"metadata":{},"spec":{"replicas":1}}`,
+	}, {
+		name:   "unknown fields are preserved",
+		raw:    `{"spec":{"newField":{"a":1}}}`,
+		before: `{"spec":{}}`,
+		after:  `{"spec":{"replicas":1}}`,
+		want: []jsonpatch.JsonPatchOperation{{
+			Operation: "add",
+			Path:      "/spec/replicas",
+			Value:     float64(1),
+		}},
+	}, {
+		name:   "fields added by the round trip",
+		raw:    `{"metadata":{"name":"a"}}`,
+		before: `{"metadata":{"name":"a"},"spec":{},"status":{}}`,
+		after:  `{"metadata":{"name":"a"},"spec":{"replicas":1},"status":{}}`,
+		want: []jsonpatch.JsonPatchOperation{{
+			Operation: "add",
+			Path:      "/spec",
+			Value:     map[string]interface{}{"replicas": float64(1)},
+		}},
+	}, {
+		name:   "array elements",
+		raw:    `{"spec":{"containers":[{"name":"a","newField":1},{"name":"b"}]}}`,
+		before: `{"spec":{"containers":[{"name":"a"},{"name":"b"}]}}`,
+		after:  `{"spec":{"containers":[{"name":"a","image":"i"},{"name":"b"},{"name":"c"}]}}`,
+		want: []jsonpatch.JsonPatchOperation{{
+			Operation: "add",
+			Path:      "/spec/containers/0/image",
+			Value:     "i",
+		}, {
+			Operation: "add",
+			Path:      "/spec/containers/2",
+			Value:     map[string]interface{}{"name": "c"},
+		}},
+	}, {
+		name:   "known field removed",
+		raw:    `{"spec":{"replicas":1,"newField":1}}`,
+		before: `{"spec":{"replicas":1}}`,
+		after:  `{"spec":{}}`,
+		want: []jsonpatch.JsonPatchOperation{{
+			Operation: "remove",
+			Path:      "/spec/replicas",
+		}},
+	}, {
+		name:    "unknown field removed",
+		raw:     `{"spec":{"template":{"newField":1}}}`,
+		before:  `{"spec":{"template":{}}}`,
+		after:   `{"spec":{"template":"replaced"}}`,
+		wantErr: "refusing to remove /spec/template/newField",
+	}, {
+		name:    "unknown value replaced",
+		raw:     `{"spec":{"mode":"custom"}}`,
+		before:  `{"spec":{}}`,
+		after:   `{"spec":{"mode":"default"}}`,
+		wantErr: "refusing to remove /spec/mode, which is unknown",
+	}, {
+		name:   "omitted zero value replaced",
+		raw:    `{"spec":{"mode":"","replicas":0}}`,
+		before: `{"spec":{}}`,
+		after:  `{"spec":{"mode":"default","replicas":1}}`,
+		want: []jsonpatch.JsonPatchOperation{{
+			Operation: "replace",
+			Path:      "/spec/mode",
+			Value:     "default",
+		}, {
+			Operation: "replace",
+			Path:      "/spec/replicas",
+			Value:     float64(1),
+		}},
+	}, {
+		name:    "unknown array elements removed",
+		raw:     `{"spec":{"args":["a","b","c"]}}`,
+		before:  `{"spec":{"args":["a","b"]}}`,
+		after:   `{"spec":"replaced"}`,
+		wantErr: "refusing to remove /spec/args/2",
+	}}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var before, after interface{}
+			if err := json.Unmarshal([]byte(tc.before), &before); err != nil {
+				t.Fatal("Unmarshal(before) =", err)
+			}
+			if err := json.Unmarshal([]byte(tc.after), &after); err != nil {
+				t.Fatal("Unmarshal(after) =", err)
+			}
+
+			got, err := rawPatch([]byte(tc.raw), before, after)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("rawPatch() = %v, wanted an error containing %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal("rawPatch() =", err)
+			}
+			b, err := json.Marshal(got)
+			if err != nil {
+				t.Fatal("Marshal() =", err)
+			}
+			ExpectPatches(t, b, tc.want)
+		})
+	}
+}
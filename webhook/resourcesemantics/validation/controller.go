@@ -26,9 +26,12 @@ import (
 	"github.com/Yangfisher1/knative-common-pkg/logging"
 	pkgreconciler "github.com/Yangfisher1/knative-common-pkg/reconciler"
 
+	duckv1 "github.com/Yangfisher1/knative-common-pkg/apis/duck/v1"
+	"github.com/Yangfisher1/knative-common-pkg/apis/feature"
 	"github.com/Yangfisher1/knative-common-pkg/controller"
 	"github.com/Yangfisher1/knative-common-pkg/system"
 	"github.com/Yangfisher1/knative-common-pkg/webhook"
+	"github.com/Yangfisher1/knative-common-pkg/webhook/json"
 	"github.com/Yangfisher1/knative-common-pkg/webhook/resourcesemantics"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
@@ -81,7 +84,12 @@ func NewAdmissionController(
 
 		withContext:           wc,
 		disallowUnknownFields: disallowUnknownFields,
+		decodeLimits:          json.GetLimits(ctx),
+		features:              feature.GetStore(ctx),
+		enforcement:           GetEnforcementStore(ctx),
+		services:              duckv1.GetServiceGetter(ctx),
 		secretName:            options.SecretName,
+		webhookOptions:        webhook.GetAdmissionWebhookOptions(ctx),
 
 		client:       client,
 		vwhlister:    vwhInformer.Lister(),
@@ -0,0 +1,236 @@
+/*
+Copyright 2023 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validation
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/Yangfisher1/knative-common-pkg/configmap"
+)
+
+// Enforcement is what happens to a request that fails validation.
+type Enforcement string
+
+const (
+	// EnforcementDeny rejects requests that fail validation.  This is the
+	// default.
+	EnforcementDeny Enforcement = "deny"
+
+	// EnforcementWarn admits requests that fail validation, and returns
+	// the validation failures as admission warnings.
+	EnforcementWarn Enforcement = "warn"
+
+	// EnforcementAudit admits requests that fail validation, and only logs
+	// and counts the validation failures.
+	EnforcementAudit Enforcement = "audit"
+)
+
+const (
+	enforcementConfigMapNameEnv = "CONFIG_VALIDATION_ENFORCEMENT_NAME"
+
+	// defaultEnforcementKey is the ConfigMap key holding the enforcement for
+	// kinds without one of their own.
+	defaultEnforcementKey = "default"
+
+	// callbackEnforcementPrefix prefixes the ConfigMap keys holding the
+	// enforcement of a kind's validation callback.
+	callbackEnforcementPrefix = "callback."
+)
+
+// EnforcementConfigMapName gets the name of the ConfigMap holding the
+// validation enforcement modes.
+func EnforcementConfigMapName() string {
+	if cm := os.Getenv(enforcementConfigMapNameEnv); cm != "" {
+		return cm
+	}
+	return "config-validation-enforcement"
+}
+
+// EnforcementConfig holds the enforcement of validation failures, per kind.
+type EnforcementConfig struct {
+	// Default applies to kinds that are not listed in Validate or Callback.
+	Default Enforcement
+
+	// Validate holds the enforcement of failures of the kinds' Validate
+	// method.  It also applies to the kinds' callbacks unless Callback lists
+	// them.
+	Validate map[schema.GroupVersionKind]Enforcement
+
+	// Callback holds the enforcement of failures of the kinds' callbacks.
+	Callback map[schema.GroupVersionKind]Enforcement
+}
+
+// NewEnforcementConfigFromConfigMap creates an EnforcementConfig from the
+// supplied ConfigMap.  Its keys are:
+//
+//	default: <enforcement>
+//	<Kind>.<version>.<group>: <enforcement>
+//	callback.<Kind>.<version>.<group>: <enforcement>
+//
+// where <group> is empty for the core group, e.g. "Pod.v1.".
+func NewEnforcementConfigFromConfigMap(cm *corev1.ConfigMap) (*EnforcementConfig, error) {
+	cfg := &EnforcementConfig{
+		Default:  EnforcementDeny,
+		Validate: make(map[schema.GroupVersionKind]Enforcement),
+		Callback: make(map[schema.GroupVersionKind]Enforcement),
+	}
+	for k, v := range cm.Data {
+		if strings.HasPrefix(k, "_") {
+			// Skip the _example key and the like.
+			continue
+		}
+		enforcement, err := parseEnforcement(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for %q: %w", k, err)
+		}
+		if k == defaultEnforcementKey {
+			cfg.Default = enforcement
+			continue
+		}
+
+		target, name := cfg.Validate, k
+		if strings.HasPrefix(k, callbackEnforcementPrefix) {
+			target, name = cfg.Callback, strings.TrimPrefix(k, callbackEnforcementPrefix)
+		}
+		gvk, err := parseKind(name)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key %q: %w", k, err)
+		}
+		target[gvk] = enforcement
+	}
+	return cfg, nil
+}
+
+func parseEnforcement(s string) (Enforcement, error) {
+	switch e := Enforcement(strings.ToLower(strings.TrimSpace(s))); e {
+	case EnforcementDeny, EnforcementWarn, EnforcementAudit:
+		return e, nil
+	default:
+		return "", fmt.Errorf("enforcement must be one of %q, %q or %q, was %q",
+			EnforcementDeny, EnforcementWarn, EnforcementAudit, s)
+	}
+}
+
+// parseKind parses <Kind>.<version>.<group>, where the group may itself
+// contain dots.
+func parseKind(s string) (schema.GroupVersionKind, error) {
+	parts := strings.SplitN(s, ".", 3)
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" {
+		return schema.GroupVersionKind{}, fmt.Errorf("want <Kind>.<version>.<group>, got %q", s)
+	}
+	return schema.GroupVersionKind{Kind: parts[0], Version: parts[1], Group: parts[2]}, nil
+}
+
+// validateEnforcement returns the enforcement of failures of the kind's
+// Validate method.
+func (cfg *EnforcementConfig) validateEnforcement(gvk schema.GroupVersionKind) Enforcement {
+	if cfg == nil {
+		return EnforcementDeny
+	}
+	if e, ok := cfg.Validate[gvk]; ok {
+		return e
+	}
+	return cfg.Default
+}
+
+// callbackEnforcement returns the enforcement of failures of the kind's
+// callback.
+func (cfg *EnforcementConfig) callbackEnforcement(gvk schema.GroupVersionKind) Enforcement {
+	if cfg == nil {
+		return EnforcementDeny
+	}
+	if e, ok := cfg.Callback[gvk]; ok {
+		return e
+	}
+	return cfg.validateEnforcement(gvk)
+}
+
+type enforcementConfigKey struct{}
+
+// WithEnforcementConfig attaches the EnforcementConfig to the context.
+func WithEnforcementConfig(ctx context.Context, cfg *EnforcementConfig) context.Context {
+	return context.WithValue(ctx, enforcementConfigKey{}, cfg)
+}
+
+// GetEnforcementConfig returns the EnforcementConfig attached to the context,
+// or nil if there is none, in which case all validation failures are denied.
+func GetEnforcementConfig(ctx context.Context) *EnforcementConfig {
+	cfg, _ := ctx.Value(enforcementConfigKey{}).(*EnforcementConfig)
+	return cfg
+}
+
+// EnforcementStore keeps the EnforcementConfig up to date with its ConfigMap.
+type EnforcementStore struct {
+	*configmap.UntypedStore
+}
+
+// NewEnforcementStore creates an EnforcementStore.  Its WatchConfigs method
+// must be called with a configmap.Watcher to keep it up to date, and the
+// EnforcementStore associated with the context NewAdmissionController is
+// constructed with (see WithEnforcementStore).
+func NewEnforcementStore(logger configmap.Logger, onAfterStore ...func(name string, value interface{})) *EnforcementStore {
+	return &EnforcementStore{
+		UntypedStore: configmap.NewUntypedStore(
+			"validation-enforcement",
+			logger,
+			configmap.Constructors{
+				EnforcementConfigMapName(): NewEnforcementConfigFromConfigMap,
+			},
+			onAfterStore...,
+		),
+	}
+}
+
+// Load returns the current EnforcementConfig, or nil if its ConfigMap has
+// not been seen yet.
+func (s *EnforcementStore) Load() *EnforcementConfig {
+	cfg, _ := s.UntypedLoad(EnforcementConfigMapName()).(*EnforcementConfig)
+	return cfg
+}
+
+// ToContext attaches the current EnforcementConfig to the context.  It does
+// nothing on a nil EnforcementStore.
+func (s *EnforcementStore) ToContext(ctx context.Context) context.Context {
+	if s == nil {
+		return ctx
+	}
+	return WithEnforcementConfig(ctx, s.Load())
+}
+
+// enforcementStoreKey is used as the key for associating an EnforcementStore
+// with a context.Context.
+type enforcementStoreKey struct{}
+
+// WithEnforcementStore associates the EnforcementStore with the admission
+// controllers constructed with the returned context, which attach its
+// EnforcementConfig to the context of the requests they admit.
+func WithEnforcementStore(ctx context.Context, s *EnforcementStore) context.Context {
+	return context.WithValue(ctx, enforcementStoreKey{}, s)
+}
+
+// GetEnforcementStore retrieves the EnforcementStore associated with the
+// given context via WithEnforcementStore (above), or nil.
+func GetEnforcementStore(ctx context.Context) *EnforcementStore {
+	s, _ := ctx.Value(enforcementStoreKey{}).(*EnforcementStore)
+	return s
+}
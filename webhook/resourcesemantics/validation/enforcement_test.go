@@ -0,0 +1,151 @@
+/*
+Copyright 2023 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validation
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	. "github.com/Yangfisher1/knative-common-pkg/logging/testing"
+)
+
+var (
+	resourceGVK = schema.GroupVersionKind{Group: "pkg.knative.dev", Version: "v1alpha1", Kind: "Resource"}
+	podGVK      = schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+)
+
+func TestNewEnforcementConfigFromConfigMap(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    map[string]string
+		want    *EnforcementConfig
+		wantErr bool
+	}{{
+		name: "empty",
+		want: &EnforcementConfig{
+			Default:  EnforcementDeny,
+			Validate: map[schema.GroupVersionKind]Enforcement{},
+			Callback: map[schema.GroupVersionKind]Enforcement{},
+		},
+	}, {
+		name: "all keys",
+		data: map[string]string{
+			"_example":                          "ignored",
+			"default":                           "Warn",
+			"Resource.v1alpha1.pkg.knative.dev": "audit",
+			"callback.Pod.v1.":                  " deny ",
+			"callback.Resource.v1alpha1.pkg.knative.dev": "warn",
+		},
+		want: &EnforcementConfig{
+			Default:  EnforcementWarn,
+			Validate: map[schema.GroupVersionKind]Enforcement{resourceGVK: EnforcementAudit},
+			Callback: map[schema.GroupVersionKind]Enforcement{
+				podGVK:      EnforcementDeny,
+				resourceGVK: EnforcementWarn,
+			},
+		},
+	}, {
+		name:    "bad enforcement",
+		data:    map[string]string{"default": "ignore"},
+		wantErr: true,
+	}, {
+		name:    "bad kind",
+		data:    map[string]string{"Resource.v1alpha1": "audit"},
+		wantErr: true,
+	}, {
+		name:    "missing version",
+		data:    map[string]string{"callback.Resource..pkg.knative.dev": "audit"},
+		wantErr: true,
+	}}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := NewEnforcementConfigFromConfigMap(&corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: EnforcementConfigMapName()},
+				Data:       tc.data,
+			})
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("NewEnforcementConfigFromConfigMap() = %v, wantErr %v", err, tc.wantErr)
+			}
+			if !cmp.Equal(got, tc.want) {
+				t.Error("NewEnforcementConfigFromConfigMap (-want, +got):", cmp.Diff(tc.want, got))
+			}
+		})
+	}
+}
+
+func TestEnforcementLookup(t *testing.T) {
+	var nilConfig *EnforcementConfig
+	if got := nilConfig.validateEnforcement(resourceGVK); got != EnforcementDeny {
+		t.Errorf("nil validateEnforcement() = %q, want %q", got, EnforcementDeny)
+	}
+	if got := nilConfig.callbackEnforcement(resourceGVK); got != EnforcementDeny {
+		t.Errorf("nil callbackEnforcement() = %q, want %q", got, EnforcementDeny)
+	}
+
+	cfg := &EnforcementConfig{
+		Default:  EnforcementAudit,
+		Validate: map[schema.GroupVersionKind]Enforcement{resourceGVK: EnforcementWarn},
+		Callback: map[schema.GroupVersionKind]Enforcement{podGVK: EnforcementDeny},
+	}
+	for _, tc := range []struct {
+		gvk          schema.GroupVersionKind
+		wantValidate Enforcement
+		wantCallback Enforcement
+	}{{
+		gvk:          resourceGVK,
+		wantValidate: EnforcementWarn,
+		wantCallback: EnforcementWarn,
+	}, {
+		gvk:          podGVK,
+		wantValidate: EnforcementAudit,
+		wantCallback: EnforcementDeny,
+	}, {
+		gvk:          schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"},
+		wantValidate: EnforcementAudit,
+		wantCallback: EnforcementAudit,
+	}} {
+		if got := cfg.validateEnforcement(tc.gvk); got != tc.wantValidate {
+			t.Errorf("validateEnforcement(%v) = %q, want %q", tc.gvk, got, tc.wantValidate)
+		}
+		if got := cfg.callbackEnforcement(tc.gvk); got != tc.wantCallback {
+			t.Errorf("callbackEnforcement(%v) = %q, want %q", tc.gvk, got, tc.wantCallback)
+		}
+	}
+}
+
+func TestEnforcementStore(t *testing.T) {
+	store := NewEnforcementStore(TestLogger(t))
+
+	if got := GetEnforcementConfig(store.ToContext(context.Background())); got != nil {
+		t.Errorf("GetEnforcementConfig() = %v before the ConfigMap was seen, want nil", got)
+	}
+
+	store.OnConfigChanged(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: EnforcementConfigMapName()},
+		Data:       map[string]string{"default": "audit"},
+	})
+	got := GetEnforcementConfig(store.ToContext(context.Background()))
+	if got == nil || got.Default != EnforcementAudit {
+		t.Errorf("GetEnforcementConfig() = %v, want default %q", got, EnforcementAudit)
+	}
+}
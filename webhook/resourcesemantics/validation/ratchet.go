@@ -0,0 +1,128 @@
+/*
+Copyright 2023 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validation
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/Yangfisher1/knative-common-pkg/apis"
+	"github.com/Yangfisher1/knative-common-pkg/webhook/resourcesemantics"
+)
+
+// ratchet downgrades to warnings the errors in result at field paths that
+// were also invalid in the old object, with unchanged values.
+func ratchet(ctx context.Context, result *apis.FieldError, oldObj, newObj resourcesemantics.GenericCRD) *apis.FieldError {
+	// The old object is validated as it was created, rather than as an
+	// update whose baseline is itself.
+	oldPaths := oldObj.Validate(apis.WithinCreate(apis.WithoutUpdate(ctx))).PathsAt(apis.ErrorLevel)
+	if len(oldPaths) == 0 {
+		return result
+	}
+	invalid := make(map[string]struct{}, len(oldPaths))
+	for _, p := range oldPaths {
+		invalid[p] = struct{}{}
+	}
+
+	oldValue, err := toUnstructured(oldObj)
+	if err != nil {
+		return result
+	}
+	newValue, err := toUnstructured(newObj)
+	if err != nil {
+		return result
+	}
+
+	return result.AtWhere(apis.WarningLevel, func(fe *apis.FieldError) bool {
+		if fe.Level != apis.ErrorLevel || len(fe.Paths) == 0 {
+			return false
+		}
+		for _, p := range fe.Paths {
+			if _, ok := invalid[p]; !ok {
+				return false
+			}
+			o, oldFound := lookupPath(oldValue, p)
+			n, newFound := lookupPath(newValue, p)
+			if oldFound != newFound || !reflect.DeepEqual(o, n) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// toUnstructured returns the JSON representation of the object, so that
+// field paths can be looked up in it.
+func toUnstructured(obj interface{}) (interface{}, error) {
+	b, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	var v interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// lookupPath returns the value at the field path of a FieldError, e.g.
+// spec.containers[0].env or metadata.annotations[example.com/key], and
+// whether it was found.
+func lookupPath(value interface{}, path string) (interface{}, bool) {
+	for path != "" {
+		var segment string
+		switch {
+		case path[0] == '.':
+			path = path[1:]
+			continue
+
+		case path[0] == '[':
+			end := strings.IndexByte(path, ']')
+			if end < 0 {
+				return nil, false
+			}
+			segment, path = path[1:end], path[end+1:]
+			if list, ok := value.([]interface{}); ok {
+				i, err := strconv.Atoi(segment)
+				if err != nil || i < 0 || i >= len(list) {
+					return nil, false
+				}
+				value = list[i]
+				continue
+			}
+
+		default:
+			end := strings.IndexAny(path, ".[")
+			if end < 0 {
+				end = len(path)
+			}
+			segment, path = path[:end], path[end:]
+		}
+
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if value, ok = m[segment]; !ok {
+			return nil, false
+		}
+	}
+	return value, true
+}
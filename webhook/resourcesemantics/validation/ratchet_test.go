@@ -0,0 +1,120 @@
+/*
+Copyright 2023 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validation
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/Yangfisher1/knative-common-pkg/apis"
+	. "github.com/Yangfisher1/knative-common-pkg/testing"
+	. "github.com/Yangfisher1/knative-common-pkg/webhook/testing"
+)
+
+// contextRecordingResource records the operations it is validated within.
+type contextRecordingResource struct {
+	*Resource
+	inCreate, inUpdate bool
+}
+
+func (r *contextRecordingResource) Validate(ctx context.Context) *apis.FieldError {
+	r.inCreate, r.inUpdate = apis.IsInCreate(ctx), apis.IsInUpdate(ctx)
+	return r.Resource.Validate(ctx)
+}
+
+func TestRatchetValidatesOldAsCreated(t *testing.T) {
+	const invalid = "not what's expected"
+	old := CreateResource("a name")
+	old.Spec.FieldWithValidation = invalid
+	new := old.DeepCopy()
+	new.Spec.FieldForCallbackValidation = "magic value"
+
+	oldObj := &contextRecordingResource{Resource: old}
+	ctx := apis.WithinUpdate(context.Background(), old)
+	result := ratchet(ctx, new.Validate(ctx), oldObj, new)
+
+	if !oldObj.inCreate || oldObj.inUpdate {
+		t.Errorf("Old object validated in create = %v and update = %v, wanted a create", oldObj.inCreate, oldObj.inUpdate)
+	}
+	if got, want := result.PathsAt(apis.WarningLevel), []string{"spec.fieldWithValidation"}; !cmp.Equal(got, want) {
+		t.Errorf("Warnings at %v, wanted %v", got, want)
+	}
+	if got := result.Filter(apis.ErrorLevel); got != nil {
+		t.Error("Unexpected errors:", got)
+	}
+}
+
+func TestLookupPath(t *testing.T) {
+	value := map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]interface{}{
+				"example.com/key": "value",
+			},
+		},
+		"spec": map[string]interface{}{
+			"containers": []interface{}{
+				map[string]interface{}{"image": "busybox"},
+			},
+		},
+	}
+
+	tests := []struct {
+		path      string
+		want      interface{}
+		wantFound bool
+	}{{
+		path:      "",
+		want:      value,
+		wantFound: true,
+	}, {
+		path:      "spec.containers[0].image",
+		want:      "busybox",
+		wantFound: true,
+	}, {
+		path:      "spec.containers[0]",
+		want:      map[string]interface{}{"image": "busybox"},
+		wantFound: true,
+	}, {
+		path:      "metadata.annotations[example.com/key]",
+		want:      "value",
+		wantFound: true,
+	}, {
+		path: "spec.containers[1].image",
+	}, {
+		path: "spec.containers[first].image",
+	}, {
+		path: "spec.containers[0].image.name",
+	}, {
+		path: "spec.volumes",
+	}, {
+		path: "metadata.annotations[example.com/key",
+	}}
+
+	for _, tc := range tests {
+		t.Run(tc.path, func(t *testing.T) {
+			got, found := lookupPath(value, tc.path)
+			if found != tc.wantFound {
+				t.Errorf("lookupPath() found = %v, want %v", found, tc.wantFound)
+			}
+			if !cmp.Equal(got, tc.want) {
+				t.Error("lookupPath (-want, +got):", cmp.Diff(tc.want, got))
+			}
+		})
+	}
+}
@@ -22,6 +22,8 @@ import (
 	"sort"
 	"strings"
 
+	duckv1 "github.com/Yangfisher1/knative-common-pkg/apis/duck/v1"
+	"github.com/Yangfisher1/knative-common-pkg/apis/feature"
 	"github.com/Yangfisher1/knative-common-pkg/controller"
 	"github.com/Yangfisher1/knative-common-pkg/kmp"
 	"github.com/Yangfisher1/knative-common-pkg/logging"
@@ -30,6 +32,7 @@ import (
 	"github.com/Yangfisher1/knative-common-pkg/system"
 	"github.com/Yangfisher1/knative-common-pkg/webhook"
 	certresources "github.com/Yangfisher1/knative-common-pkg/webhook/certificates/resources"
+	"github.com/Yangfisher1/knative-common-pkg/webhook/json"
 	"github.com/Yangfisher1/knative-common-pkg/webhook/resourcesemantics"
 	"github.com/gobuffalo/flect"
 	"go.uber.org/zap"
@@ -54,13 +57,22 @@ type reconciler struct {
 	callbacks map[schema.GroupVersionKind]Callback
 
 	withContext func(context.Context) context.Context
+	features    *feature.Store
+	enforcement *EnforcementStore
+
+	// services is attached to the context of the resources' Validate, to
+	// check the ports of the Services they reference.
+	services duckv1.ServiceGetter
 
 	client       kubernetes.Interface
 	vwhlister    admissionlisters.ValidatingWebhookConfigurationLister
 	secretlister corelisters.SecretLister
 
 	disallowUnknownFields bool
+	decodeLimits          json.Limits
 	secretName            string
+
+	webhookOptions *webhook.AdmissionWebhookOptions
 }
 
 var _ controller.Reconciler = (*reconciler)(nil)
@@ -87,9 +99,9 @@ func (ac *reconciler) Reconcile(ctx context.Context, key string) error {
 		logger.Errorw("Error fetching secret", zap.Error(err))
 		return err
 	}
-	caCert, ok := secret.Data[certresources.CACert]
-	if !ok {
-		return fmt.Errorf("secret %q is missing %q key", ac.secretName, certresources.CACert)
+	caCert, err := certresources.CABundle(secret)
+	if err != nil {
+		return err
 	}
 
 	// Reconcile the webhook configuration.
@@ -104,15 +116,16 @@ func (ac *reconciler) reconcileValidatingWebhook(ctx context.Context, caCert []b
 		plural := strings.ToLower(flect.Pluralize(gvk.Kind))
 
 		rules = append(rules, admissionregistrationv1.RuleWithOperations{
-			Operations: []admissionregistrationv1.OperationType{
+			Operations: ac.webhookOptions.Operations(gvk.GroupKind(),
 				admissionregistrationv1.Create,
 				admissionregistrationv1.Update,
 				admissionregistrationv1.Delete,
-			},
+			),
 			Rule: admissionregistrationv1.Rule{
 				APIGroups:   []string{gvk.Group},
 				APIVersions: []string{gvk.Version},
 				Resources:   []string{plural, plural + "/status"},
+				Scope:       ac.webhookOptions.Scope(gvk.GroupKind(), nil),
 			},
 		})
 	}
@@ -151,6 +164,7 @@ func (ac *reconciler) reconcileValidatingWebhook(ctx context.Context, caCert []b
 		cur := &current.Webhooks[i]
 		cur.Rules = rules
 
+		ac.webhookOptions.ApplyToValidatingWebhook(cur)
 		cur.NamespaceSelector = webhook.EnsureLabelSelectorExpressions(
 			cur.NamespaceSelector,
 			&metav1.LabelSelector{
@@ -431,6 +431,148 @@ func TestReconcile(t *testing.T) {
 	}))
 }
 
+func TestReconcileWithAdmissionWebhookOptions(t *testing.T) {
+	const name, path = "foo.bar.baz", "/blah"
+	const secretName = "webhook-secret"
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      secretName,
+			Namespace: system.Namespace(),
+		},
+		Data: map[string][]byte{
+			certresources.CACert: []byte("present"),
+		},
+	}
+	ns := &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: system.Namespace(),
+		},
+	}
+	nsRef := *metav1.NewControllerRef(ns, corev1.SchemeGroupVersion.WithKind("Namespace"))
+
+	resourceGVK := schema.GroupVersionKind{Group: "pkg.knative.dev", Version: "v1alpha1", Kind: "Resource"}
+	namespaced := admissionregistrationv1.NamespacedScope
+	failurePolicy := admissionregistrationv1.Ignore
+	sideEffects := admissionregistrationv1.SideEffectClassNone
+	opts := &webhook.AdmissionWebhookOptions{
+		NamespaceSelector: &metav1.LabelSelector{
+			MatchLabels: map[string]string{"team": "a"},
+		},
+		ObjectSelector: &metav1.LabelSelector{
+			MatchLabels: map[string]string{"validate": "true"},
+		},
+		FailurePolicy: &failurePolicy,
+		SideEffects:   &sideEffects,
+		Rules: map[schema.GroupKind]webhook.RuleOptions{
+			resourceGVK.GroupKind(): {
+				Operations: []admissionregistrationv1.OperationType{admissionregistrationv1.Create},
+				Scope:      &namespaced,
+			},
+		},
+	}
+
+	expected := admissionregistrationv1.ValidatingWebhook{
+		Name: name,
+		ClientConfig: admissionregistrationv1.WebhookClientConfig{
+			Service: &admissionregistrationv1.ServiceReference{
+				Namespace: system.Namespace(),
+				Name:      "webhook",
+				Path:      ptr.String(path),
+			},
+			CABundle: []byte("present"),
+		},
+		Rules: []admissionregistrationv1.RuleWithOperations{{
+			Operations: []admissionregistrationv1.OperationType{"CREATE"},
+			Rule: admissionregistrationv1.Rule{
+				APIGroups:   []string{"pkg.knative.dev"},
+				APIVersions: []string{"v1alpha1"},
+				Resources:   []string{"resources", "resources/status"},
+				Scope:       &namespaced,
+			},
+		}},
+		NamespaceSelector: &metav1.LabelSelector{
+			MatchLabels: map[string]string{"team": "a"},
+			MatchExpressions: []metav1.LabelSelectorRequirement{{
+				Key:      "webhooks.knative.dev/exclude",
+				Operator: metav1.LabelSelectorOpDoesNotExist,
+			}},
+		},
+		ObjectSelector: &metav1.LabelSelector{
+			MatchLabels: map[string]string{"validate": "true"},
+		},
+		FailurePolicy: &failurePolicy,
+		SideEffects:   &sideEffects,
+		// Not declared, so preserved.
+		TimeoutSeconds: ptr.Int32(5),
+	}
+
+	key := system.Namespace() + "/does not matter"
+
+	table := TableTest{{
+		Name: "declared fields are reconciled, others preserved",
+		Key:  key,
+		Objects: []runtime.Object{secret, ns,
+			&admissionregistrationv1.ValidatingWebhookConfiguration{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: name,
+				},
+				Webhooks: []admissionregistrationv1.ValidatingWebhook{{
+					Name: name,
+					ClientConfig: admissionregistrationv1.WebhookClientConfig{
+						Service: &admissionregistrationv1.ServiceReference{
+							Namespace: system.Namespace(),
+							Name:      "webhook",
+						},
+					},
+					TimeoutSeconds: ptr.Int32(5),
+				}},
+			},
+		},
+		WantUpdates: []clientgotesting.UpdateActionImpl{{
+			Object: &admissionregistrationv1.ValidatingWebhookConfiguration{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            name,
+					OwnerReferences: []metav1.OwnerReference{nsRef},
+				},
+				Webhooks: []admissionregistrationv1.ValidatingWebhook{expected},
+			},
+		}},
+	}, {
+		Name: "in sync",
+		Key:  key,
+		Objects: []runtime.Object{secret, ns,
+			&admissionregistrationv1.ValidatingWebhookConfiguration{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            name,
+					OwnerReferences: []metav1.OwnerReference{nsRef},
+				},
+				Webhooks: []admissionregistrationv1.ValidatingWebhook{*expected.DeepCopy()},
+			},
+		},
+	}}
+
+	table.Test(t, MakeFactory(func(ctx context.Context, listers *Listers, cmw configmap.Watcher) controller.Reconciler {
+		return &reconciler{
+			key: types.NamespacedName{
+				Name: name,
+			},
+			path: path,
+
+			handlers: map[schema.GroupVersionKind]resourcesemantics.GenericCRD{
+				resourceGVK: handlers[resourceGVK],
+			},
+
+			client:       kubeclient.Get(ctx),
+			vwhlister:    listers.GetValidatingWebhookConfigurationLister(),
+			secretlister: listers.GetSecretLister(),
+
+			secretName:     secretName,
+			webhookOptions: opts,
+		}
+	}))
+}
+
 func TestNew(t *testing.T) {
 	ctx, cancel, _ := SetupFakeContextWithCancel(t)
 	defer cancel()
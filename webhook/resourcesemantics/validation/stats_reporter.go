@@ -0,0 +1,85 @@
+/*
+Copyright 2023 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validation
+
+import (
+	"context"
+
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/Yangfisher1/knative-common-pkg/metrics"
+)
+
+const (
+	validationFailureCountName = "validation_failure_count"
+
+	sourceValidate = "validate"
+	sourceCallback = "callback"
+)
+
+var (
+	validationFailureCountM = stats.Int64(
+		validationFailureCountName,
+		"The number of requests that failed validation, by enforcement",
+		stats.UnitDimensionless)
+
+	// Create the tag keys that will be used to add tags to our measurements.
+	// Tag keys must conform to the restrictions described in
+	// go.opencensus.io/tag/validate.go. Currently those restrictions are:
+	// - length between 1 and 255 inclusive
+	// - characters are printable US-ASCII
+	kindGroupKey   = tag.MustNewKey("kind_group")
+	kindVersionKey = tag.MustNewKey("kind_version")
+	kindKindKey    = tag.MustNewKey("kind_kind")
+	enforcementKey = tag.MustNewKey("enforcement")
+	sourceKey      = tag.MustNewKey("source")
+)
+
+// RegisterMetrics registers the views of the validation failures.  Webhooks
+// call it alongside webhook.RegisterMetrics, which sharedmain calls for them.
+func RegisterMetrics() {
+	if err := view.Register(
+		&view.View{
+			Description: validationFailureCountM.Description(),
+			Measure:     validationFailureCountM,
+			Aggregation: view.Count(),
+			TagKeys:     []tag.Key{kindGroupKey, kindVersionKey, kindKindKey, enforcementKey, sourceKey},
+		},
+	); err != nil {
+		panic(err)
+	}
+}
+
+// reportFailure counts a validation failure of the kind, by the source of the
+// failure and its enforcement.
+func reportFailure(gvk schema.GroupVersionKind, source string, enforcement Enforcement) {
+	ctx, err := tag.New(
+		context.Background(),
+		tag.Insert(kindGroupKey, gvk.Group),
+		tag.Insert(kindVersionKey, gvk.Version),
+		tag.Insert(kindKindKey, gvk.Kind),
+		tag.Insert(enforcementKey, string(enforcement)),
+		tag.Insert(sourceKey, source),
+	)
+	if err != nil {
+		return
+	}
+	metrics.Record(ctx, validationFailureCountM.M(1))
+}
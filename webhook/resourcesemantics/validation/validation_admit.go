@@ -22,6 +22,7 @@ import (
 	"fmt"
 
 	"github.com/Yangfisher1/knative-common-pkg/apis"
+	duckv1 "github.com/Yangfisher1/knative-common-pkg/apis/duck/v1"
 	kubeclient "github.com/Yangfisher1/knative-common-pkg/client/injection/kube/client"
 	"github.com/Yangfisher1/knative-common-pkg/logging"
 	"github.com/Yangfisher1/knative-common-pkg/webhook"
@@ -43,6 +44,9 @@ type Callback struct {
 	// supportedVerbs are the verbs supported for the callback.
 	// The function will only be called on these actions.
 	supportedVerbs map[webhook.Operation]struct{}
+
+	// sideEffects is whether the function has side effects.
+	sideEffects bool
 }
 
 // NewCallback creates a new callback function to be invoked on supported verbs.
@@ -57,10 +61,41 @@ func NewCallback(function func(context.Context, *unstructured.Unstructured) erro
 	return Callback{function: function, supportedVerbs: m}
 }
 
+// WithSideEffects returns a copy of the callback declaring that its function
+// has side effects, so that the responses to the requests it is invoked on
+// are never cached.
+func (c Callback) WithSideEffects() Callback {
+	c.sideEffects = true
+	return c
+}
+
 var _ webhook.AdmissionController = (*reconciler)(nil)
+var _ webhook.SideEffectingAdmissionController = (*reconciler)(nil)
+
+// HasSideEffects implements SideEffectingAdmissionController
+func (ac *reconciler) HasSideEffects(req *admissionv1.AdmissionRequest) bool {
+	gvk := schema.GroupVersionKind{
+		Group:   req.Kind.Group,
+		Version: req.Kind.Version,
+		Kind:    req.Kind.Kind,
+	}
+	c, ok := ac.callbacks[gvk]
+	if !ok || !c.sideEffects {
+		return false
+	}
+	_, supported := c.supportedVerbs[req.Operation]
+	return supported
+}
 
 // Admit implements AdmissionController
 func (ac *reconciler) Admit(ctx context.Context, request *admissionv1.AdmissionRequest) (resp *admissionv1.AdmissionResponse) {
+	// Attach the feature flags, enforcement and ServiceGetter first, so that
+	// the context function may use or override them.
+	ctx = ac.features.ToContext(ctx)
+	ctx = ac.enforcement.ToContext(ctx)
+	if ac.services != nil {
+		ctx = duckv1.WithServiceGetter(ctx, ac.services)
+	}
 	if ac.withContext != nil {
 		ctx = ac.withContext(ctx)
 	}
@@ -72,33 +107,70 @@ func (ac *reconciler) Admit(ctx context.Context, request *admissionv1.AdmissionR
 		Kind:    kind.Kind,
 	}
 
+	_, endDecode := webhook.StartPhase(ctx, request, webhook.PhaseDecode)
 	ctx, resource, err := ac.decodeRequestAndPrepareContext(ctx, request, gvk)
+	endDecode(err)
 	if err != nil {
 		return webhook.MakeErrorStatus("decoding request failed: %v", err)
 	}
 
-	errors, warnings := validate(ctx, resource, request)
-	if warnings != nil {
+	enforcement := GetEnforcementConfig(ctx)
+	var warnings []string
+	// We cannot simply set `resp.Warnings` directly below because the
+	// return paths all overwrite `resp`, but the `defer` affords us one
+	// final crack at things.
+	defer func() {
+		if len(warnings) > 0 {
+			resp.Warnings = warnings
+		}
+	}()
+
+	errors, warn := validate(ctx, resource, request)
+	if warn != nil {
 		// If there were warnings, then keep processing things, but augment
-		// whatever AdmissionResponse we send with the warnings.  We cannot
-		// simply set `resp.Warnings` directly here because the return paths
-		// below all overwrite `resp`, but the `defer` affords us one final
-		// crack at things.
-		defer func() {
-			resp.Warnings = []string{warnings.Error()}
-		}()
+		// whatever AdmissionResponse we send with the warnings.
+		warnings = append(warnings, warn.Error())
 	}
 	if errors != nil {
-		return webhook.MakeErrorStatus("validation failed: %v", errors)
+		if errors == errMissingNewObject {
+			return webhook.MakeErrorStatus("validation failed: %v", errors)
+		}
+		e := enforcement.validateEnforcement(gvk)
+		if w, deny := enforce(ctx, gvk, sourceValidate, e, errors); deny {
+			return webhook.MakeErrorStatus("validation failed: %v", errors)
+		} else if w != "" {
+			warnings = append(warnings, w)
+		}
 	}
 
 	if err := ac.callback(ctx, request, gvk); err != nil {
-		return webhook.MakeErrorStatus("validation callback failed: %v", err)
+		e := enforcement.callbackEnforcement(gvk)
+		if w, deny := enforce(ctx, gvk, sourceCallback, e, err); deny {
+			return webhook.MakeErrorStatus("validation callback failed: %v", err)
+		} else if w != "" {
+			warnings = append(warnings, w)
+		}
 	}
 
 	return &admissionv1.AdmissionResponse{Allowed: true}
 }
 
+// enforce applies the enforcement to a validation failure, returning whether
+// the request must be denied, or else the admission warning to return, if any.
+func enforce(ctx context.Context, gvk schema.GroupVersionKind, source string, enforcement Enforcement, err error) (warning string, deny bool) {
+	reportFailure(gvk, source, enforcement)
+	switch enforcement {
+	case EnforcementWarn:
+		return err.Error(), false
+	case EnforcementAudit:
+		logging.FromContext(ctx).Infow("Admitting request that failed validation",
+			zap.String("kind", gvk.String()), zap.String("source", source), zap.Error(err))
+		return "", false
+	default:
+		return "", true
+	}
+}
+
 // decodeRequestAndPrepareContext deserializes the old and new GenericCrds from the incoming request and sets up the context.
 // nil oldObj or newObj denote absence of `old` (create) or `new` (delete) objects.
 func (ac *reconciler) decodeRequestAndPrepareContext(
@@ -120,7 +192,7 @@ func (ac *reconciler) decodeRequestAndPrepareContext(
 	var newObj resourcesemantics.GenericCRD
 	if len(newBytes) != 0 {
 		newObj = handler.DeepCopyObject().(resourcesemantics.GenericCRD)
-		err := json.Decode(newBytes, newObj, ac.disallowUnknownFields)
+		err := json.DecodeWithLimits(newBytes, newObj, ac.disallowUnknownFields, ac.decodeLimits)
 		if err != nil {
 			return ctx, nil, fmt.Errorf("cannot decode incoming new object: %w", err)
 		}
@@ -129,7 +201,7 @@ func (ac *reconciler) decodeRequestAndPrepareContext(
 	var oldObj resourcesemantics.GenericCRD
 	if len(oldBytes) != 0 {
 		oldObj = handler.DeepCopyObject().(resourcesemantics.GenericCRD)
-		err := json.Decode(oldBytes, oldObj, ac.disallowUnknownFields)
+		err := json.DecodeWithLimits(oldBytes, oldObj, ac.disallowUnknownFields, ac.decodeLimits)
 		if err != nil {
 			return ctx, nil, fmt.Errorf("cannot decode incoming old object: %w", err)
 		}
@@ -177,8 +249,17 @@ func validate(ctx context.Context, resource resourcesemantics.GenericCRD, req *a
 		return errMissingNewObject, nil
 	}
 
-	if result := resource.Validate(ctx); result != nil {
+	validateCtx, endValidate := webhook.StartPhase(ctx, req, webhook.PhaseValidate)
+	defer func() { endValidate(err) }()
+
+	if result := resource.Validate(validateCtx); result != nil {
 		logger.Errorw("Failed the resource specific validation", zap.Error(err))
+		if req.Operation == admissionv1.Update && apis.IsRatchetingValidation(ctx, schema.GroupKind{Group: req.Kind.Group, Kind: req.Kind.Kind}) {
+			if oldObj, ok := apis.GetBaseline(ctx).(resourcesemantics.GenericCRD); ok && oldObj != nil {
+				// Only reject the violations that this update introduces.
+				result = ratchet(validateCtx, result, oldObj, resource)
+			}
+		}
 		// While we have the strong typing of apis.FieldError, partition the
 		// returned error into the error-level diagnostics and warning-level
 		// diagnostics, so that the admission response can embed things into
@@ -212,11 +293,17 @@ func (ac *reconciler) callback(ctx context.Context, req *admissionv1.AdmissionRe
 	if c, ok := ac.callbacks[gvk]; ok {
 		if _, supported := c.supportedVerbs[req.Operation]; supported {
 			unstruct := &unstructured.Unstructured{}
-			if err := json.Unmarshal(toDecode, unstruct); err != nil {
+			_, endDecode := webhook.StartPhase(ctx, req, webhook.PhaseDecode)
+			err := json.Unmarshal(toDecode, unstruct)
+			endDecode(err)
+			if err != nil {
 				return fmt.Errorf("cannot decode incoming new object: %w", err)
 			}
 
-			return c.function(ctx, unstruct)
+			callbackCtx, endCallback := webhook.StartPhase(ctx, req, webhook.PhaseCallback)
+			err = c.function(callbackCtx, unstruct)
+			endCallback(err)
+			return err
 		}
 	}
 
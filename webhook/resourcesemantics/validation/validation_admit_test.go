@@ -30,15 +30,24 @@ import (
 	pkgreconciler "github.com/Yangfisher1/knative-common-pkg/reconciler"
 
 	"github.com/Yangfisher1/knative-common-pkg/apis"
+	duckv1 "github.com/Yangfisher1/knative-common-pkg/apis/duck/v1"
+	"github.com/Yangfisher1/knative-common-pkg/apis/feature"
+	"github.com/Yangfisher1/knative-common-pkg/metrics/metricstest"
+	_ "github.com/Yangfisher1/knative-common-pkg/metrics/testing"
 	"github.com/Yangfisher1/knative-common-pkg/system"
 	"github.com/Yangfisher1/knative-common-pkg/webhook"
+	webhookjson "github.com/Yangfisher1/knative-common-pkg/webhook/json"
+	"github.com/google/go-cmp/cmp"
+	"go.opencensus.io/trace"
 	admissionv1 "k8s.io/api/admission/v1"
 	admissionregistrationv1 "k8s.io/api/admissionregistration/v1beta1"
 	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/apimachinery/pkg/util/wait"
 	fakekubeclientset "k8s.io/client-go/kubernetes/fake"
 
@@ -210,7 +219,122 @@ func TestUnknownFieldFails(t *testing.T) {
 	req.Object.Raw = marshaled
 
 	ExpectFailsWith(t, ac.Admit(TestContextWithLogger(t), req),
-		`decoding request failed: cannot decode incoming new object: json: unknown field "foo"`)
+		`decoding request failed: cannot decode incoming new object: must not set the field(s): spec.foo`)
+}
+
+func TestDecodeLimits(t *testing.T) {
+	ctx, _ := SetupFakeContext(t)
+	ctx = webhook.WithOptions(ctx, webhook.Options{
+		SecretName: "webhook-secret",
+	})
+	ctx = webhookjson.WithLimits(ctx, webhookjson.Limits{MaxBytes: 64})
+
+	c := NewAdmissionController(
+		ctx, testResourceValidationName, testResourceValidationPath,
+		handlers,
+		func(ctx context.Context) context.Context {
+			return ctx
+		}, true, callbacks)
+	ac := c.Reconciler.(webhook.AdmissionController)
+
+	req := &admissionv1.AdmissionRequest{
+		Operation: admissionv1.Create,
+		Kind: metav1.GroupVersionKind{
+			Group:   "pkg.knative.dev",
+			Version: "v1alpha1",
+			Kind:    "Resource",
+		},
+	}
+	marshaled, err := json.Marshal(CreateResource("a name"))
+	if err != nil {
+		t.Fatal("Failed to marshal resource:", err)
+	}
+	req.Object.Raw = marshaled
+
+	ExpectFailsWith(t, ac.Admit(TestContextWithLogger(t), req),
+		"decoding request failed: cannot decode incoming new object: object is too large")
+}
+
+func TestFeatureFlags(t *testing.T) {
+	ctx, _ := SetupFakeContext(t)
+	ctx = webhook.WithOptions(ctx, webhook.Options{
+		SecretName: "webhook-secret",
+	})
+	store := feature.NewStore(TestLogger(t), feature.Flags{"alpha": feature.Enabled})
+	ctx = feature.WithStore(ctx, store)
+
+	var got feature.Flags
+	c := NewAdmissionController(
+		ctx, testResourceValidationName, testResourceValidationPath,
+		handlers,
+		func(ctx context.Context) context.Context {
+			got = feature.FromContext(ctx)
+			return ctx
+		}, true, callbacks)
+	ac := c.Reconciler.(webhook.AdmissionController)
+
+	req := &admissionv1.AdmissionRequest{
+		Operation: admissionv1.Delete,
+		Kind: metav1.GroupVersionKind{
+			Group:   "pkg.knative.dev",
+			Version: "v1alpha1",
+			Kind:    "Resource",
+		},
+	}
+	ExpectAllowed(t, ac.Admit(TestContextWithLogger(t), req))
+	if !got.IsEnabled("alpha") {
+		t.Errorf("The flags of the context = %v, want alpha enabled", got)
+	}
+}
+
+func TestServiceGetter(t *testing.T) {
+	ctx, _ := SetupFakeContext(t)
+	ctx = webhook.WithOptions(ctx, webhook.Options{
+		SecretName: "webhook-secret",
+	})
+	svc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Namespace: system.Namespace(), Name: "svc"},
+		Spec: corev1.ServiceSpec{
+			Ports: []corev1.ServicePort{{Name: "http", Port: 80}},
+		},
+	}
+	ctx = duckv1.WithServiceGetter(ctx, duckv1.ServiceGetterFunc(func(string, string) (*corev1.Service, error) {
+		return svc, nil
+	}))
+
+	// The ports of referenced Services are checked against the
+	// ServiceGetter the admission controller was constructed with.
+	var got *apis.FieldError
+	port := intstr.FromInt(8080)
+	c := NewAdmissionController(
+		ctx, testResourceValidationName, testResourceValidationPath,
+		handlers,
+		func(ctx context.Context) context.Context {
+			ref := &duckv1.KReference{
+				APIVersion: "v1",
+				Kind:       "Service",
+				Namespace:  system.Namespace(),
+				Name:       "svc",
+				Port:       &port,
+			}
+			got = ref.Validate(ctx)
+			return ctx
+		}, true, callbacks)
+	ac := c.Reconciler.(webhook.AdmissionController)
+
+	req := &admissionv1.AdmissionRequest{
+		Operation: admissionv1.Delete,
+		Kind: metav1.GroupVersionKind{
+			Group:   "pkg.knative.dev",
+			Version: "v1alpha1",
+			Kind:    "Resource",
+		},
+	}
+	ExpectAllowed(t, ac.Admit(TestContextWithLogger(t), req))
+	want := `port "8080" not found on Service ` + system.Namespace() + "/svc: port\navailable ports: http(80)"
+	if got.Error() != want {
+		t.Errorf("Validate() = %v, want %s", got, want)
+	}
 }
 
 func TestUnknownMetadataFieldSucceeds(t *testing.T) {
@@ -299,6 +423,10 @@ func TestAdmitCreates(t *testing.T) {
 }
 
 func resourceCallback(ctx context.Context, uns *unstructured.Unstructured) error {
+	if trace.FromContext(ctx) == nil {
+		return errors.New("callback called outside of its span")
+	}
+
 	var resource Resource
 	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(uns.UnstructuredContent(), &resource); err != nil {
 		return err
@@ -426,6 +554,158 @@ func TestValidationDeleteCallback(t *testing.T) {
 	}
 }
 
+func TestAdmitEnforcement(t *testing.T) {
+	tests := []struct {
+		name         string
+		cfg          *EnforcementConfig
+		setup        func(*Resource)
+		rejection    string
+		wantWarnings []string
+		wantMetric   map[string]string
+	}{{
+		name: "no config denies",
+		setup: func(r *Resource) {
+			r.Spec.FieldWithValidation = "not what's expected"
+		},
+		rejection: "validation failed: invalid value",
+		wantMetric: map[string]string{
+			enforcementKey.Name(): string(EnforcementDeny),
+			sourceKey.Name():      sourceValidate,
+		},
+	}, {
+		name: "warn validation",
+		cfg:  &EnforcementConfig{Default: EnforcementWarn},
+		setup: func(r *Resource) {
+			r.Spec.FieldWithValidation = "not what's expected"
+		},
+		wantWarnings: []string{"invalid value: not what's expected: spec.fieldWithValidation"},
+		wantMetric: map[string]string{
+			enforcementKey.Name(): string(EnforcementWarn),
+			sourceKey.Name():      sourceValidate,
+		},
+	}, {
+		name: "audit validation",
+		cfg: &EnforcementConfig{
+			Default:  EnforcementDeny,
+			Validate: map[schema.GroupVersionKind]Enforcement{resourceGVK: EnforcementAudit},
+		},
+		setup: func(r *Resource) {
+			r.Spec.FieldWithValidation = "not what's expected"
+		},
+		wantMetric: map[string]string{
+			enforcementKey.Name(): string(EnforcementAudit),
+			sourceKey.Name():      sourceValidate,
+		},
+	}, {
+		name: "warn callback",
+		cfg: &EnforcementConfig{
+			Default:  EnforcementDeny,
+			Callback: map[schema.GroupVersionKind]Enforcement{resourceGVK: EnforcementWarn},
+		},
+		setup: func(r *Resource) {
+			r.Spec.FieldForCallbackValidation = "callbacks hate this"
+		},
+		wantWarnings: []string{"callbacks hate this"},
+		wantMetric: map[string]string{
+			enforcementKey.Name(): string(EnforcementWarn),
+			sourceKey.Name():      sourceCallback,
+		},
+	}, {
+		name: "deny callback with audited validation",
+		cfg: &EnforcementConfig{
+			Default:  EnforcementDeny,
+			Validate: map[schema.GroupVersionKind]Enforcement{resourceGVK: EnforcementAudit},
+			Callback: map[schema.GroupVersionKind]Enforcement{resourceGVK: EnforcementDeny},
+		},
+		setup: func(r *Resource) {
+			r.Spec.FieldForCallbackValidation = "callbacks hate this"
+		},
+		rejection: "validation callback failed: callbacks hate this",
+		wantMetric: map[string]string{
+			enforcementKey.Name(): string(EnforcementDeny),
+			sourceKey.Name():      sourceCallback,
+		},
+	}, {
+		name: "valid resource",
+		cfg:  &EnforcementConfig{Default: EnforcementWarn},
+		setup: func(r *Resource) {
+			r.Spec.FieldWithValidation = "magic value"
+		},
+	}}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			metricstest.Unregister(validationFailureCountName)
+			RegisterMetrics()
+
+			r := CreateResource("a name")
+			tc.setup(r)
+			ctx := apis.WithinCreate(apis.WithUserInfo(
+				TestContextWithLogger(t),
+				&authenticationv1.UserInfo{Username: user1}))
+			if tc.cfg != nil {
+				ctx = WithEnforcementConfig(ctx, tc.cfg)
+			}
+
+			_, ac := newNonRunningTestResourceAdmissionController(t)
+			resp := ac.Admit(ctx, createCreateResource(ctx, t, r))
+
+			if tc.rejection == "" {
+				ExpectAllowed(t, resp)
+			} else {
+				ExpectFailsWith(t, resp, tc.rejection)
+			}
+			if !cmp.Equal(resp.Warnings, tc.wantWarnings) {
+				t.Error("Warnings (-want, +got):", cmp.Diff(tc.wantWarnings, resp.Warnings))
+			}
+			if tc.wantMetric == nil {
+				metricstest.AssertNoMetric(t, validationFailureCountName)
+				return
+			}
+			tags := map[string]string{
+				kindGroupKey.Name():   resourceGVK.Group,
+				kindVersionKey.Name(): resourceGVK.Version,
+				kindKindKey.Name():    resourceGVK.Kind,
+			}
+			for k, v := range tc.wantMetric {
+				tags[k] = v
+			}
+			metricstest.CheckCountData(t, validationFailureCountName, tags, 1)
+		})
+	}
+}
+
+func TestAdmitEnforcementStore(t *testing.T) {
+	ctx, _ := SetupFakeContext(t)
+	ctx = webhook.WithOptions(ctx, webhook.Options{
+		SecretName: "webhook-secret",
+	})
+	store := NewEnforcementStore(TestLogger(t))
+	store.OnConfigChanged(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: EnforcementConfigMapName()},
+		Data:       map[string]string{"default": "warn"},
+	})
+	ctx = WithEnforcementStore(ctx, store)
+
+	c := NewAdmissionController(ctx, testResourceValidationName, testResourceValidationPath,
+		handlers, nil, true, callbacks)
+	ac := c.Reconciler.(webhook.AdmissionController)
+
+	r := CreateResource("a name")
+	r.Spec.FieldWithValidation = "not what's expected"
+	reqCtx := apis.WithinCreate(apis.WithUserInfo(
+		TestContextWithLogger(t),
+		&authenticationv1.UserInfo{Username: user1}))
+	resp := ac.Admit(reqCtx, createCreateResource(reqCtx, t, r))
+
+	// The enforcement of the store applies without a context function.
+	ExpectAllowed(t, resp)
+	want := []string{"invalid value: not what's expected: spec.fieldWithValidation"}
+	if !cmp.Equal(resp.Warnings, want) {
+		t.Error("Warnings (-want, +got):", cmp.Diff(want, resp.Warnings))
+	}
+}
+
 func createDeleteResource(ctx context.Context, t *testing.T, old *Resource) *admissionv1.AdmissionRequest {
 	t.Helper()
 	req := &admissionv1.AdmissionRequest{
@@ -550,6 +830,87 @@ func TestAdmitUpdates(t *testing.T) {
 	}
 }
 
+func TestAdmitUpdatesRatcheting(t *testing.T) {
+	tests := []struct {
+		name         string
+		ratchet      bool
+		kinds        []schema.GroupKind
+		old          string
+		new          string
+		rejection    string
+		wantWarnings []string
+	}{{
+		name:         "unchanged violation",
+		ratchet:      true,
+		old:          "not what's expected",
+		new:          "not what's expected",
+		wantWarnings: []string{"invalid value: not what's expected: spec.fieldWithValidation"},
+	}, {
+		name:         "unchanged violation of this kind",
+		ratchet:      true,
+		kinds:        []schema.GroupKind{resourceGVK.GroupKind()},
+		old:          "not what's expected",
+		new:          "not what's expected",
+		wantWarnings: []string{"invalid value: not what's expected: spec.fieldWithValidation"},
+	}, {
+		name:      "unchanged violation of another kind",
+		ratchet:   true,
+		kinds:     []schema.GroupKind{{Group: "apps", Kind: "Deployment"}},
+		old:       "not what's expected",
+		new:       "not what's expected",
+		rejection: "invalid value: not what's expected",
+	}, {
+		name:      "changed violation",
+		ratchet:   true,
+		old:       "not what's expected",
+		new:       "still not what's expected",
+		rejection: "invalid value: still not what's expected",
+	}, {
+		name:      "new violation",
+		ratchet:   true,
+		old:       "magic value",
+		new:       "not what's expected",
+		rejection: "invalid value: not what's expected",
+	}, {
+		name:      "not ratcheting",
+		old:       "not what's expected",
+		new:       "not what's expected",
+		rejection: "invalid value: not what's expected",
+	}}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			old := CreateResource("a name")
+			ctx := TestContextWithLogger(t)
+			old.SetDefaults(ctx)
+			old.Spec.FieldWithValidation = tc.old
+
+			// Make an unrelated change alongside.
+			new := old.DeepCopy()
+			new.Spec.FieldWithValidation = tc.new
+			new.Spec.FieldForCallbackValidation = "magic value"
+
+			ctx = apis.WithUserInfo(apis.WithinUpdate(ctx, old),
+				&authenticationv1.UserInfo{Username: user2})
+			if tc.ratchet {
+				ctx = apis.WithRatchetingValidation(ctx, tc.kinds...)
+			}
+
+			_, ac := newNonRunningTestResourceAdmissionController(t)
+			resp := ac.Admit(ctx, createUpdateResource(ctx, t, old, new, ""))
+
+			if tc.rejection == "" {
+				ExpectAllowed(t, resp)
+			} else {
+				ExpectFailsWith(t, resp, tc.rejection)
+			}
+			if !cmp.Equal(resp.Warnings, tc.wantWarnings) {
+				t.Error("Warnings (-want, +got):", cmp.Diff(tc.wantWarnings, resp.Warnings))
+			}
+		})
+	}
+}
+
 func createUpdateResource(ctx context.Context, t *testing.T, old, new *Resource, subresource string) *admissionv1.AdmissionRequest {
 	t.Helper()
 	req := &admissionv1.AdmissionRequest{
@@ -717,3 +1078,54 @@ func newTestResourceAdmissionController(t *testing.T) webhook.AdmissionControlle
 
 	return c.Reconciler.(*reconciler)
 }
+
+func TestHasSideEffects(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "pkg.knative.dev", Version: "v1alpha1", Kind: "Resource"}
+	other := schema.GroupVersionKind{Group: "pkg.knative.dev", Version: "v1alpha1", Kind: "Other"}
+	ac := &reconciler{
+		callbacks: map[schema.GroupVersionKind]Callback{
+			gvk:   NewCallback(resourceCallback, webhook.Create).WithSideEffects(),
+			other: NewCallback(resourceCallback, webhook.Create),
+		},
+	}
+
+	tests := []struct {
+		name      string
+		kind      schema.GroupVersionKind
+		operation admissionv1.Operation
+		want      bool
+	}{{
+		name:      "side effects on the operation",
+		kind:      gvk,
+		operation: admissionv1.Create,
+		want:      true,
+	}, {
+		name:      "operation not supported by the callback",
+		kind:      gvk,
+		operation: admissionv1.Update,
+	}, {
+		name:      "callback without side effects",
+		kind:      other,
+		operation: admissionv1.Create,
+	}, {
+		name:      "no callback",
+		kind:      schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"},
+		operation: admissionv1.Create,
+	}}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := &admissionv1.AdmissionRequest{
+				Kind: metav1.GroupVersionKind{
+					Group:   tc.kind.Group,
+					Version: tc.kind.Version,
+					Kind:    tc.kind.Kind,
+				},
+				Operation: tc.operation,
+			}
+			if got := ac.HasSideEffects(req); got != tc.want {
+				t.Errorf("HasSideEffects() = %t, wanted %t", got, tc.want)
+			}
+		})
+	}
+}
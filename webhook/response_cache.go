@@ -0,0 +1,224 @@
+/*
+Copyright 2023 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"hash"
+	"strconv"
+	"sync"
+	"time"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	utilcache "k8s.io/apimachinery/pkg/util/cache"
+)
+
+const (
+	// DefaultResponseCacheSize is the default maximum number of admission
+	// responses cached.
+	DefaultResponseCacheSize = 1000
+
+	// DefaultResponseCacheTTL is the default time admission responses are
+	// cached for.
+	DefaultResponseCacheTTL = 10 * time.Second
+)
+
+// Results of looking up the response to an admission request in the cache.
+const (
+	cacheHit    = "hit"
+	cacheMiss   = "miss"
+	cacheBypass = "bypass"
+)
+
+// ResponseCacheOptions configures the cache of admission responses, which
+// answers the retries and reinvocations of admission requests without
+// admitting them again.
+//
+// Requests are identified by their UID and content: the admission
+// controller, the user, the operation and its options and the objects.  The
+// API server keeps the UID of a request when retrying or reinvoking it, and
+// the content guards against reinvocations with a changed object.  Only the
+// responses admitting requests are cached, since denials may come from
+// transient failures, e.g. of the admission controller's listers.
+type ResponseCacheOptions struct {
+	// Size is the maximum number of responses cached.  Defaults to
+	// DefaultResponseCacheSize.
+	Size int
+
+	// TTL is how long responses are cached for, and so how long the
+	// responses to requests whose outcome depends on the state of the
+	// cluster may be stale.  Defaults to DefaultResponseCacheTTL.
+	TTL time.Duration
+}
+
+// SideEffectingAdmissionController is implemented by AdmissionControllers
+// whose admission of some requests has side effects, e.g. through their
+// callbacks, so that the responses to these requests are never cached.
+type SideEffectingAdmissionController interface {
+	// HasSideEffects returns whether admitting the request has side effects.
+	HasSideEffects(*admissionv1.AdmissionRequest) bool
+}
+
+// responseCache caches admission responses, and lets identical requests in
+// flight wait for the response to the first one.  A nil responseCache
+// caches nothing.
+type responseCache struct {
+	ttl   time.Duration
+	cache *utilcache.LRUExpireCache
+
+	mu       sync.Mutex
+	inflight map[string]*inflightAdmission
+}
+
+// inflightAdmission is the admission of a request in flight, whose response
+// is set once done is closed, or left nil if it can't be cached or Admit
+// panicked.
+type inflightAdmission struct {
+	done chan struct{}
+	resp *admissionv1.AdmissionResponse
+}
+
+func newResponseCache(opts *ResponseCacheOptions) *responseCache {
+	return newResponseCacheWithClock(opts, nil)
+}
+
+func newResponseCacheWithClock(opts *ResponseCacheOptions, clock utilcache.Clock) *responseCache {
+	if opts == nil {
+		return nil
+	}
+	size, ttl := opts.Size, opts.TTL
+	if size <= 0 {
+		size = DefaultResponseCacheSize
+	}
+	if ttl <= 0 {
+		ttl = DefaultResponseCacheTTL
+	}
+	cache := utilcache.NewLRUExpireCache(size)
+	if clock != nil {
+		cache = utilcache.NewLRUExpireCacheWithClock(size, clock)
+	}
+	return &responseCache{
+		ttl:      ttl,
+		cache:    cache,
+		inflight: make(map[string]*inflightAdmission),
+	}
+}
+
+// admit returns the cached response to the request, if any, or else admits
+// it with the admission controller and caches the response.
+func (rc *responseCache) admit(ctx context.Context, c AdmissionController, req *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	if rc == nil {
+		return c.Admit(ctx, req)
+	}
+	if se, ok := c.(SideEffectingAdmissionController); ok && se.HasSideEffects(req) {
+		reportCacheResult(req, cacheBypass)
+		return c.Admit(ctx, req)
+	}
+
+	key := responseCacheKey(c.Path(), req)
+	rc.mu.Lock()
+	if resp, ok := rc.cache.Get(key); ok {
+		rc.mu.Unlock()
+		reportCacheResult(req, cacheHit)
+		return resp.(*admissionv1.AdmissionResponse).DeepCopy()
+	}
+	if call, ok := rc.inflight[key]; ok {
+		rc.mu.Unlock()
+		select {
+		case <-call.done:
+		case <-ctx.Done():
+			// Don't outlive our own request waiting on another one; the
+			// admission controller decides how to answer it in its stead.
+			reportCacheResult(req, cacheMiss)
+			return c.Admit(ctx, req)
+		}
+		if call.resp == nil {
+			reportCacheResult(req, cacheMiss)
+			return c.Admit(ctx, req)
+		}
+		reportCacheResult(req, cacheHit)
+		return call.resp.DeepCopy()
+	}
+	call := &inflightAdmission{done: make(chan struct{})}
+	rc.inflight[key] = call
+	rc.mu.Unlock()
+
+	reportCacheResult(req, cacheMiss)
+	defer func() {
+		rc.mu.Lock()
+		defer rc.mu.Unlock()
+		delete(rc.inflight, key)
+		if call.resp != nil {
+			rc.cache.Add(key, call.resp, rc.ttl)
+		}
+		close(call.done)
+	}()
+	resp := c.Admit(ctx, req)
+	if cacheable(ctx, resp) {
+		// Keep a copy, since the caller fills in the response.
+		call.resp = resp.DeepCopy()
+	}
+	return resp
+}
+
+// cacheable returns whether the response to a request admitted with the
+// context may be cached, and so answer the requests waiting for it.  It is
+// not when the context ended before the request was admitted, or when the
+// request was denied, possibly by a transient failure.
+func cacheable(ctx context.Context, resp *admissionv1.AdmissionResponse) bool {
+	return ctx.Err() == nil && resp != nil && resp.Allowed
+}
+
+// responseCacheKey returns the hash identifying the request to the admission
+// controller at the path.
+func responseCacheKey(path string, req *admissionv1.AdmissionRequest) string {
+	// The encoding of UserInfo is deterministic, its Extra map being sorted.
+	user, _ := json.Marshal(req.UserInfo)
+	dryRun := req.DryRun != nil && *req.DryRun
+
+	h := sha256.New()
+	for _, field := range [][]byte{
+		[]byte(path),
+		[]byte(req.UID),
+		[]byte(req.Kind.String()),
+		[]byte(req.Resource.String()),
+		[]byte(req.SubResource),
+		[]byte(req.Namespace),
+		[]byte(req.Name),
+		[]byte(req.Operation),
+		[]byte(strconv.FormatBool(dryRun)),
+		user,
+		req.Options.Raw,
+		req.Object.Raw,
+		req.OldObject.Raw,
+	} {
+		writeField(h, field)
+	}
+	return string(h.Sum(nil))
+}
+
+// writeField writes the field to the hash, prefixed with its length so that
+// consecutive fields can't be confused.
+func writeField(h hash.Hash, field []byte) {
+	var n [8]byte
+	binary.BigEndian.PutUint64(n[:], uint64(len(field)))
+	h.Write(n[:])
+	h.Write(field)
+}
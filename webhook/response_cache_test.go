@@ -0,0 +1,335 @@
+/*
+Copyright 2023 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Yangfisher1/knative-common-pkg/metrics/metricstest"
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// countingAdmissionController admits requests with a warning numbering the
+// calls to Admit, after waiting for release if set or for the context to be
+// done.
+type countingAdmissionController struct {
+	path        string
+	sideEffects bool
+	deny        bool
+	release     chan struct{}
+	calls       atomic.Int32
+}
+
+func (cac *countingAdmissionController) Path() string {
+	return cac.path
+}
+
+func (cac *countingAdmissionController) Admit(ctx context.Context, req *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	n := cac.calls.Add(1)
+	if cac.release != nil {
+		select {
+		case <-cac.release:
+		case <-ctx.Done():
+		}
+	}
+	return &admissionv1.AdmissionResponse{
+		Allowed:  !cac.deny,
+		Warnings: []string{fmt.Sprint("call ", n)},
+	}
+}
+
+func (cac *countingAdmissionController) HasSideEffects(*admissionv1.AdmissionRequest) bool {
+	return cac.sideEffects
+}
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (fc *fakeClock) Now() time.Time {
+	return fc.now
+}
+
+func cacheTestRequest(uid types.UID) *admissionv1.AdmissionRequest {
+	return &admissionv1.AdmissionRequest{
+		UID:       uid,
+		Kind:      metav1.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"},
+		Resource:  metav1.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"},
+		Namespace: "ns",
+		Name:      "name",
+		Operation: admissionv1.Create,
+		UserInfo:  authenticationv1.UserInfo{Username: "alice"},
+		Object:    runtime.RawExtension{Raw: []byte(`{"spec":{}}`)},
+	}
+}
+
+func TestResponseCache(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        *ResponseCacheOptions
+		sideEffects bool
+		second      func(*admissionv1.AdmissionRequest)
+		advance     time.Duration
+		wantCalls   int32
+	}{{
+		name:      "reinvocation",
+		opts:      &ResponseCacheOptions{},
+		wantCalls: 1,
+	}, {
+		name:      "new UID",
+		opts:      &ResponseCacheOptions{},
+		second:    func(req *admissionv1.AdmissionRequest) { req.UID = "other" },
+		wantCalls: 2,
+	}, {
+		name:      "no cache",
+		wantCalls: 2,
+	}, {
+		name: "different object",
+		opts: &ResponseCacheOptions{},
+		second: func(req *admissionv1.AdmissionRequest) {
+			req.Object.Raw = []byte(`{"spec":{"replicas":1}}`)
+		},
+		wantCalls: 2,
+	}, {
+		name:      "different user",
+		opts:      &ResponseCacheOptions{},
+		second:    func(req *admissionv1.AdmissionRequest) { req.UserInfo.Groups = []string{"admins"} },
+		wantCalls: 2,
+	}, {
+		name:      "different operation",
+		opts:      &ResponseCacheOptions{},
+		second:    func(req *admissionv1.AdmissionRequest) { req.Operation = admissionv1.Update },
+		wantCalls: 2,
+	}, {
+		name: "dry run",
+		opts: &ResponseCacheOptions{},
+		second: func(req *admissionv1.AdmissionRequest) {
+			dryRun := true
+			req.DryRun = &dryRun
+		},
+		wantCalls: 2,
+	}, {
+		name:      "within the TTL",
+		opts:      &ResponseCacheOptions{TTL: time.Minute},
+		advance:   59 * time.Second,
+		wantCalls: 1,
+	}, {
+		name:      "expired",
+		opts:      &ResponseCacheOptions{TTL: time.Minute},
+		advance:   61 * time.Second,
+		wantCalls: 2,
+	}, {
+		name:        "side effects",
+		opts:        &ResponseCacheOptions{},
+		sideEffects: true,
+		wantCalls:   2,
+	}}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			clock := &fakeClock{now: time.Now()}
+			rc := newResponseCacheWithClock(tc.opts, clock)
+			c := &countingAdmissionController{path: "/path", sideEffects: tc.sideEffects}
+			ctx := context.Background()
+
+			first := rc.admit(ctx, c, cacheTestRequest("first"))
+			// The response is a copy, which the caller may fill in.
+			first.UID = "first"
+
+			clock.now = clock.now.Add(tc.advance)
+			req := cacheTestRequest("first")
+			if tc.second != nil {
+				tc.second(req)
+			}
+			second := rc.admit(ctx, c, req)
+
+			if got := c.calls.Load(); got != tc.wantCalls {
+				t.Errorf("Admit was called %d times, wanted %d", got, tc.wantCalls)
+			}
+			if tc.wantCalls == 1 {
+				if second.UID != "" {
+					t.Errorf("UID = %q, wanted the cached response to be unchanged", second.UID)
+				}
+				if got, want := second.Warnings, first.Warnings; len(got) != 1 || got[0] != want[0] {
+					t.Errorf("Warnings = %v, wanted the cached %v", got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestResponseCacheUncacheable(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		deny bool
+	}{{
+		name: "denied",
+		ctx:  context.Background(),
+		deny: true,
+	}, {
+		name: "context done",
+		ctx:  canceled,
+	}}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rc := newResponseCache(&ResponseCacheOptions{})
+			c := &countingAdmissionController{path: "/path", deny: tc.deny}
+
+			rc.admit(tc.ctx, c, cacheTestRequest("uid"))
+			rc.admit(context.Background(), c, cacheTestRequest("uid"))
+
+			if got, want := c.calls.Load(), int32(2); got != want {
+				t.Errorf("Admit was called %d times, wanted %d", got, want)
+			}
+		})
+	}
+}
+
+func TestResponseCacheSize(t *testing.T) {
+	rc := newResponseCache(&ResponseCacheOptions{Size: 1})
+	c := &countingAdmissionController{path: "/path"}
+	ctx := context.Background()
+
+	first, second := cacheTestRequest("first"), cacheTestRequest("second")
+	second.Name = "other"
+	rc.admit(ctx, c, first)
+	rc.admit(ctx, c, second)
+	// The response to the first request was evicted.
+	rc.admit(ctx, c, first)
+
+	if got, want := c.calls.Load(), int32(3); got != want {
+		t.Errorf("Admit was called %d times, wanted %d", got, want)
+	}
+}
+
+func TestResponseCacheInflight(t *testing.T) {
+	setup()
+	rc := newResponseCache(&ResponseCacheOptions{})
+	c := &countingAdmissionController{path: "/path", release: make(chan struct{})}
+	ctx := context.Background()
+
+	const requests = 5
+	var wg sync.WaitGroup
+	responses := make(chan *admissionv1.AdmissionResponse, requests)
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			responses <- rc.admit(ctx, c, cacheTestRequest("uid"))
+		}()
+	}
+
+	// Wait for the first request to be admitted, and the others to wait for
+	// its response.
+	for c.calls.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(c.release)
+	wg.Wait()
+	close(responses)
+
+	if got, want := c.calls.Load(), int32(1); got != want {
+		t.Errorf("Admit was called %d times, wanted %d", got, want)
+	}
+	for resp := range responses {
+		if len(resp.Warnings) != 1 || resp.Warnings[0] != "call 1" {
+			t.Errorf("Warnings = %v, wanted the response to the first call", resp.Warnings)
+		}
+	}
+
+	req := cacheTestRequest("uid")
+	tags := func(result string) map[string]string {
+		return map[string]string{
+			requestOperationKey.Name(): string(req.Operation),
+			kindGroupKey.Name():        req.Kind.Group,
+			kindVersionKey.Name():      req.Kind.Version,
+			kindKindKey.Name():         req.Kind.Kind,
+			cacheResultKey.Name():      result,
+		}
+	}
+	want := metricstest.IntMetric(responseCacheCountName, 1, tags(cacheMiss))
+	want.Values = append(want.Values, metricstest.IntMetric(responseCacheCountName, requests-1, tags(cacheHit)).Values...)
+	metricstest.AssertMetric(t, want)
+}
+
+func TestResponseCacheInflightCanceled(t *testing.T) {
+	setup()
+	rc := newResponseCache(&ResponseCacheOptions{})
+	c := &countingAdmissionController{path: "/path", release: make(chan struct{})}
+
+	first := make(chan *admissionv1.AdmissionResponse)
+	go func() {
+		first <- rc.admit(context.Background(), c, cacheTestRequest("uid"))
+	}()
+	for c.calls.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// A request whose context ends doesn't wait for the one in flight.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	resp := rc.admit(ctx, c, cacheTestRequest("uid"))
+	if len(resp.Warnings) != 1 || resp.Warnings[0] != "call 2" {
+		t.Errorf("Warnings = %v, wanted the response to its own call", resp.Warnings)
+	}
+
+	close(c.release)
+	if resp := <-first; len(resp.Warnings) != 1 || resp.Warnings[0] != "call 1" {
+		t.Errorf("Warnings = %v, wanted the response to the first call", resp.Warnings)
+	}
+	// The response to the first call is still cached.
+	if resp := rc.admit(context.Background(), c, cacheTestRequest("uid")); len(resp.Warnings) != 1 || resp.Warnings[0] != "call 1" {
+		t.Errorf("Warnings = %v, wanted the cached response to the first call", resp.Warnings)
+	}
+}
+
+func TestResponseCacheKey(t *testing.T) {
+	req := cacheTestRequest("uid")
+	key := responseCacheKey("/path", req)
+
+	if got := responseCacheKey("/other", req); got == key {
+		t.Error("responseCacheKey() is the same for another path")
+	}
+
+	// Fields can't be confused by moving bytes from one to the next.
+	moved := req.DeepCopy()
+	moved.Namespace, moved.Name = "nsn", "ame"
+	if got := responseCacheKey("/path", moved); got == key {
+		t.Error("responseCacheKey() is the same when moving bytes between fields")
+	}
+
+	other := req.DeepCopy()
+	other.UID = "other"
+	if got := responseCacheKey("/path", other); got == key {
+		t.Error("responseCacheKey() is the same for another UID")
+	}
+}
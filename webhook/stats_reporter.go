@@ -17,7 +17,9 @@ limitations under the License.
 package webhook
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"strconv"
 	"time"
 
@@ -25,12 +27,39 @@ import (
 	"go.opencensus.io/stats"
 	"go.opencensus.io/stats/view"
 	"go.opencensus.io/tag"
+	"go.opencensus.io/trace"
 	admissionv1 "k8s.io/api/admission/v1"
+	apixv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
 const (
 	requestCountName     = "request_count"
 	requestLatenciesName = "request_latencies"
+
+	conversionRequestCountName     = "conversion_request_count"
+	conversionRequestLatenciesName = "conversion_request_latencies"
+	conversionErrorCountName       = "conversion_error_count"
+
+	phaseLatenciesName = "phase_latencies"
+	patchCountName     = "patch_count"
+
+	responseCacheCountName = "response_cache_count"
+
+	auditDroppedCountName = "audit_dropped_count"
+
+	tlsHandshakeFailureCountName = "tls_handshake_failure_count"
+	certificateConfiguredName    = "certificate_configured"
+)
+
+// Reasons of TLS handshake failures.
+const (
+	reasonSecretMissing      = "secret_missing"
+	reasonKeyMissing         = "key_missing"
+	reasonCertMissing        = "cert_missing"
+	reasonInvalidCertificate = "invalid_certificate"
+	reasonHandshake          = "handshake"
 )
 
 var (
@@ -42,6 +71,42 @@ var (
 		requestLatenciesName,
 		"The response time in milliseconds",
 		stats.UnitMilliseconds)
+	conversionRequestCountM = stats.Int64(
+		conversionRequestCountName,
+		"The number of conversion requests that are routed to webhook",
+		stats.UnitDimensionless)
+	conversionResponseTimeInMsecM = stats.Float64(
+		conversionRequestLatenciesName,
+		"The response time of conversion requests in milliseconds",
+		stats.UnitMilliseconds)
+	conversionErrorCountM = stats.Int64(
+		conversionErrorCountName,
+		"The number of objects that failed to convert",
+		stats.UnitDimensionless)
+	phaseLatencyInMsecM = stats.Float64(
+		phaseLatenciesName,
+		"The time spent in a phase of admission requests in milliseconds",
+		stats.UnitMilliseconds)
+	patchCountM = stats.Int64(
+		patchCountName,
+		"The number of admission responses with a patch, by size",
+		stats.UnitDimensionless)
+	responseCacheCountM = stats.Int64(
+		responseCacheCountName,
+		"The number of admission requests looked up in the response cache, by result",
+		stats.UnitDimensionless)
+	auditDroppedCountM = stats.Int64(
+		auditDroppedCountName,
+		"The number of admission audit records dropped because the audit queue was full",
+		stats.UnitDimensionless)
+	tlsHandshakeFailureCountM = stats.Int64(
+		tlsHandshakeFailureCountName,
+		"The number of TLS handshakes with the webhook that failed",
+		stats.UnitDimensionless)
+	certificateConfiguredM = stats.Int64(
+		certificateConfiguredName,
+		"Whether the webhook had a serving certificate at the last TLS handshake (1) or not (0)",
+		stats.UnitDimensionless)
 
 	// Create the tag keys that will be used to add tags to our measurements.
 	// Tag keys must conform to the restrictions described in
@@ -57,11 +122,40 @@ var (
 	resourceResourceKey  = tag.MustNewKey("resource_resource")
 	resourceNamespaceKey = tag.MustNewKey("resource_namespace")
 	admissionAllowedKey  = tag.MustNewKey("admission_allowed")
+	reasonKey            = tag.MustNewKey("reason")
+	sourceVersionKey     = tag.MustNewKey("source_version")
+	desiredVersionKey    = tag.MustNewKey("desired_version")
+	objectCountKey       = tag.MustNewKey("object_count")
+	conversionResultKey  = tag.MustNewKey("conversion_result")
+	phaseKey             = tag.MustNewKey("phase")
+	patchSizeKey         = tag.MustNewKey("patch_size")
+	cacheResultKey       = tag.MustNewKey("cache_result")
+	auditDecisionKey     = tag.MustNewKey("audit_decision")
+)
+
+// AdmissionPhase is a phase of the handling of admission requests, whose
+// latency is reported separately.
+type AdmissionPhase string
+
+const (
+	// PhaseDecode is the decoding of the objects of the request.
+	PhaseDecode AdmissionPhase = "decode"
+	// PhaseDefaults is the call to SetDefaults.
+	PhaseDefaults AdmissionPhase = "defaults"
+	// PhaseValidate is the call to Validate.
+	PhaseValidate AdmissionPhase = "validate"
+	// PhaseCallback is the call to the callback registered for the kind.
+	PhaseCallback AdmissionPhase = "callback"
+	// PhaseBind is the application of bindings to their subject.
+	PhaseBind AdmissionPhase = "bind"
+	// PhasePatch is the computation of the patch of the response.
+	PhasePatch AdmissionPhase = "patch"
 )
 
 // StatsReporter reports webhook metrics
 type StatsReporter interface {
 	ReportRequest(request *admissionv1.AdmissionRequest, response *admissionv1.AdmissionResponse, d time.Duration) error
+	ReportConversionRequest(request *apixv1.ConversionRequest, response *apixv1.ConversionResponse, d time.Duration) error
 }
 
 // reporter implements StatsReporter interface
@@ -105,6 +199,194 @@ func (r *reporter) ReportRequest(req *admissionv1.AdmissionRequest, resp *admiss
 	return nil
 }
 
+// Captures conversion req count metric, recording the count, the duration
+// and the number of objects that failed to convert.
+func (r *reporter) ReportConversionRequest(req *apixv1.ConversionRequest, resp *apixv1.ConversionResponse, d time.Duration) error {
+	// The objects of a request are all of the same kind, and normally of
+	// the same version, so the first one stands for them all.
+	var source schema.GroupVersionKind
+	if len(req.Objects) > 0 {
+		var tm metav1.TypeMeta
+		if err := json.Unmarshal(req.Objects[0].Raw, &tm); err == nil {
+			source = tm.GroupVersionKind()
+		}
+	}
+	desired, _ := schema.ParseGroupVersion(req.DesiredAPIVersion)
+
+	ctx, err := tag.New(
+		r.ctx,
+		tag.Insert(kindGroupKey, source.Group),
+		tag.Insert(kindKindKey, source.Kind),
+		tag.Insert(sourceVersionKey, source.Version),
+		tag.Insert(desiredVersionKey, desired.Version),
+		tag.Insert(objectCountKey, objectCountBucket(len(req.Objects))),
+		tag.Insert(conversionResultKey, string(resp.Result.Status)),
+	)
+	if err != nil {
+		return err
+	}
+
+	metrics.RecordBatch(ctx, conversionRequestCountM.M(1),
+		// Convert time.Duration in nanoseconds to milliseconds
+		conversionResponseTimeInMsecM.M(float64(d.Milliseconds())))
+	if resp.Result.Status == metav1.StatusFailure {
+		failed := int64(1)
+		if resp.Result.Details != nil && len(resp.Result.Details.Causes) > 0 {
+			failed = int64(len(resp.Result.Details.Causes))
+		}
+		metrics.Record(ctx, conversionErrorCountM.M(failed))
+	}
+	return nil
+}
+
+// objectCountBucket returns the bucket of the number of objects in a
+// conversion request, to keep the cardinality of its tag low.
+func objectCountBucket(n int) string {
+	switch {
+	case n <= 1:
+		return strconv.Itoa(n)
+	case n <= 10:
+		return "2-10"
+	case n <= 100:
+		return "11-100"
+	default:
+		return "101+"
+	}
+}
+
+// StartPhase starts a span for the phase of the admission request, as a child
+// of the span of the request in the context, and returns its context with a
+// function to call with the outcome of the phase when it is over.  The
+// function ends the span and reports the latency of the phase.
+func StartPhase(ctx context.Context, req *admissionv1.AdmissionRequest, phase AdmissionPhase) (context.Context, func(error)) {
+	ctx, span := trace.StartSpan(ctx, "admission/"+string(phase))
+	start := time.Now()
+	return ctx, func(err error) {
+		if err != nil {
+			span.SetStatus(trace.Status{
+				Code:    trace.StatusCodeUnknown,
+				Message: err.Error(),
+			})
+		}
+		span.End()
+		reportPhase(req, phase, time.Since(start))
+	}
+}
+
+// reportPhase records the latency of the phase of the admission request.
+func reportPhase(req *admissionv1.AdmissionRequest, phase AdmissionPhase, d time.Duration) {
+	ctx, err := tag.New(
+		context.Background(),
+		tag.Insert(requestOperationKey, string(req.Operation)),
+		tag.Insert(kindGroupKey, req.Kind.Group),
+		tag.Insert(kindVersionKey, req.Kind.Version),
+		tag.Insert(kindKindKey, req.Kind.Kind),
+		tag.Insert(phaseKey, string(phase)),
+	)
+	if err != nil {
+		return
+	}
+	// Phases often take less than a millisecond, so keep the fraction.
+	metrics.Record(ctx, phaseLatencyInMsecM.M(float64(d)/float64(time.Millisecond)))
+}
+
+// reportPatch counts the patch of the response to the admission request by
+// its size, unless it has no operations.
+func reportPatch(req *admissionv1.AdmissionRequest, patch []byte) {
+	if isEmptyPatch(patch) {
+		return
+	}
+	ctx, err := tag.New(
+		context.Background(),
+		tag.Insert(requestOperationKey, string(req.Operation)),
+		tag.Insert(kindGroupKey, req.Kind.Group),
+		tag.Insert(kindVersionKey, req.Kind.Version),
+		tag.Insert(kindKindKey, req.Kind.Kind),
+		tag.Insert(patchSizeKey, patchSizeBucket(len(patch))),
+	)
+	if err != nil {
+		return
+	}
+	metrics.Record(ctx, patchCountM.M(1))
+}
+
+// isEmptyPatch returns whether the patch has no operations.
+func isEmptyPatch(patch []byte) bool {
+	switch string(bytes.TrimSpace(patch)) {
+	case "", "null", "[]":
+		return true
+	}
+	return false
+}
+
+// patchSizeBucket returns the bucket of the size in bytes of a patch, to keep
+// the cardinality of its tag low.
+func patchSizeBucket(n int) string {
+	switch {
+	case n < 1<<10:
+		return "0-1KiB"
+	case n < 10<<10:
+		return "1-10KiB"
+	case n < 100<<10:
+		return "10-100KiB"
+	default:
+		return "100KiB+"
+	}
+}
+
+// reportCacheResult counts the lookup of the response to the admission
+// request in the response cache by its result.
+func reportCacheResult(req *admissionv1.AdmissionRequest, result string) {
+	ctx, err := tag.New(
+		context.Background(),
+		tag.Insert(requestOperationKey, string(req.Operation)),
+		tag.Insert(kindGroupKey, req.Kind.Group),
+		tag.Insert(kindVersionKey, req.Kind.Version),
+		tag.Insert(kindKindKey, req.Kind.Kind),
+		tag.Insert(cacheResultKey, result),
+	)
+	if err != nil {
+		return
+	}
+	metrics.Record(ctx, responseCacheCountM.M(1))
+}
+
+// reportAuditDropped counts an audit record dropped because the audit queue
+// was full.
+func reportAuditDropped(decision AuditDecision) {
+	ctx, err := tag.New(context.Background(), tag.Insert(auditDecisionKey, string(decision)))
+	if err != nil {
+		return
+	}
+	metrics.Record(ctx, auditDroppedCountM.M(1))
+}
+
+// reportHandshakeFailure counts a TLS handshake failure for the given reason.
+func reportHandshakeFailure(reason string) {
+	ctx, err := tag.New(context.Background(), tag.Insert(reasonKey, reason))
+	if err != nil {
+		return
+	}
+	metrics.Record(ctx, tlsHandshakeFailureCountM.M(1))
+}
+
+// reportNoCertificate records that a TLS handshake found no serving
+// certificate for the given reason.
+func reportNoCertificate(reason string) {
+	reportHandshakeFailure(reason)
+	reportCertificateConfigured(false)
+}
+
+// reportCertificateConfigured records whether a serving certificate is
+// configured.
+func reportCertificateConfigured(configured bool) {
+	var v int64
+	if configured {
+		v = 1
+	}
+	metrics.Record(context.Background(), certificateConfiguredM.M(v))
+}
+
 func RegisterMetrics() {
 	tagKeys := []tag.Key{
 		requestOperationKey,
@@ -116,6 +398,31 @@ func RegisterMetrics() {
 		resourceResourceKey,
 		resourceNamespaceKey,
 		admissionAllowedKey}
+	conversionTagKeys := []tag.Key{
+		kindGroupKey,
+		kindKindKey,
+		sourceVersionKey,
+		desiredVersionKey,
+		objectCountKey,
+		conversionResultKey}
+	phaseTagKeys := []tag.Key{
+		requestOperationKey,
+		kindGroupKey,
+		kindVersionKey,
+		kindKindKey,
+		phaseKey}
+	patchTagKeys := []tag.Key{
+		requestOperationKey,
+		kindGroupKey,
+		kindVersionKey,
+		kindKindKey,
+		patchSizeKey}
+	cacheTagKeys := []tag.Key{
+		requestOperationKey,
+		kindGroupKey,
+		kindVersionKey,
+		kindKindKey,
+		cacheResultKey}
 
 	if err := view.Register(
 		&view.View{
@@ -130,6 +437,59 @@ func RegisterMetrics() {
 			Aggregation: view.Distribution(metrics.Buckets125(1, 100000)...), // [1 2 5 10 20 50 100 200 500 1000 2000 5000 10000 20000 50000 100000]ms
 			TagKeys:     tagKeys,
 		},
+		&view.View{
+			Description: conversionRequestCountM.Description(),
+			Measure:     conversionRequestCountM,
+			Aggregation: view.Count(),
+			TagKeys:     conversionTagKeys,
+		},
+		&view.View{
+			Description: conversionResponseTimeInMsecM.Description(),
+			Measure:     conversionResponseTimeInMsecM,
+			Aggregation: view.Distribution(metrics.Buckets125(1, 100000)...), // [1 2 5 10 20 50 100 200 500 1000 2000 5000 10000 20000 50000 100000]ms
+			TagKeys:     conversionTagKeys,
+		},
+		&view.View{
+			Description: conversionErrorCountM.Description(),
+			Measure:     conversionErrorCountM,
+			Aggregation: view.Sum(),
+			TagKeys:     conversionTagKeys,
+		},
+		&view.View{
+			Description: phaseLatencyInMsecM.Description(),
+			Measure:     phaseLatencyInMsecM,
+			Aggregation: view.Distribution(metrics.Buckets125(0.1, 10000)...), // [0.1 0.2 0.5 1 2 5 10 20 50 100 200 500 1000 2000 5000 10000]ms
+			TagKeys:     phaseTagKeys,
+		},
+		&view.View{
+			Description: patchCountM.Description(),
+			Measure:     patchCountM,
+			Aggregation: view.Count(),
+			TagKeys:     patchTagKeys,
+		},
+		&view.View{
+			Description: responseCacheCountM.Description(),
+			Measure:     responseCacheCountM,
+			Aggregation: view.Count(),
+			TagKeys:     cacheTagKeys,
+		},
+		&view.View{
+			Description: auditDroppedCountM.Description(),
+			Measure:     auditDroppedCountM,
+			Aggregation: view.Count(),
+			TagKeys:     []tag.Key{auditDecisionKey},
+		},
+		&view.View{
+			Description: tlsHandshakeFailureCountM.Description(),
+			Measure:     tlsHandshakeFailureCountM,
+			Aggregation: view.Count(),
+			TagKeys:     []tag.Key{reasonKey},
+		},
+		&view.View{
+			Description: certificateConfiguredM.Description(),
+			Measure:     certificateConfiguredM,
+			Aggregation: view.LastValue(),
+		},
 	); err != nil {
 		panic(err)
 	}
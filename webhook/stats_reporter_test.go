@@ -17,14 +17,20 @@ limitations under the License.
 package webhook
 
 import (
+	"context"
+	"errors"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/Yangfisher1/knative-common-pkg/metrics/metricstest"
 	_ "github.com/Yangfisher1/knative-common-pkg/metrics/testing"
+	"go.opencensus.io/trace"
 	admissionv1 "k8s.io/api/admission/v1"
+	apixv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 )
 
 func TestWebhookStatsReporter(t *testing.T) {
@@ -65,12 +71,137 @@ func TestWebhookStatsReporter(t *testing.T) {
 	metricstest.CheckDistributionData(t, requestLatenciesName, expectedTags, 2, shortTime, longTime)
 }
 
+func TestWebhookConversionStatsReporter(t *testing.T) {
+	setup()
+	req := &apixv1.ConversionRequest{
+		UID:               "705ab4f5-6393-11e8-b7cc-42010a800002",
+		DesiredAPIVersion: "serving.knative.dev/v1",
+		Objects: []runtime.RawExtension{
+			{Raw: []byte(`{"apiVersion":"serving.knative.dev/v1alpha1","kind":"Service"}`)},
+			{Raw: []byte(`{"apiVersion":"serving.knative.dev/v1alpha1","kind":"Service"}`)},
+		},
+	}
+	resp := &apixv1.ConversionResponse{
+		UID: req.UID,
+		Result: metav1.Status{
+			Status: metav1.StatusFailure,
+			Details: &metav1.StatusDetails{
+				Causes: []metav1.StatusCause{{Field: "objects[0]"}, {Field: "objects[1]"}},
+			},
+		},
+	}
+
+	r, _ := NewStatsReporter()
+
+	shortTime, longTime := 1100.0, 9100.0
+	expectedTags := map[string]string{
+		kindGroupKey.Name():        "serving.knative.dev",
+		kindKindKey.Name():         "Service",
+		sourceVersionKey.Name():    "v1alpha1",
+		desiredVersionKey.Name():   "v1",
+		objectCountKey.Name():      "2-10",
+		conversionResultKey.Name(): metav1.StatusFailure,
+	}
+
+	r.ReportConversionRequest(req, resp, time.Duration(shortTime)*time.Millisecond)
+	r.ReportConversionRequest(req, resp, time.Duration(longTime)*time.Millisecond)
+
+	metricstest.CheckCountData(t, conversionRequestCountName, expectedTags, 2)
+	metricstest.CheckDistributionData(t, conversionRequestLatenciesName, expectedTags, 2, shortTime, longTime)
+	metricstest.CheckSumData(t, conversionErrorCountName, expectedTags, 4)
+}
+
+func TestStartPhase(t *testing.T) {
+	setup()
+	req := &admissionv1.AdmissionRequest{
+		Kind:      metav1.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"},
+		Operation: admissionv1.Create,
+	}
+
+	for _, phase := range []AdmissionPhase{PhaseDecode, PhaseDefaults, PhaseDefaults} {
+		ctx, end := StartPhase(context.Background(), req, phase)
+		if trace.FromContext(ctx) == nil {
+			t.Errorf("StartPhase(%s) returned a context without a span", phase)
+		}
+		end(nil)
+	}
+	_, end := StartPhase(context.Background(), req, PhaseValidate)
+	end(errors.New("invalid value"))
+
+	tags := func(phase AdmissionPhase) map[string]string {
+		return map[string]string{
+			requestOperationKey.Name(): string(req.Operation),
+			kindGroupKey.Name():        req.Kind.Group,
+			kindVersionKey.Name():      req.Kind.Version,
+			kindKindKey.Name():         req.Kind.Kind,
+			phaseKey.Name():            string(phase),
+		}
+	}
+	want := metricstest.Metric{Name: phaseLatenciesName}
+	for phase, count := range map[AdmissionPhase]int64{
+		PhaseDecode:   1,
+		PhaseDefaults: 2,
+		PhaseValidate: 1,
+	} {
+		want.Values = append(want.Values, metricstest.DistributionCountOnlyMetric(phaseLatenciesName, count, tags(phase)).Values...)
+	}
+	metricstest.AssertMetric(t, want)
+}
+
+func TestReportPatch(t *testing.T) {
+	setup()
+	req := &admissionv1.AdmissionRequest{
+		Kind:      metav1.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"},
+		Operation: admissionv1.Update,
+	}
+
+	for _, patch := range []string{"", "null", "[]", `[{"op":"add","path":"/a","value":1}]`, `[{"op":"remove","path":"/b"}]`} {
+		reportPatch(req, []byte(patch))
+	}
+	reportPatch(req, []byte(`[{"op":"add","path":"/a","value":"`+strings.Repeat("x", 2<<10)+`"}]`))
+
+	tags := func(size string) map[string]string {
+		return map[string]string{
+			requestOperationKey.Name(): string(req.Operation),
+			kindGroupKey.Name():        req.Kind.Group,
+			kindVersionKey.Name():      req.Kind.Version,
+			kindKindKey.Name():         req.Kind.Kind,
+			patchSizeKey.Name():        size,
+		}
+	}
+	want := metricstest.IntMetric(patchCountName, 2, tags("0-1KiB"))
+	want.Values = append(want.Values, metricstest.IntMetric(patchCountName, 1, tags("1-10KiB")).Values...)
+	metricstest.AssertMetric(t, want)
+}
+
+func TestPatchSizeBucket(t *testing.T) {
+	tests := []struct {
+		size int
+		want string
+	}{
+		{0, "0-1KiB"},
+		{1023, "0-1KiB"},
+		{1024, "1-10KiB"},
+		{10<<10 - 1, "1-10KiB"},
+		{10 << 10, "10-100KiB"},
+		{100 << 10, "100KiB+"},
+	}
+	for _, tc := range tests {
+		if got := patchSizeBucket(tc.size); got != tc.want {
+			t.Errorf("patchSizeBucket(%d) = %q, want %q", tc.size, got, tc.want)
+		}
+	}
+}
+
 func setup() {
 	resetMetrics()
 }
 
 // opencensus metrics carry global state that need to be reset between unit tests
 func resetMetrics() {
-	metricstest.Unregister(requestCountName, requestLatenciesName)
+	metricstest.Unregister(requestCountName, requestLatenciesName,
+		conversionRequestCountName, conversionRequestLatenciesName, conversionErrorCountName,
+		phaseLatenciesName, patchCountName, responseCacheCountName, auditDroppedCountName,
+		tlsHandshakeFailureCountName, certificateConfiguredName)
 	RegisterMetrics()
 }
@@ -0,0 +1,393 @@
+/*
+Copyright 2023 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package testing
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	apixv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	fakekubeclient "github.com/Yangfisher1/knative-common-pkg/client/injection/kube/client/fake"
+	"github.com/Yangfisher1/knative-common-pkg/controller"
+	pkgreconciler "github.com/Yangfisher1/knative-common-pkg/reconciler"
+	rtesting "github.com/Yangfisher1/knative-common-pkg/reconciler/testing"
+	"github.com/Yangfisher1/knative-common-pkg/system"
+	"github.com/Yangfisher1/knative-common-pkg/webhook"
+	certresources "github.com/Yangfisher1/knative-common-pkg/webhook/certificates/resources"
+
+	// The webhook serves with the certificates of a Secret.
+	_ "github.com/Yangfisher1/knative-common-pkg/injection/clients/namespacedkube/informers/core/v1/secret/fake"
+)
+
+const (
+	harnessServiceName = "webhook"
+	harnessSecretName  = "webhook-certs"
+	harnessTimeout     = 10 * time.Second
+)
+
+// HarnessCtor constructs the admission and conversion controllers served by
+// a Harness, e.g. the *controller.Impl returned by
+// validation.NewAdmissionController, from a context with fake clients and
+// informers, and the webhook.Options of the Harness.
+type HarnessCtor func(context.Context) []interface{}
+
+// HarnessOptions customize a Harness.
+type HarnessOptions struct {
+	// Objects are added to the fake kube client before the informers start,
+	// e.g. the webhook configurations reconciled by the controllers.
+	Objects []runtime.Object
+
+	// Options optionally customizes the webhook.Options, which default to
+	// serving with the certificates of a generated Secret.
+	Options func(*webhook.Options)
+
+	// Unsynced leaves the admission requests of controllers depending on
+	// informers blocked until Webhook.InformersHaveSynced is called.
+	Unsynced bool
+}
+
+// Harness serves controllers from a real webhook.Webhook, over HTTPS on a
+// loopback port and with fake clients, and sends them AdmissionReviews and
+// ConversionReviews the way the API server would, without a cluster.
+type Harness struct {
+	// Context is the context the controllers were constructed with.
+	Context context.Context
+
+	// Webhook is the webhook serving the controllers.
+	Webhook *webhook.Webhook
+
+	t           *testing.T
+	address     string
+	client      *http.Client
+	controllers []interface{}
+	uid         int64
+}
+
+// NewHarness constructs the controllers and starts serving them.  They are
+// stopped when the test completes.
+func NewHarness(t *testing.T, ho HarnessOptions, ctor HarnessCtor) *Harness {
+	t.Helper()
+
+	port, err := freePort()
+	if err != nil {
+		t.Fatal("Unable to find a free port:", err)
+	}
+	opts := webhook.Options{
+		ServiceName: harnessServiceName,
+		SecretName:  harnessSecretName,
+		Port:        port,
+		GracePeriod: 100 * time.Millisecond,
+	}
+	if ho.Options != nil {
+		ho.Options(&opts)
+	}
+
+	ctx, cancel, informers := rtesting.SetupFakeContextWithCancel(t)
+	ctx = webhook.WithOptions(ctx, opts)
+	t.Cleanup(cancel)
+
+	kubeClient := fakekubeclient.Get(ctx)
+	objs := ho.Objects
+	var secret *corev1.Secret
+	if opts.SecretName != "" {
+		s, err := certresources.MakeSecret(ctx, opts.SecretName, system.Namespace(), opts.ServiceName)
+		if err != nil {
+			t.Fatal("MakeSecret() =", err)
+		}
+		secret = s
+		objs = append(objs, s)
+	}
+	for _, obj := range objs {
+		if err := kubeClient.Tracker().Add(obj); err != nil {
+			t.Fatalf("Unable to add %T: %v", obj, err)
+		}
+	}
+
+	h := &Harness{
+		Context:     ctx,
+		t:           t,
+		address:     net.JoinHostPort("127.0.0.1", strconv.Itoa(port)),
+		controllers: ctor(ctx),
+	}
+
+	stopInformers, err := rtesting.RunAndSyncInformers(ctx, informers...)
+	if err != nil {
+		t.Fatal("RunAndSyncInformers() =", err)
+	}
+	t.Cleanup(func() {
+		// The informers stop once the context is cancelled.
+		cancel()
+		stopInformers()
+	})
+
+	// As in sharedmain, the webhook serves the reconcilers of the controllers.
+	served := make([]interface{}, 0, len(h.controllers))
+	for _, c := range h.controllers {
+		if impl, ok := c.(*controller.Impl); ok {
+			c = impl.Reconciler
+		}
+		served = append(served, c)
+	}
+	h.Webhook, err = webhook.New(ctx, served)
+	if err != nil {
+		t.Fatal("webhook.New() =", err)
+	}
+	if !ho.Unsynced {
+		h.Webhook.InformersHaveSynced()
+	}
+
+	stopCh := make(chan struct{})
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- h.Webhook.Run(stopCh)
+	}()
+	t.Cleanup(func() {
+		close(stopCh)
+		if err := <-errCh; err != nil {
+			t.Error("Webhook.Run() =", err)
+		}
+	})
+
+	if h.client, err = newHarnessClient(secret); err != nil {
+		t.Fatal("Unable to create the client:", err)
+	}
+	if err := wait.PollImmediate(50*time.Millisecond, harnessTimeout, func() (bool, error) {
+		conn, err := net.Dial("tcp", h.address)
+		if err != nil {
+			return false, nil
+		}
+		conn.Close()
+		return true, nil
+	}); err != nil {
+		t.Fatal("The webhook didn't start serving:", err)
+	}
+	return h
+}
+
+// newHarnessClient returns a client trusting the CA of the Secret, and
+// presenting its certificate to the webhook as the API server would.
+func newHarnessClient(secret *corev1.Secret) (*http.Client, error) {
+	if secret == nil {
+		return &http.Client{Timeout: harnessTimeout}, nil
+	}
+	pool := x509.NewCertPool()
+	pool.AppendCertsFromPEM(secret.Data[certresources.CACert])
+	cert, err := tls.X509KeyPair(secret.Data[certresources.ServerCert], secret.Data[certresources.ServerKey])
+	if err != nil {
+		return nil, err
+	}
+	return &http.Client{
+		Timeout: harnessTimeout,
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{
+				ServerName:   harnessServiceName + "." + system.Namespace(),
+				RootCAs:      pool,
+				Certificates: []tls.Certificate{cert},
+				MinVersion:   tls.VersionTLS12,
+			},
+		},
+	}, nil
+}
+
+func freePort() (int, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
+
+// URL returns the URL of the path on the webhook.
+func (h *Harness) URL(path string) string {
+	scheme := "https"
+	if h.Webhook.Options.SecretName == "" {
+		scheme = "http"
+	}
+	return fmt.Sprintf("%s://%s%s", scheme, h.address, path)
+}
+
+// Post posts the body as JSON to the path, and decodes the response into
+// out.  It returns the HTTP status code, leaving out untouched unless it is
+// 200.
+func (h *Harness) Post(path string, body, out interface{}) int {
+	h.t.Helper()
+	b, err := json.Marshal(body)
+	if err != nil {
+		h.t.Fatal("Unable to encode the request:", err)
+	}
+	resp, err := h.client.Post(h.URL(path), "application/json", bytes.NewReader(b))
+	if err != nil {
+		h.t.Fatal("Request failed:", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			h.t.Fatal("Unable to decode the response:", err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func (h *Harness) nextUID() types.UID {
+	return types.UID(fmt.Sprint("harness-", atomic.AddInt64(&h.uid, 1)))
+}
+
+// Admit sends an admission.k8s.io/v1 AdmissionReview of the request to the
+// path, and returns the response.
+func (h *Harness) Admit(path string, req *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	h.t.Helper()
+	return h.review(admissionv1.SchemeGroupVersion.String(), path, req)
+}
+
+// AdmitV1beta1 sends an admission.k8s.io/v1beta1 AdmissionReview of the
+// request to the path, and returns the response.
+func (h *Harness) AdmitV1beta1(path string, req *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	h.t.Helper()
+	return h.review("admission.k8s.io/v1beta1", path, req)
+}
+
+func (h *Harness) review(apiVersion, path string, req *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	h.t.Helper()
+	req = req.DeepCopy()
+	if req.UID == "" {
+		req.UID = h.nextUID()
+	}
+	review := &admissionv1.AdmissionReview{
+		TypeMeta: metav1.TypeMeta{APIVersion: apiVersion, Kind: "AdmissionReview"},
+		Request:  req,
+	}
+
+	var got admissionv1.AdmissionReview
+	if status := h.Post(path, review, &got); status != http.StatusOK {
+		h.t.Fatalf("AdmissionReview status = %d, wanted %d", status, http.StatusOK)
+	}
+	if got.TypeMeta != review.TypeMeta {
+		h.t.Errorf("AdmissionReview type = %v, wanted %v", got.TypeMeta, review.TypeMeta)
+	}
+	if got.Response == nil {
+		h.t.Fatal("AdmissionReview has no response")
+	}
+	if got.Response.UID != req.UID {
+		h.t.Errorf("AdmissionResponse UID = %q, wanted %q", got.Response.UID, req.UID)
+	}
+	return got.Response
+}
+
+// Convert sends an apiextensions.k8s.io/v1 ConversionReview of the request
+// to the path, and returns the response.
+func (h *Harness) Convert(path string, req *apixv1.ConversionRequest) *apixv1.ConversionResponse {
+	h.t.Helper()
+	return h.convert(apixv1.SchemeGroupVersion.String(), path, req)
+}
+
+// ConvertV1beta1 sends an apiextensions.k8s.io/v1beta1 ConversionReview of
+// the request to the path, and returns the response.
+func (h *Harness) ConvertV1beta1(path string, req *apixv1.ConversionRequest) *apixv1.ConversionResponse {
+	h.t.Helper()
+	return h.convert("apiextensions.k8s.io/v1beta1", path, req)
+}
+
+func (h *Harness) convert(apiVersion, path string, req *apixv1.ConversionRequest) *apixv1.ConversionResponse {
+	h.t.Helper()
+	req = req.DeepCopy()
+	if req.UID == "" {
+		req.UID = h.nextUID()
+	}
+	review := &apixv1.ConversionReview{
+		TypeMeta: metav1.TypeMeta{APIVersion: apiVersion, Kind: "ConversionReview"},
+		Request:  req,
+	}
+
+	var got apixv1.ConversionReview
+	if status := h.Post(path, review, &got); status != http.StatusOK {
+		h.t.Fatalf("ConversionReview status = %d, wanted %d", status, http.StatusOK)
+	}
+	if got.TypeMeta != review.TypeMeta {
+		h.t.Errorf("ConversionReview type = %v, wanted %v", got.TypeMeta, review.TypeMeta)
+	}
+	if got.Response == nil {
+		h.t.Fatal("ConversionReview has no response")
+	}
+	return got.Response
+}
+
+// ReconcileAll promotes the reconcilers of the controllers that are
+// *controller.Impl to leaders, and reconciles the keys they enqueue, e.g. to
+// reconcile their webhook configurations in the fake clients.
+func (h *Harness) ReconcileAll() {
+	h.t.Helper()
+	for _, c := range h.controllers {
+		impl, ok := c.(*controller.Impl)
+		if !ok {
+			continue
+		}
+		var keys []types.NamespacedName
+		if la, ok := impl.Reconciler.(pkgreconciler.LeaderAware); ok {
+			if err := la.Promote(pkgreconciler.UniversalBucket(), func(_ pkgreconciler.Bucket, key types.NamespacedName) {
+				keys = append(keys, key)
+			}); err != nil {
+				h.t.Fatal("Promote() =", err)
+			}
+		}
+		for _, key := range keys {
+			k := key.Name
+			if key.Namespace != "" {
+				k = key.String()
+			}
+			if err := impl.Reconciler.Reconcile(h.Context, k); err != nil {
+				h.t.Fatalf("Reconcile(%q) = %v", k, err)
+			}
+		}
+	}
+}
+
+// ExpectNoWarnings checks that a given admission response has no warnings.
+func ExpectNoWarnings(t *testing.T, resp *admissionv1.AdmissionResponse) {
+	t.Helper()
+	if len(resp.Warnings) != 0 {
+		t.Error("Expected no warnings, got", resp.Warnings)
+	}
+}
+
+// ExpectDeniedWithCode checks that a given admission response disallows the
+// initiating request with the provided HTTP status code, and contains the
+// provided string in its error message.
+func ExpectDeniedWithCode(t *testing.T, resp *admissionv1.AdmissionResponse, code int32, contains string) {
+	t.Helper()
+	ExpectFailsWith(t, resp, contains)
+	if resp.Result == nil || resp.Result.Code != code {
+		t.Errorf("Expected denial with code %d, got %+v", code, resp.Result)
+	}
+}
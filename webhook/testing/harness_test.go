@@ -0,0 +1,216 @@
+/*
+Copyright 2023 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package testing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	jsonpatch "gomodules.xyz/jsonpatch/v2"
+	admissionv1 "k8s.io/api/admission/v1"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	apixv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	fakekubeclient "github.com/Yangfisher1/knative-common-pkg/client/injection/kube/client/fake"
+	"github.com/Yangfisher1/knative-common-pkg/configmap"
+	"github.com/Yangfisher1/knative-common-pkg/system"
+	"github.com/Yangfisher1/knative-common-pkg/webhook"
+	"github.com/Yangfisher1/knative-common-pkg/webhook/configmaps"
+
+	_ "github.com/Yangfisher1/knative-common-pkg/client/injection/kube/informers/admissionregistration/v1/validatingwebhookconfiguration/fake"
+)
+
+type patchingAdmissionController struct{}
+
+func (patchingAdmissionController) Path() string {
+	return "/patch"
+}
+
+func (patchingAdmissionController) Admit(_ context.Context, req *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	if req.Operation == admissionv1.Delete {
+		return webhook.MakeErrorStatus("deletes are denied")
+	}
+	patch, _ := json.Marshal([]jsonpatch.JsonPatchOperation{{
+		Operation: "add",
+		Path:      "/metadata/labels",
+		Value:     map[string]interface{}{"patched": "true"},
+	}})
+	pt := admissionv1.PatchTypeJSONPatch
+	return &admissionv1.AdmissionResponse{
+		Allowed:   true,
+		Patch:     patch,
+		PatchType: &pt,
+		Warnings:  []string{"patched"},
+	}
+}
+
+type echoConversionController struct{}
+
+func (echoConversionController) Path() string {
+	return "/convert"
+}
+
+func (echoConversionController) Convert(_ context.Context, req *apixv1.ConversionRequest) *apixv1.ConversionResponse {
+	return &apixv1.ConversionResponse{
+		UID:              req.UID,
+		ConvertedObjects: req.Objects,
+		Result:           metav1.Status{Status: metav1.StatusSuccess},
+	}
+}
+
+func TestHarnessAdmit(t *testing.T) {
+	h := NewHarness(t, HarnessOptions{}, func(context.Context) []interface{} {
+		return []interface{}{patchingAdmissionController{}, echoConversionController{}}
+	})
+
+	for name, admit := range map[string]func(string, *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse{
+		"v1":      h.Admit,
+		"v1beta1": h.AdmitV1beta1,
+	} {
+		t.Run(name, func(t *testing.T) {
+			resp := admit("/patch", &admissionv1.AdmissionRequest{Operation: admissionv1.Create})
+			ExpectAllowed(t, resp)
+			ExpectWarnsWith(t, resp, "patched")
+			ExpectPatches(t, resp.Patch, []jsonpatch.JsonPatchOperation{{
+				Operation: "add",
+				Path:      "/metadata/labels",
+				Value:     map[string]interface{}{"patched": "true"},
+			}})
+
+			resp = admit("/patch", &admissionv1.AdmissionRequest{Operation: admissionv1.Delete})
+			ExpectDeniedWithCode(t, resp, http.StatusBadRequest, "deletes are denied")
+			ExpectNoWarnings(t, resp)
+		})
+	}
+
+	if status := h.Post("/unknown", &admissionv1.AdmissionReview{}, nil); status != http.StatusBadRequest {
+		t.Errorf("Status of an unknown path = %d, wanted %d", status, http.StatusBadRequest)
+	}
+}
+
+func TestHarnessConvert(t *testing.T) {
+	h := NewHarness(t, HarnessOptions{}, func(context.Context) []interface{} {
+		return []interface{}{echoConversionController{}}
+	})
+
+	objects := []runtime.RawExtension{{Raw: []byte(`{"apiVersion":"v1","kind":"Pod"}`)}}
+	for name, convert := range map[string]func(string, *apixv1.ConversionRequest) *apixv1.ConversionResponse{
+		"v1":      h.Convert,
+		"v1beta1": h.ConvertV1beta1,
+	} {
+		t.Run(name, func(t *testing.T) {
+			resp := convert("/convert", &apixv1.ConversionRequest{
+				DesiredAPIVersion: "v1",
+				Objects:           objects,
+			})
+			if resp.Result.Status != metav1.StatusSuccess || len(resp.ConvertedObjects) != 1 {
+				t.Errorf("Convert() = %+v, wanted the objects back", resp)
+			}
+		})
+	}
+}
+
+// blockingAdmissionController depends on informers.
+type blockingAdmissionController struct {
+	patchingAdmissionController
+}
+
+func TestHarnessUnsynced(t *testing.T) {
+	h := NewHarness(t, HarnessOptions{Unsynced: true}, func(context.Context) []interface{} {
+		return []interface{}{blockingAdmissionController{}}
+	})
+
+	done := make(chan *admissionv1.AdmissionResponse, 1)
+	go func() {
+		// Fatal must not be called outside of the test goroutine, so use a
+		// request the controller allows.
+		done <- h.Admit("/patch", &admissionv1.AdmissionRequest{Operation: admissionv1.Create})
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("Admit completed before the informers synced")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	h.Webhook.InformersHaveSynced()
+	select {
+	case resp := <-done:
+		ExpectAllowed(t, resp)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Admit didn't complete after the informers synced")
+	}
+}
+
+func TestHarnessReconcileAll(t *testing.T) {
+	const name = "config.webhook.knative.dev"
+	vwh := &admissionregistrationv1.ValidatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Webhooks: []admissionregistrationv1.ValidatingWebhook{{
+			Name: name,
+			ClientConfig: admissionregistrationv1.WebhookClientConfig{
+				Service: &admissionregistrationv1.ServiceReference{
+					Namespace: system.Namespace(),
+					Name:      "webhook",
+				},
+			},
+		}},
+	}
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: system.Namespace()}}
+
+	h := NewHarness(t, HarnessOptions{Objects: []runtime.Object{vwh, ns}}, func(ctx context.Context) []interface{} {
+		return []interface{}{configmaps.NewAdmissionController(ctx, name, "/config", configmap.Constructors{
+			"config-test": func(cm *corev1.ConfigMap) (string, error) {
+				if cm.Data["valid"] != "true" {
+					return "", errors.New("not valid")
+				}
+				return "", nil
+			},
+		})}
+	})
+	h.ReconcileAll()
+
+	got, err := fakekubeclient.Get(h.Context).AdmissionregistrationV1().ValidatingWebhookConfigurations().Get(h.Context, name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal("Unable to get the webhook configuration:", err)
+	}
+	if cc := got.Webhooks[0].ClientConfig; len(cc.CABundle) == 0 || cc.Service.Path == nil || *cc.Service.Path != "/config" {
+		t.Errorf("ClientConfig = %+v, wanted the CA bundle and path", cc)
+	}
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: system.Namespace(), Name: "config-test"},
+		Data:       map[string]string{"valid": "false"},
+	}
+	b, err := json.Marshal(cm)
+	if err != nil {
+		t.Fatal("Unable to marshal the ConfigMap:", err)
+	}
+	resp := h.Admit("/config", &admissionv1.AdmissionRequest{
+		Operation: admissionv1.Create,
+		Kind:      metav1.GroupVersionKind{Version: "v1", Kind: "ConfigMap"},
+		Object:    runtime.RawExtension{Raw: b},
+	})
+	ExpectFailsWith(t, resp, "not valid")
+}
@@ -0,0 +1,294 @@
+/*
+Copyright 2023 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	corelisters "k8s.io/client-go/listers/core/v1"
+)
+
+const (
+	// TLSMinVersionKey is the TLSPolicy ConfigMap key holding the minimum
+	// TLS version, "1.2" or "1.3".
+	TLSMinVersionKey = "min-version"
+
+	// TLSCipherSuitesKey is the TLSPolicy ConfigMap key holding the comma
+	// separated names of the TLS 1.2 cipher suites, e.g.
+	// "TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256".
+	TLSCipherSuitesKey = "cipher-suites"
+
+	// TLSCurvePreferencesKey is the TLSPolicy ConfigMap key holding the comma
+	// separated names of the elliptic curves, among "X25519", "P-256",
+	// "P-384" and "P-521".
+	TLSCurvePreferencesKey = "curve-preferences"
+
+	// TLSClientCAsKey is the TLSPolicy ConfigMap key holding the PEM bundle
+	// of the CAs that client certificates must be signed by.
+	TLSClientCAsKey = "client-ca-bundle"
+
+	// TLSAllowedClientNamesKey is the TLSPolicy ConfigMap key holding the
+	// comma separated names that client certificates must be issued to.
+	TLSAllowedClientNamesKey = "allowed-client-names"
+)
+
+var (
+	tlsVersions = map[string]uint16{
+		"1.2": tls.VersionTLS12,
+		"1.3": tls.VersionTLS13,
+	}
+
+	tlsCurves = map[string]tls.CurveID{
+		"X25519": tls.X25519,
+		"P-256":  tls.CurveP256,
+		"P-384":  tls.CurveP384,
+		"P-521":  tls.CurveP521,
+	}
+)
+
+// TLSPolicy configures the TLS connections that the webhook accepts.
+type TLSPolicy struct {
+	// MinVersion is the minimum TLS version accepted.  Defaults to TLS 1.2.
+	MinVersion uint16
+
+	// CipherSuites are the TLS 1.2 cipher suites accepted, TLS 1.3 cipher
+	// suites are not configurable.  Defaults to Go's.
+	CipherSuites []uint16
+
+	// CurvePreferences are the elliptic curves accepted, in order of
+	// preference.  Defaults to Go's.
+	CurvePreferences []tls.CurveID
+
+	// ClientCAs is a PEM bundle of CAs.  If set, admission and conversion
+	// requests must present a client certificate signed by one of them, e.g.
+	// the API server's front proxy client certificate.  Kubelet probes
+	// remain exempt.
+	ClientCAs []byte
+
+	// AllowedClientNames restricts the accepted client certificates to
+	// those whose common name or DNS names are listed.  It only applies
+	// with ClientCAs.
+	AllowedClientNames []string
+}
+
+// NewTLSPolicyFromConfigMap creates a TLSPolicy from the supplied ConfigMap.
+func NewTLSPolicyFromConfigMap(cm *corev1.ConfigMap) (*TLSPolicy, error) {
+	return parseTLSPolicy(TLSPolicy{}, cm)
+}
+
+// parseTLSPolicy overrides the fields of base with those set in the ConfigMap.
+func parseTLSPolicy(base TLSPolicy, cm *corev1.ConfigMap) (*TLSPolicy, error) {
+	p := base
+	if v, ok := cm.Data[TLSMinVersionKey]; ok {
+		version, ok := tlsVersions[strings.TrimSpace(v)]
+		if !ok {
+			return nil, fmt.Errorf("invalid %s %q, must be 1.2 or 1.3", TLSMinVersionKey, v)
+		}
+		p.MinVersion = version
+	}
+	if v, ok := cm.Data[TLSCipherSuitesKey]; ok {
+		suites := make(map[string]uint16)
+		for _, s := range tls.CipherSuites() {
+			suites[s.Name] = s.ID
+		}
+		p.CipherSuites = nil
+		for _, name := range splitList(v) {
+			id, ok := suites[name]
+			if !ok {
+				return nil, fmt.Errorf("invalid %s: unknown or insecure cipher suite %q", TLSCipherSuitesKey, name)
+			}
+			p.CipherSuites = append(p.CipherSuites, id)
+		}
+	}
+	if v, ok := cm.Data[TLSCurvePreferencesKey]; ok {
+		p.CurvePreferences = nil
+		for _, name := range splitList(v) {
+			id, ok := tlsCurves[name]
+			if !ok {
+				return nil, fmt.Errorf("invalid %s: unknown curve %q", TLSCurvePreferencesKey, name)
+			}
+			p.CurvePreferences = append(p.CurvePreferences, id)
+		}
+	}
+	if v, ok := cm.Data[TLSClientCAsKey]; ok {
+		p.ClientCAs = []byte(v)
+	}
+	if v, ok := cm.Data[TLSAllowedClientNamesKey]; ok {
+		p.AllowedClientNames = splitList(v)
+	}
+	if _, err := p.tlsConfig(nil); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func splitList(s string) []string {
+	var list []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// tlsConfig returns the tls.Config enforcing the policy.
+func (p *TLSPolicy) tlsConfig(getCertificate func(*tls.ClientHelloInfo) (*tls.Certificate, error)) (*tls.Config, error) {
+	cfg := &tls.Config{
+		MinVersion:       p.MinVersion,
+		CipherSuites:     p.CipherSuites,
+		CurvePreferences: p.CurvePreferences,
+		GetCertificate:   getCertificate,
+	}
+	if cfg.MinVersion == 0 {
+		cfg.MinVersion = tls.VersionTLS12
+	}
+	if cfg.MinVersion < tls.VersionTLS12 {
+		return nil, errors.New("the minimum TLS version may not be below 1.2")
+	}
+	if len(p.ClientCAs) > 0 {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(p.ClientCAs) {
+			return nil, errors.New("the client CA bundle holds no valid PEM certificate")
+		}
+		cfg.ClientCAs = pool
+		// Client certificates are verified if given, and then required by
+		// verifyClient, so that kubelet probes can do without.
+		cfg.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	return cfg, nil
+}
+
+// verifyClient checks that the request's client certificate is allowed by
+// the policy.  The certificate itself is verified during the handshake.
+func (p *TLSPolicy) verifyClient(r *http.Request) error {
+	if len(p.ClientCAs) == 0 {
+		return nil
+	}
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return errors.New("a client certificate is required")
+	}
+	if len(p.AllowedClientNames) == 0 {
+		return nil
+	}
+	cert := r.TLS.VerifiedChains[0][0]
+	for _, allowed := range p.AllowedClientNames {
+		if cert.Subject.CommonName == allowed {
+			return nil
+		}
+		for _, name := range cert.DNSNames {
+			if name == allowed {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("client certificate %q is not allowed", cert.Subject.CommonName)
+}
+
+// tlsPolicyCache serves the TLS configuration of the webhook's TLSPolicy,
+// reloading it from its ConfigMap, if any, when it changes.
+type tlsPolicyCache struct {
+	logger *zap.SugaredLogger
+	// lister and name locate the ConfigMap, if any.
+	lister corelisters.ConfigMapNamespaceLister
+	name   string
+
+	base           TLSPolicy
+	baseConfig     *tls.Config
+	getCertificate func(*tls.ClientHelloInfo) (*tls.Certificate, error)
+
+	cached atomic.Pointer[cachedTLSPolicy]
+}
+
+type cachedTLSPolicy struct {
+	resourceVersion string
+	policy          *TLSPolicy
+	config          *tls.Config
+}
+
+func newTLSPolicyCache(logger *zap.SugaredLogger, base TLSPolicy, getCertificate func(*tls.ClientHelloInfo) (*tls.Certificate, error)) (*tlsPolicyCache, error) {
+	cfg, err := base.tlsConfig(getCertificate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid TLS policy: %w", err)
+	}
+	return &tlsPolicyCache{
+		logger:         logger,
+		base:           base,
+		baseConfig:     cfg,
+		getCertificate: getCertificate,
+	}, nil
+}
+
+// current returns the policy in effect and its TLS configuration: that of
+// the ConfigMap, or the base policy once it is deleted.  The last good policy
+// is kept while the ConfigMap is invalid or cannot be fetched.
+func (c *tlsPolicyCache) current() (*TLSPolicy, *tls.Config) {
+	if c.lister == nil {
+		return &c.base, c.baseConfig
+	}
+	cm, err := c.lister.Get(c.name)
+	if apierrs.IsNotFound(err) {
+		if prev := c.cached.Swap(nil); prev != nil {
+			c.logger.Warnw("The TLS policy ConfigMap was deleted, reverting to the base policy", zap.String("name", c.name))
+		}
+		return &c.base, c.baseConfig
+	} else if err != nil {
+		if prev := c.cached.Load(); prev != nil {
+			c.logger.Errorw("Failed to fetch the TLS policy, keeping the previous one", zap.Error(err))
+			return prev.policy, prev.config
+		}
+		c.logger.Errorw("Failed to fetch the TLS policy", zap.Error(err))
+		return &c.base, c.baseConfig
+	}
+
+	// Objects without a resourceVersion (e.g. in tests) are never cached,
+	// since there is no telling when they change.
+	prev := c.cached.Load()
+	if prev != nil && prev.resourceVersion != "" && prev.resourceVersion == cm.ResourceVersion {
+		return prev.policy, prev.config
+	}
+
+	next := &cachedTLSPolicy{resourceVersion: cm.ResourceVersion}
+	if policy, err := parseTLSPolicy(c.base, cm); err != nil {
+		// Keep the last good policy, and don't parse this version again.
+		c.logger.Errorw("Invalid TLS policy, keeping the previous one", zap.Error(err))
+		next.policy, next.config = &c.base, c.baseConfig
+		if prev != nil {
+			next.policy, next.config = prev.policy, prev.config
+		}
+	} else {
+		next.policy = policy
+		next.config, _ = policy.tlsConfig(c.getCertificate)
+	}
+	c.cached.Store(next)
+	return next.policy, next.config
+}
+
+// GetConfigForClient implements tls.Config.GetConfigForClient.
+func (c *tlsPolicyCache) GetConfigForClient(*tls.ClientHelloInfo) (*tls.Config, error) {
+	_, cfg := c.current()
+	return cfg, nil
+}
@@ -0,0 +1,253 @@
+/*
+Copyright 2023 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+
+	logtesting "github.com/Yangfisher1/knative-common-pkg/logging/testing"
+	certresources "github.com/Yangfisher1/knative-common-pkg/webhook/certificates/resources"
+)
+
+func testCABundle(t *testing.T) []byte {
+	t.Helper()
+	secret, err := certresources.MakeSecret(logtesting.TestContextWithLogger(t), "certs", "ns", "webhook")
+	if err != nil {
+		t.Fatal("MakeSecret() =", err)
+	}
+	return secret.Data[certresources.CACert]
+}
+
+func TestNewTLSPolicyFromConfigMap(t *testing.T) {
+	caBundle := testCABundle(t)
+
+	tests := []struct {
+		name    string
+		data    map[string]string
+		want    *TLSPolicy
+		wantErr bool
+	}{{
+		name: "empty",
+		want: &TLSPolicy{},
+	}, {
+		name: "all keys",
+		data: map[string]string{
+			TLSMinVersionKey:         "1.3",
+			TLSCipherSuitesKey:       "TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256, TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256",
+			TLSCurvePreferencesKey:   "X25519,P-256",
+			TLSClientCAsKey:          string(caBundle),
+			TLSAllowedClientNamesKey: "front-proxy-client, ",
+		},
+		want: &TLSPolicy{
+			MinVersion: tls.VersionTLS13,
+			CipherSuites: []uint16{
+				tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+				tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+			},
+			CurvePreferences:   []tls.CurveID{tls.X25519, tls.CurveP256},
+			ClientCAs:          caBundle,
+			AllowedClientNames: []string{"front-proxy-client"},
+		},
+	}, {
+		name:    "old version",
+		data:    map[string]string{TLSMinVersionKey: "1.1"},
+		wantErr: true,
+	}, {
+		name:    "insecure cipher suite",
+		data:    map[string]string{TLSCipherSuitesKey: "TLS_RSA_WITH_RC4_128_SHA"},
+		wantErr: true,
+	}, {
+		name:    "unknown curve",
+		data:    map[string]string{TLSCurvePreferencesKey: "P-224"},
+		wantErr: true,
+	}, {
+		name:    "invalid client CAs",
+		data:    map[string]string{TLSClientCAsKey: "not PEM"},
+		wantErr: true,
+	}}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := NewTLSPolicyFromConfigMap(&corev1.ConfigMap{Data: tc.data})
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("NewTLSPolicyFromConfigMap() = %v, wantErr %v", err, tc.wantErr)
+			}
+			if !cmp.Equal(got, tc.want) {
+				t.Error("NewTLSPolicyFromConfigMap (-want, +got):", cmp.Diff(tc.want, got))
+			}
+		})
+	}
+}
+
+func TestTLSPolicyCache(t *testing.T) {
+	const ns, name = "ns", "config-webhook-tls"
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+
+	base := TLSPolicy{CurvePreferences: []tls.CurveID{tls.CurveP384}}
+	c, err := newTLSPolicyCache(logtesting.TestLogger(t), base, nil)
+	if err != nil {
+		t.Fatal("newTLSPolicyCache() =", err)
+	}
+	c.lister = corelisters.NewConfigMapLister(indexer).ConfigMaps(ns)
+	c.name = name
+
+	// Without the ConfigMap, the base policy applies.
+	cfg, err := c.GetConfigForClient(nil)
+	if err != nil || cfg != c.baseConfig {
+		t.Fatalf("GetConfigForClient() = %v, %v, wanted the base config", cfg, err)
+	}
+	if cfg.MinVersion != tls.VersionTLS12 {
+		t.Errorf("MinVersion = %x, wanted TLS 1.2 by default", cfg.MinVersion)
+	}
+
+	indexer.Add(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: name, ResourceVersion: "1"},
+		Data:       map[string]string{TLSMinVersionKey: "1.3"},
+	})
+	first, err := c.GetConfigForClient(nil)
+	if err != nil {
+		t.Fatal("GetConfigForClient() =", err)
+	}
+	if first.MinVersion != tls.VersionTLS13 {
+		t.Errorf("MinVersion = %x, wanted TLS 1.3 from the ConfigMap", first.MinVersion)
+	}
+	if !cmp.Equal(first.CurvePreferences, base.CurvePreferences) {
+		t.Errorf("CurvePreferences = %v, wanted the base %v", first.CurvePreferences, base.CurvePreferences)
+	}
+	if second, _ := c.GetConfigForClient(nil); second != first {
+		t.Error("GetConfigForClient() was not cached while the ConfigMap is unchanged")
+	}
+
+	// An invalid update keeps the last good policy.
+	indexer.Update(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: name, ResourceVersion: "2"},
+		Data:       map[string]string{TLSMinVersionKey: "1.0"},
+	})
+	if got, _ := c.GetConfigForClient(nil); got != first {
+		t.Error("GetConfigForClient() did not keep the last good config")
+	}
+
+	// Failing to fetch the ConfigMap keeps the last good policy.
+	lister := c.lister
+	c.lister = failingConfigMapLister{}
+	if got, _ := c.GetConfigForClient(nil); got != first {
+		t.Error("GetConfigForClient() did not keep the last good config when failing to fetch the ConfigMap")
+	}
+
+	// Deleting the ConfigMap reverts to the base policy.
+	c.lister = lister
+	indexer.Delete(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: name},
+	})
+	if got, _ := c.GetConfigForClient(nil); got != c.baseConfig {
+		t.Error("GetConfigForClient() did not revert to the base config once the ConfigMap was deleted")
+	}
+	c.lister = failingConfigMapLister{}
+	if got, _ := c.GetConfigForClient(nil); got != c.baseConfig {
+		t.Error("GetConfigForClient() did not keep the base config when failing to fetch the ConfigMap")
+	}
+}
+
+// failingConfigMapLister fails to list and get ConfigMaps.
+type failingConfigMapLister struct{}
+
+var errListerFailed = errors.New("lister failed")
+
+func (failingConfigMapLister) List(labels.Selector) ([]*corev1.ConfigMap, error) {
+	return nil, errListerFailed
+}
+
+func (failingConfigMapLister) Get(string) (*corev1.ConfigMap, error) {
+	return nil, errListerFailed
+}
+
+func TestVerifyClient(t *testing.T) {
+	caBundle := testCABundle(t)
+	verified := func(cn string, dnsNames ...string) *http.Request {
+		cert := &x509.Certificate{Subject: pkix.Name{CommonName: cn}, DNSNames: dnsNames}
+		return &http.Request{TLS: &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}}
+	}
+
+	tests := []struct {
+		name    string
+		policy  TLSPolicy
+		req     *http.Request
+		wantErr bool
+	}{{
+		name: "no client CAs",
+		req:  &http.Request{},
+	}, {
+		name:    "missing client certificate",
+		policy:  TLSPolicy{ClientCAs: caBundle},
+		req:     &http.Request{TLS: &tls.ConnectionState{}},
+		wantErr: true,
+	}, {
+		name:   "any verified client certificate",
+		policy: TLSPolicy{ClientCAs: caBundle},
+		req:    verified("anyone"),
+	}, {
+		name:   "allowed common name",
+		policy: TLSPolicy{ClientCAs: caBundle, AllowedClientNames: []string{"front-proxy-client"}},
+		req:    verified("front-proxy-client"),
+	}, {
+		name:   "allowed DNS name",
+		policy: TLSPolicy{ClientCAs: caBundle, AllowedClientNames: []string{"apiserver.example.com"}},
+		req:    verified("apiserver", "apiserver.example.com"),
+	}, {
+		name:    "disallowed name",
+		policy:  TLSPolicy{ClientCAs: caBundle, AllowedClientNames: []string{"front-proxy-client"}},
+		req:     verified("someone-else"),
+		wantErr: true,
+	}}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := tc.policy.verifyClient(tc.req); (err != nil) != tc.wantErr {
+				t.Errorf("verifyClient() = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestServeHTTPRequiresClientCertificate(t *testing.T) {
+	policies, err := newTLSPolicyCache(logtesting.TestLogger(t), TLSPolicy{ClientCAs: testCABundle(t)}, nil)
+	if err != nil {
+		t.Fatal("newTLSPolicyCache() =", err)
+	}
+	wh := &Webhook{tlsPolicies: policies}
+
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	wh.ServeHTTP(rec, req)
+	if got, want := rec.Code, http.StatusForbidden; got != want {
+		t.Errorf("ServeHTTP() status = %d, want %d", got, want)
+	}
+}
@@ -22,7 +22,9 @@ import (
 	"errors"
 	"fmt"
 	"html"
+	"log"
 	"net/http"
+	"sync/atomic"
 	"time"
 
 	// Injection stuff
@@ -56,6 +58,26 @@ type Options struct {
 	// only a single port for the service.
 	Port int
 
+	// HealthPort, if set, is where the liveness (LivenessPath) and
+	// readiness (ReadinessPath) probes are served over plain HTTP, rather
+	// than through Port.  Readiness fails until the informers have synced,
+	// and once the webhook starts draining.
+	HealthPort int
+
+	// DebugPort, if set, is where pprof profiles (under /debug/pprof/) and
+	// Prometheus metrics (under /metrics) are served over plain HTTP.
+	DebugPort int
+
+	// MaxRequestBodyBytes limits the size of admission and conversion
+	// requests.  Defaults to DefaultMaxRequestBodyBytes.
+	MaxRequestBodyBytes int64
+
+	// ReadTimeout and WriteTimeout are the timeouts for reading requests
+	// and writing responses on Port.  Default to DefaultReadTimeout and
+	// DefaultWriteTimeout.
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+
 	// StatsReporter reports metrics about the webhook.
 	// This will be automatically initialized by the constructor if left uninitialized.
 	StatsReporter StatsReporter
@@ -63,6 +85,59 @@ type Options struct {
 	// GracePeriod is how long to wait after failing readiness probes
 	// before shutting down.
 	GracePeriod time.Duration
+
+	// CALifetime is how long the generated CA certificate is valid.
+	// Defaults to one year.
+	CALifetime time.Duration
+
+	// CARenewBefore is how long before its expiry the CA is rotated.
+	// The previous CA stays in the CA bundle until it expires, so this is
+	// also how long both are trusted.  It must be less than CALifetime, and
+	// exceed ServerCertLifetime so that server certificates signed by the
+	// previous CA remain trusted.  Defaults to 30 days.
+	CARenewBefore time.Duration
+
+	// ServerCertLifetime is how long the generated server certificate is
+	// valid.  Defaults to one week.
+	ServerCertLifetime time.Duration
+
+	// ServerCertRenewBefore is how long before its expiry the server
+	// certificate is renewed, and must be less than ServerCertLifetime.
+	// Defaults to one day.  With externally managed certificates, it is how
+	// long before its expiry a warning is raised instead.
+	ServerCertRenewBefore time.Duration
+
+	// CASecretName names the secret, in the system namespace, holding the
+	// private key of the CA signing the generated server certificates.
+	// Defaults to SecretName with a "-ca" suffix.
+	CASecretName string
+
+	// ExternallyManagedCertificates indicates that the secret named by
+	// SecretName is issued by an external certificate manager (e.g.
+	// cert-manager) with the tls.crt, tls.key and ca.crt keys of secrets
+	// of type kubernetes.io/tls.  The secret is then never written, only
+	// watched for changes and checked for expiry, the problems found being
+	// exported once certificates.RegisterMetrics has registered their views.
+	ExternallyManagedCertificates bool
+
+	// TLSPolicy configures the TLS versions, cipher suites and curves
+	// accepted, and optionally the client certificates required.
+	TLSPolicy TLSPolicy
+
+	// TLSPolicyConfigMapName names a ConfigMap in the system namespace
+	// whose keys (see NewTLSPolicyFromConfigMap) override those of
+	// TLSPolicy, and which is reloaded without a restart.  Its informer must
+	// be linked in, e.g. from the namespacedkube configmap informer package.
+	TLSPolicyConfigMapName string
+
+	// Audit configures the structured audit log of admission requests,
+	// written in the background and flushed when the webhook shuts down.
+	// If nil, admission requests are not audited.
+	Audit *AuditOptions
+
+	// ResponseCache configures the cache of admission responses, shared by
+	// the admission controllers.  If nil, admission responses are not cached.
+	ResponseCache *ResponseCacheOptions
 }
 
 // Operation is the verb being operated on
@@ -86,10 +161,22 @@ type Webhook struct {
 	// synced is function that is called when the informers have been synced.
 	synced context.CancelFunc
 
+	// informersSynced is closed once synced has been called.
+	informersSynced <-chan struct{}
+
+	// draining is set once the webhook starts shutting down.
+	draining atomic.Bool
+
 	mux http.ServeMux
 
 	// The TLS configuration to use for serving (or nil for non-TLS)
 	tlsConfig *tls.Config
+
+	// tlsPolicies holds the TLS policy in effect (or nil for non-TLS)
+	tlsPolicies *tlsPolicyCache
+
+	// audit queues the admission audit records (or nil without auditing)
+	audit *auditor
 }
 
 // New constructs a Webhook
@@ -109,6 +196,9 @@ func New(
 	if opts == nil {
 		return nil, errors.New("context must have Options specified")
 	}
+	if err := opts.validateCertificateLifetimes(); err != nil {
+		return nil, err
+	}
 	logger := logging.FromContext(ctx)
 
 	if opts.StatsReporter == nil {
@@ -122,9 +212,10 @@ func New(
 	syncCtx, cancel := context.WithCancel(context.Background())
 
 	webhook = &Webhook{
-		Options: *opts,
-		Logger:  logger,
-		synced:  cancel,
+		Options:         *opts,
+		Logger:          logger,
+		synced:          cancel,
+		informersSynced: syncCtx.Done(),
 	}
 
 	if opts.SecretName != "" {
@@ -135,47 +226,43 @@ func New(
 		// a new secret informer from it.
 		secretInformer := kubeinformerfactory.Get(ctx).Core().V1().Secrets()
 
-		webhook.tlsConfig = &tls.Config{
-			MinVersion: tls.VersionTLS12,
-
-			// If we return (nil, error) the client sees - 'tls: internal error"
-			// If we return (nil, nil) the client sees - 'tls: no certificates configured'
-			//
-			// We'll return (nil, nil) when we don't find a certificate
-			GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
-				secret, err := secretInformer.Lister().Secrets(system.Namespace()).Get(opts.SecretName)
-				if err != nil {
-					logger.Errorw("failed to fetch secret", zap.Error(err))
-					return nil, nil
-				}
-
-				serverKey, ok := secret.Data[certresources.ServerKey]
-				if !ok {
-					logger.Warn("server key missing")
-					return nil, nil
-				}
-				serverCert, ok := secret.Data[certresources.ServerCert]
-				if !ok {
-					logger.Warn("server cert missing")
-					return nil, nil
-				}
-				cert, err := tls.X509KeyPair(serverCert, serverKey)
-				if err != nil {
-					return nil, err
-				}
-				return &cert, nil
-			},
+		certs := &certificateCache{
+			logger:  logger,
+			lister:  secretInformer.Lister().Secrets(system.Namespace()),
+			name:    opts.SecretName,
+			keyName: certresources.ServerKey,
+			crtName: certresources.ServerCert,
 		}
+		if opts.ExternallyManagedCertificates {
+			certs.keyName, certs.crtName = certresources.TLSKey, certresources.TLSCert
+		}
+		policies, err := newTLSPolicyCache(logger, opts.TLSPolicy, certs.GetCertificate)
+		if err != nil {
+			return nil, err
+		}
+		webhook.tlsConfig = policies.baseConfig.Clone()
+		if opts.TLSPolicyConfigMapName != "" {
+			// As for the secret informer above, this relies on the shared
+			// informer factory rather than forcing consumers to have
+			// ConfigMap access.
+			cmInformer := kubeinformerfactory.Get(ctx).Core().V1().ConfigMaps()
+			policies.lister = cmInformer.Lister().ConfigMaps(system.Namespace())
+			policies.name = opts.TLSPolicyConfigMapName
+			webhook.tlsConfig.GetConfigForClient = policies.GetConfigForClient
+		}
+		webhook.tlsPolicies = policies
 	}
 
 	webhook.mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, fmt.Sprint("no controller registered for: ", html.EscapeString(r.URL.Path)), http.StatusBadRequest)
 	})
 
+	webhook.audit = newAuditor(logger, opts.Audit)
+	cache := newResponseCache(opts.ResponseCache)
 	for _, controller := range controllers {
 		switch c := controller.(type) {
 		case AdmissionController:
-			handler := admissionHandler(logger, opts.StatsReporter, c, syncCtx.Done())
+			handler := admissionHandler(logger, opts.StatsReporter, webhook.audit, cache, c, syncCtx.Done())
 			webhook.mux.Handle(c.Path(), handler)
 
 		case ConversionController:
@@ -191,6 +278,39 @@ func New(
 	return
 }
 
+// validateCertificateLifetimes checks that the generated certificates are
+// renewed before they expire, and that the CA is rotated before the server
+// certificates it signed expire, the defaults filling in unset durations.
+// Otherwise freshly generated certificates would be due for renewal, and
+// renewed on every reconciliation.
+func (o *Options) validateCertificateLifetimes() error {
+	if o.SecretName == "" || o.ExternallyManagedCertificates {
+		return nil
+	}
+	caLifetime := durationOrDefault(o.CALifetime, certresources.DefaultCALifetime)
+	caRenewBefore := durationOrDefault(o.CARenewBefore, certresources.DefaultCARenewBefore)
+	serverCertLifetime := durationOrDefault(o.ServerCertLifetime, certresources.DefaultServerCertLifetime)
+	serverCertRenewBefore := durationOrDefault(o.ServerCertRenewBefore, certresources.DefaultServerCertRenewBefore)
+
+	switch {
+	case caRenewBefore >= caLifetime:
+		return fmt.Errorf("CARenewBefore (%v) must be less than CALifetime (%v)", caRenewBefore, caLifetime)
+	case serverCertRenewBefore >= serverCertLifetime:
+		return fmt.Errorf("ServerCertRenewBefore (%v) must be less than ServerCertLifetime (%v)",
+			serverCertRenewBefore, serverCertLifetime)
+	case caRenewBefore <= serverCertLifetime:
+		return fmt.Errorf("CARenewBefore (%v) must exceed ServerCertLifetime (%v)", caRenewBefore, serverCertLifetime)
+	}
+	return nil
+}
+
+func durationOrDefault(d, def time.Duration) time.Duration {
+	if d <= 0 {
+		return def
+	}
+	return d
+}
+
 // InformersHaveSynced is called when the informers have all been synced, which allows any outstanding
 // admission webhooks through.
 func (wh *Webhook) InformersHaveSynced() {
@@ -203,15 +323,47 @@ func (wh *Webhook) Run(stop <-chan struct{}) error {
 	logger := wh.Logger
 	ctx := logging.WithLogger(context.Background(), logger)
 
+	maxBytes := wh.Options.MaxRequestBodyBytes
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxRequestBodyBytes
+	}
+	readTimeout, writeTimeout := wh.Options.ReadTimeout, wh.Options.WriteTimeout
+	if readTimeout <= 0 {
+		readTimeout = DefaultReadTimeout
+	}
+	if writeTimeout <= 0 {
+		writeTimeout = DefaultWriteTimeout
+	}
+
 	drainer := &handlers.Drainer{
-		Inner:       wh,
+		Inner:       limitRequestBody(maxBytes, wh),
 		QuietPeriod: wh.Options.GracePeriod,
 	}
 
 	server := &http.Server{
-		Handler:   drainer,
-		Addr:      fmt.Sprint(":", wh.Options.Port),
-		TLSConfig: wh.tlsConfig,
+		Handler:      drainer,
+		Addr:         fmt.Sprint(":", wh.Options.Port),
+		TLSConfig:    wh.tlsConfig,
+		ReadTimeout:  readTimeout,
+		WriteTimeout: writeTimeout,
+		ErrorLog:     log.New(serverErrorLog{logger: logger}, "", 0),
+	}
+
+	// The health and debug listeners are plain HTTP.
+	var auxServers []*http.Server
+	if wh.Options.HealthPort != 0 {
+		auxServers = append(auxServers, &http.Server{
+			Handler:           wh.healthHandler(),
+			Addr:              fmt.Sprint(":", wh.Options.HealthPort),
+			ReadHeaderTimeout: readTimeout,
+		})
+	}
+	if wh.Options.DebugPort != 0 {
+		auxServers = append(auxServers, &http.Server{
+			Handler:           debugHandler(logger),
+			Addr:              fmt.Sprint(":", wh.Options.DebugPort),
+			ReadHeaderTimeout: readTimeout,
+		})
 	}
 
 	eg, ctx := errgroup.WithContext(ctx)
@@ -229,6 +381,16 @@ func (wh *Webhook) Run(stop <-chan struct{}) error {
 		}
 		return nil
 	})
+	for _, s := range auxServers {
+		s := s
+		eg.Go(func() error {
+			if err := s.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				logger.Errorw("ListenAndServe for "+s.Addr+" returned error", zap.Error(err))
+				return err
+			}
+			return nil
+		})
+	}
 
 	select {
 	case <-stop:
@@ -238,20 +400,61 @@ func (wh *Webhook) Run(stop <-chan struct{}) error {
 
 			// Start failing readiness probes immediately.
 			logger.Info("Starting to fail readiness probes...")
+			wh.draining.Store(true)
 			drainer.Drain()
 
-			return server.Shutdown(context.Background())
+			err := shutdown(logger, drainer.QuietPeriod, append(auxServers, server))
+
+			// No more requests are audited once the servers are down, so
+			// write the queued records within another grace period.
+			flushCtx, cancel := context.WithTimeout(context.Background(), drainer.QuietPeriod)
+			defer cancel()
+			if err := wh.audit.flush(flushCtx); err != nil {
+				logger.Warnw("Dropping the admission audit records left in the queue", zap.Error(err))
+			}
+			return err
 		})
 
 		// Wait for all outstanding go routined to terminate, including our new one.
 		return eg.Wait()
 
 	case <-ctx.Done():
+		for _, s := range auxServers {
+			s.Close()
+		}
 		return fmt.Errorf("webhook server bootstrap failed %w", ctx.Err())
 	}
 }
 
+// shutdown shuts the servers down concurrently, closing those that still have
+// active connections after the grace period.
+func shutdown(logger *zap.SugaredLogger, gracePeriod time.Duration, servers []*http.Server) error {
+	ctx, cancel := context.WithTimeout(context.Background(), gracePeriod)
+	defer cancel()
+
+	var eg errgroup.Group
+	for _, s := range servers {
+		s := s
+		eg.Go(func() error {
+			if err := s.Shutdown(ctx); err != nil {
+				logger.Warnw("Closing "+s.Addr+" after failing to shut it down gracefully", zap.Error(err))
+				return s.Close()
+			}
+			return nil
+		})
+	}
+	return eg.Wait()
+}
+
 func (wh *Webhook) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if wh.tlsPolicies != nil {
+		policy, _ := wh.tlsPolicies.current()
+		if err := policy.verifyClient(r); err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+	}
+
 	// Verify the content type is accurate.
 	contentType := r.Header.Get("Content-Type")
 	if contentType != "application/json" {
@@ -93,3 +93,67 @@ func TestRegistrationStopChanFire(t *testing.T) {
 		t.Error("Unexpected success to dial to port", opts.Port)
 	}
 }
+
+func TestValidateCertificateLifetimes(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    Options
+		wantErr string
+	}{{
+		name: "defaults",
+		opts: Options{SecretName: "webhook-certs"},
+	}, {
+		name: "no secret",
+		opts: Options{CARenewBefore: 2 * 365 * 24 * time.Hour},
+	}, {
+		name: "externally managed",
+		opts: Options{
+			SecretName:                    "webhook-certs",
+			ServerCertRenewBefore:         30 * 24 * time.Hour,
+			ExternallyManagedCertificates: true,
+		},
+	}, {
+		name: "CA renewed when generated",
+		opts: Options{
+			SecretName:    "webhook-certs",
+			CALifetime:    24 * time.Hour,
+			CARenewBefore: 24 * time.Hour,
+		},
+		wantErr: "CARenewBefore (24h0m0s) must be less than CALifetime (24h0m0s)",
+	}, {
+		name: "server certificate renewed when generated",
+		opts: Options{
+			SecretName:            "webhook-certs",
+			ServerCertRenewBefore: 8 * 24 * time.Hour,
+		},
+		wantErr: "ServerCertRenewBefore (192h0m0s) must be less than ServerCertLifetime (168h0m0s)",
+	}, {
+		name: "CA expires before the server certificates it signed",
+		opts: Options{
+			SecretName:         "webhook-certs",
+			ServerCertLifetime: 60 * 24 * time.Hour,
+		},
+		wantErr: "CARenewBefore (720h0m0s) must exceed ServerCertLifetime (1440h0m0s)",
+	}}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.opts.validateCertificateLifetimes()
+			if got := fmt.Sprint(err); (err != nil || tc.wantErr != "") && got != tc.wantErr {
+				t.Errorf("validateCertificateLifetimes() = %v, wanted %q", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewInvalidCertificateLifetimes(t *testing.T) {
+	ctx, _ := SetupFakeContext(t)
+	opts := newDefaultOptions()
+	// Shorter than the default CARenewBefore.
+	opts.CALifetime = time.Hour
+	ctx = WithOptions(ctx, opts)
+
+	if _, err := New(ctx, nil); err == nil {
+		t.Error("New() succeeded with a CA renewed when generated")
+	}
+}